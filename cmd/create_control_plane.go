@@ -4,14 +4,20 @@ Copyright © 2023 Threeport admin@threeport.io
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"github.com/threeport/tptctl/internal/config"
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	"github.com/threeport/tptctl/internal/install"
+	"github.com/threeport/tptctl/internal/kubernetes"
 	qout "github.com/threeport/tptctl/internal/output"
 	"github.com/threeport/tptctl/internal/provider"
 )
@@ -21,10 +27,44 @@ var (
 	createRootDomain            string
 	createProviderAccountID     string
 	createAdminEmail            string
+	createAWSRegion             string
+	createAWSProfile            string
+	createKubernetesVersion     string
+	createAWSInstanceTypes      []string
+	createMinNodes              int32
+	createMaxNodes              int32
+	createDesiredNodes          int32
+	createSpotInstances         bool
+	createResourceTags          map[string]string
+	createForwardProxyNamespace string
+	createForwardProxyReplicas  int
+	createForwardProxyImage     string
+	createForwardProxyResources map[string]string
 	forceOverwriteConfig        bool
+	resumeCreate                bool
 	infraProvider               string
 )
 
+// createSettingsFlags are the flags for the settings of a new control plane.
+// They are recorded when creation starts and cannot be changed on resume.
+var createSettingsFlags = []string{
+	"root-domain",
+	"provider-account-id",
+	"admin-email",
+	"aws-region",
+	"kubernetes-version",
+	"aws-instance-types",
+	"min-nodes",
+	"max-nodes",
+	"desired-nodes",
+	"spot-instances",
+	"aws-tags",
+	"forward-proxy-namespace",
+	"forward-proxy-replicas",
+	"forward-proxy-operator-image",
+	"forward-proxy-operator-manager-resources",
+}
+
 // CreateControlPlaneCmd represents the create threeport command
 var CreateControlPlaneCmd = &cobra.Command{
 	Use:          "control-plane",
@@ -32,27 +72,27 @@ var CreateControlPlaneCmd = &cobra.Command{
 	Short:        "Create a new instance of the Threeport control plane",
 	Long:         `Create a new instance of the Threeport control plane.`,
 	SilenceUsage: true,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		// get threeport config
 		threeportConfig := &config.ThreeportConfig{}
 		if err := viper.Unmarshal(threeportConfig); err != nil {
-			qout.Error("Failed to get Threeport config", err)
-			os.Exit(1)
+			return fail("Failed to get Threeport config", tperrors.ConfigError(err))
 		}
 
 		// check threeport config for exisiting instance
 		threeportInstanceConfigExists := false
+		var existingInstance config.Instance
 		for _, instance := range threeportConfig.Instances {
 			if instance.Name == createThreeportInstanceName {
 				threeportInstanceConfigExists = true
-				if !forceOverwriteConfig {
-					qout.Error(
-						"Interupted creation of Threeport instance",
-						errors.New(fmt.Sprintf("instance of Threeport with name %s already exists", instance.Name)),
-					)
+				existingInstance = instance
+				if !forceOverwriteConfig && !resumeCreate {
+					err := tperrors.Conflict(errors.New(fmt.Sprintf("instance of Threeport with name %s already exists", instance.Name)))
+					qout.Error("Interupted creation of Threeport instance", err)
 					qout.Info("If you wish to overwrite the existing config use --force-overwrite-config flag")
+					qout.Info("If a previous creation of this instance was interrupted use --resume flag to continue it")
 					qout.Warning("You will lose the ability to connect to the existing Threeport instance if it still exists")
-					os.Exit(1)
+					return failReported(err)
 				}
 			}
 		}
@@ -62,9 +102,19 @@ var CreateControlPlaneCmd = &cobra.Command{
 			infraProvider,
 			createRootDomain,
 			createProviderAccountID,
+			resumeCreate,
 		); err != nil {
-			qout.Error("Flag validation failed", err)
-			os.Exit(1)
+			return fail("Flag validation failed", tperrors.ConfigError(err))
+		}
+
+		// a resumed creation uses the settings recorded when it started
+		if resumeCreate {
+			for _, name := range createSettingsFlags {
+				if cmd.Flags().Changed(name) {
+					return fail("Flag validation failed", tperrors.ConfigError(errors.New(fmt.Sprintf(
+						"--%s cannot be used with --resume - the settings of the interrupted creation are used", name))))
+				}
+			}
 		}
 
 		// the control plane object provides the config for installing on the
@@ -76,6 +126,38 @@ var CreateControlPlaneCmd = &cobra.Command{
 			controlPlane.ProviderAccountID = createProviderAccountID
 			controlPlane.AdminEmail = createAdminEmail
 		}
+		controlPlane.AWSRegion = createAWSRegion
+		controlPlane.AWSProfile = createAWSProfile
+		controlPlane.KubernetesVersion = createKubernetesVersion
+		controlPlane.AWSInstanceTypes = createAWSInstanceTypes
+		controlPlane.MinClusterNodes = createMinNodes
+		controlPlane.MaxClusterNodes = createMaxNodes
+		controlPlane.DesiredClusterNodes = createDesiredNodes
+		controlPlane.SpotInstances = createSpotInstances
+		controlPlane.ResourceTags = createResourceTags
+		controlPlane.ForwardProxy.Namespace = createForwardProxyNamespace
+		controlPlane.ForwardProxy.Replicas = createForwardProxyReplicas
+		controlPlane.ForwardProxy.OperatorImage = createForwardProxyImage
+		controlPlane.ForwardProxy.Resources = createForwardProxyResources
+		if resumeCreate {
+			// keep the superuser credentials from the interrupted creation
+			controlPlane.Superuser.Email = existingInstance.UserEmail
+			controlPlane.Superuser.Password = existingInstance.UserPassword
+		}
+		if err := controlPlane.ForwardProxy.Validate(); err != nil {
+			return fail("Forward proxy config validation failed", tperrors.ConfigError(err))
+		}
+
+		// validate EKS cluster config before any calls to AWS
+		if infraProvider == "eks" {
+			if err := controlPlane.ValidateEKSConfig(); err != nil {
+				return fail("EKS cluster config validation failed", tperrors.ConfigError(err))
+			}
+		}
+
+		// stop cleanly if the user interrupts tptctl
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
 
 		// determine infra provider and create control plane
 		var controlPlaneErr error
@@ -83,29 +165,34 @@ var CreateControlPlaneCmd = &cobra.Command{
 		switch infraProvider {
 		case "kind":
 			if err := controlPlane.CreateControlPlaneOnKind(providerConfigDir); err != nil {
-				controlPlaneErr = fmt.Errorf("failed to install control plane on kind: %w", err)
-				threeportAPIEndpoint = fmt.Sprintf("%s://%s:%s",
-					provider.KindThreeportAPIProtocol, provider.KindThreeportAPIHostname,
-					provider.KindThreeportAPIPort)
+				controlPlaneErr = tperrors.ProviderError(fmt.Errorf("failed to install control plane on kind: %w", err))
 			}
+			threeportAPIEndpoint = fmt.Sprintf("%s://%s:%s",
+				provider.KindThreeportAPIProtocol, provider.KindThreeportAPIHostname,
+				provider.KindThreeportAPIPort)
 		case "eks":
-			tpapiEndpoint, err := controlPlane.CreateControlPlaneOnEKS(providerConfigDir)
+			tpapiEndpoint, err := controlPlane.CreateControlPlaneOnEKS(ctx, providerConfigDir, resumeCreate)
 			if err != nil {
-				controlPlaneErr = fmt.Errorf("failed to install control plane on EKS: %w", err)
+				controlPlaneErr = tperrors.ProviderError(fmt.Errorf("failed to install control plane on EKS: %w", err))
 			}
 			threeportAPIEndpoint = tpapiEndpoint
 		default:
-			qout.Error("Unrecognized infra provider",
-				errors.New(fmt.Sprintf("infra provider %s not supported", infraProvider)))
-			os.Exit(1)
+			return fail("Unrecognized infra provider",
+				tperrors.New(tperrors.KindConfig, fmt.Sprintf("infra provider %s not supported", infraProvider)))
 		}
 
 		// create threeport config for new instance
 		newThreeportInstance := &config.Instance{
-			Name:      createThreeportInstanceName,
-			Provider:  infraProvider,
-			APIServer: threeportAPIEndpoint,
+			Name:       createThreeportInstanceName,
+			Provider:   infraProvider,
+			APIServer:  threeportAPIEndpoint,
+			RootDomain: controlPlane.RootDomainName,
+			AWSProfile: createAWSProfile,
 			//APIServer: install.GetThreeportAPIEndpoint(),
+			UserID:       controlPlane.Superuser.ID,
+			UserEmail:    controlPlane.Superuser.Email,
+			UserPassword: controlPlane.Superuser.Password,
+			ForwardProxy: &controlPlane.ForwardProxy,
 		}
 
 		// update threeport config to add the new instance and set as current instance
@@ -120,14 +207,19 @@ var CreateControlPlaneCmd = &cobra.Command{
 		}
 		viper.Set("Instances", threeportConfig.Instances)
 		viper.Set("CurrentInstance", createThreeportInstanceName)
-		viper.WriteConfig()
+		if err := writeConfig(); err != nil {
+			return fail("Failed to write Threeport config", tperrors.ConfigError(err))
+		}
 		qout.Info("Threeport config updated")
 
+		// the config is written even if installation failed so that creation
+		// can be resumed or the control plane deleted
 		if controlPlaneErr != nil {
-			qout.Error("Problem encountered installing control plane", controlPlaneErr)
-		} else {
-			qout.Complete(fmt.Sprintf("Threeport instance %s created", createThreeportInstanceName))
+			return fail("Problem encountered installing control plane", controlPlaneErr)
 		}
+		qout.Complete(fmt.Sprintf("Threeport instance %s created", createThreeportInstanceName))
+
+		return nil
 	},
 }
 
@@ -141,6 +233,9 @@ func init() {
 	CreateControlPlaneCmd.Flags().BoolVar(
 		&forceOverwriteConfig, "force-overwrite-config", false,
 		"force the overwrite of an existing Threeport instance config.  Warning: this will erase the connection info for the existing instance.  Only do this if the existing instance has already been deleted and is no longer in use.")
+	CreateControlPlaneCmd.Flags().BoolVar(
+		&resumeCreate, "resume", false,
+		"resume a previous creation of the control plane that was interrupted, continuing from the first incomplete step with the settings it was started with.  Only supported for the eks provider.")
 	CreateControlPlaneCmd.Flags().StringVarP(&createRootDomain,
 		"root-domain", "d", "",
 		"the root domain name to use for the Threeport API. Requires a public hosted zone in AWS Route53. A subdomain for the Threeport API will be added to the root domain.")
@@ -150,10 +245,55 @@ func init() {
 	CreateControlPlaneCmd.Flags().StringVarP(&createAdminEmail,
 		"admin-email", "e", "",
 		"email address of control plane admin.  Provided to TLS provider.")
+	CreateControlPlaneCmd.Flags().StringVar(&createAWSRegion,
+		"aws-region", "",
+		"the AWS region to create the control plane cluster in.  Default is the region for the AWS profile or AWS_REGION.  Only applies to the eks provider.")
+	CreateControlPlaneCmd.Flags().StringVar(&createAWSProfile,
+		"aws-profile", "",
+		"the AWS config profile to use.  Default is the AWS default profile.  Only applies to the eks provider.")
+	CreateControlPlaneCmd.Flags().StringVar(&createKubernetesVersion,
+		"kubernetes-version", kubernetes.KubernetesVersion,
+		fmt.Sprintf("the Kubernetes version for the control plane cluster - one of %s.  Only applies to the eks provider.",
+			kubernetes.SupportedKubernetesVersions))
+	CreateControlPlaneCmd.Flags().StringSliceVar(&createAWSInstanceTypes,
+		"aws-instance-types", []string{provider.NewControlPlane().DefaultAWSInstanceType},
+		"comma separated list of AWS instance types for control plane cluster nodes.  Only applies to the eks provider.")
+	CreateControlPlaneCmd.Flags().Int32Var(&createMinNodes,
+		"min-nodes", provider.NewControlPlane().MinClusterNodes,
+		"the minimum number of nodes in the control plane cluster.  Only applies to the eks provider.")
+	CreateControlPlaneCmd.Flags().Int32Var(&createMaxNodes,
+		"max-nodes", provider.NewControlPlane().MaxClusterNodes,
+		"the maximum number of nodes in the control plane cluster.  Only applies to the eks provider.")
+	CreateControlPlaneCmd.Flags().Int32Var(&createDesiredNodes,
+		"desired-nodes", provider.NewControlPlane().DesiredClusterNodes,
+		"the number of nodes to start the control plane cluster with.  Only applies to the eks provider.")
+	CreateControlPlaneCmd.Flags().BoolVar(&createSpotInstances,
+		"spot-instances", false,
+		"use spot instances rather than on-demand instances for control plane cluster nodes.  Only applies to the eks provider.")
+	CreateControlPlaneCmd.Flags().StringToStringVar(&createResourceTags,
+		"aws-tags", map[string]string{},
+		"additional tags to add to AWS resources, e.g. owner=platform,env=dev.  Only applies to the eks provider.")
+	CreateControlPlaneCmd.Flags().StringVar(&createForwardProxyNamespace,
+		"forward-proxy-namespace", install.ForwardProxyDefaultNamespace,
+		"the namespace to run the forward proxy servers in on workload clusters.")
+	CreateControlPlaneCmd.Flags().IntVar(&createForwardProxyReplicas,
+		"forward-proxy-replicas", install.ForwardProxyDefaultReplicas,
+		"the number of forward proxy server replicas to run on workload clusters.")
+	CreateControlPlaneCmd.Flags().StringVar(&createForwardProxyImage,
+		"forward-proxy-operator-image", install.FowardProxyOperatorImage,
+		"the container image for the forward proxy operator.")
+	CreateControlPlaneCmd.Flags().StringToStringVar(&createForwardProxyResources,
+		"forward-proxy-operator-manager-resources", map[string]string{},
+		"resource requests and limits for the forward proxy operator's manager container, e.g. requests.cpu=50m,limits.memory=256Mi.  Keys are requests.cpu, requests.memory, limits.cpu and limits.memory.  The proxy servers the operator runs use the operator's defaults.")
 }
 
 // validateCreateControlPlaneFlags validates flag inputs as needed
-func validateCreateControlPlaneFlags(infraProvider, createRootDomain, createProviderAccountID string) error {
+func validateCreateControlPlaneFlags(
+	infraProvider string,
+	createRootDomain string,
+	createProviderAccountID string,
+	resumeCreate bool,
+) error {
 	allowedInfraProviders := []string{"kind", "eks"}
 	matched := false
 	for _, prov := range allowedInfraProviders {
@@ -172,5 +312,9 @@ func validateCreateControlPlaneFlags(infraProvider, createRootDomain, createProv
 			"if a root domain is provided for automated DNS management, your cloud provider account ID must also be provided. It is also recommended to provide an admin email, but not required.")
 	}
 
+	if resumeCreate && infraProvider != "eks" {
+		return errors.New(fmt.Sprintf("resuming control plane creation is not supported for provider '%s'", infraProvider))
+	}
+
 	return nil
 }
@@ -0,0 +1,51 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/threeport/tptctl/internal/api"
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+var createUserConfig api.UserConfig
+
+// CreateUserCmd represents the user command
+var CreateUserCmd = &cobra.Command{
+	Use:     "user",
+	Example: "tptctl create user --email dev@example.com --first-name Dana",
+	Short:   "Create a new user",
+	Long: `Create a new user.
+
+If no password is given, one is generated and shown once the user is created.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		generated := createUserConfig.Password == ""
+		user, err := createUserConfig.Create()
+		if err != nil {
+			return fail("failed to create user", err)
+		}
+		if generated {
+			qout.Secret(fmt.Sprintf("generated password for user %s", *user.Email), createUserConfig.Password)
+		}
+
+		qout.Complete(fmt.Sprintf("user %s created with ID %d\n", *user.Email, *user.ID))
+
+		return nil
+	},
+}
+
+func init() {
+	createCmd.AddCommand(CreateUserCmd)
+
+	CreateUserCmd.Flags().StringVar(&createUserConfig.Email, "email", "", "email address the user logs in with")
+	CreateUserCmd.MarkFlagRequired("email")
+	CreateUserCmd.Flags().StringVar(&createUserConfig.Password, "password", "", "password for the user - generated if not set")
+	CreateUserCmd.Flags().StringVar(&createUserConfig.FirstName, "first-name", "", "first name of the user")
+	CreateUserCmd.Flags().StringVar(&createUserConfig.LastName, "last-name", "", "last name of the user")
+	CreateUserCmd.Flags().BoolVar(&createUserConfig.Admin, "admin", false, "make the user an admin")
+}
@@ -6,16 +6,22 @@ package cmd
 import (
 	"fmt"
 	"io/ioutil"
-	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 
 	"github.com/threeport/tptctl/internal/api"
+	tperrors "github.com/threeport/tptctl/internal/errors"
 	qout "github.com/threeport/tptctl/internal/output"
 )
 
-var createWorkloadConfigPath string
+var (
+	createWorkloadConfigPath  string
+	createWorkloadWait        bool
+	createWorkloadWaitTimeout time.Duration
+)
 
 // CreateWorkloadCmd represents the workload command
 var CreateWorkloadCmd = &cobra.Command{
@@ -24,26 +30,39 @@ var CreateWorkloadCmd = &cobra.Command{
 	Short:        "Create a new workload",
 	Long:         `Create a new workload.`,
 	SilenceUsage: true,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		// load config
 		configContent, err := ioutil.ReadFile(createWorkloadConfigPath)
 		if err != nil {
-			qout.Error("failed to read config file", err)
-			os.Exit(1)
+			return fail("failed to read config file", tperrors.ConfigError(err))
 		}
 		var workloadConfig api.WorkloadConfig
 		if err := yaml.Unmarshal(configContent, &workloadConfig); err != nil {
-			qout.Error("failed to unmarshal config file yaml content", err)
-			os.Exit(1)
+			return fail("failed to unmarshal config file yaml content", tperrors.ConfigError(err))
 		}
+		workloadConfig.WorkloadDefinition.ConfigDir = filepath.Dir(createWorkloadConfigPath)
+		setDefaultUserID(&workloadConfig.WorkloadDefinition)
 
 		// create workload
 		if err := workloadConfig.Create(); err != nil {
-			qout.Error("failed to create workload", err)
-			os.Exit(1)
+			return fail("failed to create workload", err)
+		}
+
+		// wait for the workload instances to be ready
+		if createWorkloadWait && len(workloadConfig.WorkloadInstances) > 0 {
+			qout.Info(fmt.Sprintf("workload %s created - waiting for it to be ready", workloadConfig.Name))
+			for _, wi := range workloadConfig.WorkloadInstances {
+				if err := waitForWorkloadInstance(wi.Name, api.WaitConditionReady, createWorkloadWaitTimeout); err != nil {
+					return err
+				}
+			}
+			qout.Complete(fmt.Sprintf("workload %s created and ready\n", workloadConfig.Name))
+			return nil
 		}
 
 		qout.Complete(fmt.Sprintf("workload %s created\n", workloadConfig.Name))
+
+		return nil
 	},
 }
 
@@ -52,4 +71,7 @@ func init() {
 
 	CreateWorkloadCmd.Flags().StringVarP(&createWorkloadConfigPath, "config", "c", "", "path to file with workload config")
 	CreateWorkloadCmd.MarkFlagRequired("config")
+	CreateWorkloadCmd.Flags().BoolVar(&skipManifestValidation, "skip-validation", false, "submit manifests without validating them, e.g. ones with custom resources that can only be checked on the workload cluster - validation without a cluster does not catch missing required fields")
+	CreateWorkloadCmd.Flags().BoolVar(&createWorkloadWait, "wait", false, "wait for the workload instances to be ready")
+	CreateWorkloadCmd.Flags().DurationVar(&createWorkloadWaitTimeout, "wait-timeout", api.WaitTimeout, "how long to wait for each workload instance to be ready")
 }
@@ -0,0 +1,61 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/threeport/tptctl/internal/api"
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+var createWorkloadCluster api.WorkloadClusterConfig
+
+// CreateWorkloadClusterCmd represents the workload-cluster command
+var CreateWorkloadClusterCmd = &cobra.Command{
+	Use:     "workload-cluster",
+	Example: "tptctl create workload-cluster --name prod-01 --kubeconfig ~/.kube/config --context prod-01 --region us-east-1 --provider eks",
+	Short:   "Register an existing Kubernetes cluster as a workload cluster",
+	Long: `Register an existing Kubernetes cluster as a workload cluster.
+
+The endpoint and credentials for the cluster are taken from a kubeconfig
+context and stored in the Threeport API, so they must be long-lived.  Client
+certificate and token credentials are registered as they are.  Exec plugin
+credentials, e.g. from 'aws eks get-token', expire within minutes so they are
+used to create a service account for threeport, bound to the cluster-admin
+role, and its token is registered instead.  Once registered, workload instances
+can be deployed to the cluster by referencing it by name.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// create workload cluster
+		wc, err := createWorkloadCluster.Create()
+		if err != nil {
+			return fail("failed to create workload cluster", err)
+		}
+
+		qout.Complete(fmt.Sprintf("workload cluster %s created\n", *wc.Name))
+
+		return nil
+	},
+}
+
+func init() {
+	createCmd.AddCommand(CreateWorkloadClusterCmd)
+
+	CreateWorkloadClusterCmd.Flags().StringVarP(&createWorkloadCluster.Name,
+		"name", "n", "", "name of workload cluster")
+	CreateWorkloadClusterCmd.MarkFlagRequired("name")
+	CreateWorkloadClusterCmd.Flags().StringVar(&createWorkloadCluster.KubeconfigPath,
+		"kubeconfig", "", "path to kubeconfig with the cluster's credentials - default uses the standard kubeconfig loading rules")
+	CreateWorkloadClusterCmd.Flags().StringVar(&createWorkloadCluster.Context,
+		"context", "", "kubeconfig context for the cluster - default is the current context")
+	CreateWorkloadClusterCmd.Flags().StringVar(&createWorkloadCluster.Region,
+		"region", "", "region the cluster runs in")
+	CreateWorkloadClusterCmd.Flags().StringVar(&createWorkloadCluster.Provider,
+		"provider", "", "infrastructure provider the cluster runs on, e.g. eks or kind")
+	CreateWorkloadClusterCmd.Flags().StringVar(&createWorkloadCluster.APIEndpoint,
+		"api-endpoint", "", "endpoint the control plane uses to reach the cluster's Kubernetes API - default is the server in the kubeconfig")
+}
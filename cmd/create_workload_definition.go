@@ -6,12 +6,13 @@ package cmd
 import (
 	"fmt"
 	"io/ioutil"
-	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 
 	"github.com/threeport/tptctl/internal/api"
+	tperrors "github.com/threeport/tptctl/internal/errors"
 	qout "github.com/threeport/tptctl/internal/output"
 )
 
@@ -24,27 +25,28 @@ var CreateWorkloadDefinitionCmd = &cobra.Command{
 	Short:        "Create a new workload definition",
 	Long:         `Create a new workload definition.`,
 	SilenceUsage: true,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		// load config
 		configContent, err := ioutil.ReadFile(createWorkloadDefinitionConfigPath)
 		if err != nil {
-			qout.Error("failed to read config file", err)
-			os.Exit(1)
+			return fail("failed to read config file", tperrors.ConfigError(err))
 		}
 		var workloadDefinition api.WorkloadDefinitionConfig
 		if err := yaml.Unmarshal(configContent, &workloadDefinition); err != nil {
-			qout.Error("failed to unmarshal config file yaml content", err)
-			os.Exit(1)
+			return fail("failed to unmarshal config file yaml content", tperrors.ConfigError(err))
 		}
+		workloadDefinition.ConfigDir = filepath.Dir(createWorkloadDefinitionConfigPath)
+		setDefaultUserID(&workloadDefinition)
 
 		// create workload definition
 		wd, err := workloadDefinition.Create()
 		if err != nil {
-			qout.Error("failed to create workload definition", err)
-			os.Exit(1)
+			return fail("failed to create workload definition", err)
 		}
 
 		qout.Complete(fmt.Sprintf("workload definition %s created\n", *wd.Name))
+
+		return nil
 	},
 }
 
@@ -53,4 +55,5 @@ func init() {
 
 	CreateWorkloadDefinitionCmd.Flags().StringVarP(&createWorkloadDefinitionConfigPath, "config", "c", "", "path to file with workload definition config")
 	CreateWorkloadDefinitionCmd.MarkFlagRequired("config")
+	CreateWorkloadDefinitionCmd.Flags().BoolVar(&skipManifestValidation, "skip-validation", false, "submit manifests without validating them, e.g. ones with custom resources that can only be checked on the workload cluster - validation without a cluster does not catch missing required fields")
 }
@@ -6,16 +6,21 @@ package cmd
 import (
 	"fmt"
 	"io/ioutil"
-	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 
 	"github.com/threeport/tptctl/internal/api"
+	tperrors "github.com/threeport/tptctl/internal/errors"
 	qout "github.com/threeport/tptctl/internal/output"
 )
 
-var createWorkloadInstancePath string
+var (
+	createWorkloadInstancePath        string
+	createWorkloadInstanceWait        bool
+	createWorkloadInstanceWaitTimeout time.Duration
+)
 
 // CreateWorkloadInstanceCmd represents the workload-instance command
 var CreateWorkloadInstanceCmd = &cobra.Command{
@@ -24,27 +29,36 @@ var CreateWorkloadInstanceCmd = &cobra.Command{
 	Short:        "Create a new workload instance",
 	Long:         `Create a new workload instance.`,
 	SilenceUsage: true,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		// load config
 		configContent, err := ioutil.ReadFile(createWorkloadInstancePath)
 		if err != nil {
-			qout.Error("failed to read config file", err)
-			os.Exit(1)
+			return fail("failed to read config file", tperrors.ConfigError(err))
 		}
 		var workloadInstance api.WorkloadInstanceConfig
 		if err := yaml.Unmarshal(configContent, &workloadInstance); err != nil {
-			qout.Error("failed to unmarshal config file yaml content", err)
-			os.Exit(1)
+			return fail("failed to unmarshal config file yaml content", tperrors.ConfigError(err))
 		}
 
 		// create workload instance
 		wi, err := workloadInstance.Create()
 		if err != nil {
-			qout.Error("failed to create workload", err)
-			os.Exit(1)
+			return fail("failed to create workload", err)
+		}
+
+		// wait for the workload instance to be ready
+		if createWorkloadInstanceWait {
+			qout.Info(fmt.Sprintf("workload instance %s created - waiting for it to be ready", *wi.Name))
+			if err := waitForWorkloadInstance(*wi.Name, api.WaitConditionReady, createWorkloadInstanceWaitTimeout); err != nil {
+				return err
+			}
+			qout.Complete(fmt.Sprintf("workload instance %s created and ready\n", *wi.Name))
+			return nil
 		}
 
 		qout.Complete(fmt.Sprintf("workload instance %s created\n", *wi.Name))
+
+		return nil
 	},
 }
 
@@ -53,4 +67,7 @@ func init() {
 
 	CreateWorkloadInstanceCmd.Flags().StringVarP(&createWorkloadInstancePath, "config", "c", "", "path to file with workload instance config")
 	CreateWorkloadInstanceCmd.MarkFlagRequired("config")
+	CreateWorkloadInstanceCmd.Flags().BoolVar(&skipManifestValidation, "skip-validation", false, "submit manifests without validating them, e.g. ones with custom resources that can only be checked on the workload cluster - validation without a cluster does not catch missing required fields")
+	CreateWorkloadInstanceCmd.Flags().BoolVar(&createWorkloadInstanceWait, "wait", false, "wait for the workload instance to be ready")
+	CreateWorkloadInstanceCmd.Flags().DurationVar(&createWorkloadInstanceWaitTimeout, "wait-timeout", api.WaitTimeout, "how long to wait for the workload instance to be ready")
 }
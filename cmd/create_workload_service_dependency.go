@@ -4,18 +4,23 @@ Copyright © 2023 Threeport admin@threeport.io
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
-	"os"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 
 	"github.com/threeport/tptctl/internal/api"
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	kube "github.com/threeport/tptctl/internal/kubernetes"
 	qout "github.com/threeport/tptctl/internal/output"
 )
 
-var createWorkloadServiceDependencyConfigPath string
+var (
+	createWorkloadServiceDependencyConfigPath string
+	createWorkloadServiceDependencyProbe      bool
+)
 
 // CreateWorkloadServiceDependencyCmd represents the workload-service-dependency command
 var CreateWorkloadServiceDependencyCmd = &cobra.Command{
@@ -24,27 +29,35 @@ var CreateWorkloadServiceDependencyCmd = &cobra.Command{
 	Short:        "Create a new workload service dependency",
 	Long:         `Create a new workload service dependency.`,
 	SilenceUsage: true,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		// load config
 		configContent, err := ioutil.ReadFile(createWorkloadServiceDependencyConfigPath)
 		if err != nil {
-			qout.Error("failed to read config file", err)
-			os.Exit(1)
+			return fail("failed to read config file", tperrors.ConfigError(err))
 		}
 		var workloadServiceDependency api.WorkloadServiceDependencyConfig
 		if err := yaml.Unmarshal(configContent, &workloadServiceDependency); err != nil {
-			qout.Error("failed to unmarshal config file yaml content", err)
-			os.Exit(1)
+			return fail("failed to unmarshal config file yaml content", tperrors.ConfigError(err))
 		}
 
 		// create workload service dependency
 		wsd, err := workloadServiceDependency.Create()
 		if err != nil {
-			qout.Error("failed to create workload", err)
-			os.Exit(1)
+			return fail("failed to create workload", err)
+		}
+
+		// check the upstream from the workload cluster
+		if createWorkloadServiceDependencyProbe {
+			if err := runProbe(*wsd.Name, func(ctx context.Context) (*kube.ProbeResult, error) {
+				return workloadServiceDependency.Probe(ctx, defaultProbeOptions())
+			}); err != nil {
+				return err
+			}
 		}
 
 		qout.Complete(fmt.Sprintf("workload service dependency %s created\n", *wsd.Name))
+
+		return nil
 	},
 }
 
@@ -53,4 +66,5 @@ func init() {
 
 	CreateWorkloadServiceDependencyCmd.Flags().StringVarP(&createWorkloadServiceDependencyConfigPath, "config", "c", "", "path to file with workload service dependency config")
 	CreateWorkloadServiceDependencyCmd.MarkFlagRequired("config")
+	CreateWorkloadServiceDependencyCmd.Flags().BoolVar(&createWorkloadServiceDependencyProbe, "probe", false, "check the upstream resolves and answers through the forward proxy from the workload cluster")
 }
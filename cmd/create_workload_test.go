@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/threeport/tptctl/internal/api"
+	"github.com/threeport/tptctl/internal/fakeapi"
+)
+
+const testWorkloadConfig = `Name: web
+WorkloadDefinition:
+  YAMLDocument: manifest.yaml
+WorkloadInstances:
+  - WorkloadClusterName: default
+WorkloadServiceDependencies:
+  - UpstreamHost: api.example.com
+    UpstreamPath: /v1
+`
+
+const testManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.25
+`
+
+// writeTestFiles writes files to a temporary directory and returns it.
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", name, err)
+		}
+	}
+
+	return dir
+}
+
+func TestCreateWorkload(t *testing.T) {
+	server, threeportConfigPath := newFakeAPI(t)
+	dir := writeTestFiles(t, map[string]string{
+		"workload.yaml": testWorkloadConfig,
+		"manifest.yaml": testManifest,
+	})
+
+	if err := runCommand(t, threeportConfigPath, "create", "workload", "-c", filepath.Join(dir, "workload.yaml")); err != nil {
+		t.Fatalf("failed to create workload: %s", err)
+	}
+	for path, expected := range map[string]int{
+		fakeapi.WorkloadDefinitions:         1,
+		fakeapi.WorkloadInstances:           1,
+		fakeapi.WorkloadServiceDependencies: 1,
+	} {
+		if count := server.Count(path); count != expected {
+			t.Errorf("expected %d %s, got %d", expected, path, count)
+		}
+	}
+
+	// the definition is created for the user of the current threeport
+	// instance
+	workloadDefinition, err := api.NewClient(server.URL).GetWorkloadDefinitionByName("web-definition")
+	if err != nil {
+		t.Fatalf("failed to get workload definition: %s", err)
+	}
+	if workloadDefinition.UserID == nil || *workloadDefinition.UserID != 1 {
+		t.Errorf("expected workload definition for user 1, got %v", workloadDefinition.UserID)
+	}
+}
+
+func TestUpdateWorkloadServiceDependency(t *testing.T) {
+	server, threeportConfigPath := newFakeAPI(t)
+	dir := writeTestFiles(t, map[string]string{
+		"workload.yaml": testWorkloadConfig,
+		"manifest.yaml": testManifest,
+		"wsd.yaml": `Name: web-api-example-com-service-default
+UpstreamHost: api.example.com
+UpstreamPath: /v2
+WorkloadInstanceName: web-default-instance
+`,
+	})
+	if err := runCommand(t, threeportConfigPath, "create", "workload", "-c", filepath.Join(dir, "workload.yaml")); err != nil {
+		t.Fatalf("failed to create workload: %s", err)
+	}
+
+	err := runCommand(t, threeportConfigPath, "update", "workload-service-dependency", "-c", filepath.Join(dir, "wsd.yaml"))
+	if err != nil {
+		t.Fatalf("failed to update workload service dependency: %s", err)
+	}
+	workloadServiceDependency, err := api.NewClient(server.URL).GetWorkloadServiceDependencyByName("web-api-example-com-service-default")
+	if err != nil {
+		t.Fatalf("failed to get workload service dependency: %s", err)
+	}
+	if workloadServiceDependency.UpstreamPath == nil || *workloadServiceDependency.UpstreamPath != "/v2" {
+		t.Errorf("expected upstream path /v2, got %v", workloadServiceDependency.UpstreamPath)
+	}
+}
+
+func TestUpdateWorkload(t *testing.T) {
+	server, threeportConfigPath := newFakeAPI(t)
+	dir := writeTestFiles(t, map[string]string{
+		"workload.yaml": testWorkloadConfig,
+		"updated.yaml":  strings.Replace(testWorkloadConfig, "UpstreamPath: /v1", "UpstreamPath: /v2", 1),
+		"declined.yaml": strings.Replace(testWorkloadConfig, "UpstreamPath: /v1", "UpstreamPath: /v3", 1),
+		"manifest.yaml": testManifest,
+	})
+	if err := runCommand(t, threeportConfigPath, "create", "workload", "-c", filepath.Join(dir, "workload.yaml")); err != nil {
+		t.Fatalf("failed to create workload: %s", err)
+	}
+
+	testCases := []struct {
+		name             string
+		args             []string
+		wantUpstreamPath string
+	}{
+		{
+			name:             "confirmed with yes",
+			args:             []string{"-c", filepath.Join(dir, "updated.yaml"), "--yes"},
+			wantUpstreamPath: "/v2",
+		},
+		{
+			// --yes from the earlier run is not carried over and there is
+			// no answer on stdin
+			name:             "declined",
+			args:             []string{"-c", filepath.Join(dir, "declined.yaml")},
+			wantUpstreamPath: "/v2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := runCommand(t, threeportConfigPath, append([]string{"update", "workload"}, tc.args...)...); err != nil {
+				t.Fatalf("failed to update workload: %s", err)
+			}
+			workloadServiceDependency, err := api.NewClient(server.URL).GetWorkloadServiceDependencyByName("web-api-example-com-service-default")
+			if err != nil {
+				t.Fatalf("failed to get workload service dependency: %s", err)
+			}
+			if workloadServiceDependency.UpstreamPath == nil || *workloadServiceDependency.UpstreamPath != tc.wantUpstreamPath {
+				t.Errorf("expected upstream path %s, got %v", tc.wantUpstreamPath, workloadServiceDependency.UpstreamPath)
+			}
+		})
+	}
+}
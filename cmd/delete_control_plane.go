@@ -4,19 +4,25 @@ Copyright © 2023 Threeport admin@threeport.io
 package cmd
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"github.com/threeport/tptctl/internal/config"
+	tperrors "github.com/threeport/tptctl/internal/errors"
 	qout "github.com/threeport/tptctl/internal/output"
 	"github.com/threeport/tptctl/internal/provider"
 )
 
-var deleteThreeportInstanceName string
+var (
+	deleteThreeportInstanceName string
+	purgeOrphans                bool
+)
 
 // DeleteControlPlaneCmd represents the delete control-plane command
 var DeleteControlPlaneCmd = &cobra.Command{
@@ -25,12 +31,11 @@ var DeleteControlPlaneCmd = &cobra.Command{
 	Short:        "Delete an instance of the Threeport control plane",
 	Long:         `Delete an instance of the Threeport control plane.`,
 	SilenceUsage: true,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		// get threeport config
 		threeportConfig := &config.ThreeportConfig{}
 		if err := viper.Unmarshal(threeportConfig); err != nil {
-			qout.Error("Failed to get Threeport config", err)
-			os.Exit(1)
+			return fail("Failed to get Threeport config", tperrors.ConfigError(err))
 		}
 
 		// check threeport config for exisiting instance
@@ -44,32 +49,36 @@ var DeleteControlPlaneCmd = &cobra.Command{
 			}
 		}
 		if !threeportInstanceConfigExists {
-			qout.Error("Failed to find threeport instance config",
-				errors.New(fmt.Sprintf(
+			return fail("Failed to find threeport instance config",
+				tperrors.New(tperrors.KindNotFound, fmt.Sprintf(
 					"config for threeport instance with name %s not found", deleteThreeportInstanceName)))
-			os.Exit(1)
 		}
 
 		// the control plane object provides the config for installing on the
 		// provider
-		controlPlane := provider.ControlPlane{InstanceName: deleteThreeportInstanceName}
+		controlPlane := provider.ControlPlane{
+			InstanceName:   deleteThreeportInstanceName,
+			RootDomainName: instanceConfig.RootDomain,
+			AWSProfile:     instanceConfig.AWSProfile,
+		}
+
+		// stop cleanly if the user interrupts tptctl
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
 
 		// determine infra provider
 		switch instanceConfig.Provider {
 		case "kind":
 			if err := controlPlane.DeleteControlPlaneOnKind(); err != nil {
-				qout.Error("Failed to delete threeport control plane on kind", err)
-				os.Exit(1)
+				return fail("Failed to delete threeport control plane on kind", tperrors.ProviderError(err))
 			}
 		case "eks":
-			if err := controlPlane.DeleteControlPlaneOnEKS(providerConfigDir); err != nil {
-				qout.Error("Failed to delete threeport control plane on EKS", err)
-				os.Exit(1)
+			if err := controlPlane.DeleteControlPlaneOnEKS(ctx, providerConfigDir, purgeOrphans); err != nil {
+				return fail("Failed to delete threeport control plane on EKS", tperrors.ProviderError(err))
 			}
 		default:
-			qout.Error("Unrecognized infra provider",
-				errors.New(fmt.Sprintf("infra provider %s not supported", infraProvider)))
-			os.Exit(1)
+			return fail("Unrecognized infra provider",
+				tperrors.New(tperrors.KindConfig, fmt.Sprintf("infra provider %s not supported", instanceConfig.Provider)))
 		}
 
 		// update threeport config to remove the deleted threeport instance and
@@ -85,10 +94,14 @@ var DeleteControlPlaneCmd = &cobra.Command{
 
 		viper.Set("Instances", updatedInstances)
 		viper.Set("CurrentInstance", "")
-		viper.WriteConfig()
+		if err := writeConfig(); err != nil {
+			return fail("Failed to write Threeport config", tperrors.ConfigError(err))
+		}
 		qout.Info("Threeport config updated")
 
 		qout.Complete(fmt.Sprintf("Threeport instance %s deleted", deleteThreeportInstanceName))
+
+		return nil
 	},
 }
 
@@ -98,4 +111,7 @@ func init() {
 	DeleteControlPlaneCmd.Flags().StringVarP(&deleteThreeportInstanceName,
 		"name", "n", "", "name of control plane instance")
 	DeleteControlPlaneCmd.MarkFlagRequired("name")
+	DeleteControlPlaneCmd.Flags().BoolVar(&purgeOrphans,
+		"purge-orphans", false,
+		"delete any AWS resources that remain after the control plane is deleted.  Only applies to the eks provider.")
 }
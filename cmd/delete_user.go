@@ -0,0 +1,46 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/threeport/tptctl/internal/api"
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+// DeleteUserCmd represents the user command
+var DeleteUserCmd = &cobra.Command{
+	Use:     "user EMAIL",
+	Example: "tptctl delete user dev@example.com",
+	Short:   "Delete a user",
+	Long: `Delete a user.
+
+The user tptctl is logged in as for the current Threeport instance can't be
+deleted.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if instance, err := getCurrentInstance(); err == nil && instance.UserEmail == args[0] {
+			return fail("failed to delete user",
+				tperrors.New(tperrors.KindConflict, fmt.Sprintf("user %s is the identity for threeport instance %s", args[0], instance.Name)))
+		}
+
+		user, err := api.DeleteUser(args[0])
+		if err != nil {
+			return fail("failed to delete user", err)
+		}
+
+		qout.Complete(fmt.Sprintf("user %s deleted\n", *user.Email))
+
+		return nil
+	},
+}
+
+func init() {
+	deleteCmd.AddCommand(DeleteUserCmd)
+}
@@ -0,0 +1,55 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/threeport/tptctl/internal/api"
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+var deleteWorkloadInstanceTimeout time.Duration
+
+// DeleteWorkloadInstanceCmd represents the workload-instance command
+var DeleteWorkloadInstanceCmd = &cobra.Command{
+	Use:     "workload-instance NAME",
+	Example: "tptctl delete workload-instance web3-sample-app-prod",
+	Short:   "Delete a workload instance",
+	Long: `Delete a workload instance.
+
+An instance of a parameterised workload definition uses a definition rendered
+for it, named <definition>-<instance>.  The rendered definition is deleted once
+the workload controller has removed the instance, which is waited for up to
+the timeout.  The parameterised definition is left in place.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// stop waiting if the user interrupts tptctl
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		workloadInstance, err := api.DeleteWorkloadInstance(ctx, args[0], deleteWorkloadInstanceTimeout)
+		if err != nil {
+			return fail("failed to delete workload instance", err)
+		}
+
+		qout.Complete(fmt.Sprintf("workload instance %s deleted\n", *workloadInstance.Name))
+
+		return nil
+	},
+}
+
+func init() {
+	deleteCmd.AddCommand(DeleteWorkloadInstanceCmd)
+
+	DeleteWorkloadInstanceCmd.Flags().DurationVar(&deleteWorkloadInstanceTimeout, "timeout", api.WaitTimeout, "how long to wait for the workload instance to be removed before deleting its rendered definition")
+}
@@ -0,0 +1,22 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// eventsCmd represents the events command
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Get Kubernetes events for Threeport objects",
+	Long: `Get Kubernetes events for Threeport objects.
+
+The events command does nothing by itself.  Use one of the avilable subcommands
+to get the events for different objects.`,
+}
+
+func init() {
+	rootCmd.AddCommand(eventsCmd)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/duration"
+
+	"github.com/threeport/tptctl/internal/api"
+	kube "github.com/threeport/tptctl/internal/kubernetes"
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+// EventsWorkloadInstanceCmd represents the workload-instance command
+var EventsWorkloadInstanceCmd = &cobra.Command{
+	Use:     "workload-instance NAME",
+	Example: "tptctl events workload-instance web3-sample-app",
+	Short:   "Get the Kubernetes events for a workload instance",
+	Long: `Get the Kubernetes events for a workload instance.
+
+The events are retrieved from the instance's workload cluster for the resources
+in the workload definition and the pods that belong to them.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// get the workload cluster and resources for the instance
+		credentials, manifest, err := api.GetWorkloadInstanceResources(args[0])
+		if err != nil {
+			return fail("failed to get workload instance resources", err)
+		}
+
+		events, err := kube.GetWorkloadEvents(context.Background(), credentials, manifest)
+		if err != nil {
+			return fail(fmt.Sprintf("failed to get events for workload instance %s", args[0]), err)
+		}
+		if len(events) == 0 {
+			qout.Info(fmt.Sprintf("no events found for workload instance %s", args[0]))
+			return nil
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 4, 4, 4, ' ', 0)
+		fmt.Fprintln(writer, "LAST SEEN\tTYPE\tREASON\tOBJECT\tMESSAGE")
+		for _, event := range events {
+			lastSeen := kube.EventTime(&event)
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s/%s\t%s\n",
+				duration.HumanDuration(time.Since(lastSeen)),
+				event.Type,
+				event.Reason,
+				event.InvolvedObject.Kind,
+				event.InvolvedObject.Name,
+				event.Message,
+			)
+		}
+		writer.Flush()
+
+		return nil
+	},
+}
+
+func init() {
+	eventsCmd.AddCommand(EventsWorkloadInstanceCmd)
+}
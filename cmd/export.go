@@ -0,0 +1,52 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/threeport/tptctl/internal/api"
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+var (
+	exportOutputDir string
+	exportOverwrite bool
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export Threeport objects to config files",
+	Long: `Export Threeport objects to config files.
+
+The export command does nothing by itself.  Use one of the avilable subcommands
+to write the config files that would create existing objects again.`,
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.PersistentFlags().StringVarP(&exportOutputDir, "output", "o", ".", "directory to write the config files to")
+	exportCmd.PersistentFlags().BoolVar(&exportOverwrite, "overwrite", false, "replace files that already exist in the output directory")
+}
+
+// writeWorkloadExport writes the files for an exported workload to the output
+// directory and reports them along with any warnings.
+func writeWorkloadExport(workloadExport *api.WorkloadExport) error {
+	files, err := workloadExport.Write(exportOutputDir, exportOverwrite)
+	if err != nil {
+		return err
+	}
+	for _, warning := range workloadExport.Warnings {
+		qout.Warning(warning)
+	}
+	for _, file := range files {
+		qout.Info(fmt.Sprintf("wrote %s", file.Path))
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/threeport/tptctl/internal/api"
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+// ExportWorkloadCmd represents the export workload command
+var ExportWorkloadCmd = &cobra.Command{
+	Use:     "workload NAME",
+	Example: "tptctl export workload web3-sample-app -o ./web3-sample-app",
+	Short:   "Export a workload to config files",
+	Long: `Export a workload to config files.
+
+The workload definition with the name, or named <NAME>-definition, is exported
+along with all of its workload instances and their service dependencies.  Two
+files are written to the output directory: <NAME>-workload.yaml, a workload
+config that can be used with 'tptctl create workload' and 'tptctl update
+workload', and the YAML document for the definition that the config refers to
+with a relative path.
+
+A parameterised definition is exported as the template with its parameters and
+the values each instance was rendered with.  Definitions created from a Helm
+chart or Kustomize overlay are exported as the YAML document that was stored.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workloadExport, err := api.ExportWorkload(args[0])
+		if err != nil {
+			return fail("failed to export workload", err)
+		}
+		if err := writeWorkloadExport(workloadExport); err != nil {
+			return fail("failed to write workload config", err)
+		}
+
+		qout.Complete(fmt.Sprintf("workload %s exported to %s\n", args[0], exportOutputDir))
+
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.AddCommand(ExportWorkloadCmd)
+}
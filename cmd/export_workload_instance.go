@@ -0,0 +1,45 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/threeport/tptctl/internal/api"
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+// ExportWorkloadInstanceCmd represents the export workload-instance command
+var ExportWorkloadInstanceCmd = &cobra.Command{
+	Use:     "workload-instance NAME",
+	Example: "tptctl export workload-instance web3-sample-app-instance -o ./web3-sample-app",
+	Short:   "Export a workload instance and its objects to config files",
+	Long: `Export a workload instance and its objects to config files.
+
+The workload instance is exported with its workload definition and service
+dependencies as a workload config named <NAME>-workload.yaml and the YAML
+document for the definition.  If the definition is parameterised, the template
+is exported with the values the instance was rendered with.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workloadExport, err := api.ExportWorkloadInstance(args[0])
+		if err != nil {
+			return fail("failed to export workload instance", err)
+		}
+		if err := writeWorkloadExport(workloadExport); err != nil {
+			return fail("failed to write workload config", err)
+		}
+
+		qout.Complete(fmt.Sprintf("workload instance %s exported to %s\n", args[0], exportOutputDir))
+
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.AddCommand(ExportWorkloadInstanceCmd)
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	"github.com/threeport/tptctl/internal/fakeapi"
+)
+
+func TestExportWorkload(t *testing.T) {
+	server, threeportConfigPath := newFakeAPI(t)
+	dir := writeTestFiles(t, map[string]string{
+		"workload.yaml": testWorkloadConfig,
+		"manifest.yaml": testManifest,
+	})
+	if err := runCommand(t, threeportConfigPath, "create", "workload", "-c", filepath.Join(dir, "workload.yaml")); err != nil {
+		t.Fatalf("failed to create workload: %s", err)
+	}
+	exportDir := filepath.Join(t.TempDir(), "export")
+
+	testCases := []struct {
+		name         string
+		args         []string
+		wantExitCode int
+	}{
+		{
+			name: "new directory",
+			args: []string{"web", "-o", exportDir},
+		},
+		{
+			name:         "files exist",
+			args:         []string{"web", "-o", exportDir},
+			wantExitCode: tperrors.ExitCodeConflict,
+		},
+		{
+			name: "overwrite",
+			args: []string{"web", "-o", exportDir, "--overwrite"},
+		},
+		{
+			// --overwrite from the earlier run is not carried over
+			name:         "files exist after overwrite",
+			args:         []string{"web", "-o", exportDir},
+			wantExitCode: tperrors.ExitCodeConflict,
+		},
+		{
+			name:         "unknown workload",
+			args:         []string{"cache", "-o", exportDir},
+			wantExitCode: tperrors.ExitCodeNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := runCommand(t, threeportConfigPath, append([]string{"export", "workload"}, tc.args...)...)
+			if tc.wantExitCode == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			expectExitCode(t, err, tc.wantExitCode)
+		})
+	}
+
+	// the exported config updates the workload without changes
+	exportedConfigPath := filepath.Join(exportDir, "web-workload.yaml")
+	if _, err := os.Stat(exportedConfigPath); err != nil {
+		t.Fatalf("expected exported workload config: %s", err)
+	}
+	if err := runCommand(t, threeportConfigPath, "update", "workload", "-c", exportedConfigPath); err != nil {
+		t.Errorf("failed to update workload from exported config: %s", err)
+	}
+	for path, expected := range map[string]int{
+		fakeapi.WorkloadDefinitions:         1,
+		fakeapi.WorkloadInstances:           1,
+		fakeapi.WorkloadServiceDependencies: 1,
+	} {
+		if count := server.Count(path); count != expected {
+			t.Errorf("expected %d %s after update, got %d", expected, path, count)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// forwardProxyCmd represents the forward-proxy command
+var forwardProxyCmd = &cobra.Command{
+	Use:   "forward-proxy",
+	Short: "Inspect the forward proxy on workload clusters",
+	Long: `Inspect the forward proxy on workload clusters.
+
+The forward-proxy command does nothing by itself.  Use one of the avilable
+subcommands to inspect the forward proxy.`,
+}
+
+func init() {
+	rootCmd.AddCommand(forwardProxyCmd)
+}
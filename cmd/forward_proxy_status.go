@@ -0,0 +1,64 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/threeport/tptctl/internal/api"
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+// ForwardProxyStatusCmd represents the status command
+var ForwardProxyStatusCmd = &cobra.Command{
+	Use:     "status",
+	Example: "tptctl forward-proxy status",
+	Short:   "Show the upstreams routed through the forward proxy",
+	Long: `Show the upstreams routed through the forward proxy.
+
+Each workload service dependency is listed with its workload instance, workload
+cluster and upstream.  The status shows whether the forward proxy on the
+workload cluster routes the upstream.  It is unknown if the workload cluster
+can't be reached.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		routes, err := api.GetForwardProxyRoutes(context.Background())
+		if err != nil {
+			return fail("failed to get forward proxy routes", err)
+		}
+		if len(routes) == 0 {
+			qout.Info("no workload service dependencies found")
+			return nil
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 4, 4, 4, ' ', 0)
+		fmt.Fprintln(writer, "SERVICE DEPENDENCY\tWORKLOAD INSTANCE\tWORKLOAD CLUSTER\tUPSTREAM\tSTATUS")
+		for _, route := range routes {
+			status := string(route.Status)
+			if route.Message != "" {
+				status = fmt.Sprintf("%s: %s", route.Status, route.Message)
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s%s\t%s\n",
+				route.ServiceDependency,
+				route.WorkloadInstance,
+				route.WorkloadCluster,
+				route.UpstreamHost,
+				route.UpstreamPath,
+				status,
+			)
+		}
+		writer.Flush()
+
+		return nil
+	},
+}
+
+func init() {
+	forwardProxyCmd.AddCommand(ForwardProxyStatusCmd)
+}
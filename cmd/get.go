@@ -0,0 +1,22 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// getCmd represents the get command
+var getCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Get Threeport objects",
+	Long: `Get Threeport objects.
+
+The get command does nothing by itself.  Use one of the avilable subcommands
+to list different objects in the system.`,
+}
+
+func init() {
+	rootCmd.AddCommand(getCmd)
+}
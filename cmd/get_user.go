@@ -0,0 +1,83 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	tpapi "github.com/threeport/threeport-rest-api/pkg/api/v0"
+
+	"github.com/threeport/tptctl/internal/api"
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+// GetUserCmd represents the user command
+var GetUserCmd = &cobra.Command{
+	Use:     "user [EMAIL]",
+	Aliases: []string{"users"},
+	Example: "tptctl get user dev@example.com",
+	Short:   "Show users",
+	Long: `Show users.
+
+All users are listed unless an email is given.`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var users []tpapi.User
+		if len(args) == 1 {
+			user, err := api.FindUser(args[0])
+			if err != nil {
+				return fail("failed to get user", err)
+			}
+			if user == nil {
+				return fail("failed to get user", tperrors.New(tperrors.KindNotFound, fmt.Sprintf("user %s not found", args[0])))
+			}
+			users = append(users, *user)
+		} else {
+			allUsers, err := api.GetUsers()
+			if err != nil {
+				return fail("failed to get users", err)
+			}
+			users = allUsers
+		}
+		if len(users) == 0 {
+			qout.Info("no users found")
+			return nil
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 4, 4, 4, ' ', 0)
+		fmt.Fprintln(writer, "ID\tEMAIL\tNAME\tADMIN")
+		for _, user := range users {
+			name := strings.TrimSpace(fmt.Sprintf("%s %s", stringValue(user.FirstName), stringValue(user.LastName)))
+			fmt.Fprintf(writer, "%d\t%s\t%s\t%t\n",
+				*user.ID,
+				stringValue(user.Email),
+				name,
+				user.Admin != nil && *user.Admin,
+			)
+		}
+		writer.Flush()
+
+		return nil
+	},
+}
+
+func init() {
+	getCmd.AddCommand(GetUserCmd)
+}
+
+// stringValue returns the value of an optional string field or an empty
+// string if it isn't set.
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
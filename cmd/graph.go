@@ -0,0 +1,63 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/threeport/tptctl/internal/api"
+	tperrors "github.com/threeport/tptctl/internal/errors"
+)
+
+var (
+	graphWorkload string
+	graphFormat   string
+)
+
+// graphCmd represents the graph command
+var graphCmd = &cobra.Command{
+	Use:     "graph",
+	Example: "tptctl graph --workload web3-sample-app -o mermaid",
+	Short:   "Show how workloads and their upstreams relate",
+	Long: `Show how workloads and their upstreams relate.
+
+The workload definitions, instances, clusters and service dependencies in the
+Threeport API are rendered as a graph along with the upstream hosts of the
+service dependencies.  References to objects that don't exist, e.g. a service
+dependency for a deleted workload instance, are shown as missing nodes and
+listed as dangling references.
+
+The graph is written to stdout in dot, mermaid or json format so it can be
+redirected into documentation.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := api.ParseGraphFormat(graphFormat)
+		if err != nil {
+			return fail("invalid graph format", tperrors.ConfigError(err))
+		}
+
+		graph, err := api.GetGraph(graphWorkload)
+		if err != nil {
+			return fail("failed to build graph", err)
+		}
+		rendered, err := graph.Render(format)
+		if err != nil {
+			return fail("failed to render graph", err)
+		}
+		fmt.Print(rendered)
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+
+	graphCmd.Flags().StringVar(&graphWorkload, "workload", "", "only include the objects for the workload with this name")
+	graphCmd.Flags().StringVarP(&graphFormat, "output", "o", string(api.GraphFormatDot),
+		fmt.Sprintf("the format to render the graph in - one of %s",
+			[]api.GraphFormat{api.GraphFormatDot, api.GraphFormatMermaid, api.GraphFormatJSON}))
+}
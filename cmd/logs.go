@@ -0,0 +1,22 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// logsCmd represents the logs command
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Get logs for Threeport objects",
+	Long: `Get logs for Threeport objects.
+
+The logs command does nothing by itself.  Use one of the avilable subcommands
+to get the logs for different objects.`,
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+}
@@ -0,0 +1,67 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/threeport/tptctl/internal/api"
+	kube "github.com/threeport/tptctl/internal/kubernetes"
+)
+
+var (
+	logsWorkloadInstanceContainer string
+	logsWorkloadInstanceFollow    bool
+)
+
+// LogsWorkloadInstanceCmd represents the workload-instance command
+var LogsWorkloadInstanceCmd = &cobra.Command{
+	Use:     "workload-instance NAME",
+	Example: "tptctl logs workload-instance web3-sample-app -f",
+	Short:   "Get the logs for a workload instance",
+	Long: `Get the logs for a workload instance.
+
+The logs are retrieved from the pods that belong to the resources in the
+workload definition on the instance's workload cluster.  Lines from each pod
+and container are prefixed with [pod/container].`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// get the workload cluster and resources for the instance
+		credentials, manifest, err := api.GetWorkloadInstanceResources(args[0])
+		if err != nil {
+			return fail("failed to get workload instance resources", err)
+		}
+
+		// stop streaming if the user interrupts tptctl
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if err := kube.StreamWorkloadLogs(
+			ctx,
+			credentials,
+			manifest,
+			logsWorkloadInstanceContainer,
+			logsWorkloadInstanceFollow,
+			os.Stdout,
+		); err != nil {
+			return fail(fmt.Sprintf("failed to get logs for workload instance %s", args[0]), err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	logsCmd.AddCommand(LogsWorkloadInstanceCmd)
+
+	LogsWorkloadInstanceCmd.Flags().StringVar(&logsWorkloadInstanceContainer, "container", "", "only get logs for containers with this name")
+	LogsWorkloadInstanceCmd.Flags().BoolVarP(&logsWorkloadInstanceFollow, "follow", "f", false, "stream logs until interrupted")
+}
@@ -0,0 +1,22 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate Threeport objects between Threeport instances",
+	Long: `Migrate Threeport objects between Threeport instances.
+
+The migrate command does nothing by itself.  Use one of the avilable subcommands
+to copy objects from one Threeport instance in your Threeport config to another.`,
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+}
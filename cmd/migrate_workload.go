@@ -0,0 +1,136 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/threeport/tptctl/internal/api"
+	"github.com/threeport/tptctl/internal/config"
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+var (
+	migrateWorkloadFrom       string
+	migrateWorkloadTo         string
+	migrateWorkloadClusterMap map[string]string
+	migrateWorkloadDryRun     bool
+	migrateWorkloadYes        bool
+)
+
+// MigrateWorkloadCmd represents the migrate workload command
+var MigrateWorkloadCmd = &cobra.Command{
+	Use:     "workload NAME",
+	Example: "tptctl migrate workload web3-sample-app --from dev --to prod --cluster-map kind-dev=eks-prod",
+	Short:   "Migrate a workload to another Threeport instance",
+	Long: `Migrate a workload to another Threeport instance.
+
+The workload definition with the name, or named <NAME>-definition, is read from
+the source Threeport instance along with its workload instances and their
+service dependencies, and the objects are created on the target.  The source
+and target are the names of instances in your Threeport config.
+
+Workload instances keep the name of their workload cluster unless it is mapped
+to a cluster on the target with --cluster-map.  Objects that already exist on
+the target and are identical are skipped.  Objects that exist but differ, and
+workload clusters that aren't on the target, are conflicts and nothing is
+migrated until they are resolved.
+
+Workload definitions are created on the target for the user set for the target
+instance in your Threeport config.  The plan is printed before anything is
+created.  Use --dry-run to only print
+the plan.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// get the source and target threeport instances
+		threeportConfig := &config.ThreeportConfig{}
+		if err := viper.Unmarshal(threeportConfig); err != nil {
+			return fail("failed to get Threeport config", tperrors.ConfigError(err))
+		}
+		if migrateWorkloadFrom == migrateWorkloadTo {
+			return fail("invalid threeport instances",
+				tperrors.New(tperrors.KindConfig, "the source and target threeport instances must be different"))
+		}
+		source, err := threeportConfig.GetInstance(migrateWorkloadFrom)
+		if err != nil {
+			return fail("failed to find source threeport instance", err)
+		}
+		target, err := threeportConfig.GetInstance(migrateWorkloadTo)
+		if err != nil {
+			return fail("failed to find target threeport instance", err)
+		}
+		if target.UserID == 0 {
+			return fail("no user for the target threeport instance",
+				tperrors.New(tperrors.KindConfig, fmt.Sprintf(
+					"set UserID for threeport instance %s in the threeport config to the user that will own the migrated workload definitions",
+					target.Name)))
+		}
+
+		// plan the migration
+		plan, err := api.PlanWorkloadMigration(args[0], api.MigrationOptions{
+			SourceAPIEndpoint: source.APIServer,
+			TargetAPIEndpoint: target.APIServer,
+			ClusterMap:        migrateWorkloadClusterMap,
+			UserID:            target.UserID,
+		})
+		if err != nil {
+			return fail("failed to plan workload migration", err)
+		}
+		var rows [][]string
+		for _, step := range plan.Steps {
+			rows = append(rows, []string{string(step.Action), step.Object, step.Name, step.Detail})
+		}
+		qout.Table("plan", fmt.Sprintf("plan to migrate workload %s from %s to %s:", plan.Workload, source.Name, target.Name),
+			[]string{"ACTION", "OBJECT", "NAME", "DETAIL"}, rows)
+
+		if conflicts := plan.Conflicts(); len(conflicts) > 0 {
+			return fail("workload can't be migrated",
+				tperrors.New(tperrors.KindConflict, fmt.Sprintf("%d conflicts must be resolved on %s first", len(conflicts), target.Name)))
+		}
+		if migrateWorkloadDryRun {
+			qout.Complete("dry run - nothing was migrated\n")
+			return nil
+		}
+		if !migrateWorkloadYes && !qout.Confirm("Apply this plan?") {
+			qout.Info("workload migration declined")
+			return nil
+		}
+
+		// migrate the workload
+		outcomes, err := plan.Apply()
+		for _, outcome := range outcomes {
+			message := fmt.Sprintf("%s %s: %s", outcome.Object, outcome.Name, outcome.Outcome)
+			if outcome.Err != nil {
+				qout.Error(message, outcome.Err)
+				continue
+			}
+			qout.Info(message)
+		}
+		if err != nil {
+			return fail("failed to migrate workload", err)
+		}
+
+		qout.Complete(fmt.Sprintf("workload %s migrated from %s to %s\n", plan.Workload, source.Name, target.Name))
+
+		return nil
+	},
+}
+
+func init() {
+	migrateCmd.AddCommand(MigrateWorkloadCmd)
+
+	MigrateWorkloadCmd.Flags().StringVar(&migrateWorkloadFrom, "from", "", "name of the threeport instance to migrate the workload from")
+	MigrateWorkloadCmd.MarkFlagRequired("from")
+	MigrateWorkloadCmd.Flags().StringVar(&migrateWorkloadTo, "to", "", "name of the threeport instance to migrate the workload to")
+	MigrateWorkloadCmd.MarkFlagRequired("to")
+	MigrateWorkloadCmd.Flags().StringToStringVar(&migrateWorkloadClusterMap, "cluster-map", map[string]string{},
+		"workload cluster names on the source mapped to those on the target, e.g. kind-dev=eks-prod")
+	MigrateWorkloadCmd.Flags().BoolVar(&migrateWorkloadDryRun, "dry-run", false, "print the plan without migrating anything")
+	MigrateWorkloadCmd.Flags().BoolVarP(&migrateWorkloadYes, "yes", "y", false, "migrate without asking for confirmation")
+}
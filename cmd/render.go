@@ -0,0 +1,22 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// renderCmd represents the render command
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Render the manifests for Threeport objects",
+	Long: `Render the manifests for Threeport objects.
+
+The render command does nothing by itself.  Use one of the avilable subcommands
+to render the Kubernetes manifests that different objects will deploy.`,
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+}
@@ -0,0 +1,85 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/threeport/tptctl/internal/api"
+	tperrors "github.com/threeport/tptctl/internal/errors"
+)
+
+var (
+	renderWorkloadInstanceConfigPath           string
+	renderWorkloadInstanceDefinitionConfigPath string
+)
+
+// RenderWorkloadInstanceCmd represents the workload-instance command
+var RenderWorkloadInstanceCmd = &cobra.Command{
+	Use:     "workload-instance",
+	Example: "tptctl render workload-instance -c /path/to/config.yaml",
+	Short:   "Render the manifests for a workload instance",
+	Long: `Render the manifests for a workload instance.
+
+The workload definition is rendered with the values set in the workload
+instance config and the resulting manifests are written to stdout.  By default
+the workload definition is retrieved from the Threeport API.  Use
+--definition-config to render with a local workload definition config instead.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// load config
+		configContent, err := ioutil.ReadFile(renderWorkloadInstanceConfigPath)
+		if err != nil {
+			return fail("failed to read config file", tperrors.ConfigError(err))
+		}
+		var workloadInstance api.WorkloadInstanceConfig
+		if err := yaml.Unmarshal(configContent, &workloadInstance); err != nil {
+			return fail("failed to unmarshal config file yaml content", tperrors.ConfigError(err))
+		}
+
+		// render workload instance
+		var rendered string
+		if renderWorkloadInstanceDefinitionConfigPath != "" {
+			definitionContent, err := ioutil.ReadFile(renderWorkloadInstanceDefinitionConfigPath)
+			if err != nil {
+				return fail("failed to read workload definition config file", tperrors.ConfigError(err))
+			}
+			var workloadDefinition api.WorkloadDefinitionConfig
+			if err := yaml.Unmarshal(definitionContent, &workloadDefinition); err != nil {
+				return fail("failed to unmarshal workload definition config file yaml content", tperrors.ConfigError(err))
+			}
+			workloadDefinition.ConfigDir = filepath.Dir(renderWorkloadInstanceDefinitionConfigPath)
+			yamlDocument, err := workloadDefinition.StoredYAMLDocument()
+			if err != nil {
+				return fail("failed to get workload definition yaml document", err)
+			}
+			rendered, _, err = api.RenderWorkloadDefinition(yamlDocument, workloadInstance.Values)
+			if err != nil {
+				return fail(fmt.Sprintf("failed to render workload instance %s", workloadInstance.Name), err)
+			}
+		} else {
+			rendered, err = workloadInstance.Render()
+			if err != nil {
+				return fail(fmt.Sprintf("failed to render workload instance %s", workloadInstance.Name), err)
+			}
+		}
+
+		fmt.Print(rendered)
+
+		return nil
+	},
+}
+
+func init() {
+	renderCmd.AddCommand(RenderWorkloadInstanceCmd)
+
+	RenderWorkloadInstanceCmd.Flags().StringVarP(&renderWorkloadInstanceConfigPath, "config", "c", "", "path to file with workload instance config")
+	RenderWorkloadInstanceCmd.MarkFlagRequired("config")
+	RenderWorkloadInstanceCmd.Flags().StringVar(&renderWorkloadInstanceDefinitionConfigPath, "definition-config", "", "path to file with workload definition config to render instead of the one in the Threeport API")
+}
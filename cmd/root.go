@@ -4,13 +4,23 @@ Copyright © 2023 Threeport admin@threeport.io
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/threeport/tptctl/internal/api"
+	"github.com/threeport/tptctl/internal/config"
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	"github.com/threeport/tptctl/internal/install"
+	qout "github.com/threeport/tptctl/internal/output"
+	"github.com/threeport/tptctl/internal/provider"
 )
 
 const (
@@ -26,6 +36,14 @@ func configPath(homedir string) string {
 var (
 	cfgFile           string
 	providerConfigDir string
+	outputFormat      string
+	verbosity         int
+	quietOutput       bool
+	logFilePath       string
+
+	// skipManifestValidation is set by the --skip-validation flag of the
+	// commands that submit manifests
+	skipManifestValidation bool
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -35,15 +53,87 @@ var rootCmd = &cobra.Command{
 	Long: `Threeport is a global control plane for your software.  The tptctl
 CLI installs and manages instances of the Threeport control plane as well as
 applications that are deployed into the Threeport compute space.`,
+	// errors are output by Execute so they can be formatted and mapped to
+	// an exit code
+	SilenceErrors: true,
+	SilenceUsage:  true,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// tptctl exits with the code for the kind of error a command fails with.
 func Execute() {
-	err := rootCmd.Execute()
+	err := executeRoot()
+	qout.CloseLogFile()
 	if err != nil {
-		os.Exit(1)
+		os.Exit(tperrors.ExitCode(err))
+	}
+}
+
+// executeRoot runs the command given by the arguments and outputs the error
+// it fails with, if any.  Errors returned by cobra itself are for invalid
+// commands, flags and arguments and are returned as config errors.
+func executeRoot() error {
+	cmd, err := rootCmd.ExecuteC()
+	if err == nil {
+		return nil
+	}
+
+	var cmdErr *commandError
+	if errors.As(err, &cmdErr) {
+		if !cmdErr.reported {
+			qout.Error(cmdErr.message, cmdErr.err)
+		}
+	} else {
+		err = tperrors.ConfigError(err)
+		qout.Error(fmt.Sprintf("Invalid use of %s", cmd.CommandPath()), err)
+		qout.Info(fmt.Sprintf("Run '%s --help' for usage", cmd.CommandPath()))
 	}
+	qout.Debug(fmt.Sprintf("exiting with code %d", tperrors.ExitCode(err)))
+
+	return err
+}
+
+// commandError is an error a command fails with and the message that is
+// output for it.
+type commandError struct {
+	message  string
+	err      error
+	reported bool
+}
+
+// Error returns the message with the underlying error.
+func (e *commandError) Error() string {
+	if e.err == nil {
+		return e.message
+	}
+	return fmt.Sprintf("%s: %s", e.message, e.err)
+}
+
+// Unwrap returns the underlying error so its kind determines the exit code.
+func (e *commandError) Unwrap() error {
+	return e.err
+}
+
+// fail returns the error a command fails with.  The message and error are
+// output once the command returns.
+func fail(message string, err error) error {
+	return &commandError{message: message, err: err}
+}
+
+// failReported returns the error a command fails with when the failure has
+// already been output.
+func failReported(err error) error {
+	return &commandError{err: err, reported: true}
+}
+
+// exitOnConfigError outputs an error and exits with the config error exit
+// code.  It is used while tptctl is being initialized before a command runs
+// and can return an error.
+func exitOnConfigError(message string, err error) {
+	qout.Error(message, err)
+	qout.CloseLogFile()
+	os.Exit(tperrors.ExitCodeConfig)
 }
 
 func init() {
@@ -52,19 +142,49 @@ func init() {
 		"path to config file - default is $HOME/.config/threeport/config.yaml")
 	rootCmd.PersistentFlags().StringVar(&providerConfigDir, "provider-config", "",
 		"path to infra provider config directory - default is $HOME/.config/threeport/")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output-format", "text",
+		"format for tptctl output - one of text or json")
+	rootCmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v",
+		"show debug output - repeat (-vv) to also show API requests and command output")
+	rootCmd.PersistentFlags().BoolVarP(&quietOutput, "quiet", "q", false,
+		"only show errors and warnings")
+	rootCmd.PersistentFlags().StringVar(&logFilePath, "log-file", "",
+		"path to a file to append a full debug trace to, including API requests and commands run")
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
 func initConfig() {
+	// set output format
+	if err := qout.SetFormat(outputFormat); err != nil {
+		exitOnConfigError("Invalid output format", err)
+	}
+
+	// set output verbosity and log file
+	if err := qout.SetVerbosity(verbosity, quietOutput); err != nil {
+		exitOnConfigError("Invalid output flags", err)
+	}
+	if logFilePath != "" {
+		if err := qout.SetLogFile(logFilePath); err != nil {
+			exitOnConfigError("Failed to open log file", err)
+		}
+	}
+	if qout.Enabled(qout.LevelTrace) {
+		// log requests to the Threeport API
+		http.DefaultTransport = qout.NewLoggingTransport(http.DefaultTransport)
+	}
+	qout.Debug(fmt.Sprintf("running %s", strings.Join(qout.RedactArgs(os.Args), " ")))
+	api.SetSkipValidation(skipManifestValidation)
+
 	// determine user home dir
 	home, err := homedir.Dir()
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exitOnConfigError("Failed to determine home directory", err)
 	}
 	viper.AddConfigPath(configPath(home))
 	viper.SetConfigName(configName)
 	viper.SetConfigType(configType)
+	// the config holds the password for each instance
+	viper.SetConfigPermissions(0600)
 	//configFilePath := fmt.Sprintf("%s/%s.%s", configPath(home), configName, configType)
 	configFilePath := filepath.Join(configPath(home), fmt.Sprintf("%s.%s", configName, configType))
 
@@ -81,12 +201,10 @@ func initConfig() {
 		if err := viper.SafeWriteConfigAs(configFilePath); err != nil {
 			if os.IsNotExist(err) {
 				if err := os.MkdirAll(configPath(home), os.ModePerm); err != nil {
-					fmt.Println(err)
-					os.Exit(1)
+					exitOnConfigError("Failed to create config directory", err)
 				}
 				if err := viper.WriteConfigAs(configFilePath); err != nil {
-					fmt.Println(err)
-					os.Exit(1)
+					exitOnConfigError("Failed to write config file", err)
 				}
 			}
 		}
@@ -94,14 +212,62 @@ func initConfig() {
 
 	if providerConfigDir == "" {
 		if err := os.MkdirAll(configPath(home), os.ModePerm); err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			exitOnConfigError("Failed to create provider config directory", err)
 		}
 		providerConfigDir = configPath(home)
 	}
 
 	if err := viper.ReadInConfig(); err != nil {
-		fmt.Println("Can't read config:", err)
-		os.Exit(1)
+		exitOnConfigError("Can't read config", err)
+	}
+	qout.Debug(fmt.Sprintf("using config file %s", viper.ConfigFileUsed()))
+
+	// use the API of the current threeport instance if there is one, and the
+	// kubeconfig for its control plane to reach the default workload cluster
+	threeportConfig := &config.ThreeportConfig{}
+	if err := viper.Unmarshal(threeportConfig); err == nil {
+		for _, instance := range threeportConfig.Instances {
+			if instance.Name == threeportConfig.CurrentInstance {
+				install.SetThreeportAPIEndpoint(instance.APIServer)
+				controlPlane := provider.ControlPlane{InstanceName: instance.Name}
+				api.SetControlPlaneKubeconfig(controlPlane.KubeconfigFilePath(providerConfigDir))
+			}
+		}
+	}
+}
+
+// writeConfig writes the Threeport config so that only the user can read it.
+// Configs created with wider permissions by earlier versions of tptctl are
+// restricted before the credentials are written to them.
+func writeConfig() error {
+	if err := os.Chmod(viper.ConfigFileUsed(), 0600); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to restrict permissions on Threeport config: %w", err)
+	}
+
+	return viper.WriteConfig()
+}
+
+// getCurrentInstance returns the config for the current Threeport instance.
+func getCurrentInstance() (*config.Instance, error) {
+	threeportConfig := &config.ThreeportConfig{}
+	if err := viper.Unmarshal(threeportConfig); err != nil {
+		return nil, fmt.Errorf("failed to get Threeport config: %w", err)
+	}
+
+	return threeportConfig.GetCurrentInstance()
+}
+
+// setDefaultUserID sets the user for a workload definition to the identity
+// tptctl uses with the current Threeport instance unless the config sets it or
+// has no definition.
+func setDefaultUserID(workloadDefinition *api.WorkloadDefinitionConfig) {
+	if workloadDefinition.UserID != 0 || !workloadDefinition.HasSource() {
+		return
+	}
+	instance, err := getCurrentInstance()
+	if err != nil || instance.UserID == 0 {
+		qout.Warning("no user for the current threeport instance in the Threeport config - set UserID in the workload definition config")
+		return
 	}
+	workloadDefinition.UserID = instance.UserID
 }
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	tpapi "github.com/threeport/threeport-rest-api/pkg/api/v0"
+
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	"github.com/threeport/tptctl/internal/fakeapi"
+)
+
+func TestMain(m *testing.M) {
+	// keep the tests from reading or writing the user's Threeport config
+	home, err := os.MkdirTemp("", "tptctl-cmd-test-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create home directory: %s\n", err)
+		os.Exit(1)
+	}
+	os.Setenv("HOME", home)
+	code := m.Run()
+	os.RemoveAll(home)
+	os.Exit(code)
+}
+
+// newFakeAPI starts a fake Threeport API with a workload cluster named
+// default and writes a Threeport config with it as the current instance.  The
+// path to the config is returned.
+func newFakeAPI(t *testing.T) (*fakeapi.Server, string) {
+	t.Helper()
+
+	server := fakeapi.NewServer()
+	t.Cleanup(server.Close)
+	clusterName := "default"
+	if _, err := server.Add(fakeapi.WorkloadClusters, &tpapi.WorkloadCluster{Name: &clusterName}); err != nil {
+		t.Fatalf("failed to add workload cluster: %s", err)
+	}
+
+	threeportConfigPath := filepath.Join(t.TempDir(), "config.yaml")
+	threeportConfig := fmt.Sprintf(`CurrentInstance: test
+Instances:
+  - Name: test
+    Provider: kind
+    APIServer: %s
+    UserID: 1
+    UserEmail: admin@threeport.local
+`, server.URL)
+	if err := ioutil.WriteFile(threeportConfigPath, []byte(threeportConfig), 0600); err != nil {
+		t.Fatalf("failed to write threeport config: %s", err)
+	}
+
+	return server, threeportConfigPath
+}
+
+// runCommand runs tptctl with args against the Threeport config and returns
+// the error the command failed with.  The flags of all commands are reset
+// first so that none are carried over from an earlier run.
+func runCommand(t *testing.T, threeportConfigPath string, args ...string) error {
+	t.Helper()
+
+	if err := resetFlags(rootCmd); err != nil {
+		t.Fatalf("failed to reset flags: %s", err)
+	}
+	rootCmd.SetArgs(append([]string{"--threeport-config", threeportConfigPath}, args...))
+
+	return executeRoot()
+}
+
+// resetFlags sets the flags of a command and its subcommands that were given
+// in an earlier run back to their defaults.  Map flags can't be reset as
+// pflag merges values into a map once it has been set.
+func resetFlags(cmd *cobra.Command) error {
+	var err error
+	reset := func(flag *pflag.Flag) {
+		if !flag.Changed {
+			return
+		}
+		if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+			err = sliceValue.Replace(nil)
+		} else if setErr := flag.Value.Set(flag.DefValue); setErr != nil {
+			err = fmt.Errorf("failed to reset flag %s: %w", flag.Name, setErr)
+		}
+		flag.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	if err != nil {
+		return err
+	}
+	for _, subCmd := range cmd.Commands() {
+		if err := resetFlags(subCmd); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// expectExitCode fails the test if err doesn't map to an exit code.
+func expectExitCode(t *testing.T, err error, code int) {
+	t.Helper()
+
+	if actual := tperrors.ExitCode(err); actual != code {
+		t.Errorf("expected exit code %d, got %d for error: %v", code, actual, err)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	server, threeportConfigPath := newFakeAPI(t)
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		err := runCommand(t, threeportConfigPath, "get", "user", "a@example.com", "b@example.com")
+		expectExitCode(t, err, tperrors.ExitCodeConfig)
+	})
+
+	t.Run("missing config file", func(t *testing.T) {
+		err := runCommand(t, threeportConfigPath, "create", "workload", "-c", filepath.Join(t.TempDir(), "missing.yaml"))
+		expectExitCode(t, err, tperrors.ExitCodeConfig)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		err := runCommand(t, threeportConfigPath, "get", "user", "nobody@example.com")
+		expectExitCode(t, err, tperrors.ExitCodeNotFound)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		if err := runCommand(t, threeportConfigPath, "create", "user", "--email", "dev@example.com"); err != nil {
+			t.Fatalf("failed to create user: %s", err)
+		}
+		err := runCommand(t, threeportConfigPath, "create", "user", "--email", "dev@example.com")
+		expectExitCode(t, err, tperrors.ExitCodeConflict)
+	})
+
+	t.Run("conflict from the API", func(t *testing.T) {
+		dir := writeTestFiles(t, map[string]string{
+			"definition.yaml": "Name: web-definition\nYAMLDocument: manifest.yaml\n",
+			"manifest.yaml":   testManifest,
+		})
+		configPath := filepath.Join(dir, "definition.yaml")
+		if err := runCommand(t, threeportConfigPath, "create", "workload-definition", "-c", configPath); err != nil {
+			t.Fatalf("failed to create workload definition: %s", err)
+		}
+		err := runCommand(t, threeportConfigPath, "create", "workload-definition", "-c", configPath)
+		expectExitCode(t, err, tperrors.ExitCodeConflict)
+	})
+
+	t.Run("not found in the API", func(t *testing.T) {
+		err := runCommand(t, threeportConfigPath, "delete", "workload-instance", "missing")
+		expectExitCode(t, err, tperrors.ExitCodeNotFound)
+	})
+
+	t.Run("API unavailable", func(t *testing.T) {
+		server.Close()
+		err := runCommand(t, threeportConfigPath, "get", "user")
+		expectExitCode(t, err, tperrors.ExitCodeAPIUnavailable)
+	})
+}
+
+func TestWriteConfig(t *testing.T) {
+	_, threeportConfigPath := newFakeAPI(t)
+	if err := os.Chmod(threeportConfigPath, 0644); err != nil {
+		t.Fatalf("failed to set permissions on threeport config: %s", err)
+	}
+	viper.SetConfigFile(threeportConfigPath)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatalf("failed to read threeport config: %s", err)
+	}
+
+	if err := writeConfig(); err != nil {
+		t.Fatalf("failed to write threeport config: %s", err)
+	}
+	info, err := os.Stat(threeportConfigPath)
+	if err != nil {
+		t.Fatalf("failed to stat threeport config: %s", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("expected threeport config to be written with permissions 0600, got %o", mode)
+	}
+}
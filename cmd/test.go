@@ -0,0 +1,85 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/threeport/tptctl/internal/install"
+	kube "github.com/threeport/tptctl/internal/kubernetes"
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+// testCmd represents the test command
+var testCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Test Threeport objects against the clusters they run on",
+	Long: `Test Threeport objects against the clusters they run on.
+
+The test command does nothing by itself.  Use one of the avilable subcommands
+to test different objects.`,
+}
+
+func init() {
+	rootCmd.AddCommand(testCmd)
+}
+
+// defaultProbeOptions returns the probe options used when an upstream is
+// probed after it is created or updated.  The forward proxy namespace is the
+// one recorded for the current threeport instance.
+func defaultProbeOptions() kube.ProbeOptions {
+	proxyNamespace := install.ForwardProxyDefaultNamespace
+	if instance, err := getCurrentInstance(); err == nil {
+		proxyNamespace = instance.ForwardProxyNamespace()
+	}
+
+	return kube.ProbeOptions{
+		ProxyNamespace: proxyNamespace,
+		Timeout:        kube.ProbeTimeout,
+	}
+}
+
+// runProbe runs a probe, outputs the result of each stage and returns the
+// error a command fails with if the probe could not be run or any stage
+// failed.
+func runProbe(
+	name string,
+	probe func(ctx context.Context) (*kube.ProbeResult, error),
+) error {
+	// stop the probe if the user interrupts tptctl
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	qout.Info(fmt.Sprintf("probing upstream for workload service dependency %s...", name))
+	result, err := probe(ctx)
+	if err != nil {
+		return fail(fmt.Sprintf("failed to probe upstream for workload service dependency %s", name), err)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 4, 4, 4, ' ', 0)
+	fmt.Fprintln(writer, "STAGE\tSTATUS\tDETAIL")
+	for _, stage := range result.Stages {
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", stage.Name, stage.Status, stage.Message)
+	}
+	writer.Flush()
+	if result.ProxyURL != "" {
+		qout.Info(fmt.Sprintf("forward proxy: %s", result.ProxyURL))
+	}
+
+	if !result.Passed() {
+		return fail(fmt.Sprintf("upstream for workload service dependency %s failed the probe", name), nil)
+	}
+	if result.DirectFailed() {
+		qout.Warning("upstream could not be reached directly from the workload cluster - this is expected if network policies only allow egress through the forward proxy")
+	}
+
+	return nil
+}
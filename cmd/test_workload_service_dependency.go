@@ -0,0 +1,72 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/threeport/tptctl/internal/api"
+	kube "github.com/threeport/tptctl/internal/kubernetes"
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+var (
+	testWorkloadServiceDependencyProxyNamespace string
+	testWorkloadServiceDependencyTimeout        time.Duration
+	testWorkloadServiceDependencyScheme         string
+)
+
+// TestWorkloadServiceDependencyCmd represents the workload-service-dependency command
+var TestWorkloadServiceDependencyCmd = &cobra.Command{
+	Use:     "workload-service-dependency NAME",
+	Example: "tptctl test workload-service-dependency web3-sample-app-service",
+	Short:   "Check the upstream of a workload service dependency",
+	Long: `Check the upstream of a workload service dependency.
+
+A short-lived Job is run on the workload cluster of the dependency's workload
+instance, in the namespace of the workload.  It requests the upstream directly
+and reports the DNS, TCP, TLS and HTTP stages, then requests it through the
+forward proxy.  The probe passes if the upstream answers through the forward
+proxy - the direct stages show where a connection fails.  The Job is deleted
+once the results are collected.
+
+The upstream is requested with the scheme given by --scheme.  Without it, an
+upstream that is a service on the workload cluster, e.g.
+api.backend.svc.cluster.local, is requested with the scheme of its service
+port's app protocol or name, and any other upstream over HTTPS if it has no
+port or is on port 443 and over HTTP otherwise.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		options := defaultProbeOptions()
+		if testWorkloadServiceDependencyProxyNamespace != "" {
+			options.ProxyNamespace = testWorkloadServiceDependencyProxyNamespace
+		}
+		options.Timeout = testWorkloadServiceDependencyTimeout
+		if err := runProbe(args[0], func(ctx context.Context) (*kube.ProbeResult, error) {
+			return api.ProbeWorkloadServiceDependency(ctx, args[0], testWorkloadServiceDependencyScheme, options)
+		}); err != nil {
+			return err
+		}
+
+		qout.Complete(fmt.Sprintf("upstream for workload service dependency %s passed the probe\n", args[0]))
+
+		return nil
+	},
+}
+
+func init() {
+	testCmd.AddCommand(TestWorkloadServiceDependencyCmd)
+
+	TestWorkloadServiceDependencyCmd.Flags().StringVar(&testWorkloadServiceDependencyProxyNamespace,
+		"forward-proxy-namespace", "", "the namespace the forward proxy servers run in (default: the namespace recorded for the current threeport instance)")
+	TestWorkloadServiceDependencyCmd.Flags().DurationVar(&testWorkloadServiceDependencyTimeout,
+		"timeout", kube.ProbeTimeout, "how long to wait for the probe to complete")
+	TestWorkloadServiceDependencyCmd.Flags().StringVar(&testWorkloadServiceDependencyScheme,
+		"scheme", "", "the scheme to request the upstream with, http or https (default: determined from the upstream's service or port)")
+}
@@ -5,6 +5,9 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
+
+	"github.com/threeport/tptctl/internal/api"
+	qout "github.com/threeport/tptctl/internal/output"
 )
 
 // updateCmd represents the update command
@@ -20,3 +23,15 @@ to update different objects in the system.`,
 func init() {
 	rootCmd.AddCommand(updateCmd)
 }
+
+// confirmChange returns a function that outputs the diff of a change and asks
+// the user to confirm it unless assumeYes is set.
+func confirmChange(assumeYes bool) api.ConfirmFunc {
+	return func(diff string) bool {
+		qout.Diff(diff)
+		if assumeYes {
+			return true
+		}
+		return qout.Confirm("Apply these changes?")
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/threeport/tptctl/internal/api"
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+var (
+	updateWorkloadConfigPath  string
+	updateWorkloadYes         bool
+	updateWorkloadWait        bool
+	updateWorkloadWaitTimeout time.Duration
+)
+
+// UpdateWorkloadCmd represents the workload command
+var UpdateWorkloadCmd = &cobra.Command{
+	Use:     "workload",
+	Example: "tptctl update workload -c /path/to/config.yaml",
+	Short:   "Update an existing workload",
+	Long: `Update an existing workload.
+
+The workload definition, workload instances and workload service dependencies
+in the config are reconciled in that order.  Objects that changed are updated
+after their diff is confirmed, unless --yes is given, and objects that don't
+exist yet are created.  The outcome for each object is reported.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// load config
+		configContent, err := ioutil.ReadFile(updateWorkloadConfigPath)
+		if err != nil {
+			return fail("failed to read config file", tperrors.ConfigError(err))
+		}
+		var workloadConfig api.WorkloadConfig
+		if err := yaml.Unmarshal(configContent, &workloadConfig); err != nil {
+			return fail("failed to unmarshal config file yaml content", tperrors.ConfigError(err))
+		}
+		workloadConfig.WorkloadDefinition.ConfigDir = filepath.Dir(updateWorkloadConfigPath)
+		setDefaultUserID(&workloadConfig.WorkloadDefinition)
+
+		// reconcile workload objects
+		outcomes, err := workloadConfig.Update(confirmChange(updateWorkloadYes))
+		var changedInstances []string
+		for _, outcome := range outcomes {
+			message := fmt.Sprintf("%s %s: %s", outcome.Object, outcome.Name, outcome.Outcome)
+			if outcome.Err != nil {
+				qout.Error(message, outcome.Err)
+				continue
+			}
+			qout.Info(message)
+			if outcome.Object == "workload instance" &&
+				(outcome.Outcome == api.OutcomeCreated || outcome.Outcome == api.OutcomeUpdated) {
+				changedInstances = append(changedInstances, outcome.Name)
+			}
+		}
+		if err != nil {
+			if len(outcomes) == 0 {
+				return fail("failed to update workload", err)
+			}
+			// the failure was output with the outcome of the object
+			return failReported(err)
+		}
+
+		// wait for the changed workload instances to be ready
+		if updateWorkloadWait && len(changedInstances) > 0 {
+			qout.Info(fmt.Sprintf("workload %s updated - waiting for it to be ready", workloadConfig.Name))
+			for _, name := range changedInstances {
+				if err := waitForWorkloadInstance(name, api.WaitConditionReady, updateWorkloadWaitTimeout); err != nil {
+					return err
+				}
+			}
+			qout.Complete(fmt.Sprintf("workload %s updated and ready\n", workloadConfig.Name))
+			return nil
+		}
+
+		qout.Complete(fmt.Sprintf("workload %s updated\n", workloadConfig.Name))
+
+		return nil
+	},
+}
+
+func init() {
+	updateCmd.AddCommand(UpdateWorkloadCmd)
+
+	UpdateWorkloadCmd.Flags().StringVarP(&updateWorkloadConfigPath, "config", "c", "", "path to file with workload config")
+	UpdateWorkloadCmd.MarkFlagRequired("config")
+	UpdateWorkloadCmd.Flags().BoolVar(&skipManifestValidation, "skip-validation", false, "submit manifests without validating them, e.g. ones with custom resources that can only be checked on the workload cluster - validation without a cluster does not catch missing required fields")
+	UpdateWorkloadCmd.Flags().BoolVarP(&updateWorkloadYes, "yes", "y", false, "update without asking for confirmation")
+	UpdateWorkloadCmd.Flags().BoolVar(&updateWorkloadWait, "wait", false, "wait for the changed workload instances to be ready")
+	UpdateWorkloadCmd.Flags().DurationVar(&updateWorkloadWaitTimeout, "wait-timeout", api.WaitTimeout, "how long to wait for each workload instance to be ready")
+}
@@ -0,0 +1,73 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/threeport/tptctl/internal/api"
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+var (
+	updateWorkloadDefinitionConfigPath string
+	updateWorkloadDefinitionYes        bool
+)
+
+// UpdateWorkloadDefinitionCmd represents the workload-definition command
+var UpdateWorkloadDefinitionCmd = &cobra.Command{
+	Use:     "workload-definition",
+	Example: "tptctl update workload-definition -c /path/to/config.yaml",
+	Short:   "Update an existing workload definition",
+	Long: `Update an existing workload definition.
+
+The YAML document of the workload definition with the name in the config is
+replaced.  If the definition is parameterised, the definitions rendered from it
+for its workload instances are re-rendered with each instance's values and
+updated too.  A diff of the change is shown and must be confirmed unless --yes
+is given.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// load config
+		configContent, err := ioutil.ReadFile(updateWorkloadDefinitionConfigPath)
+		if err != nil {
+			return fail("failed to read config file", tperrors.ConfigError(err))
+		}
+		var workloadDefinition api.WorkloadDefinitionConfig
+		if err := yaml.Unmarshal(configContent, &workloadDefinition); err != nil {
+			return fail("failed to unmarshal config file yaml content", tperrors.ConfigError(err))
+		}
+		workloadDefinition.ConfigDir = filepath.Dir(updateWorkloadDefinitionConfigPath)
+		setDefaultUserID(&workloadDefinition)
+
+		// update workload definition
+		wd, updated, err := workloadDefinition.Update(confirmChange(updateWorkloadDefinitionYes))
+		if err != nil {
+			return fail("failed to update workload definition", err)
+		}
+		if !updated {
+			qout.Info(fmt.Sprintf("workload definition %s not updated", *wd.Name))
+			return nil
+		}
+
+		qout.Complete(fmt.Sprintf("workload definition %s updated\n", *wd.Name))
+
+		return nil
+	},
+}
+
+func init() {
+	updateCmd.AddCommand(UpdateWorkloadDefinitionCmd)
+
+	UpdateWorkloadDefinitionCmd.Flags().StringVarP(&updateWorkloadDefinitionConfigPath, "config", "c", "", "path to file with workload definition config")
+	UpdateWorkloadDefinitionCmd.MarkFlagRequired("config")
+	UpdateWorkloadDefinitionCmd.Flags().BoolVar(&skipManifestValidation, "skip-validation", false, "submit manifests without validating them, e.g. ones with custom resources that can only be checked on the workload cluster - validation without a cluster does not catch missing required fields")
+	UpdateWorkloadDefinitionCmd.Flags().BoolVarP(&updateWorkloadDefinitionYes, "yes", "y", false, "update without asking for confirmation")
+}
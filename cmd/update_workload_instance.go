@@ -0,0 +1,84 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/threeport/tptctl/internal/api"
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+var (
+	updateWorkloadInstanceConfigPath  string
+	updateWorkloadInstanceYes         bool
+	updateWorkloadInstanceWait        bool
+	updateWorkloadInstanceWaitTimeout time.Duration
+)
+
+// UpdateWorkloadInstanceCmd represents the workload-instance command
+var UpdateWorkloadInstanceCmd = &cobra.Command{
+	Use:     "workload-instance",
+	Example: "tptctl update workload-instance -c /path/to/config.yaml",
+	Short:   "Update an existing workload instance",
+	Long: `Update an existing workload instance.
+
+The workload instance with the name in the config is moved to the workload
+definition and workload cluster in the config.  A diff of the change, including
+the manifests that will be deployed, is shown and must be confirmed unless
+--yes is given.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// load config
+		configContent, err := ioutil.ReadFile(updateWorkloadInstanceConfigPath)
+		if err != nil {
+			return fail("failed to read config file", tperrors.ConfigError(err))
+		}
+		var workloadInstance api.WorkloadInstanceConfig
+		if err := yaml.Unmarshal(configContent, &workloadInstance); err != nil {
+			return fail("failed to unmarshal config file yaml content", tperrors.ConfigError(err))
+		}
+
+		// update workload instance
+		wi, updated, err := workloadInstance.Update(confirmChange(updateWorkloadInstanceYes))
+		if err != nil {
+			return fail("failed to update workload instance", err)
+		}
+		if !updated {
+			qout.Info(fmt.Sprintf("workload instance %s not updated", *wi.Name))
+			return nil
+		}
+
+		// wait for the workload instance to be ready
+		if updateWorkloadInstanceWait {
+			qout.Info(fmt.Sprintf("workload instance %s updated - waiting for it to be ready", *wi.Name))
+			if err := waitForWorkloadInstance(*wi.Name, api.WaitConditionReady, updateWorkloadInstanceWaitTimeout); err != nil {
+				return err
+			}
+			qout.Complete(fmt.Sprintf("workload instance %s updated and ready\n", *wi.Name))
+			return nil
+		}
+
+		qout.Complete(fmt.Sprintf("workload instance %s updated\n", *wi.Name))
+
+		return nil
+	},
+}
+
+func init() {
+	updateCmd.AddCommand(UpdateWorkloadInstanceCmd)
+
+	UpdateWorkloadInstanceCmd.Flags().StringVarP(&updateWorkloadInstanceConfigPath, "config", "c", "", "path to file with workload instance config")
+	UpdateWorkloadInstanceCmd.MarkFlagRequired("config")
+	UpdateWorkloadInstanceCmd.Flags().BoolVar(&skipManifestValidation, "skip-validation", false, "submit manifests without validating them, e.g. ones with custom resources that can only be checked on the workload cluster - validation without a cluster does not catch missing required fields")
+	UpdateWorkloadInstanceCmd.Flags().BoolVarP(&updateWorkloadInstanceYes, "yes", "y", false, "update without asking for confirmation")
+	UpdateWorkloadInstanceCmd.Flags().BoolVar(&updateWorkloadInstanceWait, "wait", false, "wait for the workload instance to be ready")
+	UpdateWorkloadInstanceCmd.Flags().DurationVar(&updateWorkloadInstanceWaitTimeout, "wait-timeout", api.WaitTimeout, "how long to wait for the workload instance to be ready")
+}
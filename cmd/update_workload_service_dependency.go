@@ -4,18 +4,23 @@ Copyright © 2023 Threeport admin@threeport.io
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
-	"os"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 
 	"github.com/threeport/tptctl/internal/api"
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	kube "github.com/threeport/tptctl/internal/kubernetes"
 	qout "github.com/threeport/tptctl/internal/output"
 )
 
-var updateWorkloadServiceDependencyConfigPath string
+var (
+	updateWorkloadServiceDependencyConfigPath string
+	updateWorkloadServiceDependencyProbe      bool
+)
 
 // UpdateWorkloadServiceDependencyCmd represents the workload-service-dependency command
 var UpdateWorkloadServiceDependencyCmd = &cobra.Command{
@@ -24,27 +29,35 @@ var UpdateWorkloadServiceDependencyCmd = &cobra.Command{
 	Short:        "Update an existing workload service dependency",
 	Long:         `Update an existing workload service dependency.`,
 	SilenceUsage: true,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		// load config
 		configContent, err := ioutil.ReadFile(updateWorkloadServiceDependencyConfigPath)
 		if err != nil {
-			qout.Error("failed to read config file", err)
-			os.Exit(1)
+			return fail("failed to read config file", tperrors.ConfigError(err))
 		}
 		var workloadServiceDependency api.WorkloadServiceDependencyConfig
 		if err := yaml.Unmarshal(configContent, &workloadServiceDependency); err != nil {
-			qout.Error("failed to unmarshal config file yaml content", err)
-			os.Exit(1)
+			return fail("failed to unmarshal config file yaml content", tperrors.ConfigError(err))
 		}
 
 		// update workload service dependency
 		wsd, err := workloadServiceDependency.Update()
 		if err != nil {
-			qout.Error("failed to update workload", err)
-			os.Exit(1)
+			return fail("failed to update workload", err)
+		}
+
+		// check the upstream from the workload cluster
+		if updateWorkloadServiceDependencyProbe {
+			if err := runProbe(*wsd.Name, func(ctx context.Context) (*kube.ProbeResult, error) {
+				return workloadServiceDependency.Probe(ctx, defaultProbeOptions())
+			}); err != nil {
+				return err
+			}
 		}
 
 		qout.Complete(fmt.Sprintf("workload service dependency %s updated\n", *wsd.Name))
+
+		return nil
 	},
 }
 
@@ -53,4 +66,5 @@ func init() {
 
 	UpdateWorkloadServiceDependencyCmd.Flags().StringVarP(&updateWorkloadServiceDependencyConfigPath, "config", "c", "", "path to file with workload service dependency config")
 	UpdateWorkloadServiceDependencyCmd.MarkFlagRequired("config")
+	UpdateWorkloadServiceDependencyCmd.Flags().BoolVar(&updateWorkloadServiceDependencyProbe, "probe", false, "check the upstream resolves and answers through the forward proxy from the workload cluster")
 }
@@ -0,0 +1,22 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate Threeport object configs",
+	Long: `Validate Threeport object configs.
+
+The validate command does nothing by itself.  Use one of the avilable subcommands
+to validate configs for different objects before creating them.`,
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}
@@ -0,0 +1,87 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/threeport/tptctl/internal/api"
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	kube "github.com/threeport/tptctl/internal/kubernetes"
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+var (
+	validateWorkloadDefinitionConfigPath string
+	validateWorkloadDefinitionKubeconfig string
+	validateWorkloadDefinitionContext    string
+)
+
+// ValidateWorkloadDefinitionCmd represents the workload-definition command
+var ValidateWorkloadDefinitionCmd = &cobra.Command{
+	Use:     "workload-definition",
+	Example: "tptctl validate workload-definition -c /path/to/config.yaml",
+	Short:   "Validate a workload definition",
+	Long: `Validate the Kubernetes manifests in a workload definition.
+
+Each object is checked against the built-in Kubernetes API types, which
+catches unknown fields and values of the wrong type but not missing required
+fields.  If a kubeconfig or context is provided, the kinds and OpenAPI schemas
+served by that cluster are also used so that required fields and custom
+resources can be checked.  Cluster-scoped
+objects, objects without a namespace and duplicate objects are reported.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// load config
+		configContent, err := ioutil.ReadFile(validateWorkloadDefinitionConfigPath)
+		if err != nil {
+			return fail("failed to read config file", tperrors.ConfigError(err))
+		}
+		var workloadDefinition api.WorkloadDefinitionConfig
+		if err := yaml.Unmarshal(configContent, &workloadDefinition); err != nil {
+			return fail("failed to unmarshal config file yaml content", tperrors.ConfigError(err))
+		}
+		workloadDefinition.ConfigDir = filepath.Dir(validateWorkloadDefinitionConfigPath)
+
+		// get cluster credentials if a cluster was specified
+		var credentials *kube.ClusterCredentials
+		if validateWorkloadDefinitionKubeconfig != "" || validateWorkloadDefinitionContext != "" {
+			credentials, err = kube.GetClusterCredentials(
+				validateWorkloadDefinitionKubeconfig,
+				validateWorkloadDefinitionContext,
+			)
+			if err != nil {
+				return fail("failed to get cluster credentials from kubeconfig", err)
+			}
+		}
+
+		// validate workload definition
+		issues, err := workloadDefinition.Validate(credentials)
+		if err != nil {
+			return fail("failed to validate workload definition", err)
+		}
+		api.OutputManifestIssues(issues)
+		if kube.HasErrors(issues) {
+			return fail(fmt.Sprintf("workload definition %s is not valid", workloadDefinition.Name), nil)
+		}
+
+		qout.Complete(fmt.Sprintf("workload definition %s is valid\n", workloadDefinition.Name))
+
+		return nil
+	},
+}
+
+func init() {
+	validateCmd.AddCommand(ValidateWorkloadDefinitionCmd)
+
+	ValidateWorkloadDefinitionCmd.Flags().StringVarP(&validateWorkloadDefinitionConfigPath, "config", "c", "", "path to file with workload definition config")
+	ValidateWorkloadDefinitionCmd.MarkFlagRequired("config")
+	ValidateWorkloadDefinitionCmd.Flags().StringVar(&validateWorkloadDefinitionKubeconfig, "kubeconfig", "", "path to kubeconfig for a cluster to validate against")
+	ValidateWorkloadDefinitionCmd.Flags().StringVar(&validateWorkloadDefinitionContext, "context", "", "kubeconfig context for a cluster to validate against")
+}
@@ -0,0 +1,51 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/threeport/tptctl/internal/api"
+	tperrors "github.com/threeport/tptctl/internal/errors"
+)
+
+// waitCmd represents the wait command
+var waitCmd = &cobra.Command{
+	Use:   "wait",
+	Short: "Wait for Threeport objects to reach a condition",
+	Long: `Wait for Threeport objects to reach a condition.
+
+The wait command does nothing by itself.  Use one of the avilable subcommands
+to wait for different objects.`,
+}
+
+func init() {
+	rootCmd.AddCommand(waitCmd)
+}
+
+// waitForWorkloadInstance waits for a workload instance to reach a condition
+// and returns the error a command fails with if it doesn't.  Reaching the
+// timeout is a timeout error so that it can be told apart from a failure.
+func waitForWorkloadInstance(name string, condition api.WaitCondition, timeout time.Duration) error {
+	// stop waiting if the user interrupts tptctl
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := api.WaitForWorkloadInstance(ctx, name, condition, timeout); err != nil {
+		if errors.Is(err, api.ErrWaitTimeout) {
+			err = tperrors.Timeout(err)
+		}
+		return fail(fmt.Sprintf("workload instance %s did not become %s", name, condition), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+/*
+Copyright © 2023 Threeport admin@threeport.io
+*/
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/threeport/tptctl/internal/api"
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+var (
+	waitWorkloadInstanceFor     string
+	waitWorkloadInstanceTimeout time.Duration
+)
+
+// WaitWorkloadInstanceCmd represents the workload-instance command
+var WaitWorkloadInstanceCmd = &cobra.Command{
+	Use:     "workload-instance NAME",
+	Example: "tptctl wait workload-instance web3-sample-app --for=ready --timeout=5m",
+	Short:   "Wait for a workload instance to reach a condition",
+	Long: `Wait for a workload instance to reach a condition.
+
+A workload instance is ready when it has been reconciled by the workload
+controller and the Deployments, StatefulSets and DaemonSets it defines have
+rolled out on its workload cluster.  Each change in status is output while
+waiting.
+
+The exit code is 0 when the condition is reached, 1 if the workload instance
+fails and 2 if the timeout is reached.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		condition, err := api.ParseWaitCondition(waitWorkloadInstanceFor)
+		if err != nil {
+			return fail("invalid wait condition", tperrors.ConfigError(err))
+		}
+
+		if err := waitForWorkloadInstance(args[0], condition, waitWorkloadInstanceTimeout); err != nil {
+			return err
+		}
+
+		qout.Complete(fmt.Sprintf("workload instance %s is %s\n", args[0], condition))
+
+		return nil
+	},
+}
+
+func init() {
+	waitCmd.AddCommand(WaitWorkloadInstanceCmd)
+
+	WaitWorkloadInstanceCmd.Flags().StringVar(&waitWorkloadInstanceFor, "for", string(api.WaitConditionReady), "condition to wait for - one of ready or deleted")
+	WaitWorkloadInstanceCmd.Flags().DurationVar(&waitWorkloadInstanceTimeout, "timeout", api.WaitTimeout, "how long to wait before giving up")
+}
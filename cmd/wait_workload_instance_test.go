@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/threeport/tptctl/internal/api"
+	tperrors "github.com/threeport/tptctl/internal/errors"
+)
+
+func TestWaitWorkloadInstance(t *testing.T) {
+	server, threeportConfigPath := newFakeAPI(t)
+	dir := writeTestFiles(t, map[string]string{
+		"workload.yaml": testWorkloadConfig,
+		"manifest.yaml": testManifest,
+	})
+	if err := runCommand(t, threeportConfigPath, "create", "workload", "-c", filepath.Join(dir, "workload.yaml")); err != nil {
+		t.Fatalf("failed to create workload: %s", err)
+	}
+	client := api.NewClient(server.URL)
+	workloadInstance, err := client.GetWorkloadInstanceByName("web-default-instance")
+	if err != nil {
+		t.Fatalf("failed to get workload instance: %s", err)
+	}
+
+	// the default workload cluster has no API endpoint so an instance is
+	// ready once it has been reconciled
+	testCases := []struct {
+		name         string
+		setup        func() error
+		args         []string
+		wantExitCode int
+	}{
+		{
+			name:         "not reconciled",
+			args:         []string{"web-default-instance", "--timeout", "10ms"},
+			wantExitCode: tperrors.ExitCodeTimeout,
+		},
+		{
+			name:         "invalid condition",
+			args:         []string{"web-default-instance", "--for", "healthy"},
+			wantExitCode: tperrors.ExitCodeConfig,
+		},
+		{
+			name: "reconciled",
+			setup: func() error {
+				_, err := client.UpdateWorkloadInstance(*workloadInstance.ID, []byte(`{"Reconciled":true}`))
+				return err
+			},
+			args: []string{"web-default-instance", "--timeout", "10ms"},
+		},
+		{
+			name:         "not deleted",
+			args:         []string{"web-default-instance", "--for", "deleted", "--timeout", "10ms"},
+			wantExitCode: tperrors.ExitCodeTimeout,
+		},
+		{
+			name: "deleted",
+			setup: func() error {
+				_, err := client.DeleteWorkloadInstance(*workloadInstance.ID)
+				return err
+			},
+			args: []string{"web-default-instance", "--for", "deleted", "--timeout", "10ms"},
+		},
+		{
+			name:         "deleted while waiting to be ready",
+			args:         []string{"web-default-instance", "--timeout", "10ms"},
+			wantExitCode: tperrors.ExitCodeError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.setup != nil {
+				if err := tc.setup(); err != nil {
+					t.Fatalf("failed to set up workload instance: %s", err)
+				}
+			}
+			err := runCommand(t, threeportConfigPath, append([]string{"wait", "workload-instance"}, tc.args...)...)
+			if tc.wantExitCode == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			expectExitCode(t, err, tc.wantExitCode)
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"regexp"
+
+	tpclient "github.com/threeport/threeport-go-client"
+	tpapi "github.com/threeport/threeport-rest-api/pkg/api/v0"
+
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	"github.com/threeport/tptctl/internal/install"
+)
+
+// apiStatusRegex matches the HTTP status in the errors the Threeport go client
+// returns for failed requests, e.g. "API returned status 409: ...".
+var apiStatusRegex = regexp.MustCompile(`status (\d{3})\b`)
+
+// noObjectsRegex matches the error the Threeport go client returns when a get
+// by name finds no object.
+var noObjectsRegex = regexp.MustCompile(`got 0$`)
+
+// Client is the set of Threeport API operations tptctl uses.  Objects are
+// sent to create and update operations as JSON, as with the Threeport go
+// client.
+type Client interface {
+	GetWorkloadDefinitions() (*[]tpapi.WorkloadDefinition, error)
+	GetWorkloadDefinitionByID(id uint) (*tpapi.WorkloadDefinition, error)
+	GetWorkloadDefinitionByName(name string) (*tpapi.WorkloadDefinition, error)
+	CreateWorkloadDefinition(wdJSON []byte) (*tpapi.WorkloadDefinition, error)
+	UpdateWorkloadDefinition(id uint, wdJSON []byte) (*tpapi.WorkloadDefinition, error)
+	DeleteWorkloadDefinition(id uint) (*tpapi.WorkloadDefinition, error)
+
+	GetWorkloadInstances() (*[]tpapi.WorkloadInstance, error)
+	GetWorkloadInstanceByID(id uint) (*tpapi.WorkloadInstance, error)
+	GetWorkloadInstanceByName(name string) (*tpapi.WorkloadInstance, error)
+	CreateWorkloadInstance(wiJSON []byte) (*tpapi.WorkloadInstance, error)
+	UpdateWorkloadInstance(id uint, wiJSON []byte) (*tpapi.WorkloadInstance, error)
+	DeleteWorkloadInstance(id uint) (*tpapi.WorkloadInstance, error)
+
+	GetWorkloadClusters() (*[]tpapi.WorkloadCluster, error)
+	GetWorkloadClusterByID(id uint) (*tpapi.WorkloadCluster, error)
+	GetWorkloadClusterByName(name string) (*tpapi.WorkloadCluster, error)
+	CreateWorkloadCluster(wcJSON []byte) (*tpapi.WorkloadCluster, error)
+	DeleteWorkloadCluster(id uint) (*tpapi.WorkloadCluster, error)
+
+	GetWorkloadServiceDependencies() (*[]tpapi.WorkloadServiceDependency, error)
+	GetWorkloadServiceDependencyByName(name string) (*tpapi.WorkloadServiceDependency, error)
+	CreateWorkloadServiceDependency(wsdJSON []byte) (*tpapi.WorkloadServiceDependency, error)
+	UpdateWorkloadServiceDependency(id uint, wsdJSON []byte) (*tpapi.WorkloadServiceDependency, error)
+	DeleteWorkloadServiceDependency(id uint) (*tpapi.WorkloadServiceDependency, error)
+
+	GetUsers() (*[]tpapi.User, error)
+	CreateUser(userJSON []byte) (*tpapi.User, error)
+	DeleteUser(id uint) (*tpapi.User, error)
+}
+
+// clientOverride replaces the client for the current threeport instance if
+// set.
+var clientOverride Client
+
+// SetClient sets the client used for the current threeport instance, e.g. to
+// use a fake Threeport API in tests.  Setting nil restores the default client
+// for the endpoint from install.GetThreeportAPIEndpoint.
+func SetClient(client Client) {
+	clientOverride = client
+}
+
+// apiClient returns the client for the current threeport instance.
+func apiClient() Client {
+	if clientOverride != nil {
+		return clientOverride
+	}
+
+	return NewClient(install.GetThreeportAPIEndpoint())
+}
+
+// apiError returns an error from the Threeport go client as an error of the
+// kind for the API's response so that commands exit with the matching code:
+// conflicts for objects that already exist and not found for missing ones.
+// Other errors are returned unchanged.
+func apiError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if match := apiStatusRegex.FindStringSubmatch(err.Error()); match != nil {
+		switch match[1] {
+		case "404":
+			return tperrors.NotFound(err)
+		case "409":
+			return tperrors.Conflict(err)
+		}
+	}
+	if noObjectsRegex.MatchString(err.Error()) {
+		return tperrors.NotFound(err)
+	}
+
+	return err
+}
+
+// apiResult returns the result of a Threeport go client call with its error
+// classified by apiError.
+func apiResult[T any](object T, err error) (T, error) {
+	return object, apiError(err)
+}
+
+// threeportClient is a Client that uses the Threeport go client.
+type threeportClient struct {
+	apiEndpoint string
+}
+
+// NewClient returns a client for the Threeport API at apiEndpoint.
+func NewClient(apiEndpoint string) Client {
+	return &threeportClient{apiEndpoint: apiEndpoint}
+}
+
+func (c *threeportClient) GetWorkloadDefinitions() (*[]tpapi.WorkloadDefinition, error) {
+	return apiResult(tpclient.GetWorkloadDefinitions(c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) GetWorkloadDefinitionByID(id uint) (*tpapi.WorkloadDefinition, error) {
+	return apiResult(tpclient.GetWorkloadDefinitionByID(id, c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) GetWorkloadDefinitionByName(name string) (*tpapi.WorkloadDefinition, error) {
+	return apiResult(tpclient.GetWorkloadDefinitionByName(name, c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) CreateWorkloadDefinition(wdJSON []byte) (*tpapi.WorkloadDefinition, error) {
+	return apiResult(tpclient.CreateWorkloadDefinition(wdJSON, c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) UpdateWorkloadDefinition(id uint, wdJSON []byte) (*tpapi.WorkloadDefinition, error) {
+	return apiResult(tpclient.UpdateWorkloadDefinition(id, wdJSON, c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) DeleteWorkloadDefinition(id uint) (*tpapi.WorkloadDefinition, error) {
+	return apiResult(tpclient.DeleteWorkloadDefinition(id, c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) GetWorkloadInstances() (*[]tpapi.WorkloadInstance, error) {
+	return apiResult(tpclient.GetWorkloadInstances(c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) GetWorkloadInstanceByID(id uint) (*tpapi.WorkloadInstance, error) {
+	return apiResult(tpclient.GetWorkloadInstanceByID(id, c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) GetWorkloadInstanceByName(name string) (*tpapi.WorkloadInstance, error) {
+	return apiResult(tpclient.GetWorkloadInstanceByName(name, c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) CreateWorkloadInstance(wiJSON []byte) (*tpapi.WorkloadInstance, error) {
+	return apiResult(tpclient.CreateWorkloadInstance(wiJSON, c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) UpdateWorkloadInstance(id uint, wiJSON []byte) (*tpapi.WorkloadInstance, error) {
+	return apiResult(tpclient.UpdateWorkloadInstance(id, wiJSON, c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) DeleteWorkloadInstance(id uint) (*tpapi.WorkloadInstance, error) {
+	return apiResult(tpclient.DeleteWorkloadInstance(id, c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) GetWorkloadClusters() (*[]tpapi.WorkloadCluster, error) {
+	return apiResult(tpclient.GetWorkloadClusters(c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) GetWorkloadClusterByID(id uint) (*tpapi.WorkloadCluster, error) {
+	return apiResult(tpclient.GetWorkloadClusterByID(id, c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) GetWorkloadClusterByName(name string) (*tpapi.WorkloadCluster, error) {
+	return apiResult(tpclient.GetWorkloadClusterByName(name, c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) CreateWorkloadCluster(wcJSON []byte) (*tpapi.WorkloadCluster, error) {
+	return apiResult(tpclient.CreateWorkloadCluster(wcJSON, c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) DeleteWorkloadCluster(id uint) (*tpapi.WorkloadCluster, error) {
+	return apiResult(tpclient.DeleteWorkloadCluster(id, c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) GetWorkloadServiceDependencies() (*[]tpapi.WorkloadServiceDependency, error) {
+	return apiResult(tpclient.GetWorkloadServiceDependencys(c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) GetWorkloadServiceDependencyByName(name string) (*tpapi.WorkloadServiceDependency, error) {
+	return apiResult(tpclient.GetWorkloadServiceDependencyByName(name, c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) CreateWorkloadServiceDependency(wsdJSON []byte) (*tpapi.WorkloadServiceDependency, error) {
+	return apiResult(tpclient.CreateWorkloadServiceDependency(wsdJSON, c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) UpdateWorkloadServiceDependency(id uint, wsdJSON []byte) (*tpapi.WorkloadServiceDependency, error) {
+	return apiResult(tpclient.UpdateWorkloadServiceDependency(id, wsdJSON, c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) DeleteWorkloadServiceDependency(id uint) (*tpapi.WorkloadServiceDependency, error) {
+	return apiResult(tpclient.DeleteWorkloadServiceDependency(id, c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) GetUsers() (*[]tpapi.User, error) {
+	return apiResult(tpclient.GetUsers(c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) CreateUser(userJSON []byte) (*tpapi.User, error) {
+	return apiResult(tpclient.CreateUser(userJSON, c.apiEndpoint, ""))
+}
+
+func (c *threeportClient) DeleteUser(id uint) (*tpapi.User, error) {
+	return apiResult(tpclient.DeleteUser(id, c.apiEndpoint, ""))
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	tperrors "github.com/threeport/tptctl/internal/errors"
+)
+
+func TestAPIError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected tperrors.Kind
+	}{
+		{name: "nil", err: nil, expected: ""},
+		{name: "conflict", err: errors.New("API returned status 409: WorkloadDefinition with Name web already exists"), expected: tperrors.KindConflict},
+		{name: "not found", err: errors.New("API returned status 404: WorkloadInstance with ID 9 not found"), expected: tperrors.KindNotFound},
+		{name: "no object with name", err: errors.New("expected 1 object, got 0"), expected: tperrors.KindNotFound},
+		{name: "server error", err: errors.New("API returned status 500: internal error"), expected: ""},
+		{name: "several objects with name", err: errors.New("expected 1 object, got 2"), expected: ""},
+		{name: "typed", err: tperrors.New(tperrors.KindConfig, "status 404 in config"), expected: tperrors.KindConfig},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if kind := tperrors.KindOf(apiError(tc.err)); kind != tc.expected {
+				t.Errorf("expected kind %q, got %q", tc.expected, kind)
+			}
+		})
+	}
+}
+
+func TestClientErrorKinds(t *testing.T) {
+	server, _ := newFakeAPI(t)
+	client := NewClient(server.URL)
+
+	name := "web-definition"
+	wdJSON := []byte(fmt.Sprintf(`{"Name":%q,"YAMLDocument":"kind: Service\n"}`, name))
+	if _, err := client.CreateWorkloadDefinition(wdJSON); err != nil {
+		t.Fatalf("failed to create workload definition: %s", err)
+	}
+	_, err := client.CreateWorkloadDefinition(wdJSON)
+	if kind := tperrors.KindOf(err); kind != tperrors.KindConflict {
+		t.Errorf("expected creating a workload definition that exists to be %s, got %q: %v", tperrors.KindConflict, kind, err)
+	}
+
+	_, err = client.GetWorkloadInstanceByName("missing")
+	if kind := tperrors.KindOf(err); kind != tperrors.KindNotFound {
+		t.Errorf("expected getting a missing workload instance by name to be %s, got %q: %v", tperrors.KindNotFound, kind, err)
+	}
+	_, err = client.DeleteWorkloadInstance(99)
+	if kind := tperrors.KindOf(err); kind != tperrors.KindNotFound {
+		t.Errorf("expected deleting a missing workload instance to be %s, got %q: %v", tperrors.KindNotFound, kind, err)
+	}
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+// diffContextLines is the number of unchanged lines shown around each change
+// in a diff.
+const diffContextLines = 3
+
+// noNewlineMarker follows the last line of a text without a trailing newline
+// in a diff.
+const noNewlineMarker = `\ No newline at end of file`
+
+// ConfirmFunc is called with the diff of a change before it is made.  The
+// change is only made if it returns true.
+type ConfirmFunc func(diff string) bool
+
+// diffOp is a single line in a diff.
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// ManifestDiff returns a unified diff of two workload definition YAML
+// documents.  The header recording what a definition was rendered from is
+// left out and the values of Secrets are replaced by hashes so the diff shows
+// which changed without showing them.
+func ManifestDiff(before, after, beforeName, afterName string) string {
+	return UnifiedDiff(
+		qout.RedactManifestSecrets(StripRenderedHeader(before)),
+		qout.RedactManifestSecrets(StripRenderedHeader(after)),
+		beforeName, afterName,
+	)
+}
+
+// UnifiedDiff returns a unified diff of two texts or an empty string if they
+// are the same.
+func UnifiedDiff(before, after, beforeName, afterName string) string {
+	if before == after {
+		return ""
+	}
+	ops := diffLines(diffableLines(before), diffableLines(after))
+
+	var diff strings.Builder
+	fmt.Fprintf(&diff, "--- %s\n+++ %s\n", beforeName, afterName)
+
+	// group changes into hunks with surrounding context
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		start := i - diffContextLines
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			// end the hunk once there is enough unchanged context
+			unchanged := 0
+			for end+unchanged < len(ops) && ops[end+unchanged].kind == ' ' {
+				unchanged++
+			}
+			if end+unchanged == len(ops) || unchanged > diffContextLines*2 {
+				end += min(unchanged, diffContextLines)
+				break
+			}
+			end += unchanged
+		}
+
+		beforeStart, afterStart := lineNumbers(ops, start)
+		beforeCount, afterCount := 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				beforeCount++
+			}
+			if op.kind != '-' {
+				afterCount++
+			}
+		}
+		// an empty range starts at the line before it
+		if beforeCount == 0 {
+			beforeStart--
+		}
+		if afterCount == 0 {
+			afterStart--
+		}
+		fmt.Fprintf(&diff, "@@ -%d,%d +%d,%d @@\n", beforeStart, beforeCount, afterStart, afterCount)
+		for _, op := range ops[start:end] {
+			fmt.Fprintf(&diff, "%c%s\n", op.kind, op.line)
+		}
+		i = end
+	}
+
+	return diff.String()
+}
+
+// diffLines returns the operations that turn one list of lines into another
+// using the longest common subsequence.
+func diffLines(before, after []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of before[i:]
+	// and after[j:]
+	lcs := make([][]int, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(before) && j < len(after) {
+		switch {
+		case before[i] == after[j]:
+			ops = append(ops, diffOp{' ', before[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', before[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', after[j]})
+			j++
+		}
+	}
+	for ; i < len(before); i++ {
+		ops = append(ops, diffOp{'-', before[i]})
+	}
+	for ; j < len(after); j++ {
+		ops = append(ops, diffOp{'+', after[j]})
+	}
+
+	return ops
+}
+
+// lineNumbers returns the 1-based line numbers in the before and after texts
+// of the operation at index.
+func lineNumbers(ops []diffOp, index int) (int, int) {
+	beforeLine, afterLine := 1, 1
+	for _, op := range ops[:index] {
+		if op.kind != '+' {
+			beforeLine++
+		}
+		if op.kind != '-' {
+			afterLine++
+		}
+	}
+
+	return beforeLine, afterLine
+}
+
+// splitLines splits text into lines without a trailing empty line.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffableLines splits text into lines for diffing.  If the text has no
+// trailing newline, its last line is followed by the marker so that a change
+// to only the trailing newline shows in the diff.
+func diffableLines(text string) []string {
+	lines := splitLines(text)
+	if len(lines) > 0 && !strings.HasSuffix(text, "\n") {
+		lines[len(lines)-1] += "\n" + noNewlineMarker
+	}
+
+	return lines
+}
+
+// min returns the smaller of two ints.
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	testCases := []struct {
+		name     string
+		before   string
+		after    string
+		expected string
+	}{
+		{
+			name:     "unchanged",
+			before:   "a\nb\n",
+			after:    "a\nb\n",
+			expected: "",
+		},
+		{
+			name:     "both empty",
+			expected: "",
+		},
+		{
+			name:     "from empty",
+			after:    "a\nb\n",
+			expected: "--- current\n+++ updated\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name:     "to empty",
+			before:   "a\n",
+			expected: "--- current\n+++ updated\n@@ -1,1 +0,0 @@\n-a\n",
+		},
+		{
+			name:     "changed line with context",
+			before:   "1\n2\n3\n4\n5\n6\n7\n8\n",
+			after:    "1\n2\n3\n4\nfive\n6\n7\n8\n",
+			expected: "--- current\n+++ updated\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name:     "trailing newline removed",
+			before:   "a\nb\n",
+			after:    "a\nb",
+			expected: "--- current\n+++ updated\n@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n",
+		},
+		{
+			name:     "trailing newline added",
+			before:   "a",
+			after:    "a\n",
+			expected: "--- current\n+++ updated\n@@ -1,1 +1,1 @@\n-a\n\\ No newline at end of file\n+a\n",
+		},
+		{
+			name:     "nearby changes merged into one hunk",
+			before:   "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			after:    "1\ntwo\n3\n4\n5\n6\n7\n8\nnine\n10\n",
+			expected: "--- current\n+++ updated\n@@ -1,10 +1,10 @@\n 1\n-2\n+two\n 3\n 4\n 5\n 6\n 7\n 8\n-9\n+nine\n 10\n",
+		},
+		{
+			name:   "distant changes in separate hunks",
+			before: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			after:  "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			expected: "--- current\n+++ updated\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n" +
+				"@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := UnifiedDiff(tc.before, tc.after, "current", "updated"); diff != tc.expected {
+				t.Errorf("expected diff:\n%s\ngot:\n%s", tc.expected, diff)
+			}
+		})
+	}
+}
+
+func TestManifestDiff(t *testing.T) {
+	secret := "kind: Secret\nmetadata:\n  name: web\ndata:\n  apiKey: %s\n"
+	before, err := AddRenderedHeader(fmt.Sprintf(secret, "b2xkLWtleQ=="), &RenderedFrom{Definition: "web", Instance: "prod"})
+	if err != nil {
+		t.Fatalf("failed to add rendered header: %s", err)
+	}
+
+	// definitions rendered before the header was recorded have the same manifests
+	if diff := ManifestDiff(before, fmt.Sprintf(secret, "b2xkLWtleQ=="), "current", "updated"); diff != "" {
+		t.Errorf("expected no diff when only the rendered header differs, got:\n%s", diff)
+	}
+
+	diff := ManifestDiff(before, fmt.Sprintf(secret, "bmV3LWtleQ=="), "current", "updated")
+	if !strings.Contains(diff, "-  apiKey: REDACTED-") || !strings.Contains(diff, "+  apiKey: REDACTED-") {
+		t.Errorf("expected diff to show the secret value changed, got:\n%s", diff)
+	}
+	if strings.Contains(diff, "b2xkLWtleQ==") || strings.Contains(diff, "bmV3LWtleQ==") {
+		t.Errorf("expected secret values to be redacted from the diff, got:\n%s", diff)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	testCases := []struct {
+		name     string
+		before   []string
+		after    []string
+		expected []diffOp
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:     "insert",
+			before:   []string{"a", "c"},
+			after:    []string{"a", "b", "c"},
+			expected: []diffOp{{' ', "a"}, {'+', "b"}, {' ', "c"}},
+		},
+		{
+			name:     "delete",
+			before:   []string{"a", "b", "c"},
+			after:    []string{"a", "c"},
+			expected: []diffOp{{' ', "a"}, {'-', "b"}, {' ', "c"}},
+		},
+		{
+			name:     "replace",
+			before:   []string{"a"},
+			after:    []string{"b"},
+			expected: []diffOp{{'-', "a"}, {'+', "b"}},
+		},
+		{
+			name:     "all new",
+			after:    []string{"a", "b"},
+			expected: []diffOp{{'+', "a"}, {'+', "b"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if ops := diffLines(tc.before, tc.after); !reflect.DeepEqual(ops, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, ops)
+			}
+		})
+	}
+}
@@ -0,0 +1,348 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tpapi "github.com/threeport/threeport-rest-api/pkg/api/v0"
+	"gopkg.in/yaml.v2"
+
+	tperrors "github.com/threeport/tptctl/internal/errors"
+)
+
+// WorkloadExport is a workload retrieved from the Threeport API as the config
+// and YAML document files that would create it again.
+type WorkloadExport struct {
+	Config       WorkloadConfig
+	YAMLDocument string
+	Warnings     []string
+}
+
+// ExportedFile is a file written by an export.
+type ExportedFile struct {
+	Path    string
+	Content string
+}
+
+// exportObjects are the Threeport objects a workload export is built from.
+type exportObjects struct {
+	workloadDefinitions         []tpapi.WorkloadDefinition
+	workloadInstances           []tpapi.WorkloadInstance
+	workloadClusters            []tpapi.WorkloadCluster
+	workloadServiceDependencies []tpapi.WorkloadServiceDependency
+}
+
+// getExportObjects retrieves the objects needed for an export from the
+// Threeport API.
+func getExportObjects(client Client) (*exportObjects, error) {
+	workloadDefinitions, err := client.GetWorkloadDefinitions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workload definitions: %w", err)
+	}
+	workloadInstances, err := client.GetWorkloadInstances()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workload instances: %w", err)
+	}
+	workloadClusters, err := client.GetWorkloadClusters()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workload clusters: %w", err)
+	}
+	workloadServiceDependencies, err := client.GetWorkloadServiceDependencies()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workload service dependencies: %w", err)
+	}
+
+	return &exportObjects{
+		workloadDefinitions:         *workloadDefinitions,
+		workloadInstances:           *workloadInstances,
+		workloadClusters:            *workloadClusters,
+		workloadServiceDependencies: *workloadServiceDependencies,
+	}, nil
+}
+
+// ExportWorkload exports a workload definition with all of its workload
+// instances and their service dependencies.  The name is either the name of
+// the definition or the workload name it was derived from, i.e. the definition
+// is <name>-definition.
+func ExportWorkload(name string) (*WorkloadExport, error) {
+	objects, err := getExportObjects(apiClient())
+	if err != nil {
+		return nil, err
+	}
+	workloadName, workloadDefinition, workloadInstances, err := objects.workload(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return objects.export(workloadName, workloadDefinition, workloadInstances)
+}
+
+// workload returns the workload name, workload definition and workload
+// instances for a workload.  The name is either the name of the definition or
+// the workload name it was derived from.
+func (o *exportObjects) workload(name string) (string, *tpapi.WorkloadDefinition, []tpapi.WorkloadInstance, error) {
+	workloadName := name
+	workloadDefinition := o.definitionByName(name)
+	if workloadDefinition == nil {
+		workloadDefinition = o.definitionByName(objectName(name, "definition"))
+	} else {
+		workloadName = strings.TrimSuffix(name, "-definition")
+	}
+	if workloadDefinition == nil {
+		return "", nil, nil, tperrors.New(tperrors.KindNotFound, fmt.Sprintf(
+			"workload definition %s or %s not found", name, objectName(name, "definition")))
+	}
+
+	var workloadInstances []tpapi.WorkloadInstance
+	for _, wi := range o.workloadInstances {
+		if o.templateFor(wi) == workloadDefinition {
+			workloadInstances = append(workloadInstances, wi)
+		}
+	}
+
+	return workloadName, workloadDefinition, workloadInstances, nil
+}
+
+// ExportWorkloadInstance exports a single workload instance with its workload
+// definition and service dependencies.  If the definition is parameterised the
+// template is exported rather than the definition rendered for the instance.
+func ExportWorkloadInstance(name string) (*WorkloadExport, error) {
+	objects, err := getExportObjects(apiClient())
+	if err != nil {
+		return nil, err
+	}
+	var workloadInstance *tpapi.WorkloadInstance
+	for i, wi := range objects.workloadInstances {
+		if stringValue(wi.Name) == name {
+			workloadInstance = &objects.workloadInstances[i]
+			break
+		}
+	}
+	if workloadInstance == nil {
+		return nil, tperrors.New(tperrors.KindNotFound, fmt.Sprintf("workload instance %s not found", name))
+	}
+	workloadDefinition := objects.templateFor(*workloadInstance)
+	if workloadDefinition == nil {
+		return nil, tperrors.New(tperrors.KindNotFound, fmt.Sprintf(
+			"workload definition %d for workload instance %s not found",
+			uintValue(workloadInstance.WorkloadDefinitionID), name))
+	}
+
+	return objects.export(name, workloadDefinition, []tpapi.WorkloadInstance{*workloadInstance})
+}
+
+// export builds the workload export for a workload definition and workload
+// instances that use it.
+func (o *exportObjects) export(
+	workloadName string,
+	workloadDefinition *tpapi.WorkloadDefinition,
+	workloadInstances []tpapi.WorkloadInstance,
+) (*WorkloadExport, error) {
+	definitionName := stringValue(workloadDefinition.Name)
+	parameters, template, err := ParseParametersHeader(stringValue(workloadDefinition.YAMLDocument))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parameters for workload definition %s: %w", definitionName, err)
+	}
+	we := WorkloadExport{
+		Config: WorkloadConfig{
+			Name: workloadName,
+			WorkloadDefinition: WorkloadDefinitionConfig{
+				Name:         definitionName,
+				YAMLDocument: YAMLDocumentPaths{fmt.Sprintf("%s-manifest.yaml", definitionName)},
+				Parameters:   parameters,
+			},
+		},
+		YAMLDocument: template,
+	}
+
+	sort.Slice(workloadInstances, func(i, j int) bool {
+		return stringValue(workloadInstances[i].Name) < stringValue(workloadInstances[j].Name)
+	})
+	instanceNames := make(map[uint]string)
+	for _, wi := range workloadInstances {
+		instanceName := stringValue(wi.Name)
+		instanceNames[uintValue(wi.ID)] = instanceName
+		wic := WorkloadInstanceConfig{
+			Name:                   instanceName,
+			WorkloadClusterName:    o.clusterName(wi.WorkloadClusterID),
+			WorkloadDefinitionName: definitionName,
+		}
+		if wic.WorkloadClusterName == "" {
+			we.Warnings = append(we.Warnings, fmt.Sprintf(
+				"workload cluster %d for workload instance %s not found - set WorkloadClusterName in the exported config",
+				uintValue(wi.WorkloadClusterID), instanceName))
+		}
+		if len(parameters) > 0 {
+			values, redacted, err := o.instanceValues(wi)
+			if err != nil {
+				return nil, err
+			}
+			if values == nil {
+				we.Warnings = append(we.Warnings, fmt.Sprintf(
+					"values for workload instance %s were not recorded when it was rendered - set them in the exported config",
+					instanceName))
+			}
+			if len(redacted) > 0 {
+				we.Warnings = append(we.Warnings, fmt.Sprintf(
+					"values for sensitive parameters %s of workload instance %s are not recorded - set them in the exported config",
+					strings.Join(redacted, ", "), instanceName))
+			}
+			wic.Values = values
+		}
+		we.Config.WorkloadInstances = append(we.Config.WorkloadInstances, wic)
+	}
+
+	for _, wsd := range o.workloadServiceDependencies {
+		instanceName, ok := instanceNames[uintValue(wsd.WorkloadInstanceID)]
+		if !ok {
+			continue
+		}
+		we.Config.WorkloadServiceDependencies = append(we.Config.WorkloadServiceDependencies, WorkloadServiceDependencyConfig{
+			Name:                 stringValue(wsd.Name),
+			UpstreamHost:         stringValue(wsd.UpstreamHost),
+			UpstreamPath:         stringValue(wsd.UpstreamPath),
+			WorkloadInstanceName: instanceName,
+		})
+	}
+	sort.Slice(we.Config.WorkloadServiceDependencies, func(i, j int) bool {
+		return we.Config.WorkloadServiceDependencies[i].Name < we.Config.WorkloadServiceDependencies[j].Name
+	})
+
+	return &we, nil
+}
+
+// definitionByName returns the workload definition with a name or nil if it
+// doesn't exist.
+func (o *exportObjects) definitionByName(name string) *tpapi.WorkloadDefinition {
+	for i, wd := range o.workloadDefinitions {
+		if stringValue(wd.Name) == name {
+			return &o.workloadDefinitions[i]
+		}
+	}
+
+	return nil
+}
+
+// definitionByID returns the workload definition with an ID or nil if it
+// doesn't exist.
+func (o *exportObjects) definitionByID(id *uint) *tpapi.WorkloadDefinition {
+	if id == nil {
+		return nil
+	}
+	for i, wd := range o.workloadDefinitions {
+		if wd.ID != nil && *wd.ID == *id {
+			return &o.workloadDefinitions[i]
+		}
+	}
+
+	return nil
+}
+
+// templateFor returns the workload definition a workload instance was created
+// from.  An instance of a parameterised definition uses a definition rendered
+// for it and the parameterised definition is returned instead.
+func (o *exportObjects) templateFor(workloadInstance tpapi.WorkloadInstance) *tpapi.WorkloadDefinition {
+	workloadDefinition := o.definitionByID(workloadInstance.WorkloadDefinitionID)
+	if workloadDefinition == nil {
+		return nil
+	}
+	templateName := SourceDefinitionName(workloadDefinition, stringValue(workloadInstance.Name))
+	if templateName == "" {
+		return workloadDefinition
+	}
+	template := o.definitionByName(templateName)
+	if template == nil || !strings.HasPrefix(stringValue(template.YAMLDocument), ParametersHeader) {
+		return workloadDefinition
+	}
+
+	return template
+}
+
+// instanceValues returns the values a workload instance of a parameterised
+// definition was rendered with and the names of the sensitive parameters whose
+// values weren't recorded.  Nil values are returned if none were recorded.
+func (o *exportObjects) instanceValues(workloadInstance tpapi.WorkloadInstance) (map[string]interface{}, []string, error) {
+	rendered := o.definitionByID(workloadInstance.WorkloadDefinitionID)
+	if rendered == nil {
+		return nil, nil, nil
+	}
+	renderedFrom, _, err := ParseRenderedHeader(stringValue(rendered.YAMLDocument))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read values for workload instance %s: %w",
+			stringValue(workloadInstance.Name), err)
+	}
+	if renderedFrom == nil {
+		return nil, nil, nil
+	}
+	if renderedFrom.Values == nil {
+		return map[string]interface{}{}, renderedFrom.Redacted, nil
+	}
+
+	return renderedFrom.Values, renderedFrom.Redacted, nil
+}
+
+// clusterName returns the name of the workload cluster with an ID or an empty
+// string if it doesn't exist.
+func (o *exportObjects) clusterName(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	for _, wc := range o.workloadClusters {
+		if wc.ID != nil && *wc.ID == *id {
+			return stringValue(wc.Name)
+		}
+	}
+
+	return ""
+}
+
+// Files returns the files for the export in a directory: the workload config,
+// named <workload>-workload.yaml, and the YAML document it refers to with a
+// path relative to the config.
+func (we *WorkloadExport) Files(dir string) ([]ExportedFile, error) {
+	configContent, err := yaml.Marshal(we.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal workload config: %w", err)
+	}
+
+	return []ExportedFile{
+		{
+			Path:    filepath.Join(dir, fmt.Sprintf("%s-workload.yaml", we.Config.Name)),
+			Content: string(configContent),
+		},
+		{
+			Path:    filepath.Join(dir, we.Config.WorkloadDefinition.YAMLDocument[0]),
+			Content: we.YAMLDocument,
+		},
+	}, nil
+}
+
+// Write writes the files for the export to a directory, creating it if needed.
+// Existing files are only replaced if overwrite is true.
+func (we *WorkloadExport) Write(dir string, overwrite bool) ([]ExportedFile, error) {
+	files, err := we.Files(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !overwrite {
+		for _, file := range files {
+			if _, err := os.Stat(file.Path); err == nil {
+				return nil, tperrors.New(tperrors.KindConflict, fmt.Sprintf("%s already exists", file.Path))
+			}
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	for _, file := range files {
+		if err := ioutil.WriteFile(file.Path, []byte(file.Content), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file.Path, err)
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	tpapi "github.com/threeport/threeport-rest-api/pkg/api/v0"
+
+	kube "github.com/threeport/tptctl/internal/kubernetes"
+)
+
+// RouteStatus is whether the upstream for a workload service dependency is
+// routed through the forward proxy on its workload cluster.
+type RouteStatus string
+
+const (
+	RouteStatusRouted    RouteStatus = "routed"
+	RouteStatusNotRouted RouteStatus = "not routed"
+	RouteStatusUnknown   RouteStatus = "unknown"
+)
+
+// ForwardProxyRoute is the route through the forward proxy for a workload
+// service dependency.  Message explains an unknown status.
+type ForwardProxyRoute struct {
+	ServiceDependency string
+	WorkloadInstance  string
+	WorkloadCluster   string
+	UpstreamHost      string
+	UpstreamPath      string
+	Status            RouteStatus
+	Message           string
+}
+
+// GetForwardProxyRoutes returns the route through the forward proxy for each
+// workload service dependency in the Threeport API.  The status of each route
+// is checked against the upstreams the forward proxy on the workload cluster
+// is configured with.
+func GetForwardProxyRoutes(ctx context.Context) ([]ForwardProxyRoute, error) {
+	workloadServiceDependencies, err := apiClient().GetWorkloadServiceDependencies()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workload service dependencies: %w", err)
+	}
+	workloadInstances, err := apiClient().GetWorkloadInstances()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workload instances: %w", err)
+	}
+	workloadClusters, err := apiClient().GetWorkloadClusters()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workload clusters: %w", err)
+	}
+	instancesByID := make(map[uint]*tpapi.WorkloadInstance)
+	for i, wi := range *workloadInstances {
+		instancesByID[*wi.ID] = &(*workloadInstances)[i]
+	}
+	clustersByID := make(map[uint]*tpapi.WorkloadCluster)
+	for i, wc := range *workloadClusters {
+		clustersByID[*wc.ID] = &(*workloadClusters)[i]
+	}
+
+	// the upstreams are only retrieved once for each cluster
+	type clusterUpstreams struct {
+		upstreams []kube.ForwardProxyUpstream
+		err       error
+	}
+	upstreamsByCluster := make(map[uint]clusterUpstreams)
+
+	var routes []ForwardProxyRoute
+	for _, wsd := range *workloadServiceDependencies {
+		route := ForwardProxyRoute{
+			ServiceDependency: *wsd.Name,
+			UpstreamHost:      *wsd.UpstreamHost,
+			UpstreamPath:      *wsd.UpstreamPath,
+			Status:            RouteStatusUnknown,
+		}
+		workloadInstance, ok := instancesByID[*wsd.WorkloadInstanceID]
+		if !ok {
+			route.Message = fmt.Sprintf("workload instance with ID %d not found", *wsd.WorkloadInstanceID)
+			routes = append(routes, route)
+			continue
+		}
+		route.WorkloadInstance = *workloadInstance.Name
+		workloadCluster, ok := clustersByID[*workloadInstance.WorkloadClusterID]
+		if !ok {
+			route.Message = fmt.Sprintf("workload cluster with ID %d not found", *workloadInstance.WorkloadClusterID)
+			routes = append(routes, route)
+			continue
+		}
+		route.WorkloadCluster = *workloadCluster.Name
+
+		cluster, ok := upstreamsByCluster[*workloadCluster.ID]
+		if !ok {
+			credentials, err := clusterCredentials(workloadCluster)
+			if err != nil {
+				cluster.err = err
+			} else if credentials.APIEndpoint == "" {
+				cluster.err = fmt.Errorf("no API endpoint for workload cluster %s", *workloadCluster.Name)
+			} else {
+				cluster.upstreams, cluster.err = kube.GetForwardProxyUpstreams(ctx, credentials)
+			}
+			upstreamsByCluster[*workloadCluster.ID] = cluster
+		}
+		if cluster.err != nil {
+			route.Message = cluster.err.Error()
+			routes = append(routes, route)
+			continue
+		}
+		route.Status = RouteStatusNotRouted
+		for _, upstream := range cluster.upstreams {
+			if upstream.UpstreamHost == route.UpstreamHost && upstream.UpstreamPath == route.UpstreamPath {
+				route.Status = RouteStatusRouted
+				break
+			}
+		}
+		routes = append(routes, route)
+	}
+
+	return routes, nil
+}
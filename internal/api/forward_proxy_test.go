@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	tpapi "github.com/threeport/threeport-rest-api/pkg/api/v0"
+
+	"github.com/threeport/tptctl/internal/fakeapi"
+)
+
+func TestGetForwardProxyRoutes(t *testing.T) {
+	server, _ := newFakeAPI(t)
+
+	// the forward proxy on the workload cluster routes one upstream
+	kubeAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apis/routing.qleet.io/v1alpha1/forwardproxies" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"apiVersion":"routing.qleet.io/v1alpha1","kind":"ForwardProxyList","metadata":{},"items":[
+			{"apiVersion":"routing.qleet.io/v1alpha1","kind":"ForwardProxy","metadata":{"name":"api","namespace":"web"},
+			 "spec":{"upstreamHost":"api.example.com","upstreamPath":"/v1"}}]}`)
+	}))
+	defer kubeAPI.Close()
+
+	// the default workload cluster on kind is registered with its in-cluster
+	// endpoint so the control plane kubeconfig must be used to reach it
+	kubeconfigPath := filepath.Join(t.TempDir(), "kubeconfig-threeport-test")
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: test
+clusters:
+  - name: test
+    cluster:
+      server: %s
+contexts:
+  - name: test
+    context:
+      cluster: test
+      user: test
+users:
+  - name: test
+    user:
+      token: test-token
+`, kubeAPI.URL)
+	if err := ioutil.WriteFile(kubeconfigPath, []byte(kubeconfig), 0600); err != nil {
+		t.Fatalf("failed to write kubeconfig: %s", err)
+	}
+	SetControlPlaneKubeconfig(kubeconfigPath)
+	t.Cleanup(func() { SetControlPlaneKubeconfig("") })
+
+	clusterName := "kind"
+	inCluster := "https://kubernetes.default.svc"
+	clusterID, err := server.Add(fakeapi.WorkloadClusters, &tpapi.WorkloadCluster{
+		Name:        &clusterName,
+		APIEndpoint: &inCluster,
+	})
+	if err != nil {
+		t.Fatalf("failed to add workload cluster: %s", err)
+	}
+	instanceName := "web-kind-instance"
+	instance, err := server.Add(fakeapi.WorkloadInstances, &tpapi.WorkloadInstance{
+		Name:              &instanceName,
+		WorkloadClusterID: &clusterID,
+	})
+	if err != nil {
+		t.Fatalf("failed to add workload instance: %s", err)
+	}
+	for _, upstreamPath := range []string{"/v1", "/v2"} {
+		name := "api" + upstreamPath[1:]
+		upstreamHost := "api.example.com"
+		path := upstreamPath
+		_, err := server.Add(fakeapi.WorkloadServiceDependencies, &tpapi.WorkloadServiceDependency{
+			Name:               &name,
+			UpstreamHost:       &upstreamHost,
+			UpstreamPath:       &path,
+			WorkloadInstanceID: &instance,
+		})
+		if err != nil {
+			t.Fatalf("failed to add workload service dependency: %s", err)
+		}
+	}
+
+	routes, err := GetForwardProxyRoutes(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := map[string]RouteStatus{"apiv1": RouteStatusRouted, "apiv2": RouteStatusNotRouted}
+	if len(routes) != len(expected) {
+		t.Fatalf("expected %d routes, got %+v", len(expected), routes)
+	}
+	for _, route := range routes {
+		if route.Status != expected[route.ServiceDependency] {
+			t.Errorf("expected route for %s to be %s, got %+v", route.ServiceDependency, expected[route.ServiceDependency], route)
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	tpapi "github.com/threeport/threeport-rest-api/pkg/api/v0"
+)
+
+// GraphFormat is a format a graph can be rendered in.
+type GraphFormat string
+
+const (
+	GraphFormatDot     GraphFormat = "dot"
+	GraphFormatMermaid GraphFormat = "mermaid"
+	GraphFormatJSON    GraphFormat = "json"
+)
+
+// Kinds of node in a graph.
+const (
+	NodeKindWorkloadDefinition        = "WorkloadDefinition"
+	NodeKindWorkloadInstance          = "WorkloadInstance"
+	NodeKindWorkloadCluster           = "WorkloadCluster"
+	NodeKindWorkloadServiceDependency = "WorkloadServiceDependency"
+	NodeKindUpstream                  = "Upstream"
+	NodeKindMissing                   = "Missing"
+)
+
+// GraphNode is an object in the Threeport API or an upstream host.  Missing
+// nodes stand in for objects that are referenced but don't exist.
+type GraphNode struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// GraphEdge is a reference from one node to another.
+type GraphEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Label string `json:"label"`
+}
+
+// DanglingReference is a reference to an object that doesn't exist, e.g. a
+// workload service dependency for a deleted workload instance.
+type DanglingReference struct {
+	From    string `json:"from"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Graph shows how workload definitions, instances, clusters, service
+// dependencies and upstreams relate.
+type Graph struct {
+	Nodes    []GraphNode         `json:"nodes"`
+	Edges    []GraphEdge         `json:"edges"`
+	Dangling []DanglingReference `json:"dangling"`
+}
+
+// ParseGraphFormat returns the graph format for a string.
+func ParseGraphFormat(format string) (GraphFormat, error) {
+	switch GraphFormat(format) {
+	case GraphFormatDot, GraphFormatMermaid, GraphFormatJSON:
+		return GraphFormat(format), nil
+	default:
+		return "", errors.New(fmt.Sprintf(
+			"unsupported graph format '%s' - must be one of %s", format,
+			[]GraphFormat{GraphFormatDot, GraphFormatMermaid, GraphFormatJSON}))
+	}
+}
+
+// GetGraph builds the graph of the objects in the Threeport API.  Instances
+// reference their definition and cluster, and service dependencies reference
+// their instance and upstream host.  Instances of a parameterised definition
+// use a rendered definition that is linked to the definition it was rendered
+// from.  If workload is set, the graph is limited to the objects connected to
+// the definition, instance or service dependency with that name, or the
+// definition named <workload>-definition, along with their clusters and
+// upstreams.
+func GetGraph(workload string) (*Graph, error) {
+	workloadDefinitions, err := apiClient().GetWorkloadDefinitions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workload definitions: %w", err)
+	}
+	workloadInstances, err := apiClient().GetWorkloadInstances()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workload instances: %w", err)
+	}
+	workloadClusters, err := apiClient().GetWorkloadClusters()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workload clusters: %w", err)
+	}
+	workloadServiceDependencies, err := apiClient().GetWorkloadServiceDependencies()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workload service dependencies: %w", err)
+	}
+
+	return buildGraph(*workloadDefinitions, *workloadInstances, *workloadClusters, *workloadServiceDependencies, workload)
+}
+
+// buildGraph builds the graph of the objects, limited to a workload if set.
+func buildGraph(
+	workloadDefinitions []tpapi.WorkloadDefinition,
+	workloadInstances []tpapi.WorkloadInstance,
+	workloadClusters []tpapi.WorkloadCluster,
+	workloadServiceDependencies []tpapi.WorkloadServiceDependency,
+	workload string,
+) (*Graph, error) {
+	graph := &Graph{Nodes: []GraphNode{}, Edges: []GraphEdge{}, Dangling: []DanglingReference{}}
+	nodes := make(map[string]GraphNode)
+	addNode := func(node GraphNode) {
+		if _, ok := nodes[node.ID]; !ok {
+			nodes[node.ID] = node
+		}
+	}
+	// references to missing objects get a placeholder node
+	reference := func(from GraphNode, field, kind, toID string, id *uint) string {
+		if _, ok := nodes[toID]; ok {
+			return toID
+		}
+		missingID := "missing_" + toID
+		addNode(GraphNode{ID: missingID, Kind: NodeKindMissing, Name: fmt.Sprintf("%s %d", kind, uintValue(id))})
+		graph.Dangling = append(graph.Dangling, DanglingReference{
+			From:    from.ID,
+			Field:   field,
+			Message: fmt.Sprintf("%s %s references %s %d which does not exist", from.Kind, from.Name, kind, uintValue(id)),
+		})
+		return missingID
+	}
+
+	definitionsByName := make(map[string]string)
+	definitionsByID := make(map[string]*tpapi.WorkloadDefinition)
+	for i, wd := range workloadDefinitions {
+		node := GraphNode{ID: nodeID("def", wd.ID), Kind: NodeKindWorkloadDefinition, Name: stringValue(wd.Name)}
+		addNode(node)
+		definitionsByName[node.Name] = node.ID
+		definitionsByID[node.ID] = &workloadDefinitions[i]
+	}
+	for _, wc := range workloadClusters {
+		addNode(GraphNode{ID: nodeID("cluster", wc.ID), Kind: NodeKindWorkloadCluster, Name: stringValue(wc.Name)})
+	}
+	for _, wi := range workloadInstances {
+		addNode(GraphNode{ID: nodeID("inst", wi.ID), Kind: NodeKindWorkloadInstance, Name: stringValue(wi.Name)})
+	}
+	for _, wsd := range workloadServiceDependencies {
+		addNode(GraphNode{ID: nodeID("dep", wsd.ID), Kind: NodeKindWorkloadServiceDependency, Name: stringValue(wsd.Name)})
+	}
+
+	for _, wi := range workloadInstances {
+		instance := nodes[nodeID("inst", wi.ID)]
+		definitionID := reference(instance, "WorkloadDefinitionID", NodeKindWorkloadDefinition,
+			nodeID("def", wi.WorkloadDefinitionID), wi.WorkloadDefinitionID)
+		graph.Edges = append(graph.Edges, GraphEdge{From: instance.ID, To: definitionID, Label: "definition"})
+		clusterID := reference(instance, "WorkloadClusterID", NodeKindWorkloadCluster,
+			nodeID("cluster", wi.WorkloadClusterID), wi.WorkloadClusterID)
+		graph.Edges = append(graph.Edges, GraphEdge{From: instance.ID, To: clusterID, Label: "runs on"})
+
+		// link a rendered definition to the definition it was rendered from
+		if rendered, ok := definitionsByID[definitionID]; ok {
+			if templateName := SourceDefinitionName(rendered, instance.Name); templateName != "" {
+				if templateID, ok := definitionsByName[templateName]; ok {
+					graph.Edges = append(graph.Edges, GraphEdge{From: definitionID, To: templateID, Label: "rendered from"})
+				}
+			}
+		}
+	}
+	// upstreams are numbered in the order they are first referenced since
+	// different hosts can be the same once made into a valid ID
+	upstreamIDs := make(map[string]string)
+	for _, wsd := range workloadServiceDependencies {
+		dependency := nodes[nodeID("dep", wsd.ID)]
+		instanceID := reference(dependency, "WorkloadInstanceID", NodeKindWorkloadInstance,
+			nodeID("inst", wsd.WorkloadInstanceID), wsd.WorkloadInstanceID)
+		graph.Edges = append(graph.Edges, GraphEdge{From: instanceID, To: dependency.ID, Label: "depends on"})
+		upstreamHost := stringValue(wsd.UpstreamHost)
+		upstreamID, ok := upstreamIDs[upstreamHost]
+		if !ok {
+			upstreamID = fmt.Sprintf("up_%d", len(upstreamIDs))
+			upstreamIDs[upstreamHost] = upstreamID
+		}
+		addNode(GraphNode{ID: upstreamID, Kind: NodeKindUpstream, Name: upstreamHost})
+		graph.Edges = append(graph.Edges, GraphEdge{From: dependency.ID, To: upstreamID, Label: stringValue(wsd.UpstreamPath)})
+	}
+
+	// limit the graph to a workload
+	included := make(map[string]bool)
+	if workload == "" {
+		for id := range nodes {
+			included[id] = true
+		}
+	} else {
+		// walk the references between definitions, instances and dependencies
+		// so that shared clusters and upstreams don't pull in other workloads
+		traversable := func(id string) bool {
+			kind := nodes[id].Kind
+			return kind != NodeKindWorkloadCluster && kind != NodeKindUpstream
+		}
+		adjacent := make(map[string][]string)
+		for _, edge := range graph.Edges {
+			adjacent[edge.From] = append(adjacent[edge.From], edge.To)
+			adjacent[edge.To] = append(adjacent[edge.To], edge.From)
+		}
+		var queue []string
+		for id, node := range nodes {
+			if node.Kind != NodeKindMissing && traversable(id) &&
+				(node.Name == workload || (node.Kind == NodeKindWorkloadDefinition && node.Name == objectName(workload, "definition"))) {
+				queue = append(queue, id)
+				included[id] = true
+			}
+		}
+		if len(queue) == 0 {
+			return nil, errors.New(fmt.Sprintf("no workload objects named %s found", workload))
+		}
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			for _, next := range adjacent[id] {
+				if included[next] {
+					continue
+				}
+				included[next] = true
+				if traversable(next) {
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+
+	for id, node := range nodes {
+		if included[id] {
+			graph.Nodes = append(graph.Nodes, node)
+		}
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		if graph.Nodes[i].Kind != graph.Nodes[j].Kind {
+			return nodeKindOrder(graph.Nodes[i].Kind) < nodeKindOrder(graph.Nodes[j].Kind)
+		}
+		return graph.Nodes[i].Name < graph.Nodes[j].Name
+	})
+	var edges []GraphEdge
+	for _, edge := range graph.Edges {
+		if included[edge.From] && included[edge.To] {
+			edges = append(edges, edge)
+		}
+	}
+	graph.Edges = append([]GraphEdge{}, edges...)
+	var dangling []DanglingReference
+	for _, ref := range graph.Dangling {
+		if included[ref.From] {
+			dangling = append(dangling, ref)
+		}
+	}
+	graph.Dangling = append([]DanglingReference{}, dangling...)
+
+	return graph, nil
+}
+
+// Render returns the graph in a format.  Dangling references are shown as
+// missing nodes and listed in comments for the dot and mermaid formats.
+func (g *Graph) Render(format GraphFormat) (string, error) {
+	var rendered strings.Builder
+	switch format {
+	case GraphFormatJSON:
+		graphJSON, err := json.MarshalIndent(g, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal graph to JSON: %w", err)
+		}
+		rendered.Write(graphJSON)
+		rendered.WriteString("\n")
+	case GraphFormatDot:
+		rendered.WriteString("digraph threeport {\n  rankdir=LR;\n")
+		for _, ref := range g.Dangling {
+			fmt.Fprintf(&rendered, "  // dangling: %s\n", ref.Message)
+		}
+		for _, node := range g.Nodes {
+			fmt.Fprintf(&rendered, "  %s [label=%q, %s];\n", node.ID, node.Kind+"\n"+node.Name, dotNodeStyle(node.Kind))
+		}
+		for _, edge := range g.Edges {
+			fmt.Fprintf(&rendered, "  %s -> %s [label=%q];\n", edge.From, edge.To, edge.Label)
+		}
+		rendered.WriteString("}\n")
+	case GraphFormatMermaid:
+		rendered.WriteString("flowchart LR\n")
+		for _, ref := range g.Dangling {
+			fmt.Fprintf(&rendered, "  %%%% dangling: %s\n", ref.Message)
+		}
+		for _, node := range g.Nodes {
+			fmt.Fprintf(&rendered, "  %s%s\n", node.ID, mermaidNodeShape(node.Kind, node.Kind+"<br/>"+node.Name))
+		}
+		for _, edge := range g.Edges {
+			if edge.Label == "" {
+				fmt.Fprintf(&rendered, "  %s --> %s\n", edge.From, edge.To)
+				continue
+			}
+			fmt.Fprintf(&rendered, "  %s -->|%s| %s\n", edge.From, mermaidText(edge.Label), edge.To)
+		}
+		rendered.WriteString("  classDef missing stroke:#d00,stroke-dasharray:5 5,color:#d00\n")
+		for _, node := range g.Nodes {
+			if node.Kind == NodeKindMissing {
+				fmt.Fprintf(&rendered, "  class %s missing\n", node.ID)
+			}
+		}
+	default:
+		return "", errors.New(fmt.Sprintf("unsupported graph format '%s'", format))
+	}
+
+	return rendered.String(), nil
+}
+
+// nodeID returns the ID of the node for an object.
+func nodeID(prefix string, id *uint) string {
+	return fmt.Sprintf("%s_%d", prefix, uintValue(id))
+}
+
+// nodeKindOrder returns the position of a kind of node when listing nodes.
+func nodeKindOrder(kind string) int {
+	for i, k := range []string{
+		NodeKindWorkloadDefinition, NodeKindWorkloadInstance, NodeKindWorkloadCluster,
+		NodeKindWorkloadServiceDependency, NodeKindUpstream, NodeKindMissing,
+	} {
+		if kind == k {
+			return i
+		}
+	}
+
+	return len(kind)
+}
+
+// dotNodeStyle returns the dot attributes for a kind of node.
+func dotNodeStyle(kind string) string {
+	switch kind {
+	case NodeKindWorkloadDefinition:
+		return "shape=note"
+	case NodeKindWorkloadInstance:
+		return "shape=box"
+	case NodeKindWorkloadCluster:
+		return "shape=box3d"
+	case NodeKindWorkloadServiceDependency:
+		return "shape=cds"
+	case NodeKindUpstream:
+		return "shape=ellipse"
+	default:
+		return "shape=box, style=dashed, color=red, fontcolor=red"
+	}
+}
+
+// mermaidNodeShape returns the mermaid shape with a label for a kind of node.
+func mermaidNodeShape(kind, label string) string {
+	label = mermaidText(label)
+	switch kind {
+	case NodeKindWorkloadDefinition:
+		return fmt.Sprintf("[/\"%s\"/]", label)
+	case NodeKindWorkloadCluster:
+		return fmt.Sprintf("[(\"%s\")]", label)
+	case NodeKindWorkloadServiceDependency:
+		return fmt.Sprintf("{{\"%s\"}}", label)
+	case NodeKindUpstream:
+		return fmt.Sprintf("((\"%s\"))", label)
+	default:
+		return fmt.Sprintf("[\"%s\"]", label)
+	}
+}
+
+// mermaidText escapes text for use in a mermaid label.
+func mermaidText(text string) string {
+	return strings.NewReplacer(`"`, "#quot;", "|", "#124;").Replace(text)
+}
+
+// stringValue returns the value of a string field or an empty string if it is
+// not set.
+func stringValue(field *string) string {
+	if field == nil {
+		return ""
+	}
+
+	return *field
+}
+
+// uintValue returns the value of a uint field or 0 if it is not set.
+func uintValue(field *uint) uint {
+	if field == nil {
+		return 0
+	}
+
+	return *field
+}
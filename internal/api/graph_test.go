@@ -0,0 +1,225 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	tpapi "github.com/threeport/threeport-rest-api/pkg/api/v0"
+)
+
+// testGraphObjects returns two workloads, web and db, that share a cluster
+// and an upstream, and a service dependency whose instance has been deleted.
+func testGraphObjects() (
+	[]tpapi.WorkloadDefinition,
+	[]tpapi.WorkloadInstance,
+	[]tpapi.WorkloadCluster,
+	[]tpapi.WorkloadServiceDependency,
+) {
+	id := func(id uint) tpapi.Common { return tpapi.Common{ID: &id} }
+	uintField := func(value uint) *uint { return &value }
+	stringField := func(value string) *string { return &value }
+
+	definitions := []tpapi.WorkloadDefinition{
+		{Common: id(1), Name: stringField("web-definition")},
+		{Common: id(2), Name: stringField("db-definition")},
+	}
+	instances := []tpapi.WorkloadInstance{
+		{Common: id(1), Name: stringField("web"), WorkloadDefinitionID: uintField(1), WorkloadClusterID: uintField(1)},
+		{Common: id(2), Name: stringField("db"), WorkloadDefinitionID: uintField(2), WorkloadClusterID: uintField(1)},
+	}
+	clusters := []tpapi.WorkloadCluster{
+		{Common: id(1), Name: stringField("default")},
+	}
+	dependencies := []tpapi.WorkloadServiceDependency{
+		{Common: id(1), Name: stringField("web-api"), UpstreamHost: stringField("a-b.com"),
+			UpstreamPath: stringField("/v1"), WorkloadInstanceID: uintField(1)},
+		{Common: id(2), Name: stringField("db-api"), UpstreamHost: stringField("a_b.com"),
+			UpstreamPath: stringField("/"), WorkloadInstanceID: uintField(2)},
+		{Common: id(3), Name: stringField("db-backup"), UpstreamHost: stringField("a-b.com"),
+			UpstreamPath: stringField("/backup"), WorkloadInstanceID: uintField(2)},
+		{Common: id(4), Name: stringField("orphan"), UpstreamHost: stringField("a-b.com"),
+			UpstreamPath: stringField("/"), WorkloadInstanceID: uintField(9)},
+	}
+
+	return definitions, instances, clusters, dependencies
+}
+
+func TestBuildGraph(t *testing.T) {
+	testCases := []struct {
+		name         string
+		workload     string
+		wantNodes    []string
+		wantEdges    []GraphEdge
+		wantDangling []DanglingReference
+		wantErr      string
+	}{
+		{
+			name:     "all workloads",
+			workload: "",
+			wantNodes: []string{
+				"def_2", "def_1", "inst_2", "inst_1", "cluster_1",
+				"dep_2", "dep_3", "dep_4", "dep_1", "up_0", "up_1", "missing_inst_9",
+			},
+			wantEdges: []GraphEdge{
+				{From: "inst_1", To: "def_1", Label: "definition"},
+				{From: "inst_1", To: "cluster_1", Label: "runs on"},
+				{From: "inst_2", To: "def_2", Label: "definition"},
+				{From: "inst_2", To: "cluster_1", Label: "runs on"},
+				{From: "inst_1", To: "dep_1", Label: "depends on"},
+				{From: "dep_1", To: "up_0", Label: "/v1"},
+				{From: "inst_2", To: "dep_2", Label: "depends on"},
+				{From: "dep_2", To: "up_1", Label: "/"},
+				{From: "inst_2", To: "dep_3", Label: "depends on"},
+				{From: "dep_3", To: "up_0", Label: "/backup"},
+				{From: "missing_inst_9", To: "dep_4", Label: "depends on"},
+				{From: "dep_4", To: "up_0", Label: "/"},
+			},
+			wantDangling: []DanglingReference{{
+				From:    "dep_4",
+				Field:   "WorkloadInstanceID",
+				Message: "WorkloadServiceDependency orphan references WorkloadInstance 9 which does not exist",
+			}},
+		},
+		{
+			name:      "workload by instance name",
+			workload:  "web",
+			wantNodes: []string{"def_1", "inst_1", "cluster_1", "dep_1", "up_0"},
+			wantEdges: []GraphEdge{
+				{From: "inst_1", To: "def_1", Label: "definition"},
+				{From: "inst_1", To: "cluster_1", Label: "runs on"},
+				{From: "inst_1", To: "dep_1", Label: "depends on"},
+				{From: "dep_1", To: "up_0", Label: "/v1"},
+			},
+			wantDangling: []DanglingReference{},
+		},
+		{
+			name:      "workload by definition name",
+			workload:  "db-definition",
+			wantNodes: []string{"def_2", "inst_2", "cluster_1", "dep_2", "dep_3", "up_0", "up_1"},
+			wantEdges: []GraphEdge{
+				{From: "inst_2", To: "def_2", Label: "definition"},
+				{From: "inst_2", To: "cluster_1", Label: "runs on"},
+				{From: "inst_2", To: "dep_2", Label: "depends on"},
+				{From: "dep_2", To: "up_1", Label: "/"},
+				{From: "inst_2", To: "dep_3", Label: "depends on"},
+				{From: "dep_3", To: "up_0", Label: "/backup"},
+			},
+			wantDangling: []DanglingReference{},
+		},
+		{
+			name:      "dangling service dependency",
+			workload:  "orphan",
+			wantNodes: []string{"dep_4", "up_0", "missing_inst_9"},
+			wantEdges: []GraphEdge{
+				{From: "missing_inst_9", To: "dep_4", Label: "depends on"},
+				{From: "dep_4", To: "up_0", Label: "/"},
+			},
+			wantDangling: []DanglingReference{{
+				From:    "dep_4",
+				Field:   "WorkloadInstanceID",
+				Message: "WorkloadServiceDependency orphan references WorkloadInstance 9 which does not exist",
+			}},
+		},
+		{
+			name:     "unknown workload",
+			workload: "cache",
+			wantErr:  "no workload objects named cache found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			definitions, instances, clusters, dependencies := testGraphObjects()
+			graph, err := buildGraph(definitions, instances, clusters, dependencies, tc.workload)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var nodeIDs []string
+			for _, node := range graph.Nodes {
+				nodeIDs = append(nodeIDs, node.ID)
+			}
+			if !reflect.DeepEqual(nodeIDs, tc.wantNodes) {
+				t.Errorf("expected nodes %v, got %v", tc.wantNodes, nodeIDs)
+			}
+			if !reflect.DeepEqual(graph.Edges, tc.wantEdges) {
+				t.Errorf("expected edges %+v, got %+v", tc.wantEdges, graph.Edges)
+			}
+			if !reflect.DeepEqual(graph.Dangling, tc.wantDangling) {
+				t.Errorf("expected dangling references %+v, got %+v", tc.wantDangling, graph.Dangling)
+			}
+		})
+	}
+}
+
+func TestGraphRender(t *testing.T) {
+	graph := &Graph{
+		Nodes: []GraphNode{
+			{ID: "dep_1", Kind: NodeKindWorkloadServiceDependency, Name: `web "api"`},
+			{ID: "up_0", Kind: NodeKindUpstream, Name: "a-b.com"},
+			{ID: "missing_inst_9", Kind: NodeKindMissing, Name: "WorkloadInstance 9"},
+		},
+		Edges: []GraphEdge{
+			{From: "missing_inst_9", To: "dep_1", Label: "depends on"},
+			{From: "dep_1", To: "up_0", Label: `/v1|"beta"`},
+		},
+		Dangling: []DanglingReference{{
+			From:    "dep_1",
+			Field:   "WorkloadInstanceID",
+			Message: "WorkloadServiceDependency web references WorkloadInstance 9 which does not exist",
+		}},
+	}
+
+	testCases := []struct {
+		name   string
+		format GraphFormat
+		want   string
+	}{
+		{
+			name:   "dot",
+			format: GraphFormatDot,
+			want: `digraph threeport {
+  rankdir=LR;
+  // dangling: WorkloadServiceDependency web references WorkloadInstance 9 which does not exist
+  dep_1 [label="WorkloadServiceDependency\nweb \"api\"", shape=cds];
+  up_0 [label="Upstream\na-b.com", shape=ellipse];
+  missing_inst_9 [label="Missing\nWorkloadInstance 9", shape=box, style=dashed, color=red, fontcolor=red];
+  missing_inst_9 -> dep_1 [label="depends on"];
+  dep_1 -> up_0 [label="/v1|\"beta\""];
+}
+`,
+		},
+		{
+			name:   "mermaid",
+			format: GraphFormatMermaid,
+			want: `flowchart LR
+  %% dangling: WorkloadServiceDependency web references WorkloadInstance 9 which does not exist
+  dep_1{{"WorkloadServiceDependency<br/>web #quot;api#quot;"}}
+  up_0(("Upstream<br/>a-b.com"))
+  missing_inst_9["Missing<br/>WorkloadInstance 9"]
+  missing_inst_9 -->|depends on| dep_1
+  dep_1 -->|/v1#124;#quot;beta#quot;| up_0
+  classDef missing stroke:#d00,stroke-dasharray:5 5,color:#d00
+  class missing_inst_9 missing
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rendered, err := graph.Render(tc.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rendered != tc.want {
+				t.Errorf("expected:\n%s\ngot:\n%s", tc.want, rendered)
+			}
+		})
+	}
+}
@@ -0,0 +1,346 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	tpapi "github.com/threeport/threeport-rest-api/pkg/api/v0"
+
+	tperrors "github.com/threeport/tptctl/internal/errors"
+)
+
+// MigrationAction is what a migration does with an object on the target
+// Threeport instance.
+type MigrationAction string
+
+const (
+	MigrationActionCreate   MigrationAction = "create"
+	MigrationActionSkip     MigrationAction = "skip"
+	MigrationActionConflict MigrationAction = "conflict"
+)
+
+// MigrationOptions are the Threeport API endpoints to migrate between and the
+// names of the workload clusters on the target to use in place of those on
+// the source.  Clusters that are not in the map keep their name.  Definitions
+// are created on the target for UserID, which must be a user on the target
+// since user IDs on the source mean nothing there.
+type MigrationOptions struct {
+	SourceAPIEndpoint string
+	TargetAPIEndpoint string
+	ClusterMap        map[string]string
+	UserID            uint
+}
+
+// MigrationStep is the action for a single object in a migration.
+type MigrationStep struct {
+	Object string
+	Name   string
+	Action MigrationAction
+	Detail string
+
+	// the attributes the object is created with on the target
+	yamlDocument         string
+	userID               *uint
+	workloadDefinition   string
+	workloadCluster      string
+	upstreamHost         string
+	upstreamPath         string
+	workloadInstanceName string
+}
+
+// MigrationPlan is the set of steps that migrates a workload from one
+// Threeport instance to another.  Definitions come first, then instances and
+// then service dependencies so that each object's dependencies are in place
+// before it is created.
+type MigrationPlan struct {
+	Workload          string
+	TargetAPIEndpoint string
+	Steps             []MigrationStep
+}
+
+// PlanWorkloadMigration reads a workload from the source Threeport instance
+// and compares it with the target to work out which objects need to be
+// created.  Objects that exist on the target and are identical are skipped.
+// Objects that exist but differ, and instances whose workload cluster isn't on
+// the target, are conflicts that have to be resolved before the plan can be
+// applied.  Parameterised definitions are migrated along with the definitions
+// rendered for each instance so that instances deploy the same manifests.
+func PlanWorkloadMigration(name string, options MigrationOptions) (*MigrationPlan, error) {
+	if options.UserID == 0 {
+		return nil, tperrors.New(tperrors.KindConfig,
+			"no user for the target threeport instance - set UserID for it in the threeport config")
+	}
+	source, err := getExportObjects(NewClient(options.SourceAPIEndpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source threeport instance: %w", err)
+	}
+	target, err := getExportObjects(NewClient(options.TargetAPIEndpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read target threeport instance: %w", err)
+	}
+	workloadName, workloadDefinition, workloadInstances, err := source.workload(name)
+	if err != nil {
+		return nil, err
+	}
+	plan := MigrationPlan{Workload: workloadName, TargetAPIEndpoint: options.TargetAPIEndpoint}
+
+	// the workload definition and any definitions rendered for instances
+	definitions := []*tpapi.WorkloadDefinition{workloadDefinition}
+	for _, wi := range workloadInstances {
+		if wd := source.definitionByID(wi.WorkloadDefinitionID); wd != nil && wd != workloadDefinition {
+			definitions = append(definitions, wd)
+		}
+	}
+	for _, wd := range definitions {
+		step := planDefinition(wd, target, options.UserID)
+		plan.Steps = append(plan.Steps, step)
+	}
+
+	// the instances on the mapped workload clusters
+	instanceNames := make(map[uint]string)
+	for _, wi := range workloadInstances {
+		instanceNames[uintValue(wi.ID)] = stringValue(wi.Name)
+		plan.Steps = append(plan.Steps, planInstance(wi, source, target, options.ClusterMap))
+	}
+
+	// the service dependencies of the instances
+	for _, wsd := range source.workloadServiceDependencies {
+		instanceName, ok := instanceNames[uintValue(wsd.WorkloadInstanceID)]
+		if !ok {
+			continue
+		}
+		plan.Steps = append(plan.Steps, planServiceDependency(wsd, instanceName, target))
+	}
+
+	return &plan, nil
+}
+
+// planDefinition returns the step that migrates a workload definition to be
+// created for a user on the target.
+func planDefinition(workloadDefinition *tpapi.WorkloadDefinition, target *exportObjects, userID uint) MigrationStep {
+	step := MigrationStep{
+		Object:       "workload definition",
+		Name:         stringValue(workloadDefinition.Name),
+		Action:       MigrationActionCreate,
+		yamlDocument: stringValue(workloadDefinition.YAMLDocument),
+		userID:       &userID,
+	}
+	existing := target.definitionByName(step.Name)
+	switch {
+	case existing == nil:
+	case stringValue(existing.YAMLDocument) == step.yamlDocument:
+		step.Action = MigrationActionSkip
+		step.Detail = "exists and is identical"
+	default:
+		step.Action = MigrationActionConflict
+		step.Detail = "exists with a different YAML document"
+	}
+
+	return step
+}
+
+// planInstance returns the step that migrates a workload instance.
+func planInstance(
+	workloadInstance tpapi.WorkloadInstance,
+	source *exportObjects,
+	target *exportObjects,
+	clusterMap map[string]string,
+) MigrationStep {
+	step := MigrationStep{
+		Object: "workload instance",
+		Name:   stringValue(workloadInstance.Name),
+		Action: MigrationActionCreate,
+	}
+	if wd := source.definitionByID(workloadInstance.WorkloadDefinitionID); wd != nil {
+		step.workloadDefinition = stringValue(wd.Name)
+	}
+	sourceCluster := source.clusterName(workloadInstance.WorkloadClusterID)
+	if sourceCluster == "" {
+		step.Action = MigrationActionConflict
+		step.Detail = fmt.Sprintf("workload cluster %d not found on source", uintValue(workloadInstance.WorkloadClusterID))
+		return step
+	}
+	step.workloadCluster = sourceCluster
+	if mapped, ok := clusterMap[sourceCluster]; ok {
+		step.workloadCluster = mapped
+	}
+	step.Detail = fmt.Sprintf("on workload cluster %s", step.workloadCluster)
+	if !target.hasCluster(step.workloadCluster) {
+		step.Action = MigrationActionConflict
+		step.Detail = fmt.Sprintf("workload cluster %s not found on target - map it with --cluster-map %s=<cluster>",
+			step.workloadCluster, sourceCluster)
+		return step
+	}
+
+	var existing *tpapi.WorkloadInstance
+	for i, wi := range target.workloadInstances {
+		if stringValue(wi.Name) == step.Name {
+			existing = &target.workloadInstances[i]
+			break
+		}
+	}
+	if existing == nil {
+		return step
+	}
+	existingDefinition := ""
+	if wd := target.definitionByID(existing.WorkloadDefinitionID); wd != nil {
+		existingDefinition = stringValue(wd.Name)
+	}
+	existingCluster := target.clusterName(existing.WorkloadClusterID)
+	if existingDefinition == step.workloadDefinition && existingCluster == step.workloadCluster {
+		step.Action = MigrationActionSkip
+		step.Detail = "exists and is identical"
+		return step
+	}
+	step.Action = MigrationActionConflict
+	step.Detail = fmt.Sprintf("exists with workload definition %s on workload cluster %s",
+		existingDefinition, existingCluster)
+
+	return step
+}
+
+// planServiceDependency returns the step that migrates a workload service
+// dependency.
+func planServiceDependency(
+	workloadServiceDependency tpapi.WorkloadServiceDependency,
+	workloadInstanceName string,
+	target *exportObjects,
+) MigrationStep {
+	step := MigrationStep{
+		Object:               "workload service dependency",
+		Name:                 stringValue(workloadServiceDependency.Name),
+		Action:               MigrationActionCreate,
+		upstreamHost:         stringValue(workloadServiceDependency.UpstreamHost),
+		upstreamPath:         stringValue(workloadServiceDependency.UpstreamPath),
+		workloadInstanceName: workloadInstanceName,
+	}
+	step.Detail = fmt.Sprintf("upstream %s%s", step.upstreamHost, step.upstreamPath)
+
+	for _, wsd := range target.workloadServiceDependencies {
+		if stringValue(wsd.Name) != step.Name {
+			continue
+		}
+		existingInstance := ""
+		for _, wi := range target.workloadInstances {
+			if wi.ID != nil && wsd.WorkloadInstanceID != nil && *wi.ID == *wsd.WorkloadInstanceID {
+				existingInstance = stringValue(wi.Name)
+			}
+		}
+		if stringValue(wsd.UpstreamHost) == step.upstreamHost &&
+			stringValue(wsd.UpstreamPath) == step.upstreamPath &&
+			existingInstance == step.workloadInstanceName {
+			step.Action = MigrationActionSkip
+			step.Detail = "exists and is identical"
+			return step
+		}
+		step.Action = MigrationActionConflict
+		step.Detail = fmt.Sprintf("exists with upstream %s%s for workload instance %s",
+			stringValue(wsd.UpstreamHost), stringValue(wsd.UpstreamPath), existingInstance)
+		return step
+	}
+
+	return step
+}
+
+// hasCluster returns whether a workload cluster with a name exists.
+func (o *exportObjects) hasCluster(name string) bool {
+	for _, wc := range o.workloadClusters {
+		if stringValue(wc.Name) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Conflicts returns the steps that prevent the plan from being applied.
+func (mp *MigrationPlan) Conflicts() []MigrationStep {
+	var conflicts []MigrationStep
+	for _, step := range mp.Steps {
+		if step.Action == MigrationActionConflict {
+			conflicts = append(conflicts, step)
+		}
+	}
+
+	return conflicts
+}
+
+// Apply creates the objects in the plan on the target Threeport instance.  It
+// refuses to start if there are conflicts and stops at the first failure.  The
+// outcome for each object handled is returned.
+func (mp *MigrationPlan) Apply() ([]ObjectOutcome, error) {
+	if conflicts := mp.Conflicts(); len(conflicts) > 0 {
+		return nil, errors.New(fmt.Sprintf(
+			"migration plan for workload %s has %d conflicts", mp.Workload, len(conflicts)))
+	}
+
+	var outcomes []ObjectOutcome
+	for _, step := range mp.Steps {
+		if step.Action == MigrationActionSkip {
+			outcomes = append(outcomes, ObjectOutcome{Object: step.Object, Name: step.Name, Outcome: OutcomeUnchanged})
+			continue
+		}
+		if err := mp.create(step); err != nil {
+			outcomes = append(outcomes, ObjectOutcome{Object: step.Object, Name: step.Name, Outcome: OutcomeFailed, Err: err})
+			return outcomes, fmt.Errorf("failed to create %s %s: %w", step.Object, step.Name, err)
+		}
+		outcomes = append(outcomes, ObjectOutcome{Object: step.Object, Name: step.Name, Outcome: OutcomeCreated})
+	}
+
+	return outcomes, nil
+}
+
+// create creates the object for a step on the target Threeport instance.
+// References to other objects are looked up by name on the target.
+func (mp *MigrationPlan) create(step MigrationStep) error {
+	target := NewClient(mp.TargetAPIEndpoint)
+	switch step.Object {
+	case "workload definition":
+		wdJSON, err := json.Marshal(&tpapi.WorkloadDefinition{
+			Name:         &step.Name,
+			YAMLDocument: &step.yamlDocument,
+			UserID:       step.userID,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = target.CreateWorkloadDefinition(wdJSON)
+		return err
+	case "workload instance":
+		workloadCluster, err := target.GetWorkloadClusterByName(step.workloadCluster)
+		if err != nil {
+			return err
+		}
+		workloadDefinition, err := target.GetWorkloadDefinitionByName(step.workloadDefinition)
+		if err != nil {
+			return err
+		}
+		wiJSON, err := json.Marshal(&tpapi.WorkloadInstance{
+			Name:                 &step.Name,
+			WorkloadClusterID:    workloadCluster.ID,
+			WorkloadDefinitionID: workloadDefinition.ID,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = target.CreateWorkloadInstance(wiJSON)
+		return err
+	default:
+		workloadInstance, err := target.GetWorkloadInstanceByName(step.workloadInstanceName)
+		if err != nil {
+			return err
+		}
+		wsdJSON, err := json.Marshal(&tpapi.WorkloadServiceDependency{
+			Name:               &step.Name,
+			UpstreamHost:       &step.upstreamHost,
+			UpstreamPath:       &step.upstreamPath,
+			WorkloadInstanceID: workloadInstance.ID,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = target.CreateWorkloadServiceDependency(wsdJSON)
+		return err
+	}
+}
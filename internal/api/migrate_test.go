@@ -0,0 +1,233 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	tpapi "github.com/threeport/threeport-rest-api/pkg/api/v0"
+
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	"github.com/threeport/tptctl/internal/fakeapi"
+)
+
+func TestPlanWorkloadMigrationRequiresTargetUser(t *testing.T) {
+	server, _ := newFakeAPI(t)
+
+	_, err := PlanWorkloadMigration("web", MigrationOptions{
+		SourceAPIEndpoint: server.URL,
+		TargetAPIEndpoint: server.URL,
+	})
+	if kind := tperrors.KindOf(err); kind != tperrors.KindConfig {
+		t.Errorf("expected a migration without a target user to be %s, got %s: %v", tperrors.KindConfig, kind, err)
+	}
+}
+
+// newMigrationAPIs returns a fake source Threeport API with the test workload
+// on its default workload cluster and an empty fake target API with a
+// workload cluster named prod.
+func newMigrationAPIs(t *testing.T) (*fakeapi.Server, *fakeapi.Server) {
+	t.Helper()
+
+	source, _ := newFakeAPI(t)
+	if err := testWorkloadConfig(t).Create(); err != nil {
+		t.Fatalf("failed to create workload: %s", err)
+	}
+
+	target := fakeapi.NewServer()
+	t.Cleanup(target.Close)
+	clusterName := "prod"
+	if _, err := target.Add(fakeapi.WorkloadClusters, &tpapi.WorkloadCluster{Name: &clusterName}); err != nil {
+		t.Fatalf("failed to add workload cluster: %s", err)
+	}
+
+	return source, target
+}
+
+// migrationStep is the exported part of a migration step that is checked.
+type migrationStep struct {
+	Object string
+	Name   string
+	Action MigrationAction
+}
+
+func TestPlanWorkloadMigration(t *testing.T) {
+	differentManifest := "apiVersion: v1\nkind: ConfigMap\n"
+	testCases := []struct {
+		name       string
+		clusterMap map[string]string
+		existing   func(t *testing.T, target *fakeapi.Server)
+		expected   []migrationStep
+	}{
+		{
+			name:       "empty target",
+			clusterMap: map[string]string{"default": "prod"},
+			expected: []migrationStep{
+				{"workload definition", "web-definition", MigrationActionCreate},
+				{"workload instance", "web-default-instance", MigrationActionCreate},
+				{"workload service dependency", "web-api-example-com-service-default", MigrationActionCreate},
+			},
+		},
+		{
+			name:       "identical definition",
+			clusterMap: map[string]string{"default": "prod"},
+			existing: func(t *testing.T, target *fakeapi.Server) {
+				name, manifest := "web-definition", testManifest
+				if _, err := target.Add(fakeapi.WorkloadDefinitions, &tpapi.WorkloadDefinition{Name: &name, YAMLDocument: &manifest}); err != nil {
+					t.Fatalf("failed to add workload definition: %s", err)
+				}
+			},
+			expected: []migrationStep{
+				{"workload definition", "web-definition", MigrationActionSkip},
+				{"workload instance", "web-default-instance", MigrationActionCreate},
+				{"workload service dependency", "web-api-example-com-service-default", MigrationActionCreate},
+			},
+		},
+		{
+			name:       "different definition",
+			clusterMap: map[string]string{"default": "prod"},
+			existing: func(t *testing.T, target *fakeapi.Server) {
+				name := "web-definition"
+				if _, err := target.Add(fakeapi.WorkloadDefinitions, &tpapi.WorkloadDefinition{Name: &name, YAMLDocument: &differentManifest}); err != nil {
+					t.Fatalf("failed to add workload definition: %s", err)
+				}
+			},
+			expected: []migrationStep{
+				{"workload definition", "web-definition", MigrationActionConflict},
+				{"workload instance", "web-default-instance", MigrationActionCreate},
+				{"workload service dependency", "web-api-example-com-service-default", MigrationActionCreate},
+			},
+		},
+		{
+			name: "unmapped workload cluster",
+			expected: []migrationStep{
+				{"workload definition", "web-definition", MigrationActionCreate},
+				{"workload instance", "web-default-instance", MigrationActionConflict},
+				{"workload service dependency", "web-api-example-com-service-default", MigrationActionCreate},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			source, target := newMigrationAPIs(t)
+			if tc.existing != nil {
+				tc.existing(t, target)
+			}
+
+			plan, err := PlanWorkloadMigration("web", MigrationOptions{
+				SourceAPIEndpoint: source.URL,
+				TargetAPIEndpoint: target.URL,
+				ClusterMap:        tc.clusterMap,
+				UserID:            1,
+			})
+			if err != nil {
+				t.Fatalf("failed to plan migration: %s", err)
+			}
+			var steps []migrationStep
+			for _, step := range plan.Steps {
+				steps = append(steps, migrationStep{step.Object, step.Name, step.Action})
+			}
+			if !reflect.DeepEqual(steps, tc.expected) {
+				t.Errorf("expected steps %+v, got %+v", tc.expected, steps)
+			}
+			if _, err := plan.Apply(); (len(plan.Conflicts()) > 0) != (err != nil) {
+				t.Errorf("expected a plan with %d conflicts to be refused, got %v", len(plan.Conflicts()), err)
+			}
+		})
+	}
+}
+
+func TestMigrationPlanApply(t *testing.T) {
+	source, target := newMigrationAPIs(t)
+	plan, err := PlanWorkloadMigration("web", MigrationOptions{
+		SourceAPIEndpoint: source.URL,
+		TargetAPIEndpoint: target.URL,
+		ClusterMap:        map[string]string{"default": "prod"},
+		UserID:            1,
+	})
+	if err != nil {
+		t.Fatalf("failed to plan migration: %s", err)
+	}
+
+	outcomes, err := plan.Apply()
+	if err != nil {
+		t.Fatalf("failed to apply migration: %s", err)
+	}
+	var created []string
+	for _, outcome := range outcomes {
+		if outcome.Outcome == OutcomeCreated {
+			created = append(created, outcome.Name)
+		}
+	}
+	// objects are created before those that reference them
+	expected := []string{"web-definition", "web-default-instance", "web-api-example-com-service-default"}
+	if !reflect.DeepEqual(created, expected) {
+		t.Errorf("expected %v to be created in order, got %v", expected, created)
+	}
+
+	client := NewClient(target.URL)
+	workloadDefinition, err := client.GetWorkloadDefinitionByName("web-definition")
+	if err != nil {
+		t.Fatalf("failed to get workload definition: %s", err)
+	}
+	if stringValue(workloadDefinition.YAMLDocument) != testManifest || uintValue(workloadDefinition.UserID) != 1 {
+		t.Errorf("expected workload definition with the manifest for user 1, got %+v", workloadDefinition)
+	}
+	workloadCluster, err := client.GetWorkloadClusterByName("prod")
+	if err != nil {
+		t.Fatalf("failed to get workload cluster: %s", err)
+	}
+	workloadInstance, err := client.GetWorkloadInstanceByName("web-default-instance")
+	if err != nil {
+		t.Fatalf("failed to get workload instance: %s", err)
+	}
+	if uintValue(workloadInstance.WorkloadClusterID) != uintValue(workloadCluster.ID) ||
+		uintValue(workloadInstance.WorkloadDefinitionID) != uintValue(workloadDefinition.ID) {
+		t.Errorf("expected workload instance on cluster %d for definition %d, got %+v",
+			uintValue(workloadCluster.ID), uintValue(workloadDefinition.ID), workloadInstance)
+	}
+	workloadServiceDependency, err := client.GetWorkloadServiceDependencyByName("web-api-example-com-service-default")
+	if err != nil {
+		t.Fatalf("failed to get workload service dependency: %s", err)
+	}
+	if uintValue(workloadServiceDependency.WorkloadInstanceID) != uintValue(workloadInstance.ID) {
+		t.Errorf("expected workload service dependency for workload instance %d, got %+v",
+			uintValue(workloadInstance.ID), workloadServiceDependency)
+	}
+}
+
+func TestMigrationPlanApplyPartialFailure(t *testing.T) {
+	source, target := newMigrationAPIs(t)
+	plan, err := PlanWorkloadMigration("web", MigrationOptions{
+		SourceAPIEndpoint: source.URL,
+		TargetAPIEndpoint: target.URL,
+		ClusterMap:        map[string]string{"default": "prod"},
+		UserID:            1,
+	})
+	if err != nil {
+		t.Fatalf("failed to plan migration: %s", err)
+	}
+
+	// the instance is created on the target after the plan was made
+	name := "web-default-instance"
+	if _, err := target.Add(fakeapi.WorkloadInstances, &tpapi.WorkloadInstance{Name: &name}); err != nil {
+		t.Fatalf("failed to add workload instance: %s", err)
+	}
+
+	outcomes, err := plan.Apply()
+	if kind := tperrors.KindOf(err); kind != tperrors.KindConflict {
+		t.Errorf("expected the migration to fail with %s, got %s: %v", tperrors.KindConflict, kind, err)
+	}
+	var results []Outcome
+	for _, outcome := range outcomes {
+		results = append(results, outcome.Outcome)
+	}
+	// the migration stops at the failed instance
+	expected := []Outcome{OutcomeCreated, OutcomeFailed}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected outcomes %v, got %v", expected, results)
+	}
+	if count := target.Count(fakeapi.WorkloadServiceDependencies); count != 0 {
+		t.Errorf("expected no workload service dependencies on the target, got %d", count)
+	}
+}
@@ -0,0 +1,389 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	tpapi "github.com/threeport/threeport-rest-api/pkg/api/v0"
+)
+
+// ParameterType is the type of value a workload definition parameter accepts.
+type ParameterType string
+
+const (
+	ParameterTypeString  ParameterType = "string"
+	ParameterTypeInteger ParameterType = "integer"
+	ParameterTypeNumber  ParameterType = "number"
+	ParameterTypeBoolean ParameterType = "boolean"
+)
+
+// ParametersHeader prefixes the comment line in a stored workload definition
+// YAML document that declares its parameters.  The Threeport API has no field
+// for parameters so they are kept with the template they apply to.
+const ParametersHeader = "# tptctl.threeport.io/parameters: "
+
+// RenderedHeader prefixes the comment line in a rendered workload definition
+// YAML document that records the definition and instance it was rendered for
+// and the values set for the instance, other than those of sensitive
+// parameters.  It lets tptctl find the parameterised
+// definition an instance was created from and clean up the rendered
+// definition when the instance is deleted.
+const RenderedHeader = "# tptctl.threeport.io/rendered: "
+
+// ValuesHeader prefixes the comment line in workload definitions rendered by
+// earlier versions of tptctl that records only the values they were rendered
+// with.  It is still read so that those definitions can be exported.
+const ValuesHeader = "# tptctl.threeport.io/values: "
+
+// parameterNameRegex matches valid parameter names.  Names must be usable as
+// template fields, e.g. {{ .Values.imageTag }}.
+var parameterNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// WorkloadParameter is a typed parameter declared by a workload definition
+// that is set per workload instance with Values.  The values of sensitive
+// parameters are not recorded with the rendered definition.
+type WorkloadParameter struct {
+	Name        string        `yaml:"Name" json:"name"`
+	Type        ParameterType `yaml:"Type" json:"type"`
+	Default     interface{}   `yaml:"Default,omitempty" json:"default,omitempty"`
+	Required    bool          `yaml:"Required,omitempty" json:"required,omitempty"`
+	Sensitive   bool          `yaml:"Sensitive,omitempty" json:"sensitive,omitempty"`
+	Description string        `yaml:"Description,omitempty" json:"description,omitempty"`
+}
+
+// RenderedFrom is recorded in the header of a rendered workload definition.
+// Definition and Instance are empty for definitions rendered by earlier
+// versions of tptctl.  Values are the values set for the instance that export
+// writes back to its config.  Redacted are the names of the sensitive
+// parameters that were set but left out of Values.
+type RenderedFrom struct {
+	Definition string                 `json:"definition"`
+	Instance   string                 `json:"instance"`
+	Values     map[string]interface{} `json:"values,omitempty"`
+	Redacted   []string               `json:"redacted,omitempty"`
+}
+
+// ValidateParameters checks that parameter declarations have valid, unique
+// names, supported types and defaults that match their type.
+func ValidateParameters(parameters []WorkloadParameter) error {
+	names := make(map[string]bool)
+	for _, param := range parameters {
+		if !parameterNameRegex.MatchString(param.Name) {
+			return errors.New(fmt.Sprintf(
+				"invalid parameter name '%s' - must start with a letter or underscore and contain only letters, digits and underscores",
+				param.Name))
+		}
+		if names[param.Name] {
+			return errors.New(fmt.Sprintf("parameter %s is declared more than once", param.Name))
+		}
+		names[param.Name] = true
+
+		switch param.Type {
+		case ParameterTypeString, ParameterTypeInteger, ParameterTypeNumber, ParameterTypeBoolean:
+		default:
+			return errors.New(fmt.Sprintf(
+				"parameter %s has unsupported type '%s' - must be one of %s", param.Name, param.Type,
+				[]ParameterType{ParameterTypeString, ParameterTypeInteger, ParameterTypeNumber, ParameterTypeBoolean}))
+		}
+		if param.Default != nil {
+			if _, err := param.convert(param.Default); err != nil {
+				return fmt.Errorf("invalid default for parameter %s: %w", param.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ResolveValues checks the values for a workload instance against the
+// parameters of its definition and returns the values to render the definition
+// with.  Defaults are applied for parameters that have no value.
+func ResolveValues(parameters []WorkloadParameter, values map[string]interface{}) (map[string]interface{}, error) {
+	declared := make(map[string]bool)
+	for _, param := range parameters {
+		declared[param.Name] = true
+	}
+	var unknown []string
+	for name := range values {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, errors.New(fmt.Sprintf(
+			"values set for undeclared parameters: %s", strings.Join(unknown, ", ")))
+	}
+
+	resolved := make(map[string]interface{})
+	var failures []string
+	for _, param := range parameters {
+		value, set := values[param.Name]
+		if !set || value == nil {
+			switch {
+			case param.Default != nil:
+				value = param.Default
+			case param.Required:
+				failures = append(failures, fmt.Sprintf("parameter %s is required", param.Name))
+				continue
+			default:
+				resolved[param.Name] = param.zeroValue()
+				continue
+			}
+		}
+		converted, err := param.convert(value)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("invalid value for parameter %s: %s", param.Name, err))
+			continue
+		}
+		resolved[param.Name] = converted
+	}
+	if len(failures) > 0 {
+		return nil, errors.New(strings.Join(failures, "\n"))
+	}
+
+	return resolved, nil
+}
+
+// DefaultValues returns the values a definition renders with when no values
+// are set, using the zero value of the type for parameters without defaults.
+// It is used to validate the manifests of a parameterised definition.
+func DefaultValues(parameters []WorkloadParameter) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, param := range parameters {
+		value, err := param.convert(param.Default)
+		if param.Default == nil || err != nil {
+			value = param.zeroValue()
+		}
+		values[param.Name] = value
+	}
+
+	return values
+}
+
+// RenderTemplate renders a workload definition template with values.  Values
+// are referenced in the template as {{ .Values.name }}.
+func RenderTemplate(yamlTemplate string, values map[string]interface{}) (string, error) {
+	tmpl, err := template.New("workload-definition").Option("missingkey=error").Parse(yamlTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse workload definition template: %w", err)
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, map[string]interface{}{"Values": values}); err != nil {
+		return "", fmt.Errorf("failed to render workload definition template: %w", err)
+	}
+
+	return rendered.String(), nil
+}
+
+// AddParametersHeader prefixes a workload definition template with the header
+// that declares its parameters.
+func AddParametersHeader(yamlTemplate string, parameters []WorkloadParameter) (string, error) {
+	parametersJSON, err := json.Marshal(parameters)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal parameters: %w", err)
+	}
+
+	return fmt.Sprintf("%s%s\n%s", ParametersHeader, parametersJSON, yamlTemplate), nil
+}
+
+// ParseParametersHeader returns the parameters declared in the header of a
+// stored workload definition YAML document and the template that follows it.
+// If there is no header, no parameters are returned and the document is not a
+// template.
+func ParseParametersHeader(yamlDocument string) ([]WorkloadParameter, string, error) {
+	if !strings.HasPrefix(yamlDocument, ParametersHeader) {
+		return nil, yamlDocument, nil
+	}
+	header, yamlTemplate, _ := strings.Cut(strings.TrimPrefix(yamlDocument, ParametersHeader), "\n")
+	var parameters []WorkloadParameter
+	if err := json.Unmarshal([]byte(header), &parameters); err != nil {
+		return nil, "", fmt.Errorf("failed to unmarshal workload definition parameters: %w", err)
+	}
+
+	return parameters, yamlTemplate, nil
+}
+
+// AddRenderedHeader prefixes a rendered workload definition with the header
+// that records what it was rendered from.
+func AddRenderedHeader(rendered string, renderedFrom *RenderedFrom) (string, error) {
+	renderedFromJSON, err := json.Marshal(renderedFrom)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal rendered workload definition header: %w", err)
+	}
+
+	return fmt.Sprintf("%s%s\n%s", RenderedHeader, renderedFromJSON, rendered), nil
+}
+
+// ParseRenderedHeader returns what a rendered workload definition YAML
+// document was rendered from and the manifests that follow the header.  The
+// values header written by earlier versions of tptctl is also read.  If there
+// is no header, nil is returned.
+func ParseRenderedHeader(yamlDocument string) (*RenderedFrom, string, error) {
+	switch {
+	case strings.HasPrefix(yamlDocument, RenderedHeader):
+		header, rendered, _ := strings.Cut(strings.TrimPrefix(yamlDocument, RenderedHeader), "\n")
+		var renderedFrom RenderedFrom
+		if err := json.Unmarshal([]byte(header), &renderedFrom); err != nil {
+			return nil, "", fmt.Errorf("failed to unmarshal rendered workload definition header: %w", err)
+		}
+		return &renderedFrom, rendered, nil
+	case strings.HasPrefix(yamlDocument, ValuesHeader):
+		header, rendered, _ := strings.Cut(strings.TrimPrefix(yamlDocument, ValuesHeader), "\n")
+		values := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(header), &values); err != nil {
+			return nil, "", fmt.Errorf("failed to unmarshal workload instance values: %w", err)
+		}
+		return &RenderedFrom{Values: values}, rendered, nil
+	default:
+		return nil, yamlDocument, nil
+	}
+}
+
+// RecordedValues returns the values for a workload instance that are recorded
+// with its rendered definition and the sorted names of the sensitive
+// parameters whose values are left out.
+func RecordedValues(parameters []WorkloadParameter, values map[string]interface{}) (map[string]interface{}, []string) {
+	sensitive := make(map[string]bool)
+	for _, param := range parameters {
+		sensitive[param.Name] = param.Sensitive
+	}
+	var recorded map[string]interface{}
+	var redacted []string
+	for name, value := range values {
+		if sensitive[name] {
+			redacted = append(redacted, name)
+			continue
+		}
+		if recorded == nil {
+			recorded = make(map[string]interface{})
+		}
+		recorded[name] = value
+	}
+	sort.Strings(redacted)
+
+	return recorded, redacted
+}
+
+// StripRenderedHeader returns a workload definition YAML document without the
+// header recording what it was rendered from so that rendered definitions can
+// be compared by their manifests alone.
+func StripRenderedHeader(yamlDocument string) string {
+	if _, manifests, err := ParseRenderedHeader(yamlDocument); err == nil {
+		return manifests
+	}
+
+	return yamlDocument
+}
+
+// SourceDefinitionName returns the name of the parameterised workload
+// definition that a workload definition was rendered from for a workload
+// instance, or an empty string if it wasn't rendered for the instance.
+// Definitions rendered before the source was recorded are recognised by their
+// name, <definition>-<instance>.
+func SourceDefinitionName(workloadDefinition *tpapi.WorkloadDefinition, instanceName string) string {
+	renderedFrom, _, err := ParseRenderedHeader(stringValue(workloadDefinition.YAMLDocument))
+	if err == nil && renderedFrom != nil && renderedFrom.Definition != "" {
+		if renderedFrom.Instance != instanceName {
+			return ""
+		}
+		return renderedFrom.Definition
+	}
+	definitionName := stringValue(workloadDefinition.Name)
+	suffix := "-" + instanceName
+	if !strings.HasSuffix(definitionName, suffix) || definitionName == suffix {
+		return ""
+	}
+
+	return strings.TrimSuffix(definitionName, suffix)
+}
+
+// RenderWorkloadDefinition renders a stored workload definition YAML document
+// with the values for a workload instance.  If the definition declares no
+// parameters, it is returned unchanged and no values may be set.
+func RenderWorkloadDefinition(yamlDocument string, values map[string]interface{}) (string, bool, error) {
+	parameters, yamlTemplate, err := ParseParametersHeader(yamlDocument)
+	if err != nil {
+		return "", false, err
+	}
+	if parameters == nil {
+		if len(values) > 0 {
+			return "", false, errors.New("values are set but the workload definition declares no parameters")
+		}
+		return yamlDocument, false, nil
+	}
+
+	resolved, err := ResolveValues(parameters, values)
+	if err != nil {
+		return "", true, err
+	}
+	rendered, err := RenderTemplate(yamlTemplate, resolved)
+	if err != nil {
+		return "", true, err
+	}
+
+	return rendered, true, nil
+}
+
+// convert checks that a value matches the parameter type and returns it in
+// the form used for rendering.
+func (wp *WorkloadParameter) convert(value interface{}) (interface{}, error) {
+	switch wp.Type {
+	case ParameterTypeString:
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+	case ParameterTypeBoolean:
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	case ParameterTypeInteger:
+		switch v := value.(type) {
+		case int:
+			return int64(v), nil
+		case int64:
+			return v, nil
+		case uint64:
+			return int64(v), nil
+		case float64:
+			// numbers unmarshalled from JSON are always floats
+			if v == math.Trunc(v) {
+				return int64(v), nil
+			}
+		}
+	case ParameterTypeNumber:
+		switch v := value.(type) {
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case uint64:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		}
+	}
+
+	return nil, errors.New(fmt.Sprintf("expected %s, got %v (%T)", wp.Type, value, value))
+}
+
+// zeroValue returns the zero value for the parameter type.
+func (wp *WorkloadParameter) zeroValue() interface{} {
+	switch wp.Type {
+	case ParameterTypeInteger:
+		return int64(0)
+	case ParameterTypeNumber:
+		return float64(0)
+	case ParameterTypeBoolean:
+		return false
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,332 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	tpapi "github.com/threeport/threeport-rest-api/pkg/api/v0"
+)
+
+func TestValidateParameters(t *testing.T) {
+	testCases := []struct {
+		name       string
+		parameters []WorkloadParameter
+		wantErr    string
+	}{
+		{
+			name: "valid",
+			parameters: []WorkloadParameter{
+				{Name: "imageTag", Type: ParameterTypeString, Default: "v1"},
+				{Name: "replicas", Type: ParameterTypeInteger, Default: 2},
+				{Name: "cpu_limit", Type: ParameterTypeNumber, Default: 0.5},
+				{Name: "debug", Type: ParameterTypeBoolean},
+			},
+		},
+		{
+			name: "no parameters",
+		},
+		{
+			name:       "invalid name",
+			parameters: []WorkloadParameter{{Name: "image-tag", Type: ParameterTypeString}},
+			wantErr:    "invalid parameter name 'image-tag'",
+		},
+		{
+			name:       "name starting with a digit",
+			parameters: []WorkloadParameter{{Name: "1replicas", Type: ParameterTypeInteger}},
+			wantErr:    "invalid parameter name '1replicas'",
+		},
+		{
+			name: "duplicate name",
+			parameters: []WorkloadParameter{
+				{Name: "replicas", Type: ParameterTypeInteger},
+				{Name: "replicas", Type: ParameterTypeString},
+			},
+			wantErr: "parameter replicas is declared more than once",
+		},
+		{
+			name:       "unsupported type",
+			parameters: []WorkloadParameter{{Name: "tags", Type: "list"}},
+			wantErr:    "parameter tags has unsupported type 'list'",
+		},
+		{
+			name:       "default of wrong type",
+			parameters: []WorkloadParameter{{Name: "replicas", Type: ParameterTypeInteger, Default: "two"}},
+			wantErr:    "invalid default for parameter replicas",
+		},
+		{
+			name:       "fractional integer default",
+			parameters: []WorkloadParameter{{Name: "replicas", Type: ParameterTypeInteger, Default: 1.5}},
+			wantErr:    "invalid default for parameter replicas",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateParameters(tc.parameters)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRenderWorkloadDefinition(t *testing.T) {
+	parameters := []WorkloadParameter{
+		{Name: "imageTag", Type: ParameterTypeString, Required: true},
+		{Name: "replicas", Type: ParameterTypeInteger, Default: 1},
+		{Name: "cpu", Type: ParameterTypeNumber},
+		{Name: "debug", Type: ParameterTypeBoolean},
+	}
+	template := "image: {{ .Values.imageTag }}\nreplicas: {{ .Values.replicas }}\ncpu: {{ .Values.cpu }}\ndebug: {{ .Values.debug }}\n"
+	document, err := AddParametersHeader(template, parameters)
+	if err != nil {
+		t.Fatalf("failed to add parameters header: %s", err)
+	}
+
+	testCases := []struct {
+		name          string
+		document      string
+		values        map[string]interface{}
+		expected      string
+		parameterised bool
+		wantErr       string
+	}{
+		{
+			name:          "values and defaults",
+			document:      document,
+			values:        map[string]interface{}{"imageTag": "v1"},
+			expected:      "image: v1\nreplicas: 1\ncpu: 0\ndebug: false\n",
+			parameterised: true,
+		},
+		{
+			name:     "integer from whole float",
+			document: document,
+			values: map[string]interface{}{
+				"imageTag": "v1", "replicas": float64(3), "cpu": 2, "debug": true,
+			},
+			expected:      "image: v1\nreplicas: 3\ncpu: 2\ndebug: true\n",
+			parameterised: true,
+		},
+		{
+			name:          "fractional integer",
+			document:      document,
+			values:        map[string]interface{}{"imageTag": "v1", "replicas": 1.5},
+			parameterised: true,
+			wantErr:       "invalid value for parameter replicas",
+		},
+		{
+			name:          "wrong type",
+			document:      document,
+			values:        map[string]interface{}{"imageTag": 7, "debug": "yes"},
+			parameterised: true,
+			wantErr:       "invalid value for parameter debug",
+		},
+		{
+			name:          "missing required value",
+			document:      document,
+			values:        map[string]interface{}{"replicas": 2},
+			parameterised: true,
+			wantErr:       "parameter imageTag is required",
+		},
+		{
+			name:          "undeclared value",
+			document:      document,
+			values:        map[string]interface{}{"imageTag": "v1", "tag": "v2"},
+			parameterised: true,
+			wantErr:       "values set for undeclared parameters: tag",
+		},
+		{
+			name:     "not parameterised",
+			document: "image: nginx\n",
+			expected: "image: nginx\n",
+		},
+		{
+			name:     "values without parameters",
+			document: "image: nginx\n",
+			values:   map[string]interface{}{"imageTag": "v1"},
+			wantErr:  "values are set but the workload definition declares no parameters",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rendered, parameterised, err := RenderWorkloadDefinition(tc.document, tc.values)
+			if parameterised != tc.parameterised {
+				t.Errorf("expected parameterised %t, got %t", tc.parameterised, parameterised)
+			}
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if rendered != tc.expected {
+				t.Errorf("expected rendered %q, got %q", tc.expected, rendered)
+			}
+		})
+	}
+}
+
+func TestParseRenderedHeader(t *testing.T) {
+	renderedFrom := &RenderedFrom{
+		Definition: "web-definition",
+		Instance:   "web-prod",
+		Values:     map[string]interface{}{"imageTag": "v1"},
+	}
+	document, err := AddRenderedHeader("kind: Service\n", renderedFrom)
+	if err != nil {
+		t.Fatalf("failed to add rendered header: %s", err)
+	}
+
+	testCases := []struct {
+		name     string
+		document string
+		expected *RenderedFrom
+		manifest string
+	}{
+		{
+			name:     "rendered header",
+			document: document,
+			expected: renderedFrom,
+			manifest: "kind: Service\n",
+		},
+		{
+			name:     "redacted values",
+			document: RenderedHeader + `{"definition":"web-definition","instance":"web-prod","redacted":["apiKey"]}` + "\nkind: Service\n",
+			expected: &RenderedFrom{Definition: "web-definition", Instance: "web-prod", Redacted: []string{"apiKey"}},
+			manifest: "kind: Service\n",
+		},
+		{
+			name:     "values header",
+			document: ValuesHeader + `{"imageTag":"v1"}` + "\nkind: Service\n",
+			expected: &RenderedFrom{Values: map[string]interface{}{"imageTag": "v1"}},
+			manifest: "kind: Service\n",
+		},
+		{
+			name:     "no header",
+			document: "kind: Service\n",
+			manifest: "kind: Service\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parsed, manifest, err := ParseRenderedHeader(tc.document)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(parsed, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, parsed)
+			}
+			if manifest != tc.manifest {
+				t.Errorf("expected manifest %q, got %q", tc.manifest, manifest)
+			}
+		})
+	}
+}
+
+func TestRecordedValues(t *testing.T) {
+	parameters := []WorkloadParameter{
+		{Name: "imageTag", Type: ParameterTypeString},
+		{Name: "replicas", Type: ParameterTypeInteger, Default: 1},
+		{Name: "apiKey", Type: ParameterTypeString, Sensitive: true},
+		{Name: "dbPassword", Type: ParameterTypeString, Sensitive: true},
+	}
+
+	testCases := []struct {
+		name             string
+		values           map[string]interface{}
+		expectedValues   map[string]interface{}
+		expectedRedacted []string
+	}{
+		{
+			name:           "no sensitive values",
+			values:         map[string]interface{}{"imageTag": "v1", "replicas": 2},
+			expectedValues: map[string]interface{}{"imageTag": "v1", "replicas": 2},
+		},
+		{
+			name:             "sensitive values",
+			values:           map[string]interface{}{"imageTag": "v1", "dbPassword": "hunter2", "apiKey": "abc"},
+			expectedValues:   map[string]interface{}{"imageTag": "v1"},
+			expectedRedacted: []string{"apiKey", "dbPassword"},
+		},
+		{
+			name:             "only sensitive values",
+			values:           map[string]interface{}{"apiKey": "abc"},
+			expectedRedacted: []string{"apiKey"},
+		},
+		{
+			name: "no values",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			values, redacted := RecordedValues(parameters, tc.values)
+			if !reflect.DeepEqual(values, tc.expectedValues) {
+				t.Errorf("expected values %v, got %v", tc.expectedValues, values)
+			}
+			if !reflect.DeepEqual(redacted, tc.expectedRedacted) {
+				t.Errorf("expected redacted %v, got %v", tc.expectedRedacted, redacted)
+			}
+		})
+	}
+}
+
+func TestStripRenderedHeader(t *testing.T) {
+	rendered, err := AddRenderedHeader("kind: Service\n", &RenderedFrom{Definition: "web", Instance: "prod"})
+	if err != nil {
+		t.Fatalf("failed to add rendered header: %s", err)
+	}
+
+	for _, document := range []string{
+		rendered,
+		ValuesHeader + `{"imageTag":"v1"}` + "\nkind: Service\n",
+		"kind: Service\n",
+	} {
+		if manifest := StripRenderedHeader(document); manifest != "kind: Service\n" {
+			t.Errorf("expected header to be stripped from %q, got %q", document, manifest)
+		}
+	}
+}
+
+func TestSourceDefinitionName(t *testing.T) {
+	rendered, err := AddRenderedHeader("kind: Service\n", &RenderedFrom{Definition: "web", Instance: "prod"})
+	if err != nil {
+		t.Fatalf("failed to add rendered header: %s", err)
+	}
+	plain := "kind: Service\n"
+
+	testCases := []struct {
+		name         string
+		definition   string
+		document     string
+		instanceName string
+		expected     string
+	}{
+		{name: "recorded", definition: "renamed", document: rendered, instanceName: "prod", expected: "web"},
+		{name: "recorded for another instance", definition: "web-prod", document: rendered, instanceName: "dev"},
+		{name: "named for the instance", definition: "web-prod", document: plain, instanceName: "prod", expected: "web"},
+		{name: "not rendered", definition: "web", document: plain, instanceName: "prod"},
+		{name: "only the suffix", definition: "-prod", document: plain, instanceName: "prod"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			workloadDefinition := &tpapi.WorkloadDefinition{Name: &tc.definition, YAMLDocument: &tc.document}
+			if name := SourceDefinitionName(workloadDefinition, tc.instanceName); name != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, name)
+			}
+		})
+	}
+}
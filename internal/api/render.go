@@ -0,0 +1,328 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+	"sigs.k8s.io/yaml"
+
+	kube "github.com/threeport/tptctl/internal/kubernetes"
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+const (
+	RendererAnnotation     = "tptctl.threeport.io/renderer"
+	RenderInputsAnnotation = "tptctl.threeport.io/render-inputs"
+	RendererHelm           = "helm"
+	RendererKustomize      = "kustomize"
+)
+
+// HelmConfig contains the inputs needed to render a Helm chart into a YAML
+// document for a workload definition.
+type HelmConfig struct {
+	Chart       string                 `yaml:"Chart" json:"Chart"`
+	Repo        string                 `yaml:"Repo" json:"Repo,omitempty"`
+	Version     string                 `yaml:"Version" json:"Version,omitempty"`
+	Namespace   string                 `yaml:"Namespace" json:"Namespace,omitempty"`
+	Values      map[string]interface{} `yaml:"Values" json:"Values,omitempty"`
+	ValuesFiles []string               `yaml:"ValuesFiles" json:"ValuesFiles,omitempty"`
+}
+
+// helmRenderInputs are the inputs recorded for a rendered Helm chart.  Inline
+// values are recorded with the values of secret keys replaced by a hash of
+// them.  RedactedValues are the paths to those keys, e.g. auth.adminPassword,
+// which must be supplied again to re-render the chart.  ValuesHash is a hash
+// of all inline values so a change to a secret value can be detected.
+type helmRenderInputs struct {
+	Chart          string                 `json:"Chart"`
+	Repo           string                 `json:"Repo,omitempty"`
+	Version        string                 `json:"Version,omitempty"`
+	Namespace      string                 `json:"Namespace,omitempty"`
+	ValuesFiles    []string               `json:"ValuesFiles,omitempty"`
+	Values         map[string]interface{} `json:"Values,omitempty"`
+	RedactedValues []string               `json:"RedactedValues,omitempty"`
+	ValuesHash     string                 `json:"ValuesHash,omitempty"`
+}
+
+// KustomizeConfig contains the inputs needed to render a Kustomize overlay
+// into a YAML document for a workload definition.
+type KustomizeConfig struct {
+	Path string `yaml:"Path" json:"Path"`
+}
+
+// Render renders the Helm chart locally with `helm template` and returns the
+// resulting multi-document YAML.  The release name is used as the name of the
+// Helm release.
+func (hc *HelmConfig) Render(releaseName string) (string, error) {
+	// values are unmarshalled from the config file with interface keys that
+	// cannot be marshalled to JSON
+	values := normalizeYAMLMap(hc.Values)
+	inputs := helmRenderInputs{
+		Chart:       hc.Chart,
+		Repo:        hc.Repo,
+		Version:     hc.Version,
+		Namespace:   hc.Namespace,
+		ValuesFiles: hc.ValuesFiles,
+	}
+
+	helmArgs := []string{"template", releaseName, hc.Chart}
+	if hc.Repo != "" {
+		helmArgs = append(helmArgs, "--repo", hc.Repo)
+	}
+	if hc.Version != "" {
+		helmArgs = append(helmArgs, "--version", hc.Version)
+	}
+	if hc.Namespace != "" {
+		helmArgs = append(helmArgs, "--namespace", hc.Namespace)
+	}
+	for _, valuesFile := range hc.ValuesFiles {
+		helmArgs = append(helmArgs, "--values", valuesFile)
+	}
+
+	// inline values are written to a temporary values file so they take
+	// precedence over the values files
+	if len(values) > 0 {
+		valuesYAML, err := yaml.Marshal(values)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal helm values: %w", err)
+		}
+		valuesJSON, err := json.Marshal(values)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal helm values: %w", err)
+		}
+		inputs.ValuesHash = fmt.Sprintf("sha256:%x", sha256.Sum256(valuesJSON))
+		recorded, redacted := redactHelmValues(values, "")
+		inputs.Values = recorded.(map[string]interface{})
+		inputs.RedactedValues = redacted
+		valuesFile, err := ioutil.TempFile("", "tptctl-helm-values-*.yaml")
+		if err != nil {
+			return "", fmt.Errorf("failed to create helm values file: %w", err)
+		}
+		defer os.Remove(valuesFile.Name())
+		if _, err := valuesFile.Write(valuesYAML); err != nil {
+			valuesFile.Close()
+			return "", fmt.Errorf("failed to write helm values file: %w", err)
+		}
+		valuesFile.Close()
+		helmArgs = append(helmArgs, "--values", valuesFile.Name())
+	}
+
+	helmTemplate := exec.Command("helm", helmArgs...)
+	helmTemplateOut, err := qout.RunCommand(helmTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to render helm chart %s: %w", hc.Chart, err)
+	}
+
+	return annotateRenderInputs(string(helmTemplateOut), RendererHelm, &inputs)
+}
+
+// Render renders the Kustomize overlay locally with `kubectl kustomize` and
+// returns the resulting multi-document YAML.
+func (kc *KustomizeConfig) Render() (string, error) {
+	kustomizeBuild := exec.Command("kubectl", "kustomize", kc.Path)
+	kustomizeBuildOut, err := qout.RunCommand(kustomizeBuild)
+	if err != nil {
+		return "", fmt.Errorf("failed to render kustomize overlay %s: %w", kc.Path, err)
+	}
+
+	return annotateRenderInputs(string(kustomizeBuildOut), RendererKustomize, kc)
+}
+
+// resolvePaths returns a copy of the Helm config with a local chart and values
+// files resolved relative to baseDir.  A chart is treated as local if it is
+// not from a repo and the path exists.
+func (hc *HelmConfig) resolvePaths(baseDir string) *HelmConfig {
+	resolved := *hc
+	if hc.Repo == "" && !strings.Contains(hc.Chart, "://") {
+		if _, err := os.Stat(ResolvePath(hc.Chart, baseDir)); err == nil {
+			resolved.Chart = ResolvePath(hc.Chart, baseDir)
+		}
+	}
+	resolved.ValuesFiles = make([]string, len(hc.ValuesFiles))
+	for i, valuesFile := range hc.ValuesFiles {
+		resolved.ValuesFiles[i] = ResolvePath(valuesFile, baseDir)
+	}
+
+	return &resolved
+}
+
+// resolvePaths returns a copy of the Kustomize config with a local path
+// resolved relative to baseDir.  Remote targets, e.g. git URLs, are unchanged.
+func (kc *KustomizeConfig) resolvePaths(baseDir string) *KustomizeConfig {
+	resolved := *kc
+	if !strings.Contains(kc.Path, "://") {
+		resolved.Path = ResolvePath(kc.Path, baseDir)
+	}
+
+	return &resolved
+}
+
+// annotateRenderInputs adds annotations to every object in a multi-document
+// YAML that record the renderer and the inputs used to render it.  This allows
+// a workload definition to be re-rendered later from the same inputs.  The
+// objects are otherwise unchanged, keeping their comments and key order.
+func annotateRenderInputs(yamlDocument, renderer string, inputs interface{}) (string, error) {
+	inputsJSON, err := json.Marshal(inputs)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal render inputs: %w", err)
+	}
+
+	var annotated []string
+	for _, doc := range kube.SplitManifest(yamlDocument) {
+		var node yamlv3.Node
+		if err := yamlv3.Unmarshal([]byte(doc), &node); err != nil {
+			return "", fmt.Errorf("failed to unmarshal rendered yaml: %w", err)
+		}
+		if len(node.Content) == 0 || node.Content[0].Kind != yamlv3.MappingNode {
+			// only comments or not an object
+			continue
+		}
+
+		metadata := mappingValue(node.Content[0], "metadata")
+		annotations := mappingValue(metadata, "annotations")
+		setMappingValue(annotations, RendererAnnotation, renderer)
+		setMappingValue(annotations, RenderInputsAnnotation, string(inputsJSON))
+
+		var objectYAML bytes.Buffer
+		encoder := yamlv3.NewEncoder(&objectYAML)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&node); err != nil {
+			return "", fmt.Errorf("failed to marshal annotated yaml: %w", err)
+		}
+		annotated = append(annotated, objectYAML.String())
+	}
+
+	return JoinYAMLDocuments(annotated), nil
+}
+
+// mappingValue returns the mapping for a key in a YAML mapping, adding an
+// empty one if the key is missing or has no value.
+func mappingValue(mapping *yamlv3.Node, key string) *yamlv3.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			value := mapping.Content[i+1]
+			if value.Kind != yamlv3.MappingNode {
+				// e.g. "annotations:" with no value
+				*value = yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+			}
+			return value
+		}
+	}
+	value := &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+	mapping.Content = append(mapping.Content,
+		&yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: key}, value)
+
+	return value
+}
+
+// setMappingValue sets a key in a YAML mapping to a string value.
+func setMappingValue(mapping *yamlv3.Node, key, value string) {
+	valueNode := &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: value}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = valueNode
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content,
+		&yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: key}, valueNode)
+}
+
+// JoinYAMLDocuments joins YAML documents into a single multi-document YAML.
+func JoinYAMLDocuments(docs []string) string {
+	var joined strings.Builder
+	for _, doc := range docs {
+		joined.WriteString("---\n")
+		joined.WriteString(strings.TrimRight(doc, "\n"))
+		joined.WriteString("\n")
+	}
+
+	return joined.String()
+}
+
+// redactHelmValues returns a copy of Helm values with the values of secret keys
+// replaced by a hash of them, as Secret data is in logs and diffs, and the
+// paths to the redacted keys.  Keys are redacted when they are secret, e.g.
+// adminPassword, and hold a scalar value.
+func redactHelmValues(value interface{}, path string) (interface{}, []string) {
+	var redacted []string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, fieldValue := range v {
+			fieldPath := key
+			if path != "" {
+				fieldPath = path + "." + key
+			}
+			switch fieldValue.(type) {
+			case map[string]interface{}, []interface{}, nil:
+			default:
+				if qout.IsSecretKey(key) {
+					copied[key] = qout.HashedValue(fmt.Sprint(fieldValue))
+					redacted = append(redacted, fieldPath)
+					continue
+				}
+			}
+			copiedValue, redactedPaths := redactHelmValues(fieldValue, fieldPath)
+			copied[key] = copiedValue
+			redacted = append(redacted, redactedPaths...)
+		}
+		sort.Strings(redacted)
+		return copied, redacted
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, element := range v {
+			copiedValue, redactedPaths := redactHelmValues(element, fmt.Sprintf("%s[%d]", path, i))
+			copied[i] = copiedValue
+			redacted = append(redacted, redactedPaths...)
+		}
+		return copied, redacted
+	default:
+		return value, nil
+	}
+}
+
+// normalizeYAMLMap converts any maps with interface keys, as produced by
+// gopkg.in/yaml.v2, within a map to maps with string keys.
+func normalizeYAMLMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	normalized := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		normalized[key] = normalizeYAMLValue(value)
+	}
+
+	return normalized
+}
+
+// normalizeYAMLValue converts a value with maps that have interface keys to
+// one with string keys.
+func normalizeYAMLValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			normalized[fmt.Sprintf("%v", key)] = normalizeYAMLValue(val)
+		}
+		return normalized
+	case map[string]interface{}:
+		return normalizeYAMLMap(v)
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, val := range v {
+			normalized[i] = normalizeYAMLValue(val)
+		}
+		return normalized
+	default:
+		return value
+	}
+}
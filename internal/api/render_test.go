@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+func TestRedactHelmValues(t *testing.T) {
+	var values map[string]interface{}
+	err := yaml.Unmarshal([]byte(`replicaCount: 2
+image:
+  tag: "1.25"
+auth:
+  adminPassword: hunter2
+  existingSecret: ""
+  token:
+    create: true
+apiKey: 12345
+extraEnv:
+  - name: MODE
+    value: production
+  - name: DB_PASSWORD
+    secret: s3cret
+tolerations:
+  - key: dedicated
+    value: web
+`), &values)
+	if err != nil {
+		t.Fatalf("failed to unmarshal values: %s", err)
+	}
+
+	recorded, redacted := redactHelmValues(normalizeYAMLMap(values), "")
+	wantRedacted := []string{"apiKey", "auth.adminPassword", "auth.existingSecret", "extraEnv[1].secret"}
+	if !reflect.DeepEqual(redacted, wantRedacted) {
+		t.Errorf("expected redacted values %v, got %v", wantRedacted, redacted)
+	}
+	recordedJSON, err := json.Marshal(recorded)
+	if err != nil {
+		t.Fatalf("failed to marshal recorded values: %s", err)
+	}
+	for _, secret := range []string{"hunter2", "12345", "s3cret"} {
+		if strings.Contains(string(recordedJSON), secret) {
+			t.Errorf("expected %q to be redacted, got %s", secret, recordedJSON)
+		}
+	}
+	for _, kept := range []string{
+		`"replicaCount":2`,
+		`"tag":"1.25"`,
+		`"token":{"create":true}`,
+		`"value":"production"`,
+		`"key":"dedicated"`,
+		`"adminPassword":"` + qout.HashedValue("hunter2") + `"`,
+	} {
+		if !strings.Contains(string(recordedJSON), kept) {
+			t.Errorf("expected %s to be recorded, got %s", kept, recordedJSON)
+		}
+	}
+
+	// the values passed in are left as they are for rendering
+	if values["apiKey"] != 12345 {
+		t.Errorf("expected values to be unchanged, got %v", values["apiKey"])
+	}
+}
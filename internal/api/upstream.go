@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	kube "github.com/threeport/tptctl/internal/kubernetes"
+)
+
+// Validate checks the syntax of the upstream host, path and scheme of the
+// workload service dependency.  The host is a DNS name or IP address with an
+// optional port, the path is an absolute URL path and the scheme, if set, is
+// http or https.
+func (wsdc *WorkloadServiceDependencyConfig) Validate() error {
+	if err := validateUpstreamHost(wsdc.UpstreamHost); err != nil {
+		return fmt.Errorf("invalid UpstreamHost for workload service dependency %s: %w", wsdc.Name, err)
+	}
+	if err := validateUpstreamPath(wsdc.UpstreamPath); err != nil {
+		return fmt.Errorf("invalid UpstreamPath for workload service dependency %s: %w", wsdc.Name, err)
+	}
+	if err := validateUpstreamScheme(wsdc.UpstreamScheme); err != nil {
+		return fmt.Errorf("invalid UpstreamScheme for workload service dependency %s: %w", wsdc.Name, err)
+	}
+
+	return nil
+}
+
+// validateUpstreamHost checks that an upstream host is a DNS name or IP address
+// with an optional port.
+func validateUpstreamHost(upstreamHost string) error {
+	if upstreamHost == "" {
+		return errors.New("upstream host is required")
+	}
+	if strings.Contains(upstreamHost, "://") {
+		return errors.New(fmt.Sprintf("'%s' must not include a scheme", upstreamHost))
+	}
+	if strings.ContainsAny(upstreamHost, "/?#") {
+		return errors.New(fmt.Sprintf("'%s' must not include a path - set it in UpstreamPath", upstreamHost))
+	}
+
+	host, port, err := splitUpstreamHost(upstreamHost)
+	if err != nil {
+		return err
+	}
+	// a trailing colon is an empty port rather than no port
+	if port != "" || strings.HasSuffix(upstreamHost, ":") {
+		portNumber, err := strconv.Atoi(port)
+		if err != nil || portNumber < 1 || portNumber > 65535 {
+			return errors.New(fmt.Sprintf("invalid port '%s' - must be between 1 and 65535", port))
+		}
+	}
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	if errs := validation.IsDNS1123Subdomain(strings.ToLower(host)); len(errs) > 0 {
+		return errors.New(fmt.Sprintf("invalid host name '%s': %s", host, errs[0]))
+	}
+
+	return nil
+}
+
+// validateUpstreamPath checks that an upstream path is an absolute URL path
+// without a query or fragment.  An empty path is the root path.
+func validateUpstreamPath(upstreamPath string) error {
+	if upstreamPath == "" {
+		return nil
+	}
+	if !strings.HasPrefix(upstreamPath, "/") {
+		return errors.New(fmt.Sprintf("'%s' must start with /", upstreamPath))
+	}
+	if strings.ContainsAny(upstreamPath, "?#") {
+		return errors.New(fmt.Sprintf("'%s' must not include a query or fragment", upstreamPath))
+	}
+	if strings.ContainsAny(upstreamPath, " \t\n") {
+		return errors.New(fmt.Sprintf("'%s' must not include whitespace", upstreamPath))
+	}
+	if _, err := url.ParseRequestURI(upstreamPath); err != nil {
+		return fmt.Errorf("'%s' is not a valid URL path: %w", upstreamPath, err)
+	}
+
+	return nil
+}
+
+// splitUpstreamHost splits an upstream host into the host and the port if it
+// has one.
+func splitUpstreamHost(upstreamHost string) (string, string, error) {
+	// a bare IPv6 address has colons but no port
+	if net.ParseIP(upstreamHost) != nil {
+		return upstreamHost, "", nil
+	}
+	if !strings.Contains(upstreamHost, ":") {
+		return upstreamHost, "", nil
+	}
+	host, port, err := net.SplitHostPort(upstreamHost)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid host and port '%s': %w", upstreamHost, err)
+	}
+
+	return host, port, nil
+}
+
+// validateUpstreamScheme checks that an upstream scheme is empty, http or
+// https.
+func validateUpstreamScheme(upstreamScheme string) error {
+	switch upstreamScheme {
+	case "", "http", "https":
+		return nil
+	default:
+		return errors.New(fmt.Sprintf("'%s' must be http or https", upstreamScheme))
+	}
+}
+
+// upstreamURL returns the URL requested to probe an upstream.  If no scheme is
+// given, upstreams without a port or on port 443 are requested over HTTPS and
+// all others over plain HTTP.
+func upstreamURL(upstreamScheme, upstreamHost, upstreamPath string) string {
+	host, port, _ := splitUpstreamHost(upstreamHost)
+	if upstreamScheme == "" {
+		upstreamScheme = "http"
+		if port == "" || port == "443" {
+			upstreamScheme = "https"
+		}
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		// IPv6 addresses are bracketed in URLs
+		host = "[" + host + "]"
+	}
+
+	return fmt.Sprintf("%s://%s%s", upstreamScheme, host, upstreamPath)
+}
+
+// Probe checks that the upstream of the workload service dependency resolves
+// and answers, both directly and through the forward proxy, from the workload
+// cluster of its workload instance.  The workload instance must exist in the
+// Threeport API.
+func (wsdc *WorkloadServiceDependencyConfig) Probe(
+	ctx context.Context,
+	options kube.ProbeOptions,
+) (*kube.ProbeResult, error) {
+	if err := wsdc.Validate(); err != nil {
+		return nil, err
+	}
+	credentials, manifest, err := GetWorkloadInstanceResources(wsdc.WorkloadInstanceName)
+	if err != nil {
+		return nil, err
+	}
+	if credentials.APIEndpoint == "" {
+		return nil, errors.New(fmt.Sprintf(
+			"workload cluster for workload instance %s has no API endpoint to run the probe on", wsdc.WorkloadInstanceName))
+	}
+
+	// an upstream that is a service on the workload cluster is requested with
+	// the scheme of its service port unless one is set
+	upstreamScheme := wsdc.UpstreamScheme
+	if upstreamScheme == "" {
+		host, port, _ := splitUpstreamHost(wsdc.UpstreamHost)
+		if upstreamScheme, err = kube.ServiceScheme(ctx, credentials, host, port); err != nil {
+			return nil, err
+		}
+	}
+
+	return kube.ProbeUpstream(ctx, credentials, manifest,
+		upstreamURL(upstreamScheme, wsdc.UpstreamHost, wsdc.UpstreamPath), options)
+}
+
+// ProbeWorkloadServiceDependency probes the upstream of a workload service
+// dependency in the Threeport API.  The scheme isn't stored in the API so it
+// is given by upstreamScheme, or determined as for a workload service
+// dependency config without one if empty.
+func ProbeWorkloadServiceDependency(
+	ctx context.Context,
+	name string,
+	upstreamScheme string,
+	options kube.ProbeOptions,
+) (*kube.ProbeResult, error) {
+	workloadServiceDependency, err := findWorkloadServiceDependency(name)
+	if err != nil {
+		return nil, err
+	}
+	if workloadServiceDependency == nil {
+		return nil, tperrors.New(tperrors.KindNotFound, fmt.Sprintf("workload service dependency %s not found", name))
+	}
+	if workloadServiceDependency.WorkloadInstanceID == nil {
+		return nil, errors.New(fmt.Sprintf("workload service dependency %s has no workload instance", name))
+	}
+	workloadInstance, err := apiClient().GetWorkloadInstanceByID(uintValue(workloadServiceDependency.WorkloadInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workload instance for workload service dependency %s: %w", name, err)
+	}
+	wsdc := WorkloadServiceDependencyConfig{
+		Name:                 name,
+		UpstreamHost:         stringValue(workloadServiceDependency.UpstreamHost),
+		UpstreamPath:         stringValue(workloadServiceDependency.UpstreamPath),
+		WorkloadInstanceName: stringValue(workloadInstance.Name),
+		UpstreamScheme:       upstreamScheme,
+	}
+
+	return wsdc.Probe(ctx, options)
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateUpstreamHost(t *testing.T) {
+	testCases := []struct {
+		name         string
+		upstreamHost string
+		wantErr      string
+	}{
+		{name: "host name", upstreamHost: "rpc.ankr.com"},
+		{name: "host name with port", upstreamHost: "rpc.ankr.com:443"},
+		{name: "upper case host name", upstreamHost: "RPC.Ankr.com"},
+		{name: "IPv4 address", upstreamHost: "10.0.0.1"},
+		{name: "IPv4 address with port", upstreamHost: "10.0.0.1:8080"},
+		{name: "IPv6 address", upstreamHost: "fd00::1"},
+		{name: "IPv6 address with port", upstreamHost: "[fd00::1]:443"},
+		{name: "empty", upstreamHost: "", wantErr: "upstream host is required"},
+		{name: "scheme", upstreamHost: "https://rpc.ankr.com", wantErr: "must not include a scheme"},
+		{name: "path", upstreamHost: "rpc.ankr.com/eth", wantErr: "must not include a path"},
+		{name: "query", upstreamHost: "rpc.ankr.com?key=value", wantErr: "must not include a path"},
+		{name: "port out of range", upstreamHost: "rpc.ankr.com:70000", wantErr: "invalid port '70000'"},
+		{name: "port not a number", upstreamHost: "rpc.ankr.com:https", wantErr: "invalid port 'https'"},
+		{name: "missing port", upstreamHost: "rpc.ankr.com:", wantErr: "invalid port ''"},
+		{name: "invalid host name", upstreamHost: "rpc_ankr.com", wantErr: "invalid host name 'rpc_ankr.com'"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateUpstreamHost(tc.upstreamHost)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateUpstreamPath(t *testing.T) {
+	testCases := []struct {
+		name         string
+		upstreamPath string
+		wantErr      string
+	}{
+		{name: "empty", upstreamPath: ""},
+		{name: "root", upstreamPath: "/"},
+		{name: "path", upstreamPath: "/eth/v1"},
+		{name: "relative", upstreamPath: "eth", wantErr: "must start with /"},
+		{name: "query", upstreamPath: "/eth?key=value", wantErr: "must not include a query or fragment"},
+		{name: "fragment", upstreamPath: "/eth#top", wantErr: "must not include a query or fragment"},
+		{name: "whitespace", upstreamPath: "/eth v1", wantErr: "must not include whitespace"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateUpstreamPath(tc.upstreamPath)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateUpstreamScheme(t *testing.T) {
+	testCases := []struct {
+		name           string
+		upstreamScheme string
+		wantErr        string
+	}{
+		{name: "empty", upstreamScheme: ""},
+		{name: "http", upstreamScheme: "http"},
+		{name: "https", upstreamScheme: "https"},
+		{name: "other", upstreamScheme: "grpc", wantErr: "'grpc' must be http or https"},
+		{name: "upper case", upstreamScheme: "HTTPS", wantErr: "'HTTPS' must be http or https"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateUpstreamScheme(tc.upstreamScheme)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestUpstreamURL(t *testing.T) {
+	testCases := []struct {
+		name           string
+		upstreamScheme string
+		upstreamHost   string
+		upstreamPath   string
+		want           string
+	}{
+		{name: "default port", upstreamHost: "rpc.ankr.com", upstreamPath: "/eth", want: "https://rpc.ankr.com/eth"},
+		{name: "port 443", upstreamHost: "rpc.ankr.com:443", upstreamPath: "/eth", want: "https://rpc.ankr.com:443/eth"},
+		{name: "port 80", upstreamHost: "example.com:80", upstreamPath: "/", want: "http://example.com:80/"},
+		{name: "other port", upstreamHost: "10.0.0.1:8545", want: "http://10.0.0.1:8545"},
+		{name: "scheme set", upstreamScheme: "https", upstreamHost: "10.0.0.1:8545", want: "https://10.0.0.1:8545"},
+		{name: "http on port 443", upstreamScheme: "http", upstreamHost: "example.com:443", want: "http://example.com:443"},
+		{name: "IPv6 address", upstreamHost: "fd00::1", upstreamPath: "/eth", want: "https://[fd00::1]/eth"},
+		{name: "IPv6 address with port", upstreamHost: "[fd00::1]:80", want: "http://[fd00::1]:80"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := upstreamURL(tc.upstreamScheme, tc.upstreamHost, tc.upstreamPath); got != tc.want {
+				t.Errorf("upstreamURL(%q, %q, %q) = %q, want %q", tc.upstreamScheme, tc.upstreamHost, tc.upstreamPath, got, tc.want)
+			}
+		})
+	}
+}
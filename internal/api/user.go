@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	tpapi "github.com/threeport/threeport-rest-api/pkg/api/v0"
+
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	"github.com/threeport/tptctl/internal/threeport"
+)
+
+// UserConfig contains the attributes needed to manage a user.  If no password
+// is set, one is generated when the user is created.
+type UserConfig struct {
+	Email     string
+	Password  string
+	FirstName string
+	LastName  string
+	Admin     bool
+}
+
+// Create creates a user in the Threeport API.  A generated password is set on
+// the config so it can be given to the user.
+func (uc *UserConfig) Create() (*tpapi.User, error) {
+	if uc.Email == "" {
+		return nil, errors.New("email is required to create a user")
+	}
+	existing, err := FindUser(uc.Email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, tperrors.New(tperrors.KindConflict, fmt.Sprintf("user %s already exists", uc.Email))
+	}
+	if uc.Password == "" {
+		password, err := threeport.GeneratePassword()
+		if err != nil {
+			return nil, err
+		}
+		uc.Password = password
+	}
+
+	// construct user object
+	user := &tpapi.User{
+		Email:    &uc.Email,
+		Password: &uc.Password,
+		Admin:    &uc.Admin,
+	}
+	if uc.FirstName != "" {
+		user.FirstName = &uc.FirstName
+	}
+	if uc.LastName != "" {
+		user.LastName = &uc.LastName
+	}
+
+	// create user in API
+	userJSON, err := json.Marshal(&user)
+	if err != nil {
+		return nil, err
+	}
+	u, err := apiClient().CreateUser(userJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// GetUsers returns all the users in the Threeport API.
+func GetUsers() ([]tpapi.User, error) {
+	users, err := apiClient().GetUsers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	return *users, nil
+}
+
+// FindUser returns the user with an email or nil if it doesn't exist.
+func FindUser(email string) (*tpapi.User, error) {
+	users, err := GetUsers()
+	if err != nil {
+		return nil, err
+	}
+	for i, user := range users {
+		if user.Email != nil && *user.Email == email {
+			return &users[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// DeleteUser deletes the user with an email from the Threeport API.
+func DeleteUser(email string) (*tpapi.User, error) {
+	user, err := FindUser(email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, tperrors.New(tperrors.KindNotFound, fmt.Sprintf("user %s not found", email))
+	}
+	u, err := apiClient().DeleteUser(*user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
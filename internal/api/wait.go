@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	kube "github.com/threeport/tptctl/internal/kubernetes"
+	qout "github.com/threeport/tptctl/internal/output"
+)
+
+// WaitCondition is a condition of a workload instance that can be waited for.
+type WaitCondition string
+
+const (
+	WaitConditionReady   WaitCondition = "ready"
+	WaitConditionDeleted WaitCondition = "deleted"
+)
+
+const (
+	// WaitTimeout is the default time to wait for a workload instance.
+	WaitTimeout = time.Minute * 10
+	// WaitPollInterval is how often the state of a workload instance is
+	// checked while waiting.
+	WaitPollInterval = time.Second * 5
+)
+
+var (
+	// ErrWaitTimeout is returned when a workload instance doesn't reach the
+	// condition waited for in time.
+	ErrWaitTimeout = errors.New("timed out waiting for workload instance")
+	// ErrWorkloadFailed is returned when a workload instance cannot reach the
+	// condition waited for.
+	ErrWorkloadFailed = errors.New("workload instance failed")
+)
+
+// ParseWaitCondition returns the wait condition for a string.
+func ParseWaitCondition(condition string) (WaitCondition, error) {
+	switch WaitCondition(condition) {
+	case WaitConditionReady, WaitConditionDeleted:
+		return WaitCondition(condition), nil
+	default:
+		return "", errors.New(fmt.Sprintf(
+			"unsupported wait condition '%s' - must be one of %s", condition,
+			[]WaitCondition{WaitConditionReady, WaitConditionDeleted}))
+	}
+}
+
+// WaitForWorkloadInstance polls the Threeport API until a workload instance
+// reaches a condition and outputs each change in its status.  A workload
+// instance is ready when it has been reconciled and the Deployments,
+// StatefulSets and DaemonSets it defines have rolled out on its workload
+// cluster.  If the workload cluster can't be reached from tptctl, an instance
+// is ready once it has been reconciled.  Failures to check the status are
+// retried until the timeout.  ErrWaitTimeout is returned if the timeout is
+// reached and ErrWorkloadFailed if the instance is deleted while waiting for
+// it to be ready or a rollout cannot complete.
+func WaitForWorkloadInstance(
+	ctx context.Context,
+	name string,
+	condition WaitCondition,
+	timeout time.Duration,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	lastStatus := ""
+	for {
+		done, status, err := checkWorkloadInstance(ctx, name, condition)
+		if err != nil {
+			if errors.Is(err, ErrWorkloadFailed) {
+				return err
+			}
+			// requests to the Threeport API or the workload cluster can fail
+			// briefly, e.g. while the API restarts, so keep polling
+			status = fmt.Sprintf("failed to check status, retrying: %s", err)
+		}
+		if status != lastStatus {
+			qout.Progress("workload-instance", fmt.Sprintf("workload instance %s: %s", name, status))
+			lastStatus = status
+		}
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("%w %s to be %s after %s - last status: %s",
+					ErrWaitTimeout, name, condition, timeout, lastStatus)
+			}
+			return ctx.Err()
+		case <-time.After(WaitPollInterval):
+		}
+	}
+}
+
+// checkWorkloadInstance checks whether a workload instance has reached a
+// condition and returns a description of its current status.
+func checkWorkloadInstance(ctx context.Context, name string, condition WaitCondition) (bool, string, error) {
+	// list the instances rather than getting by name so that an instance
+	// that doesn't exist can be told apart from a failed request
+	workloadInstance, err := findWorkloadInstance(name)
+	if err != nil {
+		return false, "", err
+	}
+
+	if condition == WaitConditionDeleted {
+		if workloadInstance == nil {
+			return true, "deleted", nil
+		}
+		return false, "waiting for deletion", nil
+	}
+
+	if workloadInstance == nil {
+		return false, "", fmt.Errorf("%w: workload instance %s does not exist", ErrWorkloadFailed, name)
+	}
+	if workloadInstance.Reconciled == nil || !*workloadInstance.Reconciled {
+		return false, "waiting for reconciliation", nil
+	}
+
+	// check the rollout on the workload cluster
+	credentials, manifest, err := workloadInstanceResources(workloadInstance)
+	if err != nil {
+		return false, "", err
+	}
+	if credentials.APIEndpoint == "" {
+		// rollout can't be checked without access to the cluster, e.g. for
+		// a cluster registered with its in-cluster endpoint when there is
+		// no control plane kubeconfig
+		return true, "reconciled", nil
+	}
+	workloadStatus, err := kube.GetWorkloadStatus(ctx, credentials, manifest)
+	if err != nil {
+		return false, "", err
+	}
+	if workloadStatus.Failed {
+		return false, "", fmt.Errorf("%w: %s", ErrWorkloadFailed, workloadStatus.Message)
+	}
+	if !workloadStatus.Ready {
+		return false, fmt.Sprintf("reconciled, rolling out: %s", workloadStatus.Message), nil
+	}
+
+	return true, fmt.Sprintf("ready: %s", workloadStatus.Message), nil
+}
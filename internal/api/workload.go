@@ -1,79 +1,452 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
-	"io/ioutil"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
 
-	tpclient "github.com/threeport/threeport-go-client"
 	tpapi "github.com/threeport/threeport-rest-api/pkg/api/v0"
 
-	"github.com/threeport/tptctl/internal/install"
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	kube "github.com/threeport/tptctl/internal/kubernetes"
+	qout "github.com/threeport/tptctl/internal/output"
 )
 
-// WorkloadConfig contains the attributes needed to manage a workload.
+// WorkloadConfig contains the attributes needed to manage a workload.  A
+// workload may run as several instances, e.g. one per workload cluster, and
+// have several service dependencies.  The WorkloadInstance and
+// WorkloadServiceDependency fields are supported for configs written before
+// the lists and are added to them by Resolve.  Every section is optional and
+// names left empty are derived from the workload name.
 type WorkloadConfig struct {
-	Name                      string                          `yaml:"Name"`
-	WorkloadDefinition        WorkloadDefinitionConfig        `yaml:"WorkloadDefinition"`
-	WorkloadInstance          WorkloadInstanceConfig          `yaml:"WorkloadInstance"`
-	WorkloadServiceDependency WorkloadServiceDependencyConfig `yaml:"WorkloadServiceDependency"`
+	Name                        string                            `yaml:"Name"`
+	WorkloadDefinition          WorkloadDefinitionConfig          `yaml:"WorkloadDefinition"`
+	WorkloadInstances           []WorkloadInstanceConfig          `yaml:"WorkloadInstances,omitempty"`
+	WorkloadServiceDependencies []WorkloadServiceDependencyConfig `yaml:"WorkloadServiceDependencies,omitempty"`
+	WorkloadInstance            *WorkloadInstanceConfig           `yaml:"WorkloadInstance,omitempty"`
+	WorkloadServiceDependency   *WorkloadServiceDependencyConfig  `yaml:"WorkloadServiceDependency,omitempty"`
 }
 
 // WorkloadDefinitionConfig contains the attributes needed to manage a workload
-// definition.
+// definition.  The YAML document for the definition is taken from exactly one
+// of YAMLDocument, Helm or Kustomize.  A YAMLDocument may be a template that
+// uses the declared Parameters, which are set per workload instance.  Relative
+// paths are resolved against ConfigDir, the directory of the config file.
 type WorkloadDefinitionConfig struct {
-	Name         string `yaml:"Name"`
-	YAMLDocument string `yaml:"YAMLDocument"`
-	UserID       uint   `yaml:"UserID"`
+	Name         string              `yaml:"Name"`
+	YAMLDocument YAMLDocumentPaths   `yaml:"YAMLDocument,omitempty"`
+	Helm         *HelmConfig         `yaml:"Helm,omitempty"`
+	Kustomize    *KustomizeConfig    `yaml:"Kustomize,omitempty"`
+	Parameters   []WorkloadParameter `yaml:"Parameters,omitempty"`
+	UserID       uint                `yaml:"UserID,omitempty"`
+	ConfigDir    string              `yaml:"-"`
 }
 
 // WorkloadInstanceConfig contains the attributes needed to manage a workload
-// instance.
+// instance.  Values are set for the parameters declared by the workload
+// definition.
 type WorkloadInstanceConfig struct {
-	Name                   string `yaml:"Name"`
-	WorkloadClusterName    string `yaml:"WorkloadClusterName"`
-	WorkloadDefinitionName string `yaml:"WorkloadDefinitionName"`
+	Name                   string                 `yaml:"Name"`
+	WorkloadClusterName    string                 `yaml:"WorkloadClusterName,omitempty"`
+	WorkloadDefinitionName string                 `yaml:"WorkloadDefinitionName,omitempty"`
+	Values                 map[string]interface{} `yaml:"Values,omitempty"`
 }
 
 // WorkloadServiceDependencyConfig contains the attributes needed to manage a
 // workload service dependency.
 type WorkloadServiceDependencyConfig struct {
 	Name                 string `yaml:"Name"`
-	UpstreamHost         string `yaml:"UpstreamHost"`
-	UpstreamPath         string `yaml:"UpstreamPath"`
-	WorkloadInstanceName string `yaml:"WorkloadInstanceName"`
+	UpstreamHost         string `yaml:"UpstreamHost,omitempty"`
+	UpstreamPath         string `yaml:"UpstreamPath,omitempty"`
+	WorkloadInstanceName string `yaml:"WorkloadInstanceName,omitempty"`
+
+	// UpstreamScheme is the scheme, http or https, the upstream is requested
+	// with when it is probed.  It is not stored in the Threeport API.
+	UpstreamScheme string `yaml:"UpstreamScheme,omitempty"`
+}
+
+// Resolve fills in the parts of the config that can be derived so it can be
+// acted on:
+//   - the WorkloadInstance and WorkloadServiceDependency fields are moved into
+//     the lists
+//   - an empty definition name is set to <workload>-definition
+//   - an empty instance definition name is set to the workload definition
+//   - an empty instance name is set to <workload>-<cluster>-instance
+//   - an empty service dependency name is set to
+//     <workload>-<upstream host>-service
+//   - a service dependency without an instance name applies to every instance
+//     and its name is suffixed with the instance's cluster name, unless the
+//     name is set in the config and there is only one instance
+//
+// Derived names only depend on the object they are for so that adding an
+// instance or service dependency to a workload doesn't rename the others.
+// Resolve can be called more than once.
+func (wc *WorkloadConfig) Resolve() error {
+	if wc.WorkloadInstance != nil {
+		wc.WorkloadInstances = append(wc.WorkloadInstances, *wc.WorkloadInstance)
+		wc.WorkloadInstance = nil
+	}
+	if wc.WorkloadServiceDependency != nil {
+		wc.WorkloadServiceDependencies = append(wc.WorkloadServiceDependencies, *wc.WorkloadServiceDependency)
+		wc.WorkloadServiceDependency = nil
+	}
+
+	// derive names from the workload name
+	deriveName := func(name *string, kind string, parts ...string) error {
+		if *name != "" {
+			return nil
+		}
+		if wc.Name == "" {
+			return errors.New(fmt.Sprintf("workload name is required to derive the %s name", kind))
+		}
+		*name = objectName(append([]string{wc.Name}, parts...)...)
+		return nil
+	}
+	if wc.WorkloadDefinition.HasSource() {
+		if err := deriveName(&wc.WorkloadDefinition.Name, "workload definition", "definition"); err != nil {
+			return err
+		}
+	}
+	for i := range wc.WorkloadInstances {
+		wi := &wc.WorkloadInstances[i]
+		if wi.WorkloadDefinitionName == "" {
+			if wc.WorkloadDefinition.Name == "" {
+				return errors.New(fmt.Sprintf(
+					"workload instance %d has no WorkloadDefinitionName and the workload has no definition", i+1))
+			}
+			wi.WorkloadDefinitionName = wc.WorkloadDefinition.Name
+		}
+		if wi.Name == "" && wi.WorkloadClusterName == "" {
+			return errors.New(fmt.Sprintf(
+				"workload instance %d needs a Name or WorkloadClusterName to derive its name from", i+1))
+		}
+		if err := deriveName(&wi.Name, "workload instance", wi.WorkloadClusterName, "instance"); err != nil {
+			return err
+		}
+	}
+
+	// apply service dependencies without an instance to every instance
+	var serviceDependencies []WorkloadServiceDependencyConfig
+	for i, wsd := range wc.WorkloadServiceDependencies {
+		derived := wsd.Name == ""
+		if err := deriveName(&wsd.Name, "workload service dependency", wsd.UpstreamHost, "service"); err != nil {
+			return err
+		}
+		if wsd.WorkloadInstanceName != "" {
+			serviceDependencies = append(serviceDependencies, wsd)
+			continue
+		}
+		if len(wc.WorkloadInstances) == 0 {
+			return errors.New(fmt.Sprintf(
+				"workload service dependency %d has no WorkloadInstanceName and the workload has no instances", i+1))
+		}
+		for _, wi := range wc.WorkloadInstances {
+			instanceDependency := wsd
+			// a name set in the config is only suffixed if it is needed to
+			// tell the dependencies apart
+			if derived || len(wc.WorkloadInstances) > 1 {
+				suffix := wi.WorkloadClusterName
+				if suffix == "" {
+					suffix = wi.Name
+				}
+				instanceDependency.Name = objectName(wsd.Name, suffix)
+			}
+			instanceDependency.WorkloadInstanceName = wi.Name
+			serviceDependencies = append(serviceDependencies, instanceDependency)
+		}
+	}
+	wc.WorkloadServiceDependencies = serviceDependencies
+
+	// derived names must not collide
+	instanceNames := make(map[string]bool)
+	for _, wi := range wc.WorkloadInstances {
+		if instanceNames[wi.Name] {
+			return errors.New(fmt.Sprintf("duplicate workload instance name %s", wi.Name))
+		}
+		instanceNames[wi.Name] = true
+	}
+	serviceDependencyNames := make(map[string]bool)
+	for _, wsd := range wc.WorkloadServiceDependencies {
+		if serviceDependencyNames[wsd.Name] {
+			return errors.New(fmt.Sprintf("duplicate workload service dependency name %s", wsd.Name))
+		}
+		serviceDependencyNames[wsd.Name] = true
+	}
+
+	// check upstreams before anything is created
+	for _, wsd := range wc.WorkloadServiceDependencies {
+		if err := wsd.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-// Create creates a workload in the Threeport API.
+// objectName joins parts into a name that is valid for a Kubernetes object:
+// lower case alphanumerics separated by dashes.
+func objectName(parts ...string) string {
+	var nameParts []string
+	for _, part := range parts {
+		part = strings.Trim(objectNameInvalid.ReplaceAllString(strings.ToLower(part), "-"), "-")
+		if part != "" {
+			nameParts = append(nameParts, part)
+		}
+	}
+
+	return strings.Join(nameParts, "-")
+}
+
+// objectNameInvalid matches the runs of characters that are not allowed in an
+// object name.
+var objectNameInvalid = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// Create creates a workload in the Threeport API.  The definition is created
+// first, then the instances and then the service dependencies.  Sections that
+// are not in the config are skipped.
 func (wc *WorkloadConfig) Create() error {
+	if err := wc.Resolve(); err != nil {
+		return err
+	}
+
 	// create the definition
-	_, aerr := wc.WorkloadDefinition.Create()
-	if aerr != nil {
-		return aerr
+	if wc.WorkloadDefinition.HasSource() {
+		if _, err := wc.WorkloadDefinition.Create(); err != nil {
+			return err
+		}
 	}
 
-	// create the instance
-	_, berr := wc.WorkloadInstance.Create()
-	if berr != nil {
-		return berr
+	// create the instances
+	for _, wi := range wc.WorkloadInstances {
+		if _, err := wi.Create(); err != nil {
+			return err
+		}
 	}
 
-	// create the service dependency
-	_, cerr := wc.WorkloadServiceDependency.Create()
-	if cerr != nil {
-		return cerr
+	// create the service dependencies
+	for _, wsd := range wc.WorkloadServiceDependencies {
+		if _, err := wsd.Create(); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// Outcome is the result of reconciling an object in the Threeport API with
+// its config.
+type Outcome string
+
+const (
+	OutcomeCreated   Outcome = "created"
+	OutcomeUpdated   Outcome = "updated"
+	OutcomeUnchanged Outcome = "unchanged"
+	OutcomeDeclined  Outcome = "declined"
+	OutcomeFailed    Outcome = "failed"
+)
+
+// ObjectOutcome is the outcome of reconciling a single object.
+type ObjectOutcome struct {
+	Object  string
+	Name    string
+	Outcome Outcome
+	Err     error
+}
+
+// Update reconciles the objects of a workload in the Threeport API with the
+// config.  The definition, instance and service dependency are handled in
+// that order so that each object's dependencies are in place first.  Objects
+// that exist are updated if they changed, after confirming the diff, and
+// objects that don't exist are created.  Sections that are not in the config
+// are skipped.  Reconciling stops at the first failure and the outcome
+// for each object handled is returned.
+func (wc *WorkloadConfig) Update(confirm ConfirmFunc) ([]ObjectOutcome, error) {
+	var outcomes []ObjectOutcome
+	record := func(object, name string, outcome Outcome, err error) error {
+		outcomes = append(outcomes, ObjectOutcome{Object: object, Name: name, Outcome: outcome, Err: err})
+		if err != nil {
+			return fmt.Errorf("failed to reconcile %s %s: %w", object, name, err)
+		}
+		return nil
+	}
+
+	if err := wc.Resolve(); err != nil {
+		return nil, err
+	}
+
+	// reconcile the definition
+	if wc.WorkloadDefinition.HasSource() {
+		outcome, err := wc.WorkloadDefinition.reconcile(confirm)
+		if err := record("workload definition", wc.WorkloadDefinition.Name, outcome, err); err != nil {
+			return outcomes, err
+		}
+	}
+
+	// reconcile the instances
+	for _, wi := range wc.WorkloadInstances {
+		outcome, err := wi.reconcile(confirm)
+		if err := record("workload instance", wi.Name, outcome, err); err != nil {
+			return outcomes, err
+		}
+	}
+
+	// reconcile the service dependencies
+	for _, wsd := range wc.WorkloadServiceDependencies {
+		outcome, err := wsd.reconcile(confirm)
+		if err := record("workload service dependency", wsd.Name, outcome, err); err != nil {
+			return outcomes, err
+		}
+	}
+
+	return outcomes, nil
+}
+
+// reconcile creates the workload definition if it doesn't exist or updates it
+// if it does.
+func (wdc *WorkloadDefinitionConfig) reconcile(confirm ConfirmFunc) (Outcome, error) {
+	existing, err := findWorkloadDefinition(wdc.Name)
+	if err != nil {
+		return OutcomeFailed, err
+	}
+	if existing == nil {
+		if _, err := wdc.Create(); err != nil {
+			return OutcomeFailed, err
+		}
+		return OutcomeCreated, nil
+	}
+
+	tracker := diffTracker{confirm: confirm}
+	_, updated, err := wdc.Update(tracker.confirmFunc())
+	return tracker.outcome(updated, err)
+}
+
+// reconcile creates the workload instance if it doesn't exist or updates it if
+// it does.
+func (wic *WorkloadInstanceConfig) reconcile(confirm ConfirmFunc) (Outcome, error) {
+	existing, err := findWorkloadInstance(wic.Name)
+	if err != nil {
+		return OutcomeFailed, err
+	}
+	if existing == nil {
+		if _, err := wic.Create(); err != nil {
+			return OutcomeFailed, err
+		}
+		return OutcomeCreated, nil
+	}
+
+	tracker := diffTracker{confirm: confirm}
+	_, updated, err := wic.Update(tracker.confirmFunc())
+	return tracker.outcome(updated, err)
+}
+
+// reconcile creates the workload service dependency if it doesn't exist or
+// updates it if it changed.
+func (wsdc *WorkloadServiceDependencyConfig) reconcile(confirm ConfirmFunc) (Outcome, error) {
+	existing, err := findWorkloadServiceDependency(wsdc.Name)
+	if err != nil {
+		return OutcomeFailed, err
+	}
+	if existing == nil {
+		if _, err := wsdc.Create(); err != nil {
+			return OutcomeFailed, err
+		}
+		return OutcomeCreated, nil
+	}
+
+	// show what will change - an existing service dependency without a
+	// workload instance is shown with an empty instance name
+	var existingInstanceName string
+	if existing.WorkloadInstanceID != nil {
+		existingInstance, err := apiClient().GetWorkloadInstanceByID(uintValue(existing.WorkloadInstanceID))
+		if err != nil {
+			return OutcomeFailed, err
+		}
+		existingInstanceName = stringValue(existingInstance.Name)
+	}
+	serviceDependencyFields := "UpstreamHost: %s\nUpstreamPath: %s\nWorkloadInstance: %s\n"
+	diff := UnifiedDiff(
+		fmt.Sprintf(serviceDependencyFields,
+			stringValue(existing.UpstreamHost), stringValue(existing.UpstreamPath), existingInstanceName),
+		fmt.Sprintf(serviceDependencyFields, wsdc.UpstreamHost, wsdc.UpstreamPath, wsdc.WorkloadInstanceName),
+		"current", "updated",
+	)
+	if diff == "" {
+		return OutcomeUnchanged, nil
+	}
+	if !confirm(diff) {
+		return OutcomeDeclined, nil
+	}
+	if _, err := wsdc.Update(); err != nil {
+		return OutcomeFailed, err
+	}
+
+	return OutcomeUpdated, nil
+}
+
+// diffTracker records whether confirm was called with a diff so that an
+// object without changes can be told apart from a declined change.
+type diffTracker struct {
+	confirm ConfirmFunc
+	hadDiff bool
+}
+
+// confirmFunc returns a ConfirmFunc that records the diff before confirming.
+func (dt *diffTracker) confirmFunc() ConfirmFunc {
+	return func(diff string) bool {
+		dt.hadDiff = true
+		return dt.confirm(diff)
+	}
+}
+
+// outcome returns the outcome of an update that was confirmed through the
+// tracker.
+func (dt *diffTracker) outcome(updated bool, err error) (Outcome, error) {
+	switch {
+	case err != nil:
+		return OutcomeFailed, err
+	case updated:
+		return OutcomeUpdated, nil
+	case dt.hadDiff:
+		return OutcomeDeclined, nil
+	default:
+		return OutcomeUnchanged, nil
+	}
+}
+
+// skipValidation disables the validation of manifests before workload
+// definitions are created or updated.
+var skipValidation bool
+
+// SetSkipValidation sets whether manifests are validated before workload
+// definitions are created or updated, e.g. to submit manifests with custom
+// resources whose kinds can't be checked without the cluster they run on.
+func SetSkipValidation(skip bool) {
+	skipValidation = skip
+}
+
 // Create creates a workload definition in the Threeport API.
 func (wdc *WorkloadDefinitionConfig) Create() (*tpapi.WorkloadDefinition, error) {
 	// get the content of the yaml document
-	definitionContent, err := ioutil.ReadFile(wdc.YAMLDocument)
+	stringContent, err := wdc.StoredYAMLDocument()
 	if err != nil {
 		return nil, err
 	}
-	stringContent := string(definitionContent)
+
+	// validate the yaml document before submitting it
+	if !skipValidation {
+		issues, err := wdc.validateStoredYAMLDocument(stringContent, nil)
+		if err != nil {
+			return nil, err
+		}
+		OutputManifestIssues(issues)
+		if kube.HasErrors(issues) {
+			return nil, errors.New(fmt.Sprintf("workload definition %s failed validation", wdc.Name))
+		}
+	}
 
 	// construct workload definition object
 	workloadDefinition := &tpapi.WorkloadDefinition{
@@ -87,7 +460,7 @@ func (wdc *WorkloadDefinitionConfig) Create() (*tpapi.WorkloadDefinition, error)
 	if err != nil {
 		return nil, err
 	}
-	wd, err := tpclient.CreateWorkloadDefinition(wdJSON, install.GetThreeportAPIEndpoint(), "")
+	wd, err := apiClient().CreateWorkloadDefinition(wdJSON)
 	if err != nil {
 		return nil, err
 	}
@@ -95,25 +468,292 @@ func (wdc *WorkloadDefinitionConfig) Create() (*tpapi.WorkloadDefinition, error)
 	return wd, nil
 }
 
+// Update replaces the YAML document of a workload definition in the Threeport
+// API.  If the definition is parameterised, the definitions rendered from it
+// for its instances are re-rendered with their recorded values and updated
+// too.  The diff of the YAML documents is passed to confirm before any change
+// is made.  The returned bool is false if nothing was updated because there
+// were no changes or confirm returned false.
+func (wdc *WorkloadDefinitionConfig) Update(confirm ConfirmFunc) (*tpapi.WorkloadDefinition, bool, error) {
+	// get the content of the yaml document
+	stringContent, err := wdc.StoredYAMLDocument()
+	if err != nil {
+		return nil, false, err
+	}
+
+	// validate the yaml document before submitting it
+	if !skipValidation {
+		issues, err := wdc.validateStoredYAMLDocument(stringContent, nil)
+		if err != nil {
+			return nil, false, err
+		}
+		OutputManifestIssues(issues)
+		if kube.HasErrors(issues) {
+			return nil, false, errors.New(fmt.Sprintf("workload definition %s failed validation", wdc.Name))
+		}
+	}
+
+	// get existing workload definition by name to retrieve its ID
+	existingWD, err := apiClient().GetWorkloadDefinitionByName(wdc.Name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// re-render the definitions rendered from a parameterised definition for
+	// its instances so they deploy the updated manifests
+	copies, stale, err := wdc.renderedCopies(stringContent)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// show what will change
+	diff := ManifestDiff(*existingWD.YAMLDocument, stringContent, "current", "updated")
+	for _, rc := range copies {
+		diff += ManifestDiff(*rc.existing.YAMLDocument, rc.rendered,
+			fmt.Sprintf("%s manifests", *rc.existing.Name), fmt.Sprintf("%s manifests", *rc.existing.Name))
+	}
+	if diff == "" || !confirm(diff) {
+		return existingWD, false, nil
+	}
+
+	// construct workload definition object
+	workloadDefinition := &tpapi.WorkloadDefinition{
+		Name:         &wdc.Name,
+		YAMLDocument: &stringContent,
+		UserID:       &wdc.UserID,
+	}
+
+	// update workload definition in API
+	wdJSON, err := json.Marshal(&workloadDefinition)
+	if err != nil {
+		return nil, false, err
+	}
+	wd, err := apiClient().UpdateWorkloadDefinition(*existingWD.ID, wdJSON)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, rc := range copies {
+		if _, err := rc.instance.storeRenderedDefinition(rc.rendered, rc.existing.UserID); err != nil {
+			return nil, false, fmt.Errorf("workload definition %s updated but not re-rendered for workload instance %s: %w",
+				wdc.Name, rc.instance.Name, err)
+		}
+		qout.Info(fmt.Sprintf("workload definition %s re-rendered for workload instance %s",
+			*rc.existing.Name, rc.instance.Name))
+	}
+	if len(stale) > 0 {
+		qout.Warning(fmt.Sprintf(
+			"workload instances %s use definitions rendered from %s that can't be re-rendered as not all of their values were recorded - run update workload-instance for each",
+			strings.Join(stale, ", "), wdc.Name))
+	}
+
+	return wd, true, nil
+}
+
+// renderedCopy is a workload definition rendered from a parameterised
+// definition for one of its instances along with the manifests it is to be
+// re-rendered with.
+type renderedCopy struct {
+	instance WorkloadInstanceConfig
+	existing *tpapi.WorkloadDefinition
+	rendered string
+}
+
+// renderedCopies renders the updated YAML document of the workload definition
+// for each instance that uses a definition rendered from it.  The names of the
+// instances that can't be re-rendered because their values weren't recorded,
+// or were set for sensitive parameters, are also returned.
+func (wdc *WorkloadDefinitionConfig) renderedCopies(yamlDocument string) ([]renderedCopy, []string, error) {
+	workloadInstances, err := apiClient().GetWorkloadInstances()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get workload instances: %w", err)
+	}
+	workloadDefinitions, err := apiClient().GetWorkloadDefinitions()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get workload definitions: %w", err)
+	}
+	definitionsByID := make(map[uint]*tpapi.WorkloadDefinition)
+	for i, wd := range *workloadDefinitions {
+		definitionsByID[uintValue(wd.ID)] = &(*workloadDefinitions)[i]
+	}
+
+	updated := &tpapi.WorkloadDefinition{Name: &wdc.Name, YAMLDocument: &yamlDocument}
+	var copies []renderedCopy
+	var stale []string
+	for _, wi := range *workloadInstances {
+		instanceName := stringValue(wi.Name)
+		existing := definitionsByID[uintValue(wi.WorkloadDefinitionID)]
+		if existing == nil || SourceDefinitionName(existing, instanceName) != wdc.Name {
+			continue
+		}
+		renderedFrom, _, err := ParseRenderedHeader(stringValue(existing.YAMLDocument))
+		if err != nil {
+			return nil, nil, err
+		}
+		if renderedFrom == nil || len(renderedFrom.Redacted) > 0 {
+			stale = append(stale, instanceName)
+			continue
+		}
+		wic := WorkloadInstanceConfig{
+			Name:                   instanceName,
+			WorkloadDefinitionName: wdc.Name,
+			Values:                 renderedFrom.Values,
+		}
+		rendered, parameterised, err := wic.renderDefinition(updated)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !parameterised {
+			stale = append(stale, instanceName)
+			continue
+		}
+		copies = append(copies, renderedCopy{instance: wic, existing: existing, rendered: rendered})
+	}
+
+	return copies, stale, nil
+}
+
+// GetYAMLDocument returns the YAML document for the workload definition.  It
+// is read from the YAMLDocument files or rendered locally from a Helm chart or
+// Kustomize overlay.
+func (wdc *WorkloadDefinitionConfig) GetYAMLDocument() (string, error) {
+	sources := 0
+	for _, set := range []bool{len(wdc.YAMLDocument) > 0, wdc.Helm != nil, wdc.Kustomize != nil} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return "", errors.New(fmt.Sprintf(
+			"workload definition %s must have exactly one of YAMLDocument, Helm or Kustomize", wdc.Name))
+	}
+
+	switch {
+	case wdc.Helm != nil:
+		helm := wdc.Helm.resolvePaths(wdc.ConfigDir)
+		return helm.Render(wdc.Name)
+	case wdc.Kustomize != nil:
+		kustomize := wdc.Kustomize.resolvePaths(wdc.ConfigDir)
+		return kustomize.Render()
+	default:
+		return wdc.YAMLDocument.Load(wdc.ConfigDir)
+	}
+}
+
+// StoredYAMLDocument returns the YAML document as it is stored in the
+// Threeport API.  For a parameterised definition it is the template prefixed
+// with the header that declares the parameters.
+func (wdc *WorkloadDefinitionConfig) StoredYAMLDocument() (string, error) {
+	yamlDocument, err := wdc.GetYAMLDocument()
+	if err != nil {
+		return "", err
+	}
+	if len(wdc.Parameters) == 0 {
+		return yamlDocument, nil
+	}
+	if len(wdc.YAMLDocument) == 0 {
+		return "", errors.New(fmt.Sprintf(
+			"workload definition %s declares parameters - only a YAMLDocument can be parameterised", wdc.Name))
+	}
+	if err := ValidateParameters(wdc.Parameters); err != nil {
+		return "", err
+	}
+
+	return AddParametersHeader(yamlDocument, wdc.Parameters)
+}
+
+// HasSource returns whether a source for the YAML document is set.
+func (wdc *WorkloadDefinitionConfig) HasSource() bool {
+	return len(wdc.YAMLDocument) > 0 || wdc.Helm != nil || wdc.Kustomize != nil
+}
+
+// Validate validates the YAML document for the workload definition and returns
+// the issues found.  If credentials for a cluster are provided, the kinds and
+// schemas served by that cluster are used, including custom resources.  A
+// parameterised definition is validated as rendered with default values.
+func (wdc *WorkloadDefinitionConfig) Validate(
+	credentials *kube.ClusterCredentials,
+) ([]kube.ManifestIssue, error) {
+	yamlDocument, err := wdc.StoredYAMLDocument()
+	if err != nil {
+		return nil, err
+	}
+
+	return wdc.validateStoredYAMLDocument(yamlDocument, credentials)
+}
+
+// validateStoredYAMLDocument validates a YAML document as returned by
+// StoredYAMLDocument.
+func (wdc *WorkloadDefinitionConfig) validateStoredYAMLDocument(
+	yamlDocument string,
+	credentials *kube.ClusterCredentials,
+) ([]kube.ManifestIssue, error) {
+	parameters, yamlTemplate, err := ParseParametersHeader(yamlDocument)
+	if err != nil {
+		return nil, err
+	}
+	if parameters != nil {
+		if yamlDocument, err = RenderTemplate(yamlTemplate, DefaultValues(parameters)); err != nil {
+			return nil, err
+		}
+	}
+	validator, err := kube.NewManifestValidator(credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	return validator.Validate(wdc.YAMLDocumentSource(), yamlDocument), nil
+}
+
+// YAMLDocumentSource returns a description of where the YAML document for the
+// workload definition comes from for use when reporting issues.
+func (wdc *WorkloadDefinitionConfig) YAMLDocumentSource() string {
+	switch {
+	case wdc.Helm != nil:
+		return fmt.Sprintf("helm chart %s", wdc.Helm.Chart)
+	case wdc.Kustomize != nil:
+		return fmt.Sprintf("kustomize overlay %s", wdc.Kustomize.Path)
+	default:
+		return wdc.YAMLDocument.String()
+	}
+}
+
+// OutputManifestIssues outputs the issues found when validating a manifest.
+func OutputManifestIssues(issues []kube.ManifestIssue) {
+	for _, issue := range issues {
+		if issue.Severity == kube.SeverityError {
+			qout.Error(issue.String(), nil)
+		} else {
+			qout.Warning(issue.String())
+		}
+	}
+}
+
 // Create creates a workload instance in the Threeport API.
 func (wic *WorkloadInstanceConfig) Create() (*tpapi.WorkloadInstance, error) {
 	// get workload cluster by name
-	workloadCluster, err := tpclient.GetWorkloadClusterByName(
-		wic.WorkloadClusterName,
-		install.GetThreeportAPIEndpoint(), "",
-	)
+	workloadCluster, err := apiClient().GetWorkloadClusterByName(wic.WorkloadClusterName)
 	if err != nil {
 		return nil, err
 	}
 
 	// get workload definition by name
-	workloadDefinition, err := tpclient.GetWorkloadDefinitionByName(
-		wic.WorkloadDefinitionName,
-		install.GetThreeportAPIEndpoint(), "",
-	)
+	workloadDefinition, err := apiClient().GetWorkloadDefinitionByName(wic.WorkloadDefinitionName)
+	if err != nil {
+		return nil, err
+	}
+
+	// render a parameterised definition with the instance values and store
+	// the result as a definition for this instance
+	rendered, parameterised, err := wic.renderDefinition(workloadDefinition)
 	if err != nil {
 		return nil, err
 	}
+	if parameterised {
+		if workloadDefinition, err = wic.storeRenderedDefinition(rendered, workloadDefinition.UserID); err != nil {
+			return nil, err
+		}
+	}
 
 	// construct workload instance object
 	workloadInstance := &tpapi.WorkloadInstance{
@@ -127,7 +767,7 @@ func (wic *WorkloadInstanceConfig) Create() (*tpapi.WorkloadInstance, error) {
 	if err != nil {
 		return nil, err
 	}
-	wi, err := tpclient.CreateWorkloadInstance(wiJSON, install.GetThreeportAPIEndpoint(), "")
+	wi, err := apiClient().CreateWorkloadInstance(wiJSON)
 	if err != nil {
 		return nil, err
 	}
@@ -135,13 +775,354 @@ func (wic *WorkloadInstanceConfig) Create() (*tpapi.WorkloadInstance, error) {
 	return wi, nil
 }
 
+// Update updates a workload instance in the Threeport API so that it uses the
+// workload definition and workload cluster in the config.  The diff of the
+// change, including any change to the manifests that will be deployed, is
+// passed to confirm before any change is made.  The returned bool is false if
+// nothing was updated because there were no changes or confirm returned false.
+func (wic *WorkloadInstanceConfig) Update(confirm ConfirmFunc) (*tpapi.WorkloadInstance, bool, error) {
+	// get existing workload instance by name to retrieve its ID
+	existingWI, err := apiClient().GetWorkloadInstanceByName(wic.Name)
+	if err != nil {
+		return nil, false, err
+	}
+	existingCluster, err := apiClient().GetWorkloadClusterByID(*existingWI.WorkloadClusterID)
+	if err != nil {
+		return nil, false, err
+	}
+	existingDefinition, err := apiClient().GetWorkloadDefinitionByID(*existingWI.WorkloadDefinitionID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// get workload cluster and definition by name
+	workloadCluster, err := apiClient().GetWorkloadClusterByName(wic.WorkloadClusterName)
+	if err != nil {
+		return nil, false, err
+	}
+	workloadDefinition, err := apiClient().GetWorkloadDefinitionByName(wic.WorkloadDefinitionName)
+	if err != nil {
+		return nil, false, err
+	}
+	rendered, parameterised, err := wic.renderDefinition(workloadDefinition)
+	if err != nil {
+		return nil, false, err
+	}
+	definitionName := wic.WorkloadDefinitionName
+	if parameterised {
+		definitionName = wic.RenderedDefinitionName()
+	}
+
+	// show what will change
+	before := fmt.Sprintf("WorkloadCluster: %s\nWorkloadDefinition: %s\n", *existingCluster.Name, *existingDefinition.Name)
+	after := fmt.Sprintf("WorkloadCluster: %s\nWorkloadDefinition: %s\n", *workloadCluster.Name, definitionName)
+	diff := UnifiedDiff(before, after, "current", "updated") + ManifestDiff(
+		*existingDefinition.YAMLDocument, rendered,
+		fmt.Sprintf("%s manifests", *existingDefinition.Name), fmt.Sprintf("%s manifests", definitionName),
+	)
+	if diff == "" || !confirm(diff) {
+		return existingWI, false, nil
+	}
+
+	if parameterised {
+		if workloadDefinition, err = wic.storeRenderedDefinition(rendered, workloadDefinition.UserID); err != nil {
+			return nil, false, err
+		}
+	}
+
+	// construct workload instance object
+	workloadInstance := &tpapi.WorkloadInstance{
+		Name:                 &wic.Name,
+		WorkloadClusterID:    workloadCluster.ID,
+		WorkloadDefinitionID: workloadDefinition.ID,
+	}
+
+	// update workload instance in API
+	wiJSON, err := json.Marshal(&workloadInstance)
+	if err != nil {
+		return nil, false, err
+	}
+	wi, err := apiClient().UpdateWorkloadInstance(*existingWI.ID, wiJSON)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return wi, true, nil
+}
+
+// renderDefinition renders the workload definition for the instance with its
+// values and validates the result.  The returned bool is true if the
+// definition is parameterised.
+func (wic *WorkloadInstanceConfig) renderDefinition(
+	workloadDefinition *tpapi.WorkloadDefinition,
+) (string, bool, error) {
+	rendered, parameterised, err := RenderWorkloadDefinition(*workloadDefinition.YAMLDocument, wic.Values)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to render workload definition %s: %w", wic.WorkloadDefinitionName, err)
+	}
+	if !parameterised {
+		return rendered, false, nil
+	}
+
+	if !skipValidation {
+		validator, err := kube.NewManifestValidator(nil)
+		if err != nil {
+			return "", false, err
+		}
+		issues := validator.Validate(wic.RenderedDefinitionName(), rendered)
+		OutputManifestIssues(issues)
+		if kube.HasErrors(issues) {
+			return "", false, errors.New(fmt.Sprintf(
+				"workload definition %s rendered with values for %s failed validation",
+				wic.WorkloadDefinitionName, wic.Name))
+		}
+	}
+
+	// record what the definition was rendered from so the instance config can
+	// be exported and the rendered definition deleted with the instance
+	parameters, _, err := ParseParametersHeader(*workloadDefinition.YAMLDocument)
+	if err != nil {
+		return "", false, err
+	}
+	values, redacted := RecordedValues(parameters, wic.Values)
+	rendered, err = AddRenderedHeader(rendered, &RenderedFrom{
+		Definition: wic.WorkloadDefinitionName,
+		Instance:   wic.Name,
+		Values:     values,
+		Redacted:   redacted,
+	})
+	if err != nil {
+		return "", false, err
+	}
+
+	return rendered, true, nil
+}
+
+// storeRenderedDefinition creates or updates the workload definition that
+// holds the rendered manifests for the instance.  An existing definition with
+// the same name that wasn't rendered for the instance is not overwritten.
+func (wic *WorkloadInstanceConfig) storeRenderedDefinition(
+	rendered string,
+	userID *uint,
+) (*tpapi.WorkloadDefinition, error) {
+	renderedName := wic.RenderedDefinitionName()
+	renderedDefinition := &tpapi.WorkloadDefinition{
+		Name:         &renderedName,
+		YAMLDocument: &rendered,
+		UserID:       userID,
+	}
+	rdJSON, err := json.Marshal(&renderedDefinition)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := findWorkloadDefinition(renderedName)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		renderedForInstance, err := wic.isRenderedDefinition(existing)
+		if err != nil {
+			return nil, err
+		}
+		if !renderedForInstance {
+			return nil, tperrors.New(tperrors.KindConflict, fmt.Sprintf(
+				"workload definition %s already exists and was not rendered from %s for workload instance %s",
+				renderedName, wic.WorkloadDefinitionName, wic.Name))
+		}
+	}
+	var wd *tpapi.WorkloadDefinition
+	if existing != nil {
+		wd, err = apiClient().UpdateWorkloadDefinition(uintValue(existing.ID), rdJSON)
+	} else {
+		wd, err = apiClient().CreateWorkloadDefinition(rdJSON)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to store rendered workload definition %s: %w", renderedName, err)
+	}
+
+	return wd, nil
+}
+
+// isRenderedDefinition returns true if a workload definition was rendered for
+// the instance.  Definitions rendered before tptctl recorded what they were
+// rendered from are recognised by the instance using them.
+func (wic *WorkloadInstanceConfig) isRenderedDefinition(workloadDefinition *tpapi.WorkloadDefinition) (bool, error) {
+	renderedFrom, _, err := ParseRenderedHeader(stringValue(workloadDefinition.YAMLDocument))
+	if err != nil {
+		return false, err
+	}
+	if renderedFrom != nil && renderedFrom.Definition != "" {
+		return renderedFrom.Definition == wic.WorkloadDefinitionName && renderedFrom.Instance == wic.Name, nil
+	}
+	workloadInstance, err := findWorkloadInstance(wic.Name)
+	if err != nil {
+		return false, err
+	}
+
+	return workloadInstance != nil && uintValue(workloadInstance.WorkloadDefinitionID) == uintValue(workloadDefinition.ID), nil
+}
+
+// DeleteWorkloadInstance deletes the workload instance with a name from the
+// Threeport API.  If the instance uses a workload definition rendered for it
+// from a parameterised definition, the rendered definition is deleted once the
+// workload controller has removed the instance, waiting up to timeout.
+func DeleteWorkloadInstance(ctx context.Context, name string, timeout time.Duration) (*tpapi.WorkloadInstance, error) {
+	workloadInstance, err := findWorkloadInstance(name)
+	if err != nil {
+		return nil, err
+	}
+	if workloadInstance == nil {
+		return nil, tperrors.New(tperrors.KindNotFound, fmt.Sprintf("workload instance %s not found", name))
+	}
+	workloadDefinition, err := apiClient().GetWorkloadDefinitionByID(*workloadInstance.WorkloadDefinitionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workload definition for workload instance %s: %w", name, err)
+	}
+
+	wi, err := apiClient().DeleteWorkloadInstance(*workloadInstance.ID)
+	if err != nil {
+		return nil, err
+	}
+	if SourceDefinitionName(workloadDefinition, name) == "" {
+		return wi, nil
+	}
+
+	// the rendered definition is in use until the instance is removed
+	if err := WaitForWorkloadInstance(ctx, name, WaitConditionDeleted, timeout); err != nil {
+		return nil, fmt.Errorf("workload instance %s deleted but rendered workload definition %s was not: %w",
+			name, *workloadDefinition.Name, err)
+	}
+	if _, err := apiClient().DeleteWorkloadDefinition(*workloadDefinition.ID); err != nil {
+		return nil, fmt.Errorf("workload instance %s deleted but failed to delete rendered workload definition %s: %w",
+			name, *workloadDefinition.Name, err)
+	}
+
+	return wi, nil
+}
+
+// findWorkloadDefinition returns the workload definition with a name or nil
+// if it doesn't exist.
+func findWorkloadDefinition(name string) (*tpapi.WorkloadDefinition, error) {
+	workloadDefinitions, err := apiClient().GetWorkloadDefinitions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workload definitions: %w", err)
+	}
+	for i, wd := range *workloadDefinitions {
+		if wd.Name != nil && *wd.Name == name {
+			return &(*workloadDefinitions)[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// findWorkloadInstance returns the workload instance with a name or nil if it
+// doesn't exist.
+func findWorkloadInstance(name string) (*tpapi.WorkloadInstance, error) {
+	workloadInstances, err := apiClient().GetWorkloadInstances()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workload instances: %w", err)
+	}
+	for i, wi := range *workloadInstances {
+		if wi.Name != nil && *wi.Name == name {
+			return &(*workloadInstances)[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// findWorkloadServiceDependency returns the workload service dependency with a
+// name or nil if it doesn't exist.
+func findWorkloadServiceDependency(name string) (*tpapi.WorkloadServiceDependency, error) {
+	workloadServiceDependencies, err := apiClient().GetWorkloadServiceDependencies()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workload service dependencies: %w", err)
+	}
+	for i, wsd := range *workloadServiceDependencies {
+		if wsd.Name != nil && *wsd.Name == name {
+			return &(*workloadServiceDependencies)[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Render returns the manifests for the workload instance.  The workload
+// definition is retrieved from the Threeport API and rendered with the instance
+// values.
+func (wic *WorkloadInstanceConfig) Render() (string, error) {
+	workloadDefinition, err := apiClient().GetWorkloadDefinitionByName(wic.WorkloadDefinitionName)
+	if err != nil {
+		return "", err
+	}
+	rendered, _, err := RenderWorkloadDefinition(*workloadDefinition.YAMLDocument, wic.Values)
+	if err != nil {
+		return "", fmt.Errorf("failed to render workload definition %s: %w", wic.WorkloadDefinitionName, err)
+	}
+
+	return rendered, nil
+}
+
+// GetWorkloadInstanceResources returns the credentials for the workload
+// cluster a workload instance runs on and the manifest of its resources.  A
+// cluster registered with its in-cluster endpoint is reached with the control
+// plane kubeconfig.
+func GetWorkloadInstanceResources(name string) (*kube.ClusterCredentials, string, error) {
+	workloadInstance, err := apiClient().GetWorkloadInstanceByName(name)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get workload instance %s: %w", name, err)
+	}
+	credentials, manifest, err := workloadInstanceResources(workloadInstance)
+	if err != nil {
+		return nil, "", err
+	}
+	if credentials.APIEndpoint == "" {
+		return nil, "", errors.New(fmt.Sprintf(
+			"workload cluster for workload instance %s can't be reached from tptctl - it has no API endpoint, or is registered with its in-cluster endpoint and there is no kubeconfig for the control plane in the provider config directory",
+			name))
+	}
+
+	return credentials, manifest, nil
+}
+
+// workloadInstanceResources returns the credentials for the workload cluster
+// of a workload instance and the manifest from its workload definition.
+func workloadInstanceResources(workloadInstance *tpapi.WorkloadInstance) (*kube.ClusterCredentials, string, error) {
+	workloadCluster, err := apiClient().GetWorkloadClusterByID(*workloadInstance.WorkloadClusterID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get workload cluster for workload instance %s: %w",
+			*workloadInstance.Name, err)
+	}
+	workloadDefinition, err := apiClient().GetWorkloadDefinitionByID(*workloadInstance.WorkloadDefinitionID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get workload definition for workload instance %s: %w",
+			*workloadInstance.Name, err)
+	}
+
+	credentials, err := clusterCredentials(workloadCluster)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return credentials, *workloadDefinition.YAMLDocument, nil
+}
+
+// RenderedDefinitionName returns the name of the workload definition that
+// holds the manifests for the instance when its definition is parameterised.
+func (wic *WorkloadInstanceConfig) RenderedDefinitionName() string {
+	return fmt.Sprintf("%s-%s", wic.WorkloadDefinitionName, wic.Name)
+}
+
 // Create creates a workload service dependency in the Threeport API.
 func (wsdc *WorkloadServiceDependencyConfig) Create() (*tpapi.WorkloadServiceDependency, error) {
+	if err := wsdc.Validate(); err != nil {
+		return nil, err
+	}
+
 	// get workload instance by name
-	workloadInstance, err := tpclient.GetWorkloadInstanceByName(
-		wsdc.WorkloadInstanceName,
-		install.GetThreeportAPIEndpoint(), "",
-	)
+	workloadInstance, err := apiClient().GetWorkloadInstanceByName(wsdc.WorkloadInstanceName)
 	if err != nil {
 		return nil, err
 	}
@@ -159,7 +1140,7 @@ func (wsdc *WorkloadServiceDependencyConfig) Create() (*tpapi.WorkloadServiceDep
 	if err != nil {
 		return nil, err
 	}
-	wsd, err := tpclient.CreateWorkloadServiceDependency(wsdJSON, install.GetThreeportAPIEndpoint(), "")
+	wsd, err := apiClient().CreateWorkloadServiceDependency(wsdJSON)
 	if err != nil {
 		return nil, err
 	}
@@ -169,11 +1150,12 @@ func (wsdc *WorkloadServiceDependencyConfig) Create() (*tpapi.WorkloadServiceDep
 
 // Update updates a workload service dependency in the Threeport API.
 func (wsdc *WorkloadServiceDependencyConfig) Update() (*tpapi.WorkloadServiceDependency, error) {
+	if err := wsdc.Validate(); err != nil {
+		return nil, err
+	}
+
 	// get workload instance by name
-	workloadInstance, err := tpclient.GetWorkloadInstanceByName(
-		wsdc.WorkloadInstanceName,
-		install.GetThreeportAPIEndpoint(), "",
-	)
+	workloadInstance, err := apiClient().GetWorkloadInstanceByName(wsdc.WorkloadInstanceName)
 	if err != nil {
 		return nil, err
 	}
@@ -187,10 +1169,7 @@ func (wsdc *WorkloadServiceDependencyConfig) Update() (*tpapi.WorkloadServiceDep
 	}
 
 	// get existing workload service dependency by name to retrieve its ID
-	existingWSD, err := tpclient.GetWorkloadServiceDependencyByName(
-		wsdc.Name,
-		install.GetThreeportAPIEndpoint(), "",
-	)
+	existingWSD, err := apiClient().GetWorkloadServiceDependencyByName(wsdc.Name)
 	if err != nil {
 		return nil, err
 	}
@@ -200,11 +1179,7 @@ func (wsdc *WorkloadServiceDependencyConfig) Update() (*tpapi.WorkloadServiceDep
 	if err != nil {
 		return nil, err
 	}
-	wsd, err := tpclient.UpdateWorkloadServiceDependency(
-		*existingWSD.ID,
-		wsdJSON,
-		install.GetThreeportAPIEndpoint(), "",
-	)
+	wsd, err := apiClient().UpdateWorkloadServiceDependency(uintValue(existingWSD.ID), wsdJSON)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	tpapi "github.com/threeport/threeport-rest-api/pkg/api/v0"
+
+	kube "github.com/threeport/tptctl/internal/kubernetes"
+	qout "github.com/threeport/tptctl/internal/output"
+	"github.com/threeport/tptctl/internal/threeport"
+)
+
+// WorkloadClusterConfig contains the attributes needed to manage a workload
+// cluster.
+type WorkloadClusterConfig struct {
+	Name           string `yaml:"Name"`
+	Region         string `yaml:"Region"`
+	Provider       string `yaml:"Provider"`
+	KubeconfigPath string `yaml:"KubeconfigPath"`
+	Context        string `yaml:"Context"`
+	APIEndpoint    string `yaml:"APIEndpoint"`
+}
+
+// Create registers a workload cluster in the Threeport API using the
+// credentials for the cluster found in a kubeconfig.  Connectivity to the
+// cluster is checked before it is registered.  Credentials from exec plugins
+// expire too soon to be stored, so they are used to create a service account
+// for threeport on the cluster and its token is registered instead.
+func (wcc *WorkloadClusterConfig) Create() (*tpapi.WorkloadCluster, error) {
+	// get cluster endpoint and credentials from kubeconfig
+	credentials, err := kube.GetClusterCredentials(wcc.KubeconfigPath, wcc.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	// check the cluster can be reached with the credentials
+	version, err := kube.CheckConnectivity(credentials)
+	if err != nil {
+		return nil, err
+	}
+	qout.Info(fmt.Sprintf("connected to workload cluster running Kubernetes %s", version))
+
+	// the credentials are stored for threeport to use long after they are
+	// read so ones that expire are swapped for a service account token
+	if credentials.ExecPlugin != "" {
+		qout.Info(fmt.Sprintf(
+			"kubeconfig uses exec plugin %s for short-lived credentials - creating service account %s/%s for threeport",
+			credentials.ExecPlugin, kube.ServiceAccountNamespace, kube.ServiceAccountName))
+		credentials, err = kube.ServiceAccountCredentials(context.Background(), credentials)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create service account credentials: %w", err)
+		}
+		if _, err := kube.CheckConnectivity(credentials); err != nil {
+			return nil, fmt.Errorf("failed to connect with service account credentials: %w", err)
+		}
+	}
+
+	// the API endpoint used by threeport may differ from the one in the
+	// kubeconfig, e.g. if the cluster is reached through a private network
+	apiEndpoint := credentials.APIEndpoint
+	if wcc.APIEndpoint != "" {
+		apiEndpoint = wcc.APIEndpoint
+	}
+
+	// construct workload cluster object
+	workloadCluster := &tpapi.WorkloadCluster{
+		Name:          &wcc.Name,
+		Region:        &wcc.Region,
+		Provider:      &wcc.Provider,
+		APIEndpoint:   &apiEndpoint,
+		CACertificate: &credentials.CACertificate,
+		Certificate:   &credentials.Certificate,
+		Key:           &credentials.Key,
+		Token:         &credentials.Token,
+	}
+
+	// create workload cluster in API
+	wcJSON, err := json.Marshal(&workloadCluster)
+	if err != nil {
+		return nil, err
+	}
+	wc, err := apiClient().CreateWorkloadCluster(wcJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return wc, nil
+}
+
+// WorkloadClusterCredentials returns the endpoint and credentials for
+// connecting to a workload cluster registered in the Threeport API.
+func WorkloadClusterCredentials(workloadCluster *tpapi.WorkloadCluster) *kube.ClusterCredentials {
+	valueOf := func(field *string) string {
+		if field == nil {
+			return ""
+		}
+		return *field
+	}
+
+	return &kube.ClusterCredentials{
+		APIEndpoint:   valueOf(workloadCluster.APIEndpoint),
+		CACertificate: valueOf(workloadCluster.CACertificate),
+		Certificate:   valueOf(workloadCluster.Certificate),
+		Key:           valueOf(workloadCluster.Key),
+		Token:         valueOf(workloadCluster.Token),
+	}
+}
+
+// controlPlaneKubeconfig is the kubeconfig for the cluster the current
+// Threeport control plane runs on.
+var controlPlaneKubeconfig string
+
+// SetControlPlaneKubeconfig sets the path to the kubeconfig written when the
+// current Threeport control plane was created.  It is used to connect to a
+// workload cluster that is registered with its in-cluster API endpoint.
+func SetControlPlaneKubeconfig(path string) {
+	controlPlaneKubeconfig = path
+}
+
+// clusterCredentials returns the credentials tptctl connects to a workload
+// cluster with.  The default workload cluster on kind is registered with its
+// in-cluster API endpoint, which can't be reached from outside the cluster, so
+// the control plane kubeconfig is used for it instead.  If there is no
+// kubeconfig, credentials without an API endpoint are returned.
+func clusterCredentials(workloadCluster *tpapi.WorkloadCluster) (*kube.ClusterCredentials, error) {
+	credentials := WorkloadClusterCredentials(workloadCluster)
+	if !isInClusterEndpoint(credentials.APIEndpoint) {
+		return credentials, nil
+	}
+	if controlPlaneKubeconfig == "" {
+		return &kube.ClusterCredentials{}, nil
+	}
+	if _, err := os.Stat(controlPlaneKubeconfig); err != nil {
+		qout.Debug(fmt.Sprintf("no control plane kubeconfig for workload cluster %s: %s",
+			stringValue(workloadCluster.Name), err))
+		return &kube.ClusterCredentials{}, nil
+	}
+	kubeconfigCredentials, err := kube.GetClusterCredentials(controlPlaneKubeconfig, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials for workload cluster %s from %s: %w",
+			stringValue(workloadCluster.Name), controlPlaneKubeconfig, err)
+	}
+
+	return kubeconfigCredentials, nil
+}
+
+// isInClusterEndpoint returns true if a Kubernetes API endpoint is the
+// in-cluster service address, e.g. kubernetes.default or
+// https://kubernetes.default.svc:443.
+func isInClusterEndpoint(apiEndpoint string) bool {
+	host := apiEndpoint
+	if strings.Contains(apiEndpoint, "://") {
+		endpointURL, err := url.Parse(apiEndpoint)
+		if err != nil {
+			return false
+		}
+		host = endpointURL.Host
+	}
+	if hostname, _, found := strings.Cut(host, ":"); found {
+		host = hostname
+	}
+	host = strings.TrimSuffix(strings.TrimSuffix(host, ".cluster.local"), ".svc")
+
+	return host == threeport.DefaultComputeClusterAPIEndpoint
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	tpapi "github.com/threeport/threeport-rest-api/pkg/api/v0"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: kind-threeport-test
+clusters:
+  - name: kind-threeport-test
+    cluster:
+      server: https://127.0.0.1:6443
+      certificate-authority-data: Y2EtY2VydA==
+contexts:
+  - name: kind-threeport-test
+    context:
+      cluster: kind-threeport-test
+      user: kind-threeport-test
+users:
+  - name: kind-threeport-test
+    user:
+      token: test-token
+`
+
+func TestIsInClusterEndpoint(t *testing.T) {
+	testCases := []struct {
+		apiEndpoint string
+		expected    bool
+	}{
+		{apiEndpoint: "kubernetes.default", expected: true},
+		{apiEndpoint: "kubernetes.default.svc", expected: true},
+		{apiEndpoint: "https://kubernetes.default.svc.cluster.local:443", expected: true},
+		{apiEndpoint: "kubernetes.default:443", expected: true},
+		{apiEndpoint: "https://127.0.0.1:6443", expected: false},
+		{apiEndpoint: "https://ABCD.gr7.us-east-1.eks.amazonaws.com", expected: false},
+		{apiEndpoint: "kubernetes.default.example.com", expected: false},
+		{apiEndpoint: "", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.apiEndpoint, func(t *testing.T) {
+			if inCluster := isInClusterEndpoint(tc.apiEndpoint); inCluster != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, inCluster)
+			}
+		})
+	}
+}
+
+func TestClusterCredentials(t *testing.T) {
+	kubeconfigPath := filepath.Join(t.TempDir(), "kubeconfig-threeport-test")
+	if err := ioutil.WriteFile(kubeconfigPath, []byte(testKubeconfig), 0600); err != nil {
+		t.Fatalf("failed to write kubeconfig: %s", err)
+	}
+	t.Cleanup(func() { SetControlPlaneKubeconfig("") })
+
+	name := "default"
+	inCluster := "kubernetes.default"
+	external := "https://10.0.0.1:6443"
+	testCases := []struct {
+		name        string
+		apiEndpoint string
+		kubeconfig  string
+		expected    string
+	}{
+		{name: "external endpoint", apiEndpoint: external, kubeconfig: kubeconfigPath, expected: external},
+		{name: "in-cluster endpoint", apiEndpoint: inCluster, kubeconfig: kubeconfigPath, expected: "https://127.0.0.1:6443"},
+		{name: "in-cluster endpoint without kubeconfig", apiEndpoint: inCluster},
+		{name: "in-cluster endpoint with missing kubeconfig", apiEndpoint: inCluster, kubeconfig: kubeconfigPath + "-missing"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			SetControlPlaneKubeconfig(tc.kubeconfig)
+			workloadCluster := &tpapi.WorkloadCluster{Name: &name, APIEndpoint: &tc.apiEndpoint}
+			credentials, err := clusterCredentials(workloadCluster)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if credentials.APIEndpoint != tc.expected {
+				t.Errorf("expected API endpoint %q, got %q", tc.expected, credentials.APIEndpoint)
+			}
+		})
+	}
+}
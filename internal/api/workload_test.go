@@ -0,0 +1,642 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	tpapi "github.com/threeport/threeport-rest-api/pkg/api/v0"
+
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	"github.com/threeport/tptctl/internal/fakeapi"
+)
+
+const testManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.25
+`
+
+// newFakeAPI starts a fake Threeport API with a workload cluster named
+// default and uses it for the current threeport instance until the test
+// finishes.
+func newFakeAPI(t *testing.T) (*fakeapi.Server, Client) {
+	t.Helper()
+
+	server := fakeapi.NewServer()
+	client := NewClient(server.URL)
+	SetClient(client)
+	t.Cleanup(func() {
+		SetClient(nil)
+		server.Close()
+	})
+
+	clusterName := "default"
+	if _, err := server.Add(fakeapi.WorkloadClusters, &tpapi.WorkloadCluster{Name: &clusterName}); err != nil {
+		t.Fatalf("failed to add workload cluster: %s", err)
+	}
+
+	return server, client
+}
+
+// testWorkloadConfig returns a workload config with a definition, instance
+// and service dependency whose manifest is written to a temporary directory.
+func testWorkloadConfig(t *testing.T) *WorkloadConfig {
+	t.Helper()
+
+	configDir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(configDir, "manifest.yaml"), []byte(testManifest), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %s", err)
+	}
+
+	return &WorkloadConfig{
+		Name: "web",
+		WorkloadDefinition: WorkloadDefinitionConfig{
+			YAMLDocument: YAMLDocumentPaths{"manifest.yaml"},
+			UserID:       1,
+			ConfigDir:    configDir,
+		},
+		WorkloadInstances: []WorkloadInstanceConfig{
+			{WorkloadClusterName: "default"},
+		},
+		WorkloadServiceDependencies: []WorkloadServiceDependencyConfig{
+			{UpstreamHost: "api.example.com", UpstreamPath: "/v1"},
+		},
+	}
+}
+
+func TestWorkloadConfigCreate(t *testing.T) {
+	server, client := newFakeAPI(t)
+	workloadConfig := testWorkloadConfig(t)
+
+	if err := workloadConfig.Create(); err != nil {
+		t.Fatalf("failed to create workload: %s", err)
+	}
+
+	workloadDefinition, err := client.GetWorkloadDefinitionByName("web-definition")
+	if err != nil {
+		t.Fatalf("failed to get workload definition: %s", err)
+	}
+	if stringValue(workloadDefinition.YAMLDocument) != testManifest {
+		t.Errorf("expected workload definition to have the manifest, got %q", stringValue(workloadDefinition.YAMLDocument))
+	}
+	workloadCluster, err := client.GetWorkloadClusterByName("default")
+	if err != nil {
+		t.Fatalf("failed to get workload cluster: %s", err)
+	}
+	workloadInstance, err := client.GetWorkloadInstanceByName("web-default-instance")
+	if err != nil {
+		t.Fatalf("failed to get workload instance: %s", err)
+	}
+	if uintValue(workloadInstance.WorkloadDefinitionID) != uintValue(workloadDefinition.ID) {
+		t.Errorf("expected workload instance to reference workload definition %d, got %d",
+			uintValue(workloadDefinition.ID), uintValue(workloadInstance.WorkloadDefinitionID))
+	}
+	if uintValue(workloadInstance.WorkloadClusterID) != uintValue(workloadCluster.ID) {
+		t.Errorf("expected workload instance to reference workload cluster %d, got %d",
+			uintValue(workloadCluster.ID), uintValue(workloadInstance.WorkloadClusterID))
+	}
+	workloadServiceDependency, err := client.GetWorkloadServiceDependencyByName("web-api-example-com-service-default")
+	if err != nil {
+		t.Fatalf("failed to get workload service dependency: %s", err)
+	}
+	if uintValue(workloadServiceDependency.WorkloadInstanceID) != uintValue(workloadInstance.ID) {
+		t.Errorf("expected workload service dependency to reference workload instance %d, got %d",
+			uintValue(workloadInstance.ID), uintValue(workloadServiceDependency.WorkloadInstanceID))
+	}
+
+	// the names are taken so the workload can't be created again
+	if err := workloadConfig.Create(); err == nil {
+		t.Error("expected creating the workload again to fail")
+	}
+	if count := server.Count(fakeapi.WorkloadDefinitions); count != 1 {
+		t.Errorf("expected 1 workload definition, got %d", count)
+	}
+}
+
+func TestWorkloadConfigCreateMissingCluster(t *testing.T) {
+	server, _ := newFakeAPI(t)
+	workloadConfig := testWorkloadConfig(t)
+	workloadConfig.WorkloadInstances[0].WorkloadClusterName = "missing"
+
+	if err := workloadConfig.Create(); err == nil {
+		t.Fatal("expected creating a workload instance on a missing workload cluster to fail")
+	}
+	if count := server.Count(fakeapi.WorkloadInstances); count != 0 {
+		t.Errorf("expected no workload instances, got %d", count)
+	}
+}
+
+func TestWorkloadServiceDependencyConfigUpdate(t *testing.T) {
+	_, client := newFakeAPI(t)
+	workloadConfig := testWorkloadConfig(t)
+	if err := workloadConfig.Create(); err != nil {
+		t.Fatalf("failed to create workload: %s", err)
+	}
+	created, err := client.GetWorkloadServiceDependencyByName("web-api-example-com-service-default")
+	if err != nil {
+		t.Fatalf("failed to get workload service dependency: %s", err)
+	}
+
+	wsdc := workloadConfig.WorkloadServiceDependencies[0]
+	wsdc.UpstreamHost = "api.example.com:8443"
+	wsdc.UpstreamPath = "/v2"
+	updated, err := wsdc.Update()
+	if err != nil {
+		t.Fatalf("failed to update workload service dependency: %s", err)
+	}
+	if uintValue(updated.ID) != uintValue(created.ID) {
+		t.Errorf("expected workload service dependency %d to be updated, got %d",
+			uintValue(created.ID), uintValue(updated.ID))
+	}
+
+	stored, err := client.GetWorkloadServiceDependencyByName("web-api-example-com-service-default")
+	if err != nil {
+		t.Fatalf("failed to get workload service dependency: %s", err)
+	}
+	if stringValue(stored.UpstreamHost) != "api.example.com:8443" || stringValue(stored.UpstreamPath) != "/v2" {
+		t.Errorf("expected upstream api.example.com:8443/v2, got %s%s",
+			stringValue(stored.UpstreamHost), stringValue(stored.UpstreamPath))
+	}
+}
+
+func TestWorkloadServiceDependencyConfigUpdateInvalid(t *testing.T) {
+	_, client := newFakeAPI(t)
+	workloadConfig := testWorkloadConfig(t)
+	if err := workloadConfig.Create(); err != nil {
+		t.Fatalf("failed to create workload: %s", err)
+	}
+
+	wsdc := workloadConfig.WorkloadServiceDependencies[0]
+	wsdc.UpstreamHost = "https://api.example.com"
+	if _, err := wsdc.Update(); err == nil {
+		t.Fatal("expected updating with an upstream host that includes a scheme to fail")
+	}
+	stored, err := client.GetWorkloadServiceDependencyByName("web-api-example-com-service-default")
+	if err != nil {
+		t.Fatalf("failed to get workload service dependency: %s", err)
+	}
+	if stringValue(stored.UpstreamHost) != "api.example.com" {
+		t.Errorf("expected upstream host to be unchanged, got %s", stringValue(stored.UpstreamHost))
+	}
+}
+
+func TestWorkloadServiceDependencyConfigReconcileIncomplete(t *testing.T) {
+	server, client := newFakeAPI(t)
+	workloadConfig := testWorkloadConfig(t)
+	if err := workloadConfig.Create(); err != nil {
+		t.Fatalf("failed to create workload: %s", err)
+	}
+
+	// replace the service dependency with one that has no upstream or
+	// workload instance, e.g. one created directly through the API
+	wsdc := workloadConfig.WorkloadServiceDependencies[0]
+	created, err := client.GetWorkloadServiceDependencyByName(wsdc.Name)
+	if err != nil {
+		t.Fatalf("failed to get workload service dependency: %s", err)
+	}
+	if _, err := client.DeleteWorkloadServiceDependency(uintValue(created.ID)); err != nil {
+		t.Fatalf("failed to delete workload service dependency: %s", err)
+	}
+	if _, err := server.Add(fakeapi.WorkloadServiceDependencies, &tpapi.WorkloadServiceDependency{Name: &wsdc.Name}); err != nil {
+		t.Fatalf("failed to add workload service dependency: %s", err)
+	}
+
+	var diff string
+	outcome, err := wsdc.reconcile(func(d string) bool {
+		diff = d
+		return true
+	})
+	if err != nil {
+		t.Fatalf("failed to reconcile workload service dependency: %s", err)
+	}
+	if outcome != OutcomeUpdated {
+		t.Errorf("expected outcome %s, got %s", OutcomeUpdated, outcome)
+	}
+	if !strings.Contains(diff, "-WorkloadInstance: \n") || !strings.Contains(diff, "+WorkloadInstance: web-default-instance") {
+		t.Errorf("expected diff to add the workload instance, got:\n%s", diff)
+	}
+	stored, err := client.GetWorkloadServiceDependencyByName(wsdc.Name)
+	if err != nil {
+		t.Fatalf("failed to get workload service dependency: %s", err)
+	}
+	if stringValue(stored.UpstreamHost) != "api.example.com" || stored.WorkloadInstanceID == nil {
+		t.Errorf("expected workload service dependency to be updated, got %+v", stored)
+	}
+}
+
+// testParameterisedWorkloadConfig returns a workload config whose definition
+// is a template with an imageTag parameter that its instance sets.
+func testParameterisedWorkloadConfig(t *testing.T) *WorkloadConfig {
+	t.Helper()
+
+	workloadConfig := testWorkloadConfig(t)
+	template := strings.Replace(testManifest, "nginx:1.25", "nginx:{{ .Values.imageTag }}", 1)
+	manifestPath := filepath.Join(workloadConfig.WorkloadDefinition.ConfigDir, "manifest.yaml")
+	if err := ioutil.WriteFile(manifestPath, []byte(template), 0644); err != nil {
+		t.Fatalf("failed to write manifest template: %s", err)
+	}
+	workloadConfig.WorkloadDefinition.Parameters = []WorkloadParameter{
+		{Name: "imageTag", Type: ParameterTypeString, Default: "1.24"},
+	}
+	workloadConfig.WorkloadInstances[0].Values = map[string]interface{}{"imageTag": "1.25"}
+	workloadConfig.WorkloadServiceDependencies = nil
+
+	return workloadConfig
+}
+
+func TestDeleteWorkloadInstanceRenderedDefinition(t *testing.T) {
+	server, client := newFakeAPI(t)
+	workloadConfig := testParameterisedWorkloadConfig(t)
+	if err := workloadConfig.Create(); err != nil {
+		t.Fatalf("failed to create workload: %s", err)
+	}
+
+	rendered, err := client.GetWorkloadDefinitionByName("web-definition-web-default-instance")
+	if err != nil {
+		t.Fatalf("failed to get rendered workload definition: %s", err)
+	}
+	renderedFrom, manifest, err := ParseRenderedHeader(stringValue(rendered.YAMLDocument))
+	if err != nil {
+		t.Fatalf("failed to parse rendered workload definition header: %s", err)
+	}
+	if renderedFrom == nil || renderedFrom.Definition != "web-definition" || renderedFrom.Instance != "web-default-instance" {
+		t.Errorf("expected rendered from web-definition for web-default-instance, got %+v", renderedFrom)
+	}
+	if manifest != testManifest {
+		t.Errorf("expected rendered manifest %q, got %q", testManifest, manifest)
+	}
+
+	if _, err := DeleteWorkloadInstance(context.Background(), "web-default-instance", WaitTimeout); err != nil {
+		t.Fatalf("failed to delete workload instance: %s", err)
+	}
+	if count := server.Count(fakeapi.WorkloadInstances); count != 0 {
+		t.Errorf("expected no workload instances, got %d", count)
+	}
+	definitions, err := client.GetWorkloadDefinitions()
+	if err != nil {
+		t.Fatalf("failed to get workload definitions: %s", err)
+	}
+	if len(*definitions) != 1 || stringValue((*definitions)[0].Name) != "web-definition" {
+		t.Errorf("expected only workload definition web-definition to remain, got %d definitions", len(*definitions))
+	}
+
+	// the instance can be created again
+	if _, err := workloadConfig.WorkloadInstances[0].Create(); err != nil {
+		t.Errorf("failed to create workload instance again: %s", err)
+	}
+}
+
+func TestDeleteWorkloadInstanceKeepsDefinition(t *testing.T) {
+	server, _ := newFakeAPI(t)
+	workloadConfig := testWorkloadConfig(t)
+	workloadConfig.WorkloadServiceDependencies = nil
+	if err := workloadConfig.Create(); err != nil {
+		t.Fatalf("failed to create workload: %s", err)
+	}
+
+	if _, err := DeleteWorkloadInstance(context.Background(), "web-default-instance", WaitTimeout); err != nil {
+		t.Fatalf("failed to delete workload instance: %s", err)
+	}
+	if count := server.Count(fakeapi.WorkloadDefinitions); count != 1 {
+		t.Errorf("expected workload definition to remain, got %d workload definitions", count)
+	}
+
+	_, err := DeleteWorkloadInstance(context.Background(), "web-default-instance", WaitTimeout)
+	if kind := tperrors.KindOf(err); kind != tperrors.KindNotFound {
+		t.Errorf("expected deleting a missing workload instance to be %s, got %s: %v", tperrors.KindNotFound, kind, err)
+	}
+}
+
+func TestWorkloadInstanceRenderedDefinitionConflict(t *testing.T) {
+	server, _ := newFakeAPI(t)
+	workloadConfig := testParameterisedWorkloadConfig(t)
+	name := "web-definition-web-default-instance"
+	document := testManifest
+	if _, err := server.Add(fakeapi.WorkloadDefinitions, &tpapi.WorkloadDefinition{Name: &name, YAMLDocument: &document}); err != nil {
+		t.Fatalf("failed to add workload definition: %s", err)
+	}
+
+	err := workloadConfig.Create()
+	if kind := tperrors.KindOf(err); kind != tperrors.KindConflict {
+		t.Fatalf("expected creating an instance whose rendered name is taken to be %s, got %s: %v",
+			tperrors.KindConflict, kind, err)
+	}
+	stored, err := apiClient().GetWorkloadDefinitionByName(name)
+	if err != nil {
+		t.Fatalf("failed to get workload definition: %s", err)
+	}
+	if stringValue(stored.YAMLDocument) != testManifest {
+		t.Error("expected existing workload definition to be unchanged")
+	}
+}
+
+func TestWorkloadDefinitionConfigUpdateReRendersInstances(t *testing.T) {
+	_, client := newFakeAPI(t)
+	workloadConfig := testParameterisedWorkloadConfig(t)
+	if err := workloadConfig.Create(); err != nil {
+		t.Fatalf("failed to create workload: %s", err)
+	}
+
+	// add a replicas parameter to the template
+	wdc := workloadConfig.WorkloadDefinition
+	template := strings.Replace(testManifest, "nginx:1.25", "nginx:{{ .Values.imageTag }}", 1)
+	template = strings.Replace(template, "spec:\n  selector:", "spec:\n  replicas: {{ .Values.replicas }}\n  selector:", 1)
+	if err := ioutil.WriteFile(filepath.Join(wdc.ConfigDir, "manifest.yaml"), []byte(template), 0644); err != nil {
+		t.Fatalf("failed to write manifest template: %s", err)
+	}
+	wdc.Parameters = append(wdc.Parameters, WorkloadParameter{Name: "replicas", Type: ParameterTypeInteger, Default: 2})
+
+	var shownDiff string
+	_, updated, err := wdc.Update(func(diff string) bool {
+		shownDiff = diff
+		return true
+	})
+	if err != nil {
+		t.Fatalf("failed to update workload definition: %s", err)
+	}
+	if !updated {
+		t.Fatal("expected workload definition to be updated")
+	}
+	if !strings.Contains(shownDiff, "web-definition-web-default-instance manifests") || !strings.Contains(shownDiff, "+  replicas: 2") {
+		t.Errorf("expected diff to include the re-rendered instance definition, got:\n%s", shownDiff)
+	}
+
+	rendered, err := client.GetWorkloadDefinitionByName("web-definition-web-default-instance")
+	if err != nil {
+		t.Fatalf("failed to get rendered workload definition: %s", err)
+	}
+	_, manifest, err := ParseRenderedHeader(stringValue(rendered.YAMLDocument))
+	if err != nil {
+		t.Fatalf("failed to parse rendered workload definition header: %s", err)
+	}
+	if !strings.Contains(manifest, "replicas: 2") || !strings.Contains(manifest, "image: nginx:1.25") {
+		t.Errorf("expected rendered definition to be re-rendered with the instance values, got:\n%s", manifest)
+	}
+}
+
+func TestWorkloadDefinitionConfigUpdateSensitiveValues(t *testing.T) {
+	_, client := newFakeAPI(t)
+	workloadConfig := testParameterisedWorkloadConfig(t)
+	workloadConfig.WorkloadDefinition.Parameters[0].Sensitive = true
+	if err := workloadConfig.Create(); err != nil {
+		t.Fatalf("failed to create workload: %s", err)
+	}
+
+	rendered, err := client.GetWorkloadDefinitionByName("web-definition-web-default-instance")
+	if err != nil {
+		t.Fatalf("failed to get rendered workload definition: %s", err)
+	}
+	renderedFrom, _, err := ParseRenderedHeader(stringValue(rendered.YAMLDocument))
+	if err != nil {
+		t.Fatalf("failed to parse rendered workload definition header: %s", err)
+	}
+	if renderedFrom.Values != nil || !reflect.DeepEqual(renderedFrom.Redacted, []string{"imageTag"}) {
+		t.Errorf("expected the sensitive value to be left out of the header, got %+v", renderedFrom)
+	}
+
+	// the instance can't be re-rendered without its value
+	var shownDiff string
+	if _, _, err := workloadConfig.WorkloadDefinition.Update(func(diff string) bool {
+		shownDiff = diff
+		return true
+	}); err != nil {
+		t.Fatalf("failed to update workload definition: %s", err)
+	}
+	if strings.Contains(shownDiff, "web-definition-web-default-instance manifests") {
+		t.Errorf("expected the instance definition not to be re-rendered, got:\n%s", shownDiff)
+	}
+}
+
+func TestWorkloadInstanceConfigUpdateIgnoresRenderedHeader(t *testing.T) {
+	_, client := newFakeAPI(t)
+	workloadConfig := testParameterisedWorkloadConfig(t)
+	if err := workloadConfig.Create(); err != nil {
+		t.Fatalf("failed to create workload: %s", err)
+	}
+
+	// definitions rendered by earlier versions of tptctl have no header
+	rendered, err := client.GetWorkloadDefinitionByName("web-definition-web-default-instance")
+	if err != nil {
+		t.Fatalf("failed to get rendered workload definition: %s", err)
+	}
+	_, manifest, err := ParseRenderedHeader(stringValue(rendered.YAMLDocument))
+	if err != nil {
+		t.Fatalf("failed to parse rendered workload definition header: %s", err)
+	}
+	wdJSON, err := json.Marshal(&tpapi.WorkloadDefinition{YAMLDocument: &manifest})
+	if err != nil {
+		t.Fatalf("failed to marshal workload definition: %s", err)
+	}
+	if _, err := client.UpdateWorkloadDefinition(*rendered.ID, wdJSON); err != nil {
+		t.Fatalf("failed to update workload definition: %s", err)
+	}
+
+	wic := workloadConfig.WorkloadInstances[0]
+	_, updated, err := wic.Update(func(diff string) bool {
+		t.Errorf("expected no diff for unchanged manifests, got:\n%s", diff)
+		return false
+	})
+	if err != nil {
+		t.Fatalf("failed to update workload instance: %s", err)
+	}
+	if updated {
+		t.Error("expected workload instance not to be updated")
+	}
+}
+
+func TestWorkloadConfigResolve(t *testing.T) {
+	definition := WorkloadDefinitionConfig{YAMLDocument: YAMLDocumentPaths{"manifest.yaml"}}
+	dependency := WorkloadServiceDependencyConfig{UpstreamHost: "api.example.com", UpstreamPath: "/v1"}
+
+	testCases := []struct {
+		name                 string
+		config               WorkloadConfig
+		expectedInstances    []WorkloadInstanceConfig
+		expectedDependencies []WorkloadServiceDependencyConfig
+		wantErr              string
+	}{
+		{
+			name: "single instance",
+			config: WorkloadConfig{
+				Name:                        "web",
+				WorkloadDefinition:          definition,
+				WorkloadInstances:           []WorkloadInstanceConfig{{WorkloadClusterName: "default"}},
+				WorkloadServiceDependencies: []WorkloadServiceDependencyConfig{dependency},
+			},
+			expectedInstances: []WorkloadInstanceConfig{
+				{Name: "web-default-instance", WorkloadClusterName: "default", WorkloadDefinitionName: "web-definition"},
+			},
+			expectedDependencies: []WorkloadServiceDependencyConfig{
+				{Name: "web-api-example-com-service-default", UpstreamHost: "api.example.com", UpstreamPath: "/v1",
+					WorkloadInstanceName: "web-default-instance"},
+			},
+		},
+		{
+			name: "added instance doesn't rename the first",
+			config: WorkloadConfig{
+				Name:               "web",
+				WorkloadDefinition: definition,
+				WorkloadInstances: []WorkloadInstanceConfig{
+					{WorkloadClusterName: "default"},
+					{WorkloadClusterName: "EU West"},
+				},
+				WorkloadServiceDependencies: []WorkloadServiceDependencyConfig{dependency},
+			},
+			expectedInstances: []WorkloadInstanceConfig{
+				{Name: "web-default-instance", WorkloadClusterName: "default", WorkloadDefinitionName: "web-definition"},
+				{Name: "web-eu-west-instance", WorkloadClusterName: "EU West", WorkloadDefinitionName: "web-definition"},
+			},
+			expectedDependencies: []WorkloadServiceDependencyConfig{
+				{Name: "web-api-example-com-service-default", UpstreamHost: "api.example.com", UpstreamPath: "/v1",
+					WorkloadInstanceName: "web-default-instance"},
+				{Name: "web-api-example-com-service-eu-west", UpstreamHost: "api.example.com", UpstreamPath: "/v1",
+					WorkloadInstanceName: "web-eu-west-instance"},
+			},
+		},
+		{
+			name: "earlier single fields and names set in the config",
+			config: WorkloadConfig{
+				Name:               "web",
+				WorkloadDefinition: WorkloadDefinitionConfig{Name: "web-def", YAMLDocument: YAMLDocumentPaths{"manifest.yaml"}},
+				WorkloadInstance:   &WorkloadInstanceConfig{Name: "web-prod", WorkloadClusterName: "default"},
+				WorkloadServiceDependency: &WorkloadServiceDependencyConfig{
+					Name: "web-api", UpstreamHost: "api.example.com", UpstreamPath: "/v1"},
+			},
+			expectedInstances: []WorkloadInstanceConfig{
+				{Name: "web-prod", WorkloadClusterName: "default", WorkloadDefinitionName: "web-def"},
+			},
+			expectedDependencies: []WorkloadServiceDependencyConfig{
+				{Name: "web-api", UpstreamHost: "api.example.com", UpstreamPath: "/v1", WorkloadInstanceName: "web-prod"},
+			},
+		},
+		{
+			name: "name set in the config suffixed for several instances",
+			config: WorkloadConfig{
+				Name:               "web",
+				WorkloadDefinition: definition,
+				WorkloadInstances: []WorkloadInstanceConfig{
+					{WorkloadClusterName: "us"},
+					{Name: "web-canary", WorkloadClusterName: ""},
+				},
+				WorkloadServiceDependencies: []WorkloadServiceDependencyConfig{
+					{Name: "web-api", UpstreamHost: "api.example.com", UpstreamPath: "/v1"},
+				},
+			},
+			expectedInstances: []WorkloadInstanceConfig{
+				{Name: "web-us-instance", WorkloadClusterName: "us", WorkloadDefinitionName: "web-definition"},
+				{Name: "web-canary", WorkloadDefinitionName: "web-definition"},
+			},
+			expectedDependencies: []WorkloadServiceDependencyConfig{
+				{Name: "web-api-us", UpstreamHost: "api.example.com", UpstreamPath: "/v1", WorkloadInstanceName: "web-us-instance"},
+				{Name: "web-api-web-canary", UpstreamHost: "api.example.com", UpstreamPath: "/v1", WorkloadInstanceName: "web-canary"},
+			},
+		},
+		{
+			name: "no workload name to derive from",
+			config: WorkloadConfig{
+				WorkloadDefinition: definition,
+			},
+			wantErr: "workload name is required to derive the workload definition name",
+		},
+		{
+			name: "instance without name or cluster",
+			config: WorkloadConfig{
+				Name:               "web",
+				WorkloadDefinition: definition,
+				WorkloadInstances:  []WorkloadInstanceConfig{{}},
+			},
+			wantErr: "workload instance 1 needs a Name or WorkloadClusterName",
+		},
+		{
+			name: "instance without definition",
+			config: WorkloadConfig{
+				Name:              "web",
+				WorkloadInstances: []WorkloadInstanceConfig{{WorkloadClusterName: "default"}},
+			},
+			wantErr: "workload instance 1 has no WorkloadDefinitionName",
+		},
+		{
+			name: "service dependency without instances",
+			config: WorkloadConfig{
+				Name:                        "web",
+				WorkloadServiceDependencies: []WorkloadServiceDependencyConfig{dependency},
+			},
+			wantErr: "workload service dependency 1 has no WorkloadInstanceName",
+		},
+		{
+			name: "duplicate instance names",
+			config: WorkloadConfig{
+				Name:               "web",
+				WorkloadDefinition: definition,
+				WorkloadInstances: []WorkloadInstanceConfig{
+					{WorkloadClusterName: "default"},
+					{Name: "web-default-instance", WorkloadClusterName: "other"},
+				},
+			},
+			wantErr: "duplicate workload instance name web-default-instance",
+		},
+		{
+			name: "invalid upstream",
+			config: WorkloadConfig{
+				Name:               "web",
+				WorkloadDefinition: definition,
+				WorkloadInstances:  []WorkloadInstanceConfig{{WorkloadClusterName: "default"}},
+				WorkloadServiceDependencies: []WorkloadServiceDependencyConfig{
+					{UpstreamHost: "https://api.example.com"},
+				},
+			},
+			wantErr: "https://api.example.com",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Resolve()
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(tc.config.WorkloadInstances, tc.expectedInstances) {
+				t.Errorf("expected instances %+v, got %+v", tc.expectedInstances, tc.config.WorkloadInstances)
+			}
+			if !reflect.DeepEqual(tc.config.WorkloadServiceDependencies, tc.expectedDependencies) {
+				t.Errorf("expected service dependencies %+v, got %+v",
+					tc.expectedDependencies, tc.config.WorkloadServiceDependencies)
+			}
+
+			// resolving again changes nothing
+			instances := append([]WorkloadInstanceConfig{}, tc.config.WorkloadInstances...)
+			dependencies := append([]WorkloadServiceDependencyConfig{}, tc.config.WorkloadServiceDependencies...)
+			if err := tc.config.Resolve(); err != nil {
+				t.Fatalf("failed to resolve again: %s", err)
+			}
+			if !reflect.DeepEqual(tc.config.WorkloadInstances, instances) ||
+				!reflect.DeepEqual(tc.config.WorkloadServiceDependencies, dependencies) {
+				t.Error("expected resolving again to change nothing")
+			}
+		})
+	}
+}
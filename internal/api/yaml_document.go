@@ -0,0 +1,207 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	kube "github.com/threeport/tptctl/internal/kubernetes"
+)
+
+// yamlDocumentFetchTimeout is the timeout for fetching a YAML document from an
+// http(s) URL.
+const yamlDocumentFetchTimeout = time.Second * 30
+
+// YAMLDocumentPaths are the locations of the YAML for a workload definition.
+// Each location is a file, a directory, a glob or an http(s):// or file:// URL.
+// In a config it can be set to a single location or a list of them.
+type YAMLDocumentPaths []string
+
+// UnmarshalYAML allows YAMLDocumentPaths to be set to a string or a list of
+// strings.
+func (p *YAMLDocumentPaths) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var single string
+	if err := unmarshal(&single); err == nil {
+		if single != "" {
+			*p = YAMLDocumentPaths{single}
+		}
+		return nil
+	}
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return errors.New("YAMLDocument must be a string or a list of strings")
+	}
+	*p = list
+
+	return nil
+}
+
+// MarshalYAML writes a single location as a string so that exported configs
+// match the way they are usually written.
+func (p YAMLDocumentPaths) MarshalYAML() (interface{}, error) {
+	if len(p) == 1 {
+		return p[0], nil
+	}
+
+	return []string(p), nil
+}
+
+// String returns the locations as a comma-separated list.
+func (p YAMLDocumentPaths) String() string {
+	return strings.Join(p, ", ")
+}
+
+// yamlDocumentFile is a file that contributes to a YAML document.
+type yamlDocumentFile struct {
+	name    string
+	content string
+}
+
+// Load reads all the files at the locations and concatenates them into one
+// multi-document YAML.  Relative paths are resolved against baseDir.  Files
+// from a directory or glob are read in lexical order.  If there is more than
+// one file, each is preceded by a comment with its name so issues can be
+// traced back to it.
+func (p YAMLDocumentPaths) Load(baseDir string) (string, error) {
+	var files []yamlDocumentFile
+	for _, location := range p {
+		locationFiles, err := loadYAMLDocumentLocation(location, baseDir)
+		if err != nil {
+			return "", err
+		}
+		files = append(files, locationFiles...)
+	}
+
+	switch len(files) {
+	case 0:
+		return "", errors.New(fmt.Sprintf("no YAML files found in %s", p))
+	case 1:
+		return files[0].content, nil
+	}
+	var yamlDocument strings.Builder
+	for _, file := range files {
+		yamlDocument.WriteString("---\n")
+		yamlDocument.WriteString(kube.SourceMarker + file.name + "\n")
+		yamlDocument.WriteString(strings.TrimRight(file.content, "\n"))
+		yamlDocument.WriteString("\n")
+	}
+
+	return yamlDocument.String(), nil
+}
+
+// loadYAMLDocumentLocation reads the files at a single location.
+func loadYAMLDocumentLocation(location, baseDir string) ([]yamlDocumentFile, error) {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		content, err := fetchYAMLDocument(location)
+		if err != nil {
+			return nil, err
+		}
+		return []yamlDocumentFile{{name: location, content: content}}, nil
+	}
+
+	path := location
+	if strings.HasPrefix(location, "file://") {
+		fileURL, err := url.Parse(location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file URL %s: %w", location, err)
+		}
+		// file://relative/path parses the first element as the host
+		path = filepath.Join(fileURL.Host, fileURL.Path)
+	}
+	path = ResolvePath(path, baseDir)
+
+	// expand globs
+	var paths []string
+	if strings.ContainsAny(path, "*?[") {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %s: %w", location, err)
+		}
+		if len(matches) == 0 {
+			return nil, errors.New(fmt.Sprintf("no files match %s", location))
+		}
+		paths = matches
+	} else {
+		paths = []string{path}
+	}
+
+	var files []yamlDocumentFile
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read YAML document %s: %w", location, err)
+		}
+		if !info.IsDir() {
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read YAML document %s: %w", path, err)
+			}
+			files = append(files, yamlDocumentFile{name: path, content: string(content)})
+			continue
+		}
+
+		// read the YAML files in a directory, not including subdirectories
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read YAML document directory %s: %w", path, err)
+		}
+		var dirPaths []string
+		for _, entry := range entries {
+			ext := filepath.Ext(entry.Name())
+			if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+				continue
+			}
+			dirPaths = append(dirPaths, filepath.Join(path, entry.Name()))
+		}
+		if len(dirPaths) == 0 {
+			return nil, errors.New(fmt.Sprintf("no YAML files found in directory %s", path))
+		}
+		sort.Strings(dirPaths)
+		for _, dirPath := range dirPaths {
+			content, err := ioutil.ReadFile(dirPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read YAML document %s: %w", dirPath, err)
+			}
+			files = append(files, yamlDocumentFile{name: dirPath, content: string(content)})
+		}
+	}
+
+	return files, nil
+}
+
+// fetchYAMLDocument retrieves a YAML document from an http(s) URL.
+func fetchYAMLDocument(location string) (string, error) {
+	client := &http.Client{Timeout: yamlDocumentFetchTimeout}
+	resp, err := client.Get(location)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch YAML document %s: %w", location, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(fmt.Sprintf(
+			"failed to fetch YAML document %s: unexpected status %s", location, resp.Status))
+	}
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read YAML document %s: %w", location, err)
+	}
+
+	return string(content), nil
+}
+
+// ResolvePath returns path relative to baseDir if it is a relative path.
+// Absolute paths and an empty baseDir leave the path unchanged.
+func ResolvePath(path, baseDir string) string {
+	if baseDir == "" || path == "" || filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(baseDir, path)
+}
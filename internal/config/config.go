@@ -1,5 +1,13 @@
 package config
 
+import (
+	"errors"
+	"fmt"
+
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	"github.com/threeport/tptctl/internal/install"
+)
+
 // ThreeportConfig is the client's configuration for connecting to Threeport instances
 type ThreeportConfig struct {
 	Instances       []Instance `yaml:"Instances"`
@@ -8,7 +16,49 @@ type ThreeportConfig struct {
 
 // ThreeportInstance is an instance of Threeport the client can use
 type Instance struct {
-	Name      string `yaml:"Name"`
-	Provider  string `yaml:"Provider"`
-	APIServer string `yaml:"APIServer"`
+	Name       string `yaml:"Name"`
+	Provider   string `yaml:"Provider"`
+	APIServer  string `yaml:"APIServer"`
+	RootDomain string `yaml:"RootDomain"`
+	AWSProfile string `yaml:"AWSProfile"`
+
+	// the identity tptctl uses with the instance, bootstrapped as the
+	// superuser when the control plane is created
+	UserID       uint   `yaml:"UserID"`
+	UserEmail    string `yaml:"UserEmail"`
+	UserPassword string `yaml:"UserPassword"`
+
+	// the forward proxy settings the control plane was created with
+	ForwardProxy *install.ForwardProxyConfig `yaml:"ForwardProxy,omitempty"`
+}
+
+// GetInstance returns the config for the Threeport instance with a name.
+func (c *ThreeportConfig) GetInstance(name string) (*Instance, error) {
+	for i, instance := range c.Instances {
+		if instance.Name == name {
+			return &c.Instances[i], nil
+		}
+	}
+
+	return nil, tperrors.New(tperrors.KindNotFound, fmt.Sprintf("config for threeport instance with name %s not found", name))
+}
+
+// GetCurrentInstance returns the config for the current Threeport instance.
+func (c *ThreeportConfig) GetCurrentInstance() (*Instance, error) {
+	if c.CurrentInstance == "" {
+		return nil, errors.New("no current threeport instance is set")
+	}
+
+	return c.GetInstance(c.CurrentInstance)
+}
+
+// ForwardProxyNamespace returns the namespace the forward proxy servers run in
+// on workload clusters for the instance.  Instances created before the forward
+// proxy settings were recorded use the default namespace.
+func (i *Instance) ForwardProxyNamespace() string {
+	if i.ForwardProxy == nil || i.ForwardProxy.Namespace == "" {
+		return install.ForwardProxyDefaultNamespace
+	}
+
+	return i.ForwardProxy.Namespace
 }
@@ -0,0 +1,161 @@
+// Package errors provides the kinds of error tptctl fails with and the exit
+// code for each so that scripts can tell failures apart.
+package errors
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+)
+
+// Kind is the category of a failure.
+type Kind string
+
+const (
+	KindConfig         Kind = "config"
+	KindNotFound       Kind = "not found"
+	KindConflict       Kind = "conflict"
+	KindProvider       Kind = "provider"
+	KindAPIUnavailable Kind = "API unavailable"
+	KindTimeout        Kind = "timeout"
+	KindInterrupted    Kind = "interrupted"
+)
+
+// Exit codes for each kind of error.  Any other error exits with
+// ExitCodeError.  ExitCodeTimeout is 2 as it was before the other codes were
+// added.
+const (
+	ExitCodeError          = 1
+	ExitCodeTimeout        = 2
+	ExitCodeConfig         = 3
+	ExitCodeNotFound       = 4
+	ExitCodeConflict       = 5
+	ExitCodeProvider       = 6
+	ExitCodeAPIUnavailable = 7
+	ExitCodeInterrupted    = 130
+)
+
+// exitCodes maps each kind of error to its exit code.
+var exitCodes = map[Kind]int{
+	KindConfig:         ExitCodeConfig,
+	KindNotFound:       ExitCodeNotFound,
+	KindConflict:       ExitCodeConflict,
+	KindProvider:       ExitCodeProvider,
+	KindAPIUnavailable: ExitCodeAPIUnavailable,
+	KindTimeout:        ExitCodeTimeout,
+	KindInterrupted:    ExitCodeInterrupted,
+}
+
+// Error is an error of a known kind.
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+// Error returns the message of the underlying error.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns an error of a kind with a message.
+func New(kind Kind, message string) error {
+	return &Error{Kind: kind, Err: errors.New(message)}
+}
+
+// Wrap returns err as an error of a kind.  If err already has a kind it is
+// returned unchanged so the most specific kind, set closest to the failure,
+// is kept, and an interruption by the user stays an interruption.  Wrap
+// returns nil if err is nil.
+func Wrap(kind Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	var typed *Error
+	if errors.As(err, &typed) || errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return &Error{Kind: kind, Err: err}
+}
+
+// ConfigError returns err as an invalid config, flag or argument error.
+func ConfigError(err error) error {
+	return Wrap(KindConfig, err)
+}
+
+// NotFound returns err as an error for an object that doesn't exist.
+func NotFound(err error) error {
+	return Wrap(KindNotFound, err)
+}
+
+// Conflict returns err as an error for an object that already exists or
+// differs from what was asked for.
+func Conflict(err error) error {
+	return Wrap(KindConflict, err)
+}
+
+// ProviderError returns err as a failure of an infra provider such as kind
+// or AWS, including missing credentials.
+func ProviderError(err error) error {
+	return Wrap(KindProvider, err)
+}
+
+// APIUnavailable returns err as a failure to reach the Threeport API.
+func APIUnavailable(err error) error {
+	return Wrap(KindAPIUnavailable, err)
+}
+
+// Timeout returns err as a timeout waiting for a condition.
+func Timeout(err error) error {
+	return Wrap(KindTimeout, err)
+}
+
+// KindOf returns the kind of an error.  Errors without a kind are classified
+// from the errors they wrap where possible: network errors are
+// KindAPIUnavailable, deadlines are KindTimeout and cancellations are
+// KindInterrupted.  An empty kind is returned for anything else.
+func KindOf(err error) Kind {
+	if err == nil {
+		return ""
+	}
+	var typed *Error
+	if errors.As(err, &typed) {
+		return typed.Kind
+	}
+	var urlErr *url.Error
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return KindTimeout
+	case errors.Is(err, context.Canceled):
+		return KindInterrupted
+	case errors.As(err, &urlErr), errors.As(err, &opErr):
+		return KindAPIUnavailable
+	}
+
+	return ""
+}
+
+// Is returns whether an error is of a kind.
+func Is(err error, kind Kind) bool {
+	return KindOf(err) == kind
+}
+
+// ExitCode returns the exit code for an error: 0 for nil, the code for its
+// kind or ExitCodeError.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if code, ok := exitCodes[KindOf(err)]; ok {
+		return code
+	}
+
+	return ExitCodeError
+}
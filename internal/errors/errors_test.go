@@ -0,0 +1,72 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+)
+
+func TestKindOf(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected Kind
+	}{
+		{name: "nil", err: nil, expected: ""},
+		{name: "untyped", err: errors.New("failed"), expected: ""},
+		{name: "typed", err: New(KindConflict, "user exists"), expected: KindConflict},
+		{name: "wrapped typed", err: fmt.Errorf("failed to create user: %w", NotFound(errors.New("missing"))), expected: KindNotFound},
+		{name: "most specific kind kept", err: ConfigError(ProviderError(errors.New("no AWS credentials"))), expected: KindProvider},
+		{name: "deadline", err: fmt.Errorf("waiting: %w", context.DeadlineExceeded), expected: KindTimeout},
+		{name: "cancelled", err: fmt.Errorf("waiting: %w", context.Canceled), expected: KindInterrupted},
+		{name: "cancelled stays interrupted", err: Timeout(context.Canceled), expected: KindInterrupted},
+		{
+			name:     "url error",
+			err:      &url.Error{Op: "Get", URL: "http://localhost:1323", Err: errors.New("connection refused")},
+			expected: KindAPIUnavailable,
+		},
+		{
+			name:     "network error",
+			err:      fmt.Errorf("request failed: %w", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}),
+			expected: KindAPIUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if kind := KindOf(tc.err); kind != tc.expected {
+				t.Errorf("expected kind %q, got %q", tc.expected, kind)
+			}
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "nil", err: nil, expected: 0},
+		{name: "untyped", err: errors.New("failed"), expected: ExitCodeError},
+		{name: "config", err: ConfigError(errors.New("invalid flag")), expected: ExitCodeConfig},
+		{name: "not found", err: NotFound(errors.New("missing")), expected: ExitCodeNotFound},
+		{name: "conflict", err: Conflict(errors.New("exists")), expected: ExitCodeConflict},
+		{name: "provider", err: ProviderError(errors.New("no AWS credentials")), expected: ExitCodeProvider},
+		{name: "API unavailable", err: APIUnavailable(errors.New("connection refused")), expected: ExitCodeAPIUnavailable},
+		{name: "timeout", err: Timeout(errors.New("not ready")), expected: ExitCodeTimeout},
+		{name: "interrupted", err: context.Canceled, expected: ExitCodeInterrupted},
+		{name: "unknown kind", err: New(Kind("other"), "failed"), expected: ExitCodeError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if code := ExitCode(tc.err); code != tc.expected {
+				t.Errorf("expected exit code %d, got %d", tc.expected, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,342 @@
+// Package fakeapi provides an in-memory fake of the Threeport REST API for
+// testing tptctl without a control plane.  It serves the v0 routes the
+// Threeport go client uses for workload definitions, workload instances,
+// workload clusters, workload service dependencies and users:
+//
+//	GET    /v0/<objects>         list, filtered by query, e.g. ?name=web
+//	GET    /v0/<objects>/<id>    get by ID
+//	POST   /v0/<objects>         create, assigning the next ID
+//	PATCH  /v0/<objects>/<id>    update the attributes sent
+//	DELETE /v0/<objects>/<id>    delete
+//
+// Responses use the API's envelope with the objects in Data.  Names, or
+// emails for users, are unique within each type of object as in the API.
+package fakeapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiVersion is the version prefix of every route.
+const apiVersion = "v0"
+
+// Object paths and the object types returned for them.
+const (
+	WorkloadDefinitions         = "workload-definitions"
+	WorkloadInstances           = "workload-instances"
+	WorkloadClusters            = "workload-clusters"
+	WorkloadServiceDependencies = "workload-service-dependencies"
+	Users                       = "users"
+)
+
+// objectTypes maps each object path to the type in responses and the
+// attribute that is unique for the type.
+var objectTypes = map[string]struct {
+	name      string
+	uniqueKey string
+}{
+	WorkloadDefinitions:         {name: "WorkloadDefinition", uniqueKey: "Name"},
+	WorkloadInstances:           {name: "WorkloadInstance", uniqueKey: "Name"},
+	WorkloadClusters:            {name: "WorkloadCluster", uniqueKey: "Name"},
+	WorkloadServiceDependencies: {name: "WorkloadServiceDependency", uniqueKey: "Name"},
+	Users:                       {name: "User", uniqueKey: "Email"},
+}
+
+// object is an object stored by the server as its JSON attributes.
+type object map[string]interface{}
+
+// response is the envelope every response is sent in.
+type response struct {
+	Meta   meta          `json:"Meta"`
+	Type   string        `json:"Type"`
+	Data   []interface{} `json:"Data"`
+	Status status        `json:"Status"`
+}
+
+// meta is the paging information in a response.
+type meta struct {
+	Page       int `json:"Page"`
+	Size       int `json:"Size"`
+	TotalCount int `json:"TotalCount"`
+}
+
+// status is the outcome of a request.
+type status struct {
+	Code    int    `json:"Code"`
+	Message string `json:"Message"`
+	Error   string `json:"Error"`
+}
+
+// Server is a fake Threeport API served over HTTP on a local port.  Use URL as
+// the Threeport API endpoint and Close it when finished.
+type Server struct {
+	*httptest.Server
+
+	mutex   sync.Mutex
+	nextID  uint
+	objects map[string]map[uint]object
+}
+
+// NewServer starts a fake Threeport API with no objects.
+func NewServer() *Server {
+	s := &Server{
+		nextID:  1,
+		objects: make(map[string]map[uint]object),
+	}
+	for path := range objectTypes {
+		s.objects[path] = make(map[uint]object)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+
+	return s
+}
+
+// Add stores an object, e.g. a tpapi.WorkloadCluster, as if it was created
+// through the API and returns its ID.
+func (s *Server) Add(path string, value interface{}) (uint, error) {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal object: %w", err)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	created, _, err := s.create(path, valueJSON)
+	if err != nil {
+		return 0, err
+	}
+
+	return objectID(created), nil
+}
+
+// Count returns the number of objects stored for a path.
+func (s *Server) Count(path string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.objects[path])
+}
+
+// handle routes a request to the objects for its path.
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] != apiVersion {
+		writeError(w, "", http.StatusNotFound, errors.New(fmt.Sprintf("no route for %s", r.URL.Path)))
+		return
+	}
+	path := parts[1]
+	objectType, ok := objectTypes[path]
+	if !ok {
+		writeError(w, "", http.StatusNotFound, errors.New(fmt.Sprintf("no route for %s", r.URL.Path)))
+		return
+	}
+
+	if len(parts) == 2 {
+		switch r.Method {
+		case http.MethodGet:
+			writeObjects(w, objectType.name, http.StatusOK, s.list(path, r)...)
+		case http.MethodPost:
+			var body json.RawMessage
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeError(w, objectType.name, http.StatusBadRequest, err)
+				return
+			}
+			created, code, err := s.create(path, body)
+			if err != nil {
+				writeError(w, objectType.name, code, err)
+				return
+			}
+			writeObjects(w, objectType.name, http.StatusCreated, created)
+		default:
+			writeError(w, objectType.name, http.StatusMethodNotAllowed,
+				errors.New(fmt.Sprintf("method %s not allowed", r.Method)))
+		}
+		return
+	}
+
+	id, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		writeError(w, objectType.name, http.StatusBadRequest, errors.New(fmt.Sprintf("invalid ID %s", parts[2])))
+		return
+	}
+	existing, ok := s.objects[path][uint(id)]
+	if !ok {
+		writeError(w, objectType.name, http.StatusNotFound,
+			errors.New(fmt.Sprintf("%s with ID %d not found", objectType.name, id)))
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeObjects(w, objectType.name, http.StatusOK, existing)
+	case http.MethodPatch:
+		var body json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, objectType.name, http.StatusBadRequest, err)
+			return
+		}
+		updated, code, err := s.update(path, existing, body)
+		if err != nil {
+			writeError(w, objectType.name, code, err)
+			return
+		}
+		writeObjects(w, objectType.name, http.StatusOK, updated)
+	case http.MethodDelete:
+		delete(s.objects[path], uint(id))
+		writeObjects(w, objectType.name, http.StatusOK, existing)
+	default:
+		writeError(w, objectType.name, http.StatusMethodNotAllowed,
+			errors.New(fmt.Sprintf("method %s not allowed", r.Method)))
+	}
+}
+
+// list returns the objects for a path that match the query in order of ID.
+// Query parameters are matched to attributes regardless of case, e.g. name
+// matches Name.
+func (s *Server) list(path string, r *http.Request) []object {
+	var ids []uint
+	for id := range s.objects[path] {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var matched []object
+	for _, id := range ids {
+		obj := s.objects[path][id]
+		if matchesQuery(obj, r) {
+			matched = append(matched, obj)
+		}
+	}
+
+	return matched
+}
+
+// create stores a new object from its JSON and returns it with the status
+// code for a failure.
+func (s *Server) create(path string, body []byte) (object, int, error) {
+	obj := object{}
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid object: %w", err)
+	}
+	if err := s.checkUnique(path, obj, 0); err != nil {
+		return nil, http.StatusConflict, err
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	obj["ID"] = s.nextID
+	obj["CreatedAt"] = now
+	obj["UpdatedAt"] = now
+	s.objects[path][s.nextID] = obj
+	s.nextID++
+
+	return obj, 0, nil
+}
+
+// update sets the attributes in the JSON on an existing object and returns it
+// with the status code for a failure.
+func (s *Server) update(path string, existing object, body []byte) (object, int, error) {
+	changes := object{}
+	if err := json.Unmarshal(body, &changes); err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid object: %w", err)
+	}
+	delete(changes, "ID")
+	if err := s.checkUnique(path, changes, objectID(existing)); err != nil {
+		return nil, http.StatusConflict, err
+	}
+
+	for key, value := range changes {
+		existing[key] = value
+	}
+	existing["UpdatedAt"] = time.Now().UTC().Format(time.RFC3339Nano)
+
+	return existing, 0, nil
+}
+
+// checkUnique returns an error if another object for a path has the same
+// unique attribute.  The object with ID except is ignored.
+func (s *Server) checkUnique(path string, obj object, except uint) error {
+	key := objectTypes[path].uniqueKey
+	value, ok := obj[key]
+	if !ok {
+		return nil
+	}
+	for id, existing := range s.objects[path] {
+		if id != except && existing[key] == value {
+			return errors.New(fmt.Sprintf("%s with %s %v already exists", objectTypes[path].name, key, value))
+		}
+	}
+
+	return nil
+}
+
+// matchesQuery returns whether an object has the attributes in a request's
+// query.
+func matchesQuery(obj object, r *http.Request) bool {
+	for param, values := range r.URL.Query() {
+		matched := false
+		for key, value := range obj {
+			if strings.EqualFold(key, param) && fmt.Sprint(value) == values[0] {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// objectID returns the ID of a stored object.
+func objectID(obj object) uint {
+	switch id := obj["ID"].(type) {
+	case uint:
+		return id
+	case float64:
+		return uint(id)
+	}
+
+	return 0
+}
+
+// writeObjects writes a response with objects.
+func writeObjects(w http.ResponseWriter, objectType string, code int, objects ...object) {
+	data := make([]interface{}, 0, len(objects))
+	for _, obj := range objects {
+		data = append(data, obj)
+	}
+	writeResponse(w, code, response{
+		Meta:   meta{Page: 1, Size: len(data), TotalCount: len(data)},
+		Type:   objectType,
+		Data:   data,
+		Status: status{Code: code, Message: http.StatusText(code)},
+	})
+}
+
+// writeError writes a response for a failed request.
+func writeError(w http.ResponseWriter, objectType string, code int, err error) {
+	writeResponse(w, code, response{
+		Type:   objectType,
+		Data:   []interface{}{},
+		Status: status{Code: code, Message: http.StatusText(code), Error: err.Error()},
+	})
+}
+
+// writeResponse writes a response as JSON.
+func writeResponse(w http.ResponseWriter, code int, resp response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
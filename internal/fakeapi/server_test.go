@@ -0,0 +1,80 @@
+package fakeapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// request sends a request to the server and returns the status code and the
+// objects in the response.
+func request(t *testing.T, server *Server, method, path, body string) (int, []map[string]interface{}) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, server.URL+path, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("failed to build request: %s", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to send request: %s", err)
+	}
+	defer resp.Body.Close()
+
+	var decoded struct {
+		Data []map[string]interface{}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		t.Fatalf("failed to decode response: %s", err)
+	}
+
+	return resp.StatusCode, decoded.Data
+}
+
+func TestServer(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	// IDs are assigned in order across object types
+	code, data := request(t, server, http.MethodPost, "/v0/workload-definitions", `{"Name": "web"}`)
+	if code != http.StatusCreated || len(data) != 1 || data[0]["ID"] != float64(1) {
+		t.Fatalf("expected workload definition to be created with ID 1, got %d %v", code, data)
+	}
+	code, data = request(t, server, http.MethodPost, "/v0/workload-instances", `{"Name": "web", "WorkloadDefinitionID": 1}`)
+	if code != http.StatusCreated || data[0]["ID"] != float64(2) {
+		t.Fatalf("expected workload instance to be created with ID 2, got %d %v", code, data)
+	}
+
+	// names are unique within an object type
+	if code, _ := request(t, server, http.MethodPost, "/v0/workload-definitions", `{"Name": "web"}`); code != http.StatusConflict {
+		t.Errorf("expected conflict creating a duplicate workload definition, got %d", code)
+	}
+
+	// lookup by name
+	request(t, server, http.MethodPost, "/v0/workload-definitions", `{"Name": "api"}`)
+	code, data = request(t, server, http.MethodGet, "/v0/workload-definitions?name=api", "")
+	if code != http.StatusOK || len(data) != 1 || data[0]["Name"] != "api" {
+		t.Errorf("expected workload definition api, got %d %v", code, data)
+	}
+	if _, data := request(t, server, http.MethodGet, "/v0/workload-definitions?name=missing", ""); len(data) != 0 {
+		t.Errorf("expected no workload definitions named missing, got %v", data)
+	}
+
+	// update
+	code, data = request(t, server, http.MethodPatch, "/v0/workload-definitions/1", `{"YAMLDocument": "kind: Pod"}`)
+	if code != http.StatusOK || data[0]["YAMLDocument"] != "kind: Pod" || data[0]["Name"] != "web" {
+		t.Errorf("expected workload definition web to be updated, got %d %v", code, data)
+	}
+
+	// delete
+	if code, _ := request(t, server, http.MethodDelete, "/v0/workload-definitions/1", ""); code != http.StatusOK {
+		t.Errorf("expected workload definition to be deleted, got %d", code)
+	}
+	if code, _ := request(t, server, http.MethodGet, "/v0/workload-definitions/1", ""); code != http.StatusNotFound {
+		t.Errorf("expected deleted workload definition to be not found, got %d", code)
+	}
+	if count := server.Count(WorkloadDefinitions); count != 1 {
+		t.Errorf("expected 1 workload definition, got %d", count)
+	}
+}
@@ -45,9 +45,7 @@ func InstallAPI(kubeconfig, threeportAPISubdomain, rootDomain, loadBalancerURL s
 		"-f",
 		APIDepsManifestPath,
 	)
-	apiDepsCreateOut, err := apiDepsCreate.CombinedOutput()
-	if err != nil {
-		qout.Error(fmt.Sprintf("kubectl error: %s", apiDepsCreateOut), nil)
+	if _, err := qout.RunCommand(apiDepsCreate); err != nil {
 		return fmt.Errorf("failed to install API dependencies: %w", err)
 	}
 
@@ -61,10 +59,9 @@ func InstallAPI(kubeconfig, threeportAPISubdomain, rootDomain, loadBalancerURL s
 		"-n",
 		ThreeportControlPlaneNs,
 	)
-	psqlConfigCreateOut, err := psqlConfigCreate.CombinedOutput()
-	if err != nil {
-		fmt.Println(psqlConfigCreateOut)
-		//qout.Error(fmt.Sprintf("kubectl error: %s", psqlConfigCreateOut), nil)
+	if _, err := qout.RunCommand(psqlConfigCreate); err != nil {
+		// the config map already exists when the install is repeated
+		qout.Debug(fmt.Sprintf("failed to create API database config: %s", err))
 		//return fmt.Errorf("failed to create API database config: %w", err)
 	}
 	qout.Info("Threeport API dependencies created")
@@ -88,9 +85,7 @@ func InstallAPI(kubeconfig, threeportAPISubdomain, rootDomain, loadBalancerURL s
 		"-f",
 		APIServerManifestPath,
 	)
-	apiServerCreateOut, err := apiServerCreate.CombinedOutput()
-	if err != nil {
-		qout.Error(fmt.Sprintf("kubectl error: %s", apiServerCreateOut), nil)
+	if _, err := qout.RunCommand(apiServerCreate); err != nil {
 		return fmt.Errorf("failed to create API server: %w", err)
 	}
 
@@ -117,9 +112,7 @@ func InstallAPI(kubeconfig, threeportAPISubdomain, rootDomain, loadBalancerURL s
 		"-f",
 		APIIngressManifestPath,
 	)
-	apiIngressCreateOut, err := apiIngressCreate.CombinedOutput()
-	if err != nil {
-		qout.Error(fmt.Sprintf("kubectl error: %s", apiIngressCreateOut), nil)
+	if _, err := qout.RunCommand(apiIngressCreate); err != nil {
 		return fmt.Errorf("failed to create API ingress: %w", err)
 	}
 
@@ -142,9 +135,7 @@ func UninstallAPIIngress(kubeconfig string) error {
 		ThreeportControlPlaneNs,
 		APIIngressResourceName,
 	)
-	apiIngressDeleteOut, err := apiIngressDelete.CombinedOutput()
-	if err != nil {
-		qout.Error(fmt.Sprintf("kubectl error: %s", apiIngressDeleteOut), nil)
+	if _, err := qout.RunCommand(apiIngressDelete); err != nil {
 		return fmt.Errorf("failed to delete Threeport API ingress resource: %w", err)
 	}
 
@@ -156,27 +147,20 @@ func UninstallAPIIngress(kubeconfig string) error {
 	return nil
 }
 
+// threeportAPIEndpoint is the endpoint of the Threeport API for the current
+// threeport instance.
+var threeportAPIEndpoint string
+
+// SetThreeportAPIEndpoint sets the endpoint returned by
+// GetThreeportAPIEndpoint, i.e. the APIServer of the current threeport
+// instance.
+func SetThreeportAPIEndpoint(endpoint string) {
+	threeportAPIEndpoint = endpoint
+}
+
 // GetThreeportAPIEndpoint returns the threeport API endpoint
 func GetThreeportAPIEndpoint() string {
-	var apiProtocol string
-	var apiHostname string
-	var apiPort string
-
-	//switch infraProvider {
-	//case "kind":
-	//	apiProtocol = provider.KindThreeportAPIProtocol
-	//	apiHostname = provider.KindThreeportAPIHostname
-	//	apiPort = provider.KindThreeportAPIPort
-	//case "eks":
-	//	apiProtocol = "?"
-	//	apiHostname = "?"
-	//	apiPort = "?"
-	//}
-
-	return fmt.Sprintf(
-		"%s://%s:%s",
-		apiProtocol, apiHostname, apiPort,
-	)
+	return threeportAPIEndpoint
 }
 
 // APIDepsManifest returns a yaml manifest for the threeport API dependencies
@@ -1,16 +1,93 @@
 package install
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
 
 const (
-	FowardProxyOperatorImage = "lander2k2/forward-proxy-operator:v0.0.4"
+	FowardProxyOperatorImage          = "lander2k2/forward-proxy-operator:v0.0.4"
+	ForwardProxyServerName            = "foward-proxy-main"
+	ForwardProxyDefaultNamespace      = "forward-proxy-system"
+	ForwardProxyDefaultReplicas       = 2
+	ForwardProxyOperatorCPURequest    = "10m"
+	ForwardProxyOperatorMemoryRequest = "64Mi"
+	ForwardProxyOperatorCPULimit      = "500m"
+	ForwardProxyOperatorMemoryLimit   = "128Mi"
+	forwardProxyResourceCPURequest    = "requests.cpu"
+	forwardProxyResourceMemoryRequest = "requests.memory"
+	forwardProxyResourceCPULimit      = "limits.cpu"
+	forwardProxyResourceMemoryLimit   = "limits.memory"
 )
 
+// ForwardProxyConfig contains the settings for the forward proxy deployed to
+// workload clusters.  Namespace and Replicas are for the envoy forward proxy
+// servers.  The operator always runs in the forward-proxy-system namespace and
+// Resources are the resource requests and limits for its manager container,
+// keyed by requests.cpu, requests.memory, limits.cpu and limits.memory.  Any
+// that are not set use the operator's defaults.
+type ForwardProxyConfig struct {
+	Namespace     string            `yaml:"Namespace"`
+	Replicas      int               `yaml:"Replicas"`
+	OperatorImage string            `yaml:"OperatorImage"`
+	Resources     map[string]string `yaml:"Resources,omitempty"`
+}
+
+// NewForwardProxyConfig returns a ForwardProxyConfig with default values set.
+func NewForwardProxyConfig() ForwardProxyConfig {
+	return ForwardProxyConfig{
+		Namespace:     ForwardProxyDefaultNamespace,
+		Replicas:      ForwardProxyDefaultReplicas,
+		OperatorImage: FowardProxyOperatorImage,
+	}
+}
+
+// Validate checks the forward proxy config.
+func (fpc *ForwardProxyConfig) Validate() error {
+	if errs := validation.IsDNS1123Label(fpc.Namespace); len(errs) > 0 {
+		return errors.New(fmt.Sprintf("invalid forward proxy namespace '%s': %s", fpc.Namespace, errs[0]))
+	}
+	if fpc.Replicas < 1 {
+		return errors.New("forward proxy replicas must be at least 1")
+	}
+	if fpc.OperatorImage == "" {
+		return errors.New("forward proxy operator image is required")
+	}
+	for key, value := range fpc.Resources {
+		switch key {
+		case forwardProxyResourceCPURequest, forwardProxyResourceMemoryRequest,
+			forwardProxyResourceCPULimit, forwardProxyResourceMemoryLimit:
+		default:
+			return errors.New(fmt.Sprintf(
+				"unsupported forward proxy resource '%s' - must be one of %s", key,
+				[]string{forwardProxyResourceCPURequest, forwardProxyResourceMemoryRequest,
+					forwardProxyResourceCPULimit, forwardProxyResourceMemoryLimit}))
+		}
+		if _, err := resource.ParseQuantity(value); err != nil {
+			return fmt.Errorf("invalid quantity '%s' for forward proxy resource %s: %w", value, key, err)
+		}
+	}
+
+	return nil
+}
+
+// resource returns the quantity for a resource or its default if not set.
+func (fpc *ForwardProxyConfig) resource(key, defaultValue string) string {
+	if value, ok := fpc.Resources[key]; ok && value != "" {
+		return value
+	}
+
+	return defaultValue
+}
+
 // ForwardProxyManifest returns a yaml manifest for the forward proxy operator
 // and a ForwardProxyServer manifest to spin up the envoy forward proxy
 // instance.
 // https://github.com/qleet/forward-proxy-operator
-func ForwardProxyManifest() string {
+func ForwardProxyManifest(config ForwardProxyConfig) string {
 	return fmt.Sprintf(`---
 apiVersion: v1
 kind: Namespace
@@ -606,11 +683,11 @@ spec:
           periodSeconds: 10
         resources:
           limits:
-            cpu: 500m
-            memory: 128Mi
+            cpu: %[4]s
+            memory: %[5]s
           requests:
-            cpu: 10m
-            memory: 64Mi
+            cpu: %[2]s
+            memory: %[3]s
         securityContext:
           allowPrivilegeEscalation: false
           capabilities:
@@ -624,9 +701,15 @@ spec:
 apiVersion: routing.qleet.io/v1alpha1
 kind: ForwardProxyServer
 metadata:
-  name: foward-proxy-main
+  name: %[6]s
 spec:
-  namespace: "forward-proxy-system"
-  replicas: 2
-`, FowardProxyOperatorImage)
+  namespace: "%[7]s"
+  replicas: %[8]d
+`, config.OperatorImage,
+		config.resource(forwardProxyResourceCPURequest, ForwardProxyOperatorCPURequest),
+		config.resource(forwardProxyResourceMemoryRequest, ForwardProxyOperatorMemoryRequest),
+		config.resource(forwardProxyResourceCPULimit, ForwardProxyOperatorCPULimit),
+		config.resource(forwardProxyResourceMemoryLimit, ForwardProxyOperatorMemoryLimit),
+		ForwardProxyServerName, config.Namespace, config.Replicas,
+	)
 }
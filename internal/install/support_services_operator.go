@@ -1,9 +1,11 @@
 package install
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"time"
 
 	qout "github.com/threeport/tptctl/internal/output"
 )
@@ -17,6 +19,8 @@ const (
 	SupportServicesIngressNamespace            = "threeport-ingress"
 	SupportServicesIngressServiceName          = "threeport-ingress-service"
 	SupportServicesDNSManagementServiceAccount = "external-dns"
+	LoadBalancerHostnameTimeout                = time.Minute * 10
+	LoadBalancerHostnamePollInterval           = time.Second * 10
 )
 
 // InstallSupportServicesOperator installs the support services operator into a
@@ -62,9 +66,7 @@ func InstallSupportServicesOperator(
 		"-f",
 		SupportServicesCRDsManifestPath,
 	)
-	supportServicesCRDsCreateOut, err := supportServicesCRDsCreate.CombinedOutput()
-	if err != nil {
-		qout.Error(fmt.Sprintf("kubectl error: %s", supportServicesCRDsCreateOut), nil)
+	if _, err := qout.RunCommand(supportServicesCRDsCreate); err != nil {
 		return loadBalancerURL, fmt.Errorf("failed to create support services custom resource definitions: %w", err)
 	}
 
@@ -77,9 +79,7 @@ func InstallSupportServicesOperator(
 		"-f",
 		SupportServicesOperatorManifestPath,
 	)
-	supportServicesOperatorCreateOut, err := supportServicesOperatorCreate.CombinedOutput()
-	if err != nil {
-		qout.Error(fmt.Sprintf("kubectl error: %s", supportServicesOperatorCreateOut), nil)
+	if _, err := qout.RunCommand(supportServicesOperatorCreate); err != nil {
 		return loadBalancerURL, fmt.Errorf("failed to create support services operator: %w", err)
 	}
 
@@ -92,17 +92,75 @@ func InstallSupportServicesOperator(
 		"-f",
 		SupportServicesComponentsManifestPath,
 	)
-	supportServicesComponentsCreateOut, err := supportServicesComponentsCreate.CombinedOutput()
-	if err != nil {
-		qout.Error(fmt.Sprintf("kubectl error: %s", supportServicesComponentsCreateOut), nil)
+	if _, err := qout.RunCommand(supportServicesComponentsCreate); err != nil {
 		return loadBalancerURL, fmt.Errorf("failed to create support services operator: %w", err)
 	}
 
 	qout.Info("Threeport support services operator created")
 
+	// wait for the cloud provider to assign a hostname to the ingress load
+	// balancer
+	qout.Info("waiting for ingress load balancer to be provisioned...")
+	loadBalancerURL, err = WaitForLoadBalancerHostname(
+		kubeconfig,
+		SupportServicesIngressNamespace,
+		SupportServicesIngressServiceName,
+		LoadBalancerHostnameTimeout,
+	)
+	if err != nil {
+		return loadBalancerURL, fmt.Errorf("failed to get ingress load balancer hostname: %w", err)
+	}
+	qout.Info(fmt.Sprintf("ingress load balancer available at %s", loadBalancerURL))
+
 	return loadBalancerURL, nil
 }
 
+// WaitForLoadBalancerHostname polls a LoadBalancer Service until the cloud
+// provider has assigned it an external hostname and returns that hostname.  An
+// error is returned if no hostname is assigned before the timeout elapses.
+func WaitForLoadBalancerHostname(
+	kubeconfig string,
+	namespace string,
+	serviceName string,
+	timeout time.Duration,
+) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		// the service may not exist yet if the operator has not reconciled the
+		// ingress component so errors here are retried until the timeout
+		getHostname := exec.Command(
+			"kubectl",
+			"--kubeconfig",
+			kubeconfig,
+			"get",
+			"service",
+			"-n",
+			namespace,
+			serviceName,
+			"-o",
+			"jsonpath={.status.loadBalancer.ingress[0].hostname}",
+		)
+		getHostnameOut, err := qout.RunCommand(getHostname)
+		if err == nil && len(getHostnameOut) > 0 {
+			return string(getHostnameOut), nil
+		}
+
+		if time.Now().After(deadline) {
+			if err != nil {
+				return "", fmt.Errorf(
+					"timed out after %s waiting for service %s in namespace %s to get a load balancer hostname: %w",
+					timeout, serviceName, namespace, err,
+				)
+			}
+			return "", errors.New(fmt.Sprintf(
+				"timed out after %s waiting for service %s in namespace %s to get a load balancer hostname",
+				timeout, serviceName, namespace,
+			))
+		}
+		time.Sleep(LoadBalancerHostnamePollInterval)
+	}
+}
+
 // UninstallIngressComponent removes the support services ingress component.
 // This must be done before deleting cluster infra so the load balancer for the
 // ingress layer is deleted.
@@ -115,9 +173,7 @@ func UninstallIngressComponent(kubeconfig string) error {
 		"ingresscomponent",
 		SupportServicesIngressComponentName,
 	)
-	supportServicesIngressComponentDeleteOut, err := supportServicesIngressComponentDelete.CombinedOutput()
-	if err != nil {
-		qout.Error(fmt.Sprintf("kubectl error: %s", supportServicesIngressComponentDeleteOut), nil)
+	if _, err := qout.RunCommand(supportServicesIngressComponentDelete); err != nil {
 		return fmt.Errorf("failed to delete support services ingress component: %w", err)
 	}
 
@@ -19,7 +19,7 @@ func InstallWorkloadController(kubeconfig string) error {
 	// write workload controller manifest to /tmp directory
 	workloadControllerManifest, err := os.Create(WorkloadControllerManifestPath)
 	if err != nil {
-		return fmt.Errorf("failed to write workload controller manifest to disk", err)
+		return fmt.Errorf("failed to write workload controller manifest to disk: %w", err)
 		//qout.Error("failed to write workload controller manifest to disk", err)
 		//os.Exit(1)
 	}
@@ -37,10 +37,8 @@ func InstallWorkloadController(kubeconfig string) error {
 		"-f",
 		WorkloadControllerManifestPath,
 	)
-	workloadControllerCreateOut, err := workloadControllerCreate.CombinedOutput()
-	if err != nil {
-		qout.Error(fmt.Sprintf("kubectl error: %s", workloadControllerCreateOut), nil)
-		return fmt.Errorf("failed to create workload controller", err)
+	if _, err := qout.RunCommand(workloadControllerCreate); err != nil {
+		return fmt.Errorf("failed to create workload controller: %w", err)
 	}
 
 	qout.Info("Threeport workload controller created")
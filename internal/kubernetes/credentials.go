@@ -0,0 +1,200 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"k8s.io/client-go/discovery"
+	k8sclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	kubeclient "k8s.io/client-go/tools/clientcmd"
+	kubeapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// ClusterCredentials contains the endpoint and credentials used to connect to
+// a Kubernetes API.  Either a client certificate and key or a bearer token is
+// set.
+type ClusterCredentials struct {
+	APIEndpoint   string
+	CACertificate string
+	Certificate   string
+	Key           string
+	Token         string
+
+	// ExecPlugin is the command of the exec plugin the credentials were
+	// obtained from, if any.  Such credentials are short-lived, e.g. the
+	// tokens from aws eks get-token expire after 15 minutes, so are only
+	// suitable for immediate use.
+	ExecPlugin string
+}
+
+// execCredential is the subset of the client.authentication.k8s.io
+// ExecCredential object returned by exec credential plugins that is used.
+type execCredential struct {
+	Status struct {
+		Token                 string `json:"token"`
+		ClientCertificateData string `json:"clientCertificateData"`
+		ClientKeyData         string `json:"clientKeyData"`
+	} `json:"status"`
+}
+
+// GetClusterCredentials extracts the endpoint and credentials for a Kubernetes
+// cluster from a kubeconfig.  If kubeconfigPath is empty the default kubeconfig
+// loading rules are used.  If contextName is empty the current context is used.
+// Client certificate, token and exec plugin authentication are supported.  The
+// credentials from an exec plugin are short-lived and have ExecPlugin set.
+func GetClusterCredentials(kubeconfigPath, contextName string) (*ClusterCredentials, error) {
+	// load kubeconfig
+	var kubeConfig *kubeapi.Config
+	var err error
+	if kubeconfigPath == "" {
+		kubeConfig, err = kubeclient.NewDefaultClientConfigLoadingRules().Load()
+	} else {
+		kubeConfig, err = kubeclient.LoadFromFile(kubeconfigPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+
+	// find the context
+	if contextName == "" {
+		contextName = kubeConfig.CurrentContext
+	}
+	kubeContext, found := kubeConfig.Contexts[contextName]
+	if !found {
+		return nil, errors.New(fmt.Sprintf("context %s not found in kubeconfig", contextName))
+	}
+
+	// get cluster CA and server endpoint
+	cluster, found := kubeConfig.Clusters[kubeContext.Cluster]
+	if !found {
+		return nil, errors.New(fmt.Sprintf("cluster %s not found in kubeconfig", kubeContext.Cluster))
+	}
+	caCert, err := dataOrFile(cluster.CertificateAuthorityData, cluster.CertificateAuthority)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster CA certificate: %w", err)
+	}
+	credentials := ClusterCredentials{
+		APIEndpoint:   cluster.Server,
+		CACertificate: caCert,
+	}
+
+	// get user credentials
+	user, found := kubeConfig.AuthInfos[kubeContext.AuthInfo]
+	if !found {
+		return nil, errors.New(fmt.Sprintf("user %s not found in kubeconfig", kubeContext.AuthInfo))
+	}
+	switch {
+	case len(user.ClientCertificateData) > 0 || user.ClientCertificate != "":
+		if credentials.Certificate, err = dataOrFile(user.ClientCertificateData, user.ClientCertificate); err != nil {
+			return nil, fmt.Errorf("failed to read client certificate: %w", err)
+		}
+		if credentials.Key, err = dataOrFile(user.ClientKeyData, user.ClientKey); err != nil {
+			return nil, fmt.Errorf("failed to read client key: %w", err)
+		}
+	case user.Token != "" || user.TokenFile != "":
+		if credentials.Token, err = dataOrFile([]byte(user.Token), user.TokenFile); err != nil {
+			return nil, fmt.Errorf("failed to read token: %w", err)
+		}
+	case user.Exec != nil:
+		execCred, err := runExecPlugin(user.Exec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get credentials from exec plugin %s: %w", user.Exec.Command, err)
+		}
+		credentials.Token = execCred.Status.Token
+		credentials.Certificate = execCred.Status.ClientCertificateData
+		credentials.Key = execCred.Status.ClientKeyData
+		credentials.ExecPlugin = user.Exec.Command
+	default:
+		return nil, errors.New(fmt.Sprintf(
+			"user %s in kubeconfig has no supported credentials - must use a client certificate, token or exec plugin",
+			kubeContext.AuthInfo))
+	}
+
+	return &credentials, nil
+}
+
+// CheckConnectivity connects to the Kubernetes API using the credentials and
+// returns the version of Kubernetes running.
+func CheckConnectivity(credentials *ClusterCredentials) (string, error) {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(credentials.restConfig())
+	if err != nil {
+		return "", fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+	version, err := discoveryClient.ServerVersion()
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to Kubernetes API at %s: %w", credentials.APIEndpoint, err)
+	}
+
+	return version.GitVersion, nil
+}
+
+// restConfig returns the client config for connecting to the Kubernetes API
+// with the credentials.
+func (cc *ClusterCredentials) restConfig() *rest.Config {
+	return &rest.Config{
+		Host:        cc.APIEndpoint,
+		BearerToken: cc.Token,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData:   []byte(cc.CACertificate),
+			CertData: []byte(cc.Certificate),
+			KeyData:  []byte(cc.Key),
+		},
+	}
+}
+
+// clientset returns a client for the Kubernetes API using the credentials.
+func (cc *ClusterCredentials) clientset() (*k8sclient.Clientset, error) {
+	clientset, err := k8sclient.NewForConfig(cc.restConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	return clientset, nil
+}
+
+// dataOrFile returns the data if set, otherwise the content of the file at
+// path.
+func dataOrFile(data []byte, path string) (string, error) {
+	if len(data) > 0 || path == "" {
+		return string(data), nil
+	}
+	fileContent, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return string(fileContent), nil
+}
+
+// runExecPlugin runs a kubeconfig exec credential plugin and returns the
+// credential it provides.
+func runExecPlugin(execConfig *kubeapi.ExecConfig) (*execCredential, error) {
+	execInfo := fmt.Sprintf(
+		`{"apiVersion":"%s","kind":"ExecCredential","spec":{"interactive":false}}`,
+		execConfig.APIVersion,
+	)
+	pluginCmd := exec.Command(execConfig.Command, execConfig.Args...)
+	pluginCmd.Env = append(os.Environ(), fmt.Sprintf("KUBERNETES_EXEC_INFO=%s", execInfo))
+	for _, env := range execConfig.Env {
+		pluginCmd.Env = append(pluginCmd.Env, fmt.Sprintf("%s=%s", env.Name, env.Value))
+	}
+	pluginOut, err := pluginCmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var execCred execCredential
+	if err := json.Unmarshal(pluginOut, &execCred); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal exec credential: %w", err)
+	}
+	if execCred.Status.Token == "" && execCred.Status.ClientCertificateData == "" {
+		return nil, errors.New("exec plugin returned no token or client certificate")
+	}
+
+	return &execCred, nil
+}
@@ -0,0 +1,58 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// forwardProxyResource is the resource for the ForwardProxy objects the forward
+// proxy operator routes upstreams for.
+var forwardProxyResource = schema.GroupVersionResource{
+	Group:    "routing.qleet.io",
+	Version:  "v1alpha1",
+	Resource: "forwardproxies",
+}
+
+// ForwardProxyUpstream is an upstream routed through the forward proxy on a
+// cluster.
+type ForwardProxyUpstream struct {
+	Namespace    string
+	Name         string
+	UpstreamHost string
+	UpstreamPath string
+}
+
+// GetForwardProxyUpstreams returns the upstreams routed through the forward
+// proxy on the cluster the credentials are for.
+func GetForwardProxyUpstreams(
+	ctx context.Context,
+	credentials *ClusterCredentials,
+) ([]ForwardProxyUpstream, error) {
+	dynamicClient, err := dynamic.NewForConfig(credentials.restConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+	forwardProxies, err := dynamicClient.Resource(forwardProxyResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list forward proxies: %w", err)
+	}
+
+	upstreams := make([]ForwardProxyUpstream, 0, len(forwardProxies.Items))
+	for _, forwardProxy := range forwardProxies.Items {
+		upstreamHost, _, _ := unstructured.NestedString(forwardProxy.Object, "spec", "upstreamHost")
+		upstreamPath, _, _ := unstructured.NestedString(forwardProxy.Object, "spec", "upstreamPath")
+		upstreams = append(upstreams, ForwardProxyUpstream{
+			Namespace:    forwardProxy.GetNamespace(),
+			Name:         forwardProxy.GetName(),
+			UpstreamHost: upstreamHost,
+			UpstreamPath: upstreamPath,
+		})
+	}
+
+	return upstreams, nil
+}
@@ -1,3 +1,9 @@
 package kubernetes
 
+// KubernetesVersion is the default version of Kubernetes used for threeport
+// control plane clusters.
 const KubernetesVersion = "1.24"
+
+// SupportedKubernetesVersions are the versions of Kubernetes that can be used
+// for threeport control plane clusters.
+var SupportedKubernetesVersions = []string{"1.22", "1.23", "1.24"}
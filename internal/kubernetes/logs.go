@@ -0,0 +1,279 @@
+package kubernetes
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sclient "k8s.io/client-go/kubernetes"
+)
+
+// podSelector selects the pods that belong to a workload resource.
+type podSelector struct {
+	namespace string
+	selector  *metav1.LabelSelector
+	podName   string
+}
+
+// workloadPodSelectors returns the selectors for the pods that belong to the
+// workload resources in a manifest.
+func workloadPodSelectors(objects []runtime.Object) []podSelector {
+	var selectors []podSelector
+	for _, object := range objects {
+		switch obj := object.(type) {
+		case *appsv1.Deployment:
+			selectors = append(selectors, podSelector{namespace: namespaceOrDefault(obj.Namespace), selector: obj.Spec.Selector})
+		case *appsv1.StatefulSet:
+			selectors = append(selectors, podSelector{namespace: namespaceOrDefault(obj.Namespace), selector: obj.Spec.Selector})
+		case *appsv1.DaemonSet:
+			selectors = append(selectors, podSelector{namespace: namespaceOrDefault(obj.Namespace), selector: obj.Spec.Selector})
+		case *appsv1.ReplicaSet:
+			selectors = append(selectors, podSelector{namespace: namespaceOrDefault(obj.Namespace), selector: obj.Spec.Selector})
+		case *batchv1.Job:
+			// job selectors are usually generated so select on the label the
+			// job controller adds to its pods
+			selectors = append(selectors, podSelector{
+				namespace: namespaceOrDefault(obj.Namespace),
+				selector:  &metav1.LabelSelector{MatchLabels: map[string]string{"job-name": obj.Name}},
+			})
+		case *corev1.Pod:
+			selectors = append(selectors, podSelector{namespace: namespaceOrDefault(obj.Namespace), podName: obj.Name})
+		}
+	}
+
+	return selectors
+}
+
+// getWorkloadPods returns the pods that belong to the workload resources in a
+// manifest sorted by namespace and name.
+func getWorkloadPods(ctx context.Context, clientset k8sclient.Interface, objects []runtime.Object) ([]corev1.Pod, error) {
+	found := make(map[string]corev1.Pod)
+	for _, ps := range workloadPodSelectors(objects) {
+		if ps.podName != "" {
+			pod, err := clientset.CoreV1().Pods(ps.namespace).Get(ctx, ps.podName, metav1.GetOptions{})
+			if err != nil {
+				if _, notFoundErr := notFoundStatus("pod", ps.namespace, ps.podName, err); notFoundErr != nil {
+					return nil, notFoundErr
+				}
+				continue
+			}
+			found[ps.namespace+"/"+pod.Name] = *pod
+			continue
+		}
+		if ps.selector == nil {
+			continue
+		}
+		labelSelector, err := metav1.LabelSelectorAsSelector(ps.selector)
+		if err != nil {
+			continue
+		}
+		pods, err := clientset.CoreV1().Pods(ps.namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: labelSelector.String(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods in namespace %s: %w", ps.namespace, err)
+		}
+		for _, pod := range pods.Items {
+			found[ps.namespace+"/"+pod.Name] = pod
+		}
+	}
+
+	keys := make([]string, 0, len(found))
+	for key := range found {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pods := make([]corev1.Pod, 0, len(keys))
+	for _, key := range keys {
+		pods = append(pods, found[key])
+	}
+
+	return pods, nil
+}
+
+// StreamWorkloadLogs writes the logs of the pods that belong to the workload
+// resources in a manifest to out.  Lines from all pods and containers are
+// multiplexed with a [pod/container] prefix.  If container is set, only the
+// logs for containers with that name are included.  If follow is set, logs
+// are streamed until the context is cancelled.
+func StreamWorkloadLogs(
+	ctx context.Context,
+	credentials *ClusterCredentials,
+	manifest string,
+	container string,
+	follow bool,
+	out io.Writer,
+) error {
+	clientset, err := credentials.clientset()
+	if err != nil {
+		return err
+	}
+	pods, err := getWorkloadPods(ctx, clientset, decodeManifest(manifest))
+	if err != nil {
+		return err
+	}
+	if len(pods) == 0 {
+		return errors.New("no pods found for the workload")
+	}
+
+	var wg sync.WaitGroup
+	var outMutex, errMutex sync.Mutex
+	var failures []string
+	streams := 0
+	for _, pod := range pods {
+		for _, podContainer := range pod.Spec.Containers {
+			if container != "" && podContainer.Name != container {
+				continue
+			}
+			streams++
+			wg.Add(1)
+			go func(pod corev1.Pod, containerName string) {
+				defer wg.Done()
+				prefix := fmt.Sprintf("[%s/%s] ", pod.Name, containerName)
+				if err := streamContainerLogs(ctx, clientset, pod, containerName, follow, prefix, out, &outMutex); err != nil {
+					errMutex.Lock()
+					failures = append(failures, fmt.Sprintf("%s%s", prefix, err))
+					errMutex.Unlock()
+				}
+			}(pod, podContainer.Name)
+		}
+	}
+	if streams == 0 {
+		return errors.New(fmt.Sprintf("no containers named %s found in the workload pods", container))
+	}
+	wg.Wait()
+
+	if len(failures) > 0 && ctx.Err() == nil {
+		return errors.New(fmt.Sprintf("failed to get logs:\n%s", strings.Join(failures, "\n")))
+	}
+
+	return nil
+}
+
+// streamContainerLogs writes the logs of a single container to out with each
+// line prefixed.
+func streamContainerLogs(
+	ctx context.Context,
+	clientset k8sclient.Interface,
+	pod corev1.Pod,
+	container string,
+	follow bool,
+	prefix string,
+	out io.Writer,
+	outMutex *sync.Mutex,
+) error {
+	logStream, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container: container,
+		Follow:    follow,
+	}).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer logStream.Close()
+
+	scanner := bufio.NewScanner(logStream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		outMutex.Lock()
+		fmt.Fprintf(out, "%s%s\n", prefix, scanner.Text())
+		outMutex.Unlock()
+	}
+
+	return scanner.Err()
+}
+
+// GetWorkloadEvents returns the events for the resources in a manifest, the
+// pods that belong to them and the replica sets of any deployments, ordered by
+// when they last occurred.
+func GetWorkloadEvents(
+	ctx context.Context,
+	credentials *ClusterCredentials,
+	manifest string,
+) ([]corev1.Event, error) {
+	clientset, err := credentials.clientset()
+	if err != nil {
+		return nil, err
+	}
+
+	return workloadEvents(ctx, clientset, decodeManifest(manifest))
+}
+
+// workloadEvents returns the events for objects, the pods that belong to them
+// and their owners, ordered by when they last occurred.
+func workloadEvents(ctx context.Context, clientset k8sclient.Interface, objects []runtime.Object) ([]corev1.Event, error) {
+
+	// collect the objects whose events are included
+	involved := make(map[string]bool)
+	namespaces := make(map[string]bool)
+	for _, object := range objects {
+		objectMeta, ok := object.(metav1.Object)
+		if !ok {
+			continue
+		}
+		kind := object.GetObjectKind().GroupVersionKind().Kind
+		namespace := namespaceOrDefault(objectMeta.GetNamespace())
+		involved[involvedKey(kind, namespace, objectMeta.GetName())] = true
+		namespaces[namespace] = true
+	}
+	pods, err := getWorkloadPods(ctx, clientset, objects)
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods {
+		involved[involvedKey("Pod", pod.Namespace, pod.Name)] = true
+		for _, owner := range pod.OwnerReferences {
+			// includes the replica sets created by deployments
+			involved[involvedKey(owner.Kind, pod.Namespace, owner.Name)] = true
+		}
+	}
+
+	var events []corev1.Event
+	for namespace := range namespaces {
+		eventList, err := clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list events in namespace %s: %w", namespace, err)
+		}
+		for _, event := range eventList.Items {
+			ref := event.InvolvedObject
+			if involved[involvedKey(ref.Kind, namespaceOrDefault(ref.Namespace), ref.Name)] {
+				events = append(events, event)
+			}
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return EventTime(&events[i]).Before(EventTime(&events[j]))
+	})
+
+	return events, nil
+}
+
+// EventTime returns the time an event last occurred.
+func EventTime(event *corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case event.Series != nil:
+		return event.Series.LastObservedTime.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.FirstTimestamp.Time
+	}
+}
+
+// involvedKey returns the key used to match an object to the objects events
+// are for.
+func involvedKey(kind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
@@ -0,0 +1,162 @@
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testWorkloadManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.25
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: web
+spec:
+  selector:
+    app: web
+  ports:
+    - port: 80
+`
+
+func TestWorkloadPodSelectors(t *testing.T) {
+	webSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	objects := []runtime.Object{
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "web"}, Spec: appsv1.DeploymentSpec{Selector: webSelector}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db"}, Spec: appsv1.StatefulSetSpec{Selector: webSelector}},
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "system"}, Spec: appsv1.DaemonSetSpec{Selector: webSelector}},
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "web"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "web"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "web"}},
+	}
+	expected := []podSelector{
+		{namespace: "web", selector: webSelector},
+		{namespace: "default", selector: webSelector},
+		{namespace: "system", selector: webSelector},
+		{namespace: "web", selector: &metav1.LabelSelector{MatchLabels: map[string]string{"job-name": "migrate"}}},
+		{namespace: "web", podName: "debug"},
+	}
+
+	if selectors := workloadPodSelectors(objects); !reflect.DeepEqual(selectors, expected) {
+		t.Errorf("expected selectors %+v, got %+v", expected, selectors)
+	}
+}
+
+func TestWorkloadEvents(t *testing.T) {
+	base := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	event := func(name, kind, namespace, objectName string, lastSeen time.Duration) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: namespace},
+			InvolvedObject: corev1.ObjectReference{Kind: kind, Namespace: namespace, Name: objectName},
+			LastTimestamp:  metav1.NewTime(base.Add(lastSeen)),
+		}
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "web-6d4b-abcde",
+			Namespace:       "web",
+			Labels:          map[string]string{"app": "web"},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-6d4b"}},
+		},
+	}
+	otherPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-1", Namespace: "web", Labels: map[string]string{"app": "api"}},
+	}
+	clientset := fake.NewSimpleClientset(
+		pod,
+		otherPod,
+		event("pod-pulled", "Pod", "web", "web-6d4b-abcde", time.Minute*3),
+		event("deployment-scaled", "Deployment", "web", "web", time.Minute),
+		event("replicaset-created", "ReplicaSet", "web", "web-6d4b", time.Minute*2),
+		event("service-synced", "Service", "web", "web", time.Minute*4),
+		event("other-pod-pulled", "Pod", "web", "api-1", 0),
+		event("other-namespace", "Deployment", "other", "web", 0),
+	)
+
+	events, err := workloadEvents(context.Background(), clientset, decodeManifest(testWorkloadManifest))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var names []string
+	for _, e := range events {
+		names = append(names, e.Name)
+	}
+	expected := []string{"deployment-scaled", "replicaset-created", "pod-pulled", "service-synced"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected events %v, got %v", expected, names)
+	}
+}
+
+func TestEventTime(t *testing.T) {
+	first := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	last := first.Add(time.Minute)
+	observed := first.Add(time.Minute * 2)
+	eventTime := first.Add(time.Minute * 3)
+
+	testCases := []struct {
+		name     string
+		event    corev1.Event
+		expected time.Time
+	}{
+		{
+			name: "last timestamp",
+			event: corev1.Event{
+				FirstTimestamp: metav1.NewTime(first),
+				LastTimestamp:  metav1.NewTime(last),
+				EventTime:      metav1.NewMicroTime(eventTime),
+			},
+			expected: last,
+		},
+		{
+			name: "series",
+			event: corev1.Event{
+				EventTime: metav1.NewMicroTime(eventTime),
+				Series:    &corev1.EventSeries{LastObservedTime: metav1.NewMicroTime(observed)},
+			},
+			expected: observed,
+		},
+		{
+			name:     "event time",
+			event:    corev1.Event{EventTime: metav1.NewMicroTime(eventTime)},
+			expected: eventTime,
+		},
+		{
+			name:     "first timestamp",
+			event:    corev1.Event{FirstTimestamp: metav1.NewTime(first)},
+			expected: first,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if eventTime := EventTime(&tc.event); !eventTime.Equal(tc.expected) {
+				t.Errorf("expected %s, got %s", tc.expected, eventTime)
+			}
+		})
+	}
+}
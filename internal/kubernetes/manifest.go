@@ -0,0 +1,524 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	openapi_v2 "github.com/google/gnostic/openapiv2"
+	yamlv3 "gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kube-openapi/pkg/util/proto"
+	"k8s.io/kube-openapi/pkg/util/proto/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// SourceMarker prefixes the comment line that precedes the content of each
+// file when a manifest is assembled from multiple files.  Issues found after a
+// marker are reported against the file it names.
+const SourceMarker = "# tptctl.threeport.io/source: "
+
+// Severity is the severity of an issue found in a manifest.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// clusterScopedKinds are the built-in kinds that are not namespaced.  It is
+// used to determine scope when no cluster is available for discovery.
+var clusterScopedKinds = map[schema.GroupKind]bool{
+	{Group: "", Kind: "Namespace"}:                                                  true,
+	{Group: "", Kind: "Node"}:                                                       true,
+	{Group: "", Kind: "PersistentVolume"}:                                           true,
+	{Group: "", Kind: "ComponentStatus"}:                                            true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}:                       true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}:                true,
+	{Group: "storage.k8s.io", Kind: "StorageClass"}:                                 true,
+	{Group: "storage.k8s.io", Kind: "CSIDriver"}:                                    true,
+	{Group: "storage.k8s.io", Kind: "CSINode"}:                                      true,
+	{Group: "storage.k8s.io", Kind: "VolumeAttachment"}:                             true,
+	{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}:               true,
+	{Group: "apiregistration.k8s.io", Kind: "APIService"}:                           true,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingWebhookConfiguration"}: true,
+	{Group: "admissionregistration.k8s.io", Kind: "MutatingWebhookConfiguration"}:   true,
+	{Group: "scheduling.k8s.io", Kind: "PriorityClass"}:                             true,
+	{Group: "networking.k8s.io", Kind: "IngressClass"}:                              true,
+	{Group: "node.k8s.io", Kind: "RuntimeClass"}:                                    true,
+	{Group: "policy", Kind: "PodSecurityPolicy"}:                                    true,
+	{Group: "certificates.k8s.io", Kind: "CertificateSigningRequest"}:               true,
+	{Group: "flowcontrol.apiserver.k8s.io", Kind: "FlowSchema"}:                     true,
+	{Group: "flowcontrol.apiserver.k8s.io", Kind: "PriorityLevelConfiguration"}:     true,
+}
+
+var (
+	// quotedFieldRegex matches the field path in strict decoding errors, e.g.
+	// unknown field "spec.replicaz"
+	quotedFieldRegex = regexp.MustCompile(`field "([^"]+)"`)
+	// structFieldRegex matches the field path in type errors, e.g. Go struct
+	// field DeploymentSpec.spec.replicas of type int32
+	structFieldRegex = regexp.MustCompile(`Go struct field [^.\s]+\.(\S+) of type`)
+	// yamlLineRegex matches the line number in YAML syntax errors.
+	yamlLineRegex = regexp.MustCompile(`line (\d+)`)
+)
+
+// ManifestIssue is a problem found in a Kubernetes manifest.  Line is the line
+// in the source where the object or field with the issue is defined.
+type ManifestIssue struct {
+	Source   string
+	Line     int
+	Severity Severity
+	Message  string
+}
+
+// String returns the issue in the form source:line: severity: message.
+func (mi *ManifestIssue) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", mi.Source, mi.Line, mi.Severity, mi.Message)
+}
+
+// ManifestValidator validates multi-document Kubernetes manifests.  When a
+// cluster is available, kinds and scopes are checked using API discovery and
+// objects are validated against the cluster's OpenAPI schemas, which include
+// those for built-in kinds and custom resources.  Without a cluster, or for
+// built-in kinds missing from the cluster's schemas, built-in kinds are checked
+// by strictly decoding them into the Kubernetes API types.  Decoding catches
+// unknown fields and values of the wrong type but not missing required fields,
+// which are only caught when validating against a cluster.
+type ManifestValidator struct {
+	namespaced map[schema.GroupVersionKind]bool
+	models     proto.Models
+}
+
+// schemaDiscovery is the part of the Kubernetes discovery client used to get
+// the kinds and OpenAPI schemas served by a cluster.
+type schemaDiscovery interface {
+	ServerGroupsAndResources() ([]*metav1.APIGroup, []*metav1.APIResourceList, error)
+	OpenAPISchema() (*openapi_v2.Document, error)
+}
+
+// NewManifestValidator returns a validator for manifests.  If credentials is
+// nil, validation is done offline against built-in kinds only.
+func NewManifestValidator(credentials *ClusterCredentials) (*ManifestValidator, error) {
+	if credentials == nil {
+		return &ManifestValidator{}, nil
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(credentials.restConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	return newManifestValidator(discoveryClient, credentials.APIEndpoint)
+}
+
+// newManifestValidator returns a validator that uses the kinds and OpenAPI
+// schemas served by the cluster at apiEndpoint.
+func newManifestValidator(discoveryClient schemaDiscovery, apiEndpoint string) (*ManifestValidator, error) {
+	var mv ManifestValidator
+
+	// get the kinds served by the cluster and their scope - an unavailable
+	// API group, e.g. a broken aggregated API, shouldn't prevent validation
+	_, resourceLists, err := discoveryClient.ServerGroupsAndResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("failed to discover API resources at %s: %w", apiEndpoint, err)
+	}
+	mv.namespaced = make(map[schema.GroupVersionKind]bool)
+	for _, resourceList := range resourceLists {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range resourceList.APIResources {
+			// skip subresources such as deployments/scale
+			if strings.Contains(resource.Name, "/") {
+				continue
+			}
+			mv.namespaced[gv.WithKind(resource.Kind)] = resource.Namespaced
+		}
+	}
+
+	// get the OpenAPI schemas
+	openAPIDoc, err := discoveryClient.OpenAPISchema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OpenAPI schema from %s: %w", apiEndpoint, err)
+	}
+	if mv.models, err = proto.NewOpenAPIData(openAPIDoc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI schema: %w", err)
+	}
+
+	return &mv, nil
+}
+
+// manifestObject is an object found in a manifest along with what is needed
+// to report issues against it.
+type manifestObject struct {
+	line      int
+	node      *yamlv3.Node
+	content   map[string]interface{}
+	gvk       schema.GroupVersionKind
+	name      string
+	namespace string
+}
+
+// Validate validates a multi-document YAML manifest.  The source is the file
+// or other origin of the manifest and is used when reporting issues.  Issues
+// are returned in the order they are found.
+func (mv *ManifestValidator) Validate(source, manifest string) []ManifestIssue {
+	var issues []ManifestIssue
+	markers := sourceMarkers(manifest)
+	locate := func(line int) (string, int) {
+		for i := len(markers) - 1; i >= 0; i-- {
+			if markers[i].line < line {
+				return markers[i].source, line - markers[i].line
+			}
+		}
+		return source, line
+	}
+	addIssue := func(line int, severity Severity, format string, a ...interface{}) {
+		issueSource, issueLine := locate(line)
+		issues = append(issues, ManifestIssue{
+			Source:   issueSource,
+			Line:     issueLine,
+			Severity: severity,
+			Message:  fmt.Sprintf(format, a...),
+		})
+	}
+
+	// parse each document
+	var objects []manifestObject
+	for _, doc := range splitManifest(manifest) {
+		var node yamlv3.Node
+		if err := yamlv3.Unmarshal([]byte(doc.content), &node); err != nil {
+			addIssue(doc.line+yamlErrorLine(err)-1, SeverityError, "invalid YAML: %s", err)
+			continue
+		}
+		objectJSON, err := yaml.YAMLToJSON([]byte(doc.content))
+		if err != nil {
+			addIssue(doc.line, SeverityError, "invalid YAML: %s", err)
+			continue
+		}
+		var content map[string]interface{}
+		if err := json.Unmarshal(objectJSON, &content); err != nil {
+			addIssue(doc.line, SeverityError, "document is not a Kubernetes object")
+			continue
+		}
+		if content == nil {
+			continue
+		}
+
+		object := manifestObject{
+			line:    doc.line + documentStartLine(&node) - 1,
+			node:    &node,
+			content: content,
+		}
+		apiVersion, _ := content["apiVersion"].(string)
+		kind, _ := content["kind"].(string)
+		if apiVersion == "" || kind == "" {
+			addIssue(object.line, SeverityError, "object is missing apiVersion or kind")
+			continue
+		}
+		object.gvk = schema.FromAPIVersionAndKind(apiVersion, kind)
+		if metadata, ok := content["metadata"].(map[string]interface{}); ok {
+			object.name, _ = metadata["name"].(string)
+			object.namespace, _ = metadata["namespace"].(string)
+			if object.name == "" {
+				object.name, _ = metadata["generateName"].(string)
+			}
+		}
+		if object.name == "" {
+			addIssue(object.line, SeverityError, "%s object is missing metadata.name", kind)
+		}
+		objects = append(objects, object)
+	}
+
+	// custom resources defined in the same manifest are valid kinds
+	customKinds := make(map[schema.GroupKind]bool)
+	for _, object := range objects {
+		if object.gvk.GroupKind() != (schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}) {
+			continue
+		}
+		spec, _ := object.content["spec"].(map[string]interface{})
+		names, _ := spec["names"].(map[string]interface{})
+		group, _ := spec["group"].(string)
+		kind, _ := names["kind"].(string)
+		scope, _ := spec["scope"].(string)
+		customKinds[schema.GroupKind{Group: group, Kind: kind}] = scope != "Cluster"
+	}
+
+	seen := make(map[string]int)
+	for _, object := range objects {
+		objectRef := fmt.Sprintf("%s %s", object.gvk.Kind, object.name)
+		if object.namespace != "" {
+			objectRef = fmt.Sprintf("%s %s/%s", object.gvk.Kind, object.namespace, object.name)
+		}
+
+		// check the kind and its schema
+		var namespaced, knownScope bool
+		if model := mv.lookupModel(object.gvk); model != nil {
+			for _, err := range validation.ValidateModel(object.content, model, object.gvk.Kind) {
+				addIssue(object.line+fieldLine(object.node, err), SeverityError, "%s: %s", objectRef, err)
+			}
+		} else if scheme.Scheme.Recognizes(object.gvk) {
+			for _, err := range strictDecode(object.content) {
+				addIssue(object.line+fieldLine(object.node, err), SeverityError, "%s: %s", objectRef, err)
+			}
+		}
+		if scheme.Scheme.Recognizes(object.gvk) {
+			namespaced, knownScope = !clusterScopedKinds[object.gvk.GroupKind()], true
+		}
+		if clusterNamespaced, found := mv.namespaced[object.gvk]; found {
+			namespaced, knownScope = clusterNamespaced, true
+		} else if crdNamespaced, found := customKinds[object.gvk.GroupKind()]; found {
+			namespaced, knownScope = crdNamespaced, true
+		} else if !knownScope {
+			if mv.namespaced != nil {
+				addIssue(object.line, SeverityError,
+					"%s: kind %s is not served by the cluster", objectRef, object.gvk)
+			} else {
+				addIssue(object.line, SeverityWarning,
+					"%s: kind %s is not a built-in kind and could not be checked without a cluster", objectRef, object.gvk)
+			}
+		}
+
+		// check the scope
+		if knownScope {
+			switch {
+			case !namespaced:
+				addIssue(object.line, SeverityWarning,
+					"%s is cluster-scoped and will affect the whole workload cluster", objectRef)
+			case object.namespace == "":
+				addIssue(object.line, SeverityWarning,
+					"%s has no namespace and will be created in the default namespace", objectRef)
+			}
+		}
+
+		// check for duplicates
+		key := fmt.Sprintf("%s/%s/%s/%s", object.gvk.Group, object.gvk.Kind, object.namespace, object.name)
+		if firstLine, found := seen[key]; found {
+			firstSource, firstSourceLine := locate(firstLine)
+			addIssue(object.line, SeverityError, "%s is a duplicate of the object at %s:%d",
+				objectRef, firstSource, firstSourceLine)
+			continue
+		}
+		seen[key] = object.line
+	}
+
+	return issues
+}
+
+// lookupModel returns the OpenAPI schema for a kind or nil if the cluster
+// schemas are not available or don't include the kind.
+func (mv *ManifestValidator) lookupModel(gvk schema.GroupVersionKind) proto.Schema {
+	if mv.models == nil {
+		return nil
+	}
+	for _, modelName := range mv.models.ListModels() {
+		model := mv.models.LookupModel(modelName)
+		gvkExtensions, ok := model.GetExtensions()["x-kubernetes-group-version-kind"].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, gvkExtension := range gvkExtensions {
+			ext, ok := gvkExtension.(map[interface{}]interface{})
+			if !ok {
+				continue
+			}
+			if fmt.Sprint(ext["group"]) == gvk.Group &&
+				fmt.Sprint(ext["version"]) == gvk.Version &&
+				fmt.Sprint(ext["kind"]) == gvk.Kind {
+				return model
+			}
+		}
+	}
+
+	return nil
+}
+
+// HasErrors returns true if any of the issues is an error.
+func HasErrors(issues []ManifestIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// strictDecode decodes an object into its built-in Kubernetes type and returns
+// any unknown fields, duplicate fields or invalid values.
+func strictDecode(content map[string]interface{}) []error {
+	objectJSON, err := json.Marshal(content)
+	if err != nil {
+		return []error{err}
+	}
+	decoder := serializer.NewCodecFactory(scheme.Scheme, serializer.EnableStrict).UniversalDeserializer()
+	if _, _, err := decoder.Decode(objectJSON, nil, nil); err != nil {
+		if strictErr, ok := runtime.AsStrictDecodingError(err); ok {
+			return strictErr.Errors()
+		}
+		return []error{err}
+	}
+
+	return nil
+}
+
+// SplitManifest splits a multi-document YAML into its documents.  Empty
+// documents are dropped.
+func SplitManifest(manifest string) []string {
+	var docs []string
+	for _, doc := range splitManifest(manifest) {
+		docs = append(docs, doc.content)
+	}
+
+	return docs
+}
+
+// manifestDocument is a single document in a multi-document YAML and the line
+// in the manifest where it starts.
+type manifestDocument struct {
+	line    int
+	content string
+}
+
+// splitManifest splits a multi-document YAML into its documents, keeping
+// track of the line each one starts on.  Empty documents are dropped.
+func splitManifest(manifest string) []manifestDocument {
+	var docs []manifestDocument
+	var current []string
+	start := 1
+	flush := func() {
+		content := strings.Join(current, "\n")
+		if strings.TrimSpace(content) != "" {
+			docs = append(docs, manifestDocument{line: start, content: content})
+		}
+	}
+	for i, line := range strings.Split(manifest, "\n") {
+		if strings.HasPrefix(line, "---") {
+			flush()
+			current = nil
+			start = i + 2
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+
+	return docs
+}
+
+// sourceMarker is a source marker in a manifest and the line it is on.
+type sourceMarker struct {
+	line   int
+	source string
+}
+
+// sourceMarkers returns the source markers in a manifest in line order.
+func sourceMarkers(manifest string) []sourceMarker {
+	var markers []sourceMarker
+	for i, line := range strings.Split(manifest, "\n") {
+		if strings.HasPrefix(line, SourceMarker) {
+			markers = append(markers, sourceMarker{
+				line:   i + 1,
+				source: strings.TrimSpace(strings.TrimPrefix(line, SourceMarker)),
+			})
+		}
+	}
+
+	return markers
+}
+
+// documentStartLine returns the line within a document where the object
+// starts, skipping any leading comments and blank lines.
+func documentStartLine(node *yamlv3.Node) int {
+	if len(node.Content) > 0 {
+		return node.Content[0].Line
+	}
+
+	return 1
+}
+
+// yamlErrorLine returns the line number from a YAML syntax error or 1 if it
+// doesn't include one.
+func yamlErrorLine(err error) int {
+	match := yamlLineRegex.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 1
+	}
+	line, _ := strconv.Atoi(match[1])
+
+	return line
+}
+
+// fieldLine returns the offset from the start of an object to the line where
+// the field referenced in a decoding or validation error is defined.  If the
+// field can't be found, the offset to the deepest parent found is returned.
+func fieldLine(node *yamlv3.Node, err error) int {
+	var path string
+	switch e := err.(type) {
+	case validation.ValidationError:
+		path = validationErrorPath(e)
+	default:
+		if match := quotedFieldRegex.FindStringSubmatch(err.Error()); match != nil {
+			path = match[1]
+		} else if match := structFieldRegex.FindStringSubmatch(err.Error()); match != nil {
+			path = match[1]
+		}
+	}
+	if len(node.Content) == 0 {
+		return 0
+	}
+	current := node.Content[0]
+	start := current.Line
+
+	elements := strings.FieldsFunc(path, func(r rune) bool { return r == '.' || r == '[' })
+	for i, element := range elements {
+		var next *yamlv3.Node
+		if index, err := strconv.Atoi(strings.TrimSuffix(element, "]")); err == nil && strings.HasSuffix(element, "]") {
+			if current.Kind == yamlv3.SequenceNode && index < len(current.Content) {
+				next = current.Content[index]
+			}
+		} else if current.Kind == yamlv3.MappingNode {
+			for j := 0; j+1 < len(current.Content); j += 2 {
+				if current.Content[j].Value == element {
+					// point to the key of the last field in the path
+					next = current.Content[j+1]
+					if i == len(elements)-1 {
+						next = current.Content[j]
+					}
+					break
+				}
+			}
+		}
+		if next == nil {
+			break
+		}
+		current = next
+	}
+
+	return current.Line - start
+}
+
+// validationErrorPath returns the field path from an OpenAPI validation error
+// with the leading kind removed.  The paths in the wrapped errors are schema
+// paths, e.g. io.k8s.api.apps.v1.DeploymentSpec, so only the field name of an
+// unknown field is taken from them.
+func validationErrorPath(err validation.ValidationError) string {
+	path := err.Path
+	if e, ok := err.Err.(validation.UnknownFieldError); ok {
+		path = fmt.Sprintf("%s.%s", path, e.Field)
+	}
+	if _, fieldPath, found := strings.Cut(path, "."); found {
+		return fieldPath
+	}
+
+	return ""
+}
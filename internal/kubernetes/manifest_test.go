@@ -0,0 +1,292 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	openapi_v2 "github.com/google/gnostic/openapiv2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testOpenAPISchema is a minimal OpenAPI schema served by the fake cluster
+// with a built-in kind and a custom resource.
+const testOpenAPISchema = `{
+  "swagger": "2.0",
+  "info": {"title": "Kubernetes", "version": "v1.26.0"},
+  "paths": {},
+  "definitions": {
+    "io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string"},
+        "namespace": {"type": "string"}
+      }
+    },
+    "io.k8s.api.apps.v1.Deployment": {
+      "type": "object",
+      "properties": {
+        "apiVersion": {"type": "string"},
+        "kind": {"type": "string"},
+        "metadata": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"},
+        "spec": {"$ref": "#/definitions/io.k8s.api.apps.v1.DeploymentSpec"}
+      },
+      "x-kubernetes-group-version-kind": [{"group": "apps", "kind": "Deployment", "version": "v1"}]
+    },
+    "io.k8s.api.apps.v1.DeploymentSpec": {
+      "type": "object",
+      "required": ["selector", "template"],
+      "properties": {
+        "replicas": {"type": "integer", "format": "int32"},
+        "selector": {"type": "object", "additionalProperties": {"type": "object"}},
+        "template": {"type": "object", "additionalProperties": {"type": "object"}}
+      }
+    },
+    "com.example.v1.Widget": {
+      "type": "object",
+      "properties": {
+        "apiVersion": {"type": "string"},
+        "kind": {"type": "string"},
+        "metadata": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"},
+        "spec": {
+          "type": "object",
+          "properties": {
+            "size": {"type": "integer"}
+          }
+        }
+      },
+      "x-kubernetes-group-version-kind": [{"group": "example.com", "kind": "Widget", "version": "v1"}]
+    }
+  }
+}`
+
+// fakeDiscovery serves the kinds and OpenAPI schema of a test cluster.
+type fakeDiscovery struct {
+	t *testing.T
+}
+
+func (fd *fakeDiscovery) ServerGroupsAndResources() ([]*metav1.APIGroup, []*metav1.APIResourceList, error) {
+	return nil, []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "namespaces", Kind: "Namespace", Namespaced: false},
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+			},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments", Kind: "Deployment", Namespaced: true},
+				{Name: "deployments/scale", Kind: "Scale", Namespaced: true},
+			},
+		},
+		{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "widgets", Kind: "Widget", Namespaced: true},
+			},
+		},
+	}, nil
+}
+
+func (fd *fakeDiscovery) OpenAPISchema() (*openapi_v2.Document, error) {
+	doc, err := openapi_v2.ParseDocument([]byte(testOpenAPISchema))
+	if err != nil {
+		fd.t.Fatalf("failed to parse test OpenAPI schema: %s", err)
+	}
+
+	return doc, nil
+}
+
+// deployment is a valid Deployment used as the base of test manifests.
+const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: app
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx
+`
+
+// expectedIssue is an issue expected from validation.  The message only has
+// to be contained in the message of the issue found.
+type expectedIssue struct {
+	source   string
+	line     int
+	severity Severity
+	message  string
+}
+
+func TestValidateOffline(t *testing.T) {
+	testCases := []struct {
+		name     string
+		manifest string
+		expected []expectedIssue
+	}{
+		{
+			name:     "valid",
+			manifest: deployment,
+		},
+		{
+			name:     "bad type",
+			manifest: strings.Replace(deployment, "replicas: 2", `replicas: "two"`, 1),
+			expected: []expectedIssue{
+				{"manifest.yaml", 7, SeverityError, "Deployment app/web:"},
+			},
+		},
+		{
+			name:     "unknown field",
+			manifest: strings.Replace(deployment, "replicas: 2", "replicaz: 2", 1),
+			expected: []expectedIssue{
+				{"manifest.yaml", 7, SeverityError, `unknown field "spec.replicaz"`},
+			},
+		},
+		{
+			// required fields can only be checked against a cluster's schemas
+			name:     "missing required field",
+			manifest: strings.Replace(deployment, "  selector:\n    matchLabels:\n      app: web\n", "", 1),
+		},
+		{
+			name: "multiple documents",
+			manifest: `# tptctl.threeport.io/source: web.yaml
+` + deployment + `---
+# tptctl.threeport.io/source: extra.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-config
+data:
+  key: value
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: app
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: widget
+  namespace: app
+---
+` + deployment,
+			expected: []expectedIssue{
+				{"extra.yaml", 1, SeverityWarning, "ConfigMap web-config has no namespace"},
+				{"extra.yaml", 8, SeverityWarning, "Namespace app is cluster-scoped"},
+				{"extra.yaml", 13, SeverityWarning, "kind example.com/v1, Kind=Widget is not a built-in kind"},
+				{"extra.yaml", 19, SeverityError, "Deployment app/web is a duplicate of the object at web.yaml:1"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			validator, err := NewManifestValidator(nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			checkIssues(t, validator.Validate("manifest.yaml", tc.manifest), tc.expected)
+		})
+	}
+}
+
+func TestValidateWithDiscovery(t *testing.T) {
+	testCases := []struct {
+		name     string
+		manifest string
+		expected []expectedIssue
+	}{
+		{
+			name:     "valid",
+			manifest: deployment,
+		},
+		{
+			name:     "bad type",
+			manifest: strings.Replace(deployment, "replicas: 2", `replicas: "two"`, 1),
+			expected: []expectedIssue{
+				{"manifest.yaml", 7, SeverityError, "spec.replicas"},
+			},
+		},
+		{
+			name:     "missing required field",
+			manifest: strings.Replace(deployment, "  selector:\n    matchLabels:\n      app: web\n", "", 1),
+			expected: []expectedIssue{
+				{"manifest.yaml", 6, SeverityError, `missing required field "selector"`},
+			},
+		},
+		{
+			name: "custom resource with unknown field",
+			manifest: `apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: widget
+  namespace: app
+spec:
+  size: 3
+  colour: blue
+`,
+			expected: []expectedIssue{
+				{"manifest.yaml", 8, SeverityError, `unknown field "colour"`},
+			},
+		},
+		{
+			name: "kind not served",
+			manifest: `apiVersion: example.com/v1
+kind: Gadget
+metadata:
+  name: gadget
+  namespace: app
+`,
+			expected: []expectedIssue{
+				{"manifest.yaml", 1, SeverityError, "kind example.com/v1, Kind=Gadget is not served by the cluster"},
+			},
+		},
+		{
+			name: "cluster-scoped",
+			manifest: `apiVersion: v1
+kind: Namespace
+metadata:
+  name: app
+`,
+			expected: []expectedIssue{
+				{"manifest.yaml", 1, SeverityWarning, "Namespace app is cluster-scoped"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			validator, err := newManifestValidator(&fakeDiscovery{t: t}, "https://test.example.com")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			checkIssues(t, validator.Validate("manifest.yaml", tc.manifest), tc.expected)
+		})
+	}
+}
+
+// checkIssues compares the issues found by validation with those expected.
+func checkIssues(t *testing.T, issues []ManifestIssue, expected []expectedIssue) {
+	t.Helper()
+	if len(issues) != len(expected) {
+		t.Fatalf("expected %d issues, got %d: %+v", len(expected), len(issues), issues)
+	}
+	for i, issue := range issues {
+		if issue.Source != expected[i].source || issue.Line != expected[i].line ||
+			issue.Severity != expected[i].severity || !strings.Contains(issue.Message, expected[i].message) {
+			t.Errorf("expected issue %d to be %s:%d: %s: ...%s..., got %s",
+				i, expected[i].source, expected[i].line, expected[i].severity, expected[i].message, issue.String())
+		}
+	}
+}
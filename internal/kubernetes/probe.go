@@ -0,0 +1,480 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sclient "k8s.io/client-go/kubernetes"
+)
+
+const (
+	// ProbeImage is the container image used to probe an upstream.
+	ProbeImage = "curlimages/curl:8.1.2"
+	// ProbeTimeout is the default time allowed for a probe to complete.
+	ProbeTimeout = time.Minute * 2
+
+	// probeRequestTimeout is the time allowed for each request made by the
+	// probe.
+	probeRequestTimeout = 10
+	// probeJobTTL is how long a finished probe job is kept if it can't be
+	// deleted.
+	probeJobTTL = 300
+	// probeOutputPrefix marks the lines of probe output with results.
+	probeOutputPrefix = "PROBE "
+	// forwardProxyOperatorPrefix is the name prefix of the services that
+	// belong to the forward proxy operator rather than the proxy servers.
+	forwardProxyOperatorPrefix = "forward-proxy-controller-manager"
+	// forwardProxyStage is the name of the stage that requests the upstream
+	// through the forward proxy.
+	forwardProxyStage = "HTTP via forward proxy"
+)
+
+// tlsExitCodes are the curl exit codes for failures during the TLS handshake.
+var tlsExitCodes = map[int]bool{
+	35: true, 51: true, 53: true, 54: true, 58: true, 59: true, 60: true,
+	64: true, 66: true, 77: true, 80: true, 82: true, 83: true, 90: true, 91: true,
+}
+
+// ProbeStageStatus is the result of a stage of a probe.
+type ProbeStageStatus string
+
+const (
+	ProbeStagePassed  ProbeStageStatus = "passed"
+	ProbeStageFailed  ProbeStageStatus = "failed"
+	ProbeStageSkipped ProbeStageStatus = "skipped"
+)
+
+// ProbeStage is the result of one stage of connecting to an upstream: DNS,
+// TCP, TLS, HTTP or HTTP through the forward proxy.
+type ProbeStage struct {
+	Name    string
+	Status  ProbeStageStatus
+	Message string
+}
+
+// ProbeResult is the result of probing an upstream.  ProxyURL is the forward
+// proxy the request was sent through.
+type ProbeResult struct {
+	Stages   []ProbeStage
+	ProxyURL string
+}
+
+// Passed returns whether the upstream answered through the forward proxy.
+// Workloads reach their dependencies through the proxy so the stages of the
+// direct request only help find where a connection fails.  They can fail
+// where network policies block egress that doesn't go through the proxy.
+func (pr *ProbeResult) Passed() bool {
+	for _, stage := range pr.Stages {
+		if stage.Name == forwardProxyStage {
+			return stage.Status == ProbeStagePassed
+		}
+	}
+
+	return false
+}
+
+// DirectFailed returns whether a stage of the direct request failed.
+func (pr *ProbeResult) DirectFailed() bool {
+	for _, stage := range pr.Stages {
+		if stage.Name != forwardProxyStage && stage.Status == ProbeStageFailed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ProbeOptions are the settings for probing an upstream.  ProxyNamespace is
+// the namespace the forward proxy servers run in.
+type ProbeOptions struct {
+	ProxyNamespace string
+	Timeout        time.Duration
+}
+
+// ProbeUpstream runs a short-lived Job on the cluster the credentials are for
+// that requests the upstream URL directly and through the forward proxy.  The
+// job runs in the namespace of the resources in the workload manifest so it is
+// subject to the same network policies as the workload.  The job is deleted
+// once the results are collected.
+func ProbeUpstream(
+	ctx context.Context,
+	credentials *ClusterCredentials,
+	manifest string,
+	upstreamURL string,
+	options ProbeOptions,
+) (*ProbeResult, error) {
+	clientset, err := credentials.clientset()
+	if err != nil {
+		return nil, err
+	}
+	if options.Timeout == 0 {
+		options.Timeout = ProbeTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, options.Timeout)
+	defer cancel()
+
+	proxyURL, err := forwardProxyURL(ctx, clientset, options.ProxyNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	// run the probe
+	namespace := manifestNamespace(manifest)
+	job, err := clientset.BatchV1().Jobs(namespace).Create(ctx, probeJob(upstreamURL, proxyURL), metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create probe job in namespace %s: %w", namespace, err)
+	}
+	defer func() {
+		// the probe context may have expired so deletion gets its own
+		propagation := metav1.DeletePropagationBackground
+		clientset.BatchV1().Jobs(namespace).Delete(context.Background(), job.Name, metav1.DeleteOptions{
+			PropagationPolicy: &propagation,
+		})
+	}()
+	output, err := probeJobOutput(ctx, clientset, job)
+	if err != nil {
+		return nil, err
+	}
+
+	stages, err := parseProbeOutput(output, strings.HasPrefix(upstreamURL, "https://"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProbeResult{Stages: stages, ProxyURL: proxyURL}, nil
+}
+
+// forwardProxyURL returns the URL of the forward proxy servers in a namespace.
+// It returns an error if the namespace has no proxy service since the probe
+// can't check the path workloads use to reach their dependencies without one.
+func forwardProxyURL(ctx context.Context, clientset k8sclient.Interface, namespace string) (string, error) {
+	if namespace == "" {
+		return "", errors.New("forward proxy namespace is required")
+	}
+	services, err := clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list services in forward proxy namespace %s: %w", namespace, err)
+	}
+	for _, service := range services.Items {
+		if strings.HasPrefix(service.Name, forwardProxyOperatorPrefix) || len(service.Spec.Ports) == 0 {
+			continue
+		}
+		return fmt.Sprintf("http://%s.%s.svc.cluster.local:%d", service.Name, namespace, service.Spec.Ports[0].Port), nil
+	}
+
+	return "", errors.New(fmt.Sprintf(
+		"no forward proxy service found in namespace %s - check the forward proxy is deployed to the workload cluster", namespace))
+}
+
+// ServiceScheme returns the scheme, http or https, to request an upstream that
+// is a service on the cluster the credentials are for with.  The host must be
+// the service's cluster DNS name, e.g. api.backend.svc.cluster.local.  The
+// scheme is taken from the app protocol of the service port, or its name if
+// it has none.  An empty scheme is returned if the host isn't a service on the
+// cluster or its port doesn't indicate a scheme.
+func ServiceScheme(ctx context.Context, credentials *ClusterCredentials, host, port string) (string, error) {
+	if _, _, ok := serviceName(host); !ok {
+		return "", nil
+	}
+	clientset, err := credentials.clientset()
+	if err != nil {
+		return "", err
+	}
+
+	return serviceScheme(ctx, clientset, host, port)
+}
+
+// serviceScheme returns the scheme for an upstream that is a service using the
+// clientset.
+func serviceScheme(ctx context.Context, clientset k8sclient.Interface, host, port string) (string, error) {
+	name, namespace, ok := serviceName(host)
+	if !ok {
+		return "", nil
+	}
+	service, err := clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if kubeerrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get service %s/%s: %w", namespace, name, err)
+	}
+
+	for _, servicePort := range service.Spec.Ports {
+		// a port must be given unless the service only has one
+		if strconv.Itoa(int(servicePort.Port)) != port && (port != "" || len(service.Spec.Ports) > 1) {
+			continue
+		}
+		if servicePort.AppProtocol != nil {
+			if scheme := portScheme(*servicePort.AppProtocol); scheme != "" {
+				return scheme, nil
+			}
+		}
+		return portScheme(servicePort.Name), nil
+	}
+
+	return "", nil
+}
+
+// serviceName returns the name and namespace of the service a host name is
+// the cluster DNS name of.  It returns false if the host isn't one, e.g. for a
+// host outside the cluster.
+func serviceName(host string) (string, string, bool) {
+	host = strings.ToLower(host)
+	var trimmed bool
+	for _, suffix := range []string{".svc.cluster.local", ".svc"} {
+		if strings.HasSuffix(host, suffix) {
+			host, trimmed = strings.TrimSuffix(host, suffix), true
+			break
+		}
+	}
+	parts := strings.Split(host, ".")
+	if !trimmed || len(parts) != 2 {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+// portScheme returns the scheme a service port's app protocol or name
+// indicates, following the <protocol>[-<suffix>] naming convention, or an
+// empty string if it doesn't indicate one.
+func portScheme(protocol string) string {
+	protocol = strings.ToLower(protocol)
+	switch {
+	case protocol == "https" || strings.HasPrefix(protocol, "https-"):
+		return "https"
+	case protocol == "http" || strings.HasPrefix(protocol, "http-") || protocol == "kubernetes.io/h2c":
+		return "http"
+	default:
+		return ""
+	}
+}
+
+// manifestNamespace returns the namespace of the first namespaced resource in a
+// manifest or the default namespace if there is none.
+func manifestNamespace(manifest string) string {
+	for _, object := range decodeManifest(manifest) {
+		if objectMeta, ok := object.(metav1.Object); ok && objectMeta.GetNamespace() != "" {
+			return objectMeta.GetNamespace()
+		}
+	}
+
+	return metav1.NamespaceDefault
+}
+
+// probeJob returns a job that requests the upstream URL directly and through
+// the forward proxy.  The job always succeeds and
+// reports the curl exit code, the resolved address, the connect and TLS times
+// and the HTTP status of each request.
+func probeJob(upstreamURL, proxyURL string) *batchv1.Job {
+	writeOut := "%{remote_ip} %{time_connect} %{time_appconnect} %{http_code}"
+	request := func(label, proxyArg string) string {
+		return fmt.Sprintf(
+			`out=$(curl -sS -o /dev/null --max-time %d %s -w '%s' "$UPSTREAM_URL" 2>/tmp/%[4]s.err); `+
+				`echo "%[5]s%[4]s $? $out"; sed 's/^/ERROR %[4]s /' /tmp/%[4]s.err`,
+			probeRequestTimeout, proxyArg, writeOut, label, probeOutputPrefix,
+		)
+	}
+	script := request("direct", "--noproxy '*'") + "; " + request("proxy", `--proxy "$PROXY_URL"`)
+
+	backoffLimit := int32(0)
+	ttl := int32(probeJobTTL)
+	activeDeadline := int64(probeRequestTimeout*2 + 30)
+	runAsNonRoot := true
+	runAsUser := int64(65534)
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "tptctl-probe-",
+			Labels:       map[string]string{"app.kubernetes.io/managed-by": "tptctl"},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			TTLSecondsAfterFinished: &ttl,
+			ActiveDeadlineSeconds:   &activeDeadline,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					SecurityContext: &corev1.PodSecurityContext{
+						RunAsNonRoot: &runAsNonRoot,
+						RunAsUser:    &runAsUser,
+					},
+					Containers: []corev1.Container{{
+						Name:    "probe",
+						Image:   ProbeImage,
+						Command: []string{"sh", "-c", script},
+						Env: []corev1.EnvVar{
+							{Name: "UPSTREAM_URL", Value: upstreamURL},
+							{Name: "PROXY_URL", Value: proxyURL},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// probeJobOutput waits for a probe job to finish and returns the logs of its
+// pod.
+func probeJobOutput(ctx context.Context, clientset *k8sclient.Clientset, job *batchv1.Job) (string, error) {
+	for {
+		current, err := clientset.BatchV1().Jobs(job.Namespace).Get(ctx, job.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("failed to get probe job %s: %w", job.Name, err)
+		}
+		if current.Status.Succeeded > 0 || current.Status.Failed > 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("probe job %s did not finish: %w", job.Name, ctx.Err())
+		case <-time.After(time.Second * 2):
+		}
+	}
+
+	pods, err := clientset.CoreV1().Pods(job.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("job-name=%s", job.Name),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list pods for probe job %s: %w", job.Name, err)
+	}
+	if len(pods.Items) == 0 {
+		return "", errors.New(fmt.Sprintf("no pods found for probe job %s", job.Name))
+	}
+	logStream, err := clientset.CoreV1().Pods(job.Namespace).GetLogs(pods.Items[0].Name, &corev1.PodLogOptions{}).Stream(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get logs for probe job %s: %w", job.Name, err)
+	}
+	defer logStream.Close()
+	output, err := ioutil.ReadAll(logStream)
+	if err != nil {
+		return "", fmt.Errorf("failed to read logs for probe job %s: %w", job.Name, err)
+	}
+
+	return string(output), nil
+}
+
+// probeRequest is the result of a request made by the probe job.
+type probeRequest struct {
+	exitCode    int
+	remoteIP    string
+	connectTime float64
+	tlsTime     float64
+	httpCode    string
+	errors      []string
+}
+
+// message returns the errors curl reported for the request or a description
+// of its exit code.
+func (pr *probeRequest) message() string {
+	if len(pr.errors) > 0 {
+		return strings.Join(pr.errors, "; ")
+	}
+
+	return fmt.Sprintf("curl exited with code %d", pr.exitCode)
+}
+
+// parseProbeOutput returns the stages of a probe from the output of the probe
+// job.
+func parseProbeOutput(output string, https bool) ([]ProbeStage, error) {
+	requests := make(map[string]*probeRequest)
+	var errorLines []string
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, probeOutputPrefix):
+			fields := strings.Fields(strings.TrimPrefix(line, probeOutputPrefix))
+			if len(fields) < 2 {
+				continue
+			}
+			request := &probeRequest{}
+			request.exitCode, _ = strconv.Atoi(fields[1])
+			if len(fields) == 6 {
+				request.remoteIP = fields[2]
+				request.connectTime, _ = strconv.ParseFloat(fields[3], 64)
+				request.tlsTime, _ = strconv.ParseFloat(fields[4], 64)
+				request.httpCode = fields[5]
+			}
+			requests[fields[0]] = request
+		case strings.HasPrefix(line, "ERROR "):
+			errorLines = append(errorLines, strings.TrimPrefix(line, "ERROR "))
+		}
+	}
+	for _, line := range errorLines {
+		label, message, found := strings.Cut(line, " ")
+		if request, ok := requests[label]; ok && found {
+			request.errors = append(request.errors, strings.TrimSpace(message))
+		}
+	}
+	direct, ok := requests["direct"]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("unexpected probe output: %s", output))
+	}
+
+	// work out which stage the direct request failed at
+	var stages []ProbeStage
+	failed := false
+	addStage := func(name string, passed bool, message string) {
+		switch {
+		case failed:
+			stages = append(stages, ProbeStage{Name: name, Status: ProbeStageSkipped})
+		case passed:
+			stages = append(stages, ProbeStage{Name: name, Status: ProbeStagePassed, Message: message})
+		default:
+			stages = append(stages, ProbeStage{Name: name, Status: ProbeStageFailed, Message: message})
+			failed = true
+		}
+	}
+	timedOut := direct.exitCode == 28
+	if direct.exitCode == 6 {
+		addStage("DNS", false, direct.message())
+	} else {
+		addStage("DNS", true, fmt.Sprintf("resolved to %s", direct.remoteIP))
+	}
+	if direct.exitCode == 7 || (timedOut && direct.connectTime == 0) {
+		addStage("TCP", false, direct.message())
+	} else {
+		addStage("TCP", true, fmt.Sprintf("connected in %.3fs", direct.connectTime))
+	}
+	switch {
+	case !https:
+		stages = append(stages, ProbeStage{Name: "TLS", Status: ProbeStageSkipped, Message: "upstream uses plain HTTP"})
+	case tlsExitCodes[direct.exitCode] || (timedOut && direct.tlsTime == 0):
+		addStage("TLS", false, direct.message())
+	default:
+		addStage("TLS", true, fmt.Sprintf("handshake completed in %.3fs", direct.tlsTime))
+	}
+	addStage("HTTP", direct.exitCode == 0, httpMessage(direct))
+
+	// the request through the forward proxy is independent of the direct one
+	proxied, ok := requests["proxy"]
+	switch {
+	case !ok:
+		stages = append(stages, ProbeStage{Name: forwardProxyStage, Status: ProbeStageFailed,
+			Message: "no result from the probe"})
+	case proxied.exitCode != 0:
+		stages = append(stages, ProbeStage{Name: forwardProxyStage, Status: ProbeStageFailed,
+			Message: proxied.message()})
+	default:
+		stages = append(stages, ProbeStage{Name: forwardProxyStage, Status: ProbeStagePassed,
+			Message: httpMessage(proxied)})
+	}
+
+	return stages, nil
+}
+
+// httpMessage returns a description of the response to a request.
+func httpMessage(request *probeRequest) string {
+	if request.exitCode != 0 {
+		return request.message()
+	}
+
+	return fmt.Sprintf("responded with status %s", request.httpCode)
+}
@@ -0,0 +1,212 @@
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseProbeOutput(t *testing.T) {
+	testCases := []struct {
+		name       string
+		output     string
+		https      bool
+		wantStages []ProbeStage
+		wantPassed bool
+		wantErr    string
+	}{
+		{
+			name: "passed",
+			output: "PROBE direct 0 1.2.3.4 0.010 0.050 200\n" +
+				"PROBE proxy 0 10.0.0.5 0.002 0.060 200\n",
+			https: true,
+			wantStages: []ProbeStage{
+				{Name: "DNS", Status: ProbeStagePassed, Message: "resolved to 1.2.3.4"},
+				{Name: "TCP", Status: ProbeStagePassed, Message: "connected in 0.010s"},
+				{Name: "TLS", Status: ProbeStagePassed, Message: "handshake completed in 0.050s"},
+				{Name: "HTTP", Status: ProbeStagePassed, Message: "responded with status 200"},
+				{Name: forwardProxyStage, Status: ProbeStagePassed, Message: "responded with status 200"},
+			},
+			wantPassed: true,
+		},
+		{
+			name: "plain HTTP",
+			output: "PROBE direct 0 1.2.3.4 0.010 0.000 204\n" +
+				"PROBE proxy 0 10.0.0.5 0.002 0.000 204\n",
+			wantStages: []ProbeStage{
+				{Name: "DNS", Status: ProbeStagePassed, Message: "resolved to 1.2.3.4"},
+				{Name: "TCP", Status: ProbeStagePassed, Message: "connected in 0.010s"},
+				{Name: "TLS", Status: ProbeStageSkipped, Message: "upstream uses plain HTTP"},
+				{Name: "HTTP", Status: ProbeStagePassed, Message: "responded with status 204"},
+				{Name: forwardProxyStage, Status: ProbeStagePassed, Message: "responded with status 204"},
+			},
+			wantPassed: true,
+		},
+		{
+			name: "direct egress blocked",
+			output: "PROBE direct 28 1.2.3.4 0.000 0.000 000\n" +
+				"ERROR direct curl: (28) Connection timed out after 10001 milliseconds\n" +
+				"PROBE proxy 0 10.0.0.5 0.002 0.060 200\n",
+			https: true,
+			wantStages: []ProbeStage{
+				{Name: "DNS", Status: ProbeStagePassed, Message: "resolved to 1.2.3.4"},
+				{Name: "TCP", Status: ProbeStageFailed, Message: "curl: (28) Connection timed out after 10001 milliseconds"},
+				{Name: "TLS", Status: ProbeStageSkipped},
+				{Name: "HTTP", Status: ProbeStageSkipped},
+				{Name: forwardProxyStage, Status: ProbeStagePassed, Message: "responded with status 200"},
+			},
+			wantPassed: true,
+		},
+		{
+			name: "DNS failure",
+			output: "PROBE direct 6  0.000 0.000 000\n" +
+				"ERROR direct curl: (6) Could not resolve host: rpc.example.com\n" +
+				"PROBE proxy 56  0.000 0.000 000\n" +
+				"ERROR proxy curl: (56) CONNECT tunnel failed, response 503\n",
+			https: true,
+			wantStages: []ProbeStage{
+				{Name: "DNS", Status: ProbeStageFailed, Message: "curl: (6) Could not resolve host: rpc.example.com"},
+				{Name: "TCP", Status: ProbeStageSkipped},
+				{Name: "TLS", Status: ProbeStageSkipped},
+				{Name: "HTTP", Status: ProbeStageSkipped},
+				{Name: forwardProxyStage, Status: ProbeStageFailed, Message: "curl: (56) CONNECT tunnel failed, response 503"},
+			},
+		},
+		{
+			name: "TLS failure",
+			output: "PROBE direct 60 1.2.3.4 0.010 0.000 000\n" +
+				"ERROR direct curl: (60) SSL certificate problem: self-signed certificate\n" +
+				"PROBE proxy 60 10.0.0.5 0.002 0.000 000\n",
+			https: true,
+			wantStages: []ProbeStage{
+				{Name: "DNS", Status: ProbeStagePassed, Message: "resolved to 1.2.3.4"},
+				{Name: "TCP", Status: ProbeStagePassed, Message: "connected in 0.010s"},
+				{Name: "TLS", Status: ProbeStageFailed, Message: "curl: (60) SSL certificate problem: self-signed certificate"},
+				{Name: "HTTP", Status: ProbeStageSkipped},
+				{Name: forwardProxyStage, Status: ProbeStageFailed, Message: "curl exited with code 60"},
+			},
+		},
+		{
+			name:   "no proxy result",
+			output: "PROBE direct 0 1.2.3.4 0.010 0.050 200\n",
+			https:  true,
+			wantStages: []ProbeStage{
+				{Name: "DNS", Status: ProbeStagePassed, Message: "resolved to 1.2.3.4"},
+				{Name: "TCP", Status: ProbeStagePassed, Message: "connected in 0.010s"},
+				{Name: "TLS", Status: ProbeStagePassed, Message: "handshake completed in 0.050s"},
+				{Name: "HTTP", Status: ProbeStagePassed, Message: "responded with status 200"},
+				{Name: forwardProxyStage, Status: ProbeStageFailed, Message: "no result from the probe"},
+			},
+		},
+		{
+			name:    "unexpected output",
+			output:  "sh: curl: not found\n",
+			wantErr: "unexpected probe output",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stages, err := parseProbeOutput(tc.output, tc.https)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(stages, tc.wantStages) {
+				t.Errorf("expected stages %+v, got %+v", tc.wantStages, stages)
+			}
+			result := ProbeResult{Stages: stages}
+			if result.Passed() != tc.wantPassed {
+				t.Errorf("expected Passed() to be %t", tc.wantPassed)
+			}
+		})
+	}
+}
+
+func TestForwardProxyURL(t *testing.T) {
+	service := func(name, namespace string, ports ...int32) *corev1.Service {
+		svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+		for _, port := range ports {
+			svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{Port: port})
+		}
+		return svc
+	}
+	clientset := fake.NewSimpleClientset(
+		service("forward-proxy-controller-manager-metrics-service", "forward-proxy-system", 8443),
+		service("foward-proxy-main", "forward-proxy-system", 8080),
+		service("forward-proxy-controller-manager-metrics-service", "operator-only", 8443),
+	)
+
+	proxyURL, err := forwardProxyURL(context.Background(), clientset, "forward-proxy-system")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "http://foward-proxy-main.forward-proxy-system.svc.cluster.local:8080"; proxyURL != expected {
+		t.Errorf("expected proxy URL %s, got %s", expected, proxyURL)
+	}
+
+	for _, namespace := range []string{"operator-only", "empty", ""} {
+		if _, err := forwardProxyURL(context.Background(), clientset, namespace); err == nil {
+			t.Errorf("expected an error for namespace %q with no forward proxy service", namespace)
+		}
+	}
+}
+
+func TestServiceScheme(t *testing.T) {
+	appProtocol := func(protocol string) *string { return &protocol }
+	clientset := fake.NewSimpleClientset(
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "backend"},
+			Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+				{Name: "web", Port: 8080, AppProtocol: appProtocol("http")},
+				{Name: "https-api", Port: 8443},
+				{Name: "metrics", Port: 9090},
+			}},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "rpc", Namespace: "backend"},
+			Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+				{Name: "rpc", Port: 8545, AppProtocol: appProtocol("HTTPS")},
+			}},
+		},
+	)
+
+	testCases := []struct {
+		name string
+		host string
+		port string
+		want string
+	}{
+		{name: "app protocol", host: "api.backend.svc.cluster.local", port: "8080", want: "http"},
+		{name: "port name", host: "api.backend.svc", port: "8443", want: "https"},
+		{name: "no indication", host: "api.backend.svc", port: "9090", want: ""},
+		{name: "unknown port", host: "api.backend.svc", port: "1234", want: ""},
+		{name: "ambiguous port", host: "api.backend.svc", want: ""},
+		{name: "only port", host: "rpc.backend.svc.cluster.local", want: "https"},
+		{name: "service not found", host: "web.backend.svc", port: "80", want: ""},
+		{name: "not a service", host: "api.backend.example.com", port: "8080", want: ""},
+		{name: "service without namespace", host: "api.svc", port: "8080", want: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme, err := serviceScheme(context.Background(), clientset, tc.host, tc.port)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if scheme != tc.want {
+				t.Errorf("expected scheme %q, got %q", tc.want, scheme)
+			}
+		})
+	}
+}
@@ -0,0 +1,359 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	k8sclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// deploymentRevisionAnnotation is the annotation the deployment controller
+// sets on a deployment and its replica sets to record their revision.
+const deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+
+// failedContainerReasons are the reasons a container is waiting that indicate
+// it will not start without a change to the workload.  ErrImagePull is not
+// included as a pull can fail transiently - the container moves on to
+// ImagePullBackOff if it keeps failing.
+var failedContainerReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+}
+
+// WorkloadStatus is the rollout status of the Deployments, StatefulSets and
+// DaemonSets in a manifest.  Failed is set when a rollout cannot complete
+// without intervention.  Message describes what is not yet ready.
+type WorkloadStatus struct {
+	Ready   bool
+	Failed  bool
+	Message string
+}
+
+// GetWorkloadStatus checks the rollout of the workload resources in a manifest
+// on the cluster the credentials are for.
+func GetWorkloadStatus(
+	ctx context.Context,
+	credentials *ClusterCredentials,
+	manifest string,
+) (*WorkloadStatus, error) {
+	clientset, err := credentials.clientset()
+	if err != nil {
+		return nil, err
+	}
+
+	return getWorkloadStatus(ctx, clientset, manifest)
+}
+
+// getWorkloadStatus checks the rollout of the workload resources in a manifest
+// using the clientset.
+func getWorkloadStatus(
+	ctx context.Context,
+	clientset k8sclient.Interface,
+	manifest string,
+) (*WorkloadStatus, error) {
+	var notReady, failures []string
+	workloads := 0
+	for _, object := range decodeManifest(manifest) {
+		var status string
+		var podSelector labels.Selector
+		var namespace string
+		switch obj := object.(type) {
+		case *appsv1.Deployment:
+			namespace = namespaceOrDefault(obj.Namespace)
+			live, err := clientset.AppsV1().Deployments(namespace).Get(ctx, obj.Name, metav1.GetOptions{})
+			if err != nil {
+				status, err = notFoundStatus("deployment", namespace, obj.Name, err)
+				if err != nil {
+					return nil, err
+				}
+				break
+			}
+			podSelector, err = deploymentPodSelector(ctx, clientset, live)
+			if err != nil {
+				return nil, err
+			}
+			status = deploymentStatus(live)
+			for _, condition := range live.Status.Conditions {
+				if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+					failures = append(failures, fmt.Sprintf("deployment %s/%s: %s", namespace, obj.Name, condition.Message))
+				}
+			}
+		case *appsv1.StatefulSet:
+			namespace = namespaceOrDefault(obj.Namespace)
+			live, err := clientset.AppsV1().StatefulSets(namespace).Get(ctx, obj.Name, metav1.GetOptions{})
+			if err != nil {
+				status, err = notFoundStatus("statefulset", namespace, obj.Name, err)
+				if err != nil {
+					return nil, err
+				}
+				break
+			}
+			if live.Status.UpdateRevision != "" {
+				podSelector, err = revisionSelector(live.Spec.Selector, appsv1.ControllerRevisionHashLabelKey, live.Status.UpdateRevision)
+				if err != nil {
+					return nil, err
+				}
+			}
+			status = statefulSetStatus(live)
+		case *appsv1.DaemonSet:
+			namespace = namespaceOrDefault(obj.Namespace)
+			live, err := clientset.AppsV1().DaemonSets(namespace).Get(ctx, obj.Name, metav1.GetOptions{})
+			if err != nil {
+				status, err = notFoundStatus("daemonset", namespace, obj.Name, err)
+				if err != nil {
+					return nil, err
+				}
+				break
+			}
+			podSelector, err = daemonSetPodSelector(ctx, clientset, live)
+			if err != nil {
+				return nil, err
+			}
+			status = daemonSetStatus(live)
+		default:
+			continue
+		}
+		workloads++
+		if status != "" {
+			notReady = append(notReady, status)
+		}
+
+		// check the pods of the current revision for containers that won't
+		// start - pods of earlier revisions that are being replaced are
+		// ignored
+		if podSelector == nil {
+			continue
+		}
+		pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: podSelector.String(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods in namespace %s: %w", namespace, err)
+		}
+		failures = append(failures, failedPods(pods.Items)...)
+	}
+
+	switch {
+	case len(failures) > 0:
+		return &WorkloadStatus{Failed: true, Message: strings.Join(failures, "; ")}, nil
+	case len(notReady) > 0:
+		return &WorkloadStatus{Message: strings.Join(notReady, "; ")}, nil
+	default:
+		return &WorkloadStatus{Ready: true, Message: fmt.Sprintf("%d workload resources ready", workloads)}, nil
+	}
+}
+
+// decodeManifest decodes the objects of built-in kinds in a manifest.  Custom
+// resources and invalid objects are skipped.
+func decodeManifest(manifest string) []runtime.Object {
+	decoder := scheme.Codecs.UniversalDeserializer()
+	var objects []runtime.Object
+	for _, doc := range splitManifest(manifest) {
+		object, _, err := decoder.Decode([]byte(doc.content), nil, nil)
+		if err != nil {
+			continue
+		}
+		objects = append(objects, object)
+	}
+
+	return objects
+}
+
+// deploymentPodSelector returns a selector for the pods of the current
+// revision of a deployment, i.e. those with the pod template hash of the
+// replica set for its revision.  It returns nil if the replica set hasn't been
+// created yet.
+func deploymentPodSelector(
+	ctx context.Context,
+	clientset k8sclient.Interface,
+	deployment *appsv1.Deployment,
+) (labels.Selector, error) {
+	revision := deployment.Annotations[deploymentRevisionAnnotation]
+	if revision == "" || deployment.Spec.Selector == nil {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, nil
+	}
+	replicaSets, err := clientset.AppsV1().ReplicaSets(deployment.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replica sets in namespace %s: %w", deployment.Namespace, err)
+	}
+	for _, replicaSet := range replicaSets.Items {
+		if !metav1.IsControlledBy(&replicaSet, deployment) ||
+			replicaSet.Annotations[deploymentRevisionAnnotation] != revision {
+			continue
+		}
+		hash := replicaSet.Labels[appsv1.DefaultDeploymentUniqueLabelKey]
+		if hash == "" {
+			return nil, nil
+		}
+		return revisionSelector(deployment.Spec.Selector, appsv1.DefaultDeploymentUniqueLabelKey, hash)
+	}
+
+	return nil, nil
+}
+
+// daemonSetPodSelector returns a selector for the pods of the current revision
+// of a daemon set, i.e. those with the hash of its latest controller revision.
+// It returns nil if no controller revision has been created yet.
+func daemonSetPodSelector(
+	ctx context.Context,
+	clientset k8sclient.Interface,
+	daemonSet *appsv1.DaemonSet,
+) (labels.Selector, error) {
+	if daemonSet.Spec.Selector == nil {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(daemonSet.Spec.Selector)
+	if err != nil {
+		return nil, nil
+	}
+	revisions, err := clientset.AppsV1().ControllerRevisions(daemonSet.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list controller revisions in namespace %s: %w", daemonSet.Namespace, err)
+	}
+	var latest *appsv1.ControllerRevision
+	for i, revision := range revisions.Items {
+		if !metav1.IsControlledBy(&revision, daemonSet) {
+			continue
+		}
+		if latest == nil || revision.Revision > latest.Revision {
+			latest = &revisions.Items[i]
+		}
+	}
+	if latest == nil || latest.Labels[appsv1.ControllerRevisionHashLabelKey] == "" {
+		return nil, nil
+	}
+
+	return revisionSelector(daemonSet.Spec.Selector, appsv1.ControllerRevisionHashLabelKey,
+		latest.Labels[appsv1.ControllerRevisionHashLabelKey])
+}
+
+// revisionSelector returns a selector for the pods matched by a workload
+// resource's selector that also have the given revision label.
+func revisionSelector(selector *metav1.LabelSelector, key, value string) (labels.Selector, error) {
+	podSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pod selector: %w", err)
+	}
+	requirement, err := labels.NewRequirement(key, selection.Equals, []string{value})
+	if err != nil {
+		return nil, fmt.Errorf("failed to select pods with %s=%s: %w", key, value, err)
+	}
+
+	return podSelector.Add(*requirement), nil
+}
+
+// deploymentStatus returns a description of why a deployment is not ready or
+// an empty string if it is.
+func deploymentStatus(deployment *appsv1.Deployment) string {
+	replicas := replicasOrDefault(deployment.Spec.Replicas)
+	switch {
+	case deployment.Status.ObservedGeneration < deployment.Generation:
+		return fmt.Sprintf("deployment %s/%s: waiting for spec update to be observed",
+			deployment.Namespace, deployment.Name)
+	case deployment.Status.UpdatedReplicas < replicas || deployment.Status.AvailableReplicas < replicas:
+		return fmt.Sprintf("deployment %s/%s: %d of %d updated replicas available",
+			deployment.Namespace, deployment.Name, deployment.Status.AvailableReplicas, replicas)
+	default:
+		return ""
+	}
+}
+
+// statefulSetStatus returns a description of why a stateful set is not ready
+// or an empty string if it is.
+func statefulSetStatus(statefulSet *appsv1.StatefulSet) string {
+	replicas := replicasOrDefault(statefulSet.Spec.Replicas)
+	switch {
+	case statefulSet.Status.ObservedGeneration < statefulSet.Generation:
+		return fmt.Sprintf("statefulset %s/%s: waiting for spec update to be observed",
+			statefulSet.Namespace, statefulSet.Name)
+	case statefulSet.Status.UpdatedReplicas < replicas || statefulSet.Status.ReadyReplicas < replicas:
+		return fmt.Sprintf("statefulset %s/%s: %d of %d updated replicas ready",
+			statefulSet.Namespace, statefulSet.Name, statefulSet.Status.ReadyReplicas, replicas)
+	default:
+		return ""
+	}
+}
+
+// daemonSetStatus returns a description of why a daemon set is not ready or
+// an empty string if it is.
+func daemonSetStatus(daemonSet *appsv1.DaemonSet) string {
+	desired := daemonSet.Status.DesiredNumberScheduled
+	switch {
+	case daemonSet.Status.ObservedGeneration < daemonSet.Generation:
+		return fmt.Sprintf("daemonset %s/%s: waiting for spec update to be observed",
+			daemonSet.Namespace, daemonSet.Name)
+	case daemonSet.Status.UpdatedNumberScheduled < desired || daemonSet.Status.NumberReady < desired:
+		return fmt.Sprintf("daemonset %s/%s: %d of %d updated pods ready",
+			daemonSet.Namespace, daemonSet.Name, daemonSet.Status.NumberReady, desired)
+	default:
+		return ""
+	}
+}
+
+// failedPods returns a description of each container in the pods that is
+// waiting for a reason that indicates it won't start.
+func failedPods(pods []corev1.Pod) []string {
+	var failures []string
+	for _, pod := range pods {
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			waiting := containerStatus.State.Waiting
+			if waiting == nil || !failedContainerReasons[waiting.Reason] {
+				continue
+			}
+			failures = append(failures, fmt.Sprintf("pod %s/%s container %s: %s: %s",
+				pod.Namespace, pod.Name, containerStatus.Name, waiting.Reason, waiting.Message))
+		}
+	}
+
+	return failures
+}
+
+// notFoundStatus returns the status for a workload resource that could not be
+// retrieved.  A resource that doesn't exist yet is not ready, any other error
+// is returned.
+func notFoundStatus(kind, namespace, name string, err error) (string, error) {
+	if kubeerrors.IsNotFound(err) {
+		return fmt.Sprintf("%s %s/%s: not created yet", kind, namespace, name), nil
+	}
+
+	return "", fmt.Errorf("failed to get %s %s/%s: %w", kind, namespace, name, err)
+}
+
+// namespaceOrDefault returns the namespace or the default namespace if empty.
+func namespaceOrDefault(namespace string) string {
+	if namespace == "" {
+		return metav1.NamespaceDefault
+	}
+
+	return namespace
+}
+
+// replicasOrDefault returns the number of replicas or the Kubernetes default
+// of 1 if unset.
+func replicasOrDefault(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+
+	return *replicas
+}
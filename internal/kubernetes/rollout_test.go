@@ -0,0 +1,96 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetWorkloadStatus(t *testing.T) {
+	replicas := int32(1)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "web",
+			UID:         "web-uid",
+			Annotations: map[string]string{deploymentRevisionAnnotation: "2"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		},
+		Status: appsv1.DeploymentStatus{UpdatedReplicas: 1, AvailableReplicas: 1},
+	}
+	controller := []metav1.OwnerReference{*metav1.NewControllerRef(deployment, appsv1.SchemeGroupVersion.WithKind("Deployment"))}
+	replicaSet := func(name, revision, hash string) *appsv1.ReplicaSet {
+		return &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "web",
+			Labels:          map[string]string{"app": "web", appsv1.DefaultDeploymentUniqueLabelKey: hash},
+			Annotations:     map[string]string{deploymentRevisionAnnotation: revision},
+			OwnerReferences: controller,
+		}}
+	}
+	pod := func(name, hash, waitingReason string) *corev1.Pod {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "web",
+			Labels:    map[string]string{"app": "web", appsv1.DefaultDeploymentUniqueLabelKey: hash},
+		}}
+		if waitingReason != "" {
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+				Name:  "web",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: waitingReason}},
+			}}
+		}
+		return pod
+	}
+	// the pod of the previous revision is crashing while it is replaced
+	previousRevision := []runtime.Object{
+		deployment,
+		replicaSet("web-old", "1", "old"),
+		pod("web-old-1", "old", "CrashLoopBackOff"),
+	}
+
+	testCases := []struct {
+		name       string
+		objects    []runtime.Object
+		wantFailed bool
+	}{
+		{
+			name:    "previous revision failing",
+			objects: append(previousRevision, replicaSet("web-new", "2", "new"), pod("web-new-1", "new", "")),
+		},
+		{
+			name:       "current revision failing",
+			objects:    append(previousRevision, replicaSet("web-new", "2", "new"), pod("web-new-1", "new", "CrashLoopBackOff")),
+			wantFailed: true,
+		},
+		{
+			name:    "current revision pulling image",
+			objects: append(previousRevision, replicaSet("web-new", "2", "new"), pod("web-new-1", "new", "ErrImagePull")),
+		},
+		{
+			name:    "current revision not created yet",
+			objects: previousRevision,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(tc.objects...)
+			status, err := getWorkloadStatus(context.Background(), clientset, testWorkloadManifest)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if status.Failed != tc.wantFailed {
+				t.Errorf("expected failed to be %t, got %+v", tc.wantFailed, status)
+			}
+		})
+	}
+}
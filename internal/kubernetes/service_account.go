@@ -0,0 +1,126 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sclient "k8s.io/client-go/kubernetes"
+)
+
+const (
+	// ServiceAccountName is the name of the service account created for
+	// threeport on a workload cluster whose kubeconfig credentials are
+	// short-lived.
+	ServiceAccountName = "threeport-workload-controller"
+	// ServiceAccountNamespace is the namespace the service account is
+	// created in.
+	ServiceAccountNamespace = "kube-system"
+
+	// serviceAccountTokenTimeout is how long to wait for Kubernetes to
+	// populate the service account token.
+	serviceAccountTokenTimeout = time.Second * 30
+	// serviceAccountTokenPollInterval is how often the token secret is
+	// checked while waiting.
+	serviceAccountTokenPollInterval = time.Second
+)
+
+// ServiceAccountCredentials creates a service account for threeport on the
+// cluster the credentials are for, binds it to the cluster-admin role so that
+// threeport can deploy any workload and returns credentials with a long-lived
+// token for it.  It is used to register clusters whose kubeconfig only
+// provides short-lived credentials, e.g. from an exec plugin.  Objects that
+// already exist are reused so it can be run again for the same cluster.
+func ServiceAccountCredentials(ctx context.Context, credentials *ClusterCredentials) (*ClusterCredentials, error) {
+	clientset, err := credentials.clientset()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := createServiceAccountToken(ctx, clientset, serviceAccountTokenPollInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClusterCredentials{
+		APIEndpoint:   credentials.APIEndpoint,
+		CACertificate: credentials.CACertificate,
+		Token:         token,
+	}, nil
+}
+
+// createServiceAccountToken creates the threeport service account, its role
+// binding and a secret for its token and returns the token once Kubernetes
+// has populated it.
+func createServiceAccountToken(ctx context.Context, clientset k8sclient.Interface, pollInterval time.Duration) (string, error) {
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ServiceAccountName,
+			Namespace: ServiceAccountNamespace,
+		},
+	}
+	_, err := clientset.CoreV1().ServiceAccounts(ServiceAccountNamespace).Create(ctx, serviceAccount, metav1.CreateOptions{})
+	if err != nil && !kerrors.IsAlreadyExists(err) {
+		return "", fmt.Errorf("failed to create service account %s: %w", ServiceAccountName, err)
+	}
+
+	roleBinding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: ServiceAccountName},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     "cluster-admin",
+		},
+		Subjects: []rbacv1.Subject{{
+			Kind:      rbacv1.ServiceAccountKind,
+			Name:      ServiceAccountName,
+			Namespace: ServiceAccountNamespace,
+		}},
+	}
+	_, err = clientset.RbacV1().ClusterRoleBindings().Create(ctx, roleBinding, metav1.CreateOptions{})
+	if err != nil && !kerrors.IsAlreadyExists(err) {
+		return "", fmt.Errorf("failed to create cluster role binding %s: %w", ServiceAccountName, err)
+	}
+
+	// tokens are no longer created for service accounts automatically so a
+	// secret is created for Kubernetes to populate with one that doesn't
+	// expire
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ServiceAccountName,
+			Namespace: ServiceAccountNamespace,
+			Annotations: map[string]string{
+				corev1.ServiceAccountNameKey: ServiceAccountName,
+			},
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+	}
+	_, err = clientset.CoreV1().Secrets(ServiceAccountNamespace).Create(ctx, secret, metav1.CreateOptions{})
+	if err != nil && !kerrors.IsAlreadyExists(err) {
+		return "", fmt.Errorf("failed to create token secret %s: %w", ServiceAccountName, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, serviceAccountTokenTimeout)
+	defer cancel()
+	for {
+		secret, err := clientset.CoreV1().Secrets(ServiceAccountNamespace).Get(ctx, ServiceAccountName, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("failed to get token secret %s: %w", ServiceAccountName, err)
+		}
+		if token := secret.Data[corev1.ServiceAccountTokenKey]; len(token) > 0 {
+			return string(token), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", errors.New(fmt.Sprintf(
+				"token for service account %s was not populated after %s", ServiceAccountName, serviceAccountTokenTimeout))
+		case <-time.After(pollInterval):
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCreateServiceAccountToken(t *testing.T) {
+	testCases := []struct {
+		name     string
+		existing []runtime.Object
+	}{
+		{
+			name: "new cluster",
+		},
+		{
+			// the service account was created by an earlier registration
+			name: "existing service account",
+			existing: []runtime.Object{
+				&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{
+					Name: ServiceAccountName, Namespace: ServiceAccountNamespace,
+				}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(tc.existing...)
+			// populate the token as the Kubernetes token controller would
+			clientset.PrependReactor("create", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				secret := action.(k8stesting.CreateAction).GetObject().(*corev1.Secret)
+				secret.Data = map[string][]byte{corev1.ServiceAccountTokenKey: []byte("sa-token")}
+				return false, nil, nil
+			})
+
+			token, err := createServiceAccountToken(context.Background(), clientset, time.Millisecond)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if token != "sa-token" {
+				t.Errorf("expected token sa-token, got %s", token)
+			}
+
+			secret, err := clientset.CoreV1().Secrets(ServiceAccountNamespace).Get(context.Background(), ServiceAccountName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get token secret: %s", err)
+			}
+			if secret.Type != corev1.SecretTypeServiceAccountToken ||
+				secret.Annotations[corev1.ServiceAccountNameKey] != ServiceAccountName {
+				t.Errorf("expected a service account token secret for %s, got %+v", ServiceAccountName, secret)
+			}
+			roleBinding, err := clientset.RbacV1().ClusterRoleBindings().Get(context.Background(), ServiceAccountName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get cluster role binding: %s", err)
+			}
+			if roleBinding.RoleRef.Name != "cluster-admin" || len(roleBinding.Subjects) != 1 ||
+				roleBinding.Subjects[0].Name != ServiceAccountName {
+				t.Errorf("expected cluster-admin to be bound to %s, got %+v", ServiceAccountName, roleBinding)
+			}
+		})
+	}
+}
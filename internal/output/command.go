@@ -0,0 +1,85 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// RunCommand runs an external command, such as kubectl, and returns what it
+// writes to stdout.  The command is logged at debug level and its output at
+// trace level.  If the command fails, its stderr is logged at debug level and
+// the last line of it, which usually holds the reason, is added to the error
+// so raw output isn't printed to the user.
+func RunCommand(cmd *exec.Cmd) ([]byte, error) {
+	return runCommand(cmd, false)
+}
+
+// RunSecretCommand runs an external command whose output holds secrets, such
+// as `kind get kubeconfig`, like RunCommand but without logging its output.
+func RunSecretCommand(cmd *exec.Cmd) ([]byte, error) {
+	return runCommand(cmd, true)
+}
+
+// runCommand runs an external command and logs its output unless it holds
+// secrets.
+func runCommand(cmd *exec.Cmd, secretOutput bool) ([]byte, error) {
+	Debug(fmt.Sprintf("running %s", strings.Join(RedactArgs(cmd.Args), " ")))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if Enabled(LevelTrace) && len(out) > 0 {
+		if secretOutput {
+			Trace(fmt.Sprintf("%s output: %d bytes not logged as they hold secrets", cmd.Args[0], len(out)))
+		} else {
+			Trace(fmt.Sprintf("%s output:\n%s", cmd.Args[0], out))
+		}
+	}
+	if err != nil {
+		Debug(fmt.Sprintf("%s failed: %s\n%s", cmd.Args[0], err, stderr.String()))
+		if reason := lastLine(stderr.String()); reason != "" {
+			return out, fmt.Errorf("%s: %w: %s", cmd.Args[0], err, reason)
+		}
+		return out, fmt.Errorf("%s: %w", cmd.Args[0], err)
+	}
+	if Enabled(LevelTrace) && stderr.Len() > 0 {
+		Trace(fmt.Sprintf("%s stderr:\n%s", cmd.Args[0], stderr.String()))
+	}
+
+	return out, nil
+}
+
+// lastLine returns the last non-empty line of some output.
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// RedactArgs returns command line arguments with the values of flags that
+// hold secrets, e.g. --password, replaced.
+func RedactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+	for i, arg := range redacted {
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "--") || !isSecretFlag(name) {
+			continue
+		}
+		if hasValue {
+			redacted[i] = fmt.Sprintf("--%s=%s", name, redactedValue)
+		} else if i+1 < len(redacted) {
+			redacted[i+1] = redactedValue
+		}
+	}
+
+	return redacted
+}
+
+// isSecretFlag returns whether a command line flag holds a secret.
+func isSecretFlag(name string) bool {
+	name = strings.ToLower(name)
+
+	return strings.Contains(name, "password") || strings.Contains(name, "token") || strings.Contains(name, "secret")
+}
@@ -0,0 +1,252 @@
+package output
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// maxLoggedBodySize is the number of bytes of a request or response body that
+// is logged.
+const maxLoggedBodySize = 16 * 1024
+
+// redactedValue replaces the value of secret fields in logged bodies.
+const redactedValue = "REDACTED"
+
+// secretFields are the JSON fields, compared case-insensitively, whose values
+// are redacted from logged bodies, e.g. user passwords and workload cluster
+// credentials.
+var secretFields = []string{"password", "token", "key", "certificate", "cacertificate", "secret"}
+
+// secretKeySuffixes are the endings, compared case-insensitively, of keys in
+// configuration values that hold secrets.
+var secretKeySuffixes = []string{"password", "token", "secret", "apikey", "privatekey", "certificate"}
+
+// manifestFields are the JSON fields, compared case-insensitively, that hold
+// Kubernetes manifests whose Secret data is redacted from logged bodies.
+var manifestFields = []string{"yamldocument"}
+
+// secretKindRegex matches the kind of a Secret in a YAML document.
+var secretKindRegex = regexp.MustCompile(`^kind:\s*["']?Secret["']?\s*$`)
+
+// loggingTransport logs HTTP requests and responses at trace level.
+type loggingTransport struct {
+	next http.RoundTripper
+}
+
+// NewLoggingTransport returns an http.RoundTripper that logs each request and
+// response, with secrets redacted, at trace level before passing it on to
+// next.
+func NewLoggingTransport(next http.RoundTripper) http.RoundTripper {
+	return &loggingTransport{next: next}
+}
+
+// RoundTrip logs a request and its response.
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !Enabled(LevelTrace) {
+		return t.next.RoundTrip(req)
+	}
+
+	var reqBody []byte
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = body
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	Trace(fmt.Sprintf("API request %s %s%s", req.Method, req.URL.Redacted(), loggedBody(reqBody)))
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		Trace(fmt.Sprintf("API request %s %s failed: %s", req.Method, req.URL.Redacted(), err))
+		return resp, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	Trace(fmt.Sprintf("API response %s %s: %s%s", req.Method, req.URL.Redacted(), resp.Status, loggedBody(respBody)))
+
+	return resp, nil
+}
+
+// loggedBody returns a body to append to a log message with secrets redacted
+// and truncated to maxLoggedBodySize.
+func loggedBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	logged := RedactJSON(body)
+	if len(logged) > maxLoggedBodySize {
+		logged = logged[:maxLoggedBodySize] + "... (truncated)"
+	}
+
+	return "\n" + logged
+}
+
+// RedactJSON returns a JSON document with the values of secret fields
+// replaced.  A body that isn't JSON is returned unchanged.
+func RedactJSON(body []byte) string {
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return string(body)
+	}
+	redacted, err := json.Marshal(redactValue(document))
+	if err != nil {
+		return string(body)
+	}
+
+	return string(redacted)
+}
+
+// redactValue replaces the values of secret fields in a decoded JSON value.
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for field, fieldValue := range v {
+			if isSecretField(field) && fieldValue != nil {
+				v[field] = redactedValue
+				continue
+			}
+			if manifest, ok := fieldValue.(string); ok && isManifestField(field) {
+				v[field] = RedactManifestSecrets(manifest)
+				continue
+			}
+			v[field] = redactValue(fieldValue)
+		}
+	case []interface{}:
+		for i, element := range v {
+			v[i] = redactValue(element)
+		}
+	}
+
+	return value
+}
+
+// isSecretField returns whether a JSON field holds a secret.
+func isSecretField(field string) bool {
+	field = strings.ToLower(field)
+	for _, secret := range secretFields {
+		if field == secret {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsSecretKey returns whether a key in configuration values, such as Helm
+// values, holds a secret because it ends in a secret word, e.g. adminPassword.
+// A bare key is not secret here as values use it for other things, e.g. the
+// key of a toleration.
+func IsSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, suffix := range secretKeySuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isManifestField returns whether a JSON field holds Kubernetes manifests.
+func isManifestField(field string) bool {
+	field = strings.ToLower(field)
+	for _, manifest := range manifestFields {
+		if field == manifest {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RedactManifestSecrets returns Kubernetes manifests with each value in the
+// data and stringData of Secrets replaced by a hash of it.  A diff of redacted
+// manifests shows which values changed without showing the values.
+func RedactManifestSecrets(manifests string) string {
+	lines := strings.Split(manifests, "\n")
+	var redacted []string
+	start := 0
+	for i := 0; i <= len(lines); i++ {
+		if i < len(lines) && strings.TrimSpace(lines[i]) != "---" {
+			continue
+		}
+		redacted = append(redacted, redactSecretDocument(lines[start:i])...)
+		if i < len(lines) {
+			redacted = append(redacted, lines[i])
+		}
+		start = i + 1
+	}
+
+	return strings.Join(redacted, "\n")
+}
+
+// redactSecretDocument redacts the data and stringData values of a single
+// YAML document if it is a Secret.
+func redactSecretDocument(lines []string) []string {
+	isSecret := false
+	for _, line := range lines {
+		if secretKindRegex.MatchString(line) {
+			isSecret = true
+			break
+		}
+	}
+	if !isSecret {
+		return lines
+	}
+
+	var redacted []string
+	inData := false
+	keyIndent := -1
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		switch {
+		case indent == 0 && trimmed != "" && !strings.HasPrefix(trimmed, "#"):
+			inData = trimmed == "data:" || trimmed == "stringData:"
+			keyIndent = -1
+		case inData && trimmed != "":
+			if keyIndent == -1 {
+				keyIndent = indent
+			}
+			key, value, found := strings.Cut(trimmed, ":")
+			if indent != keyIndent || !found {
+				break
+			}
+			// a block scalar continues on the lines indented below the key
+			value = strings.TrimSpace(value)
+			if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+				var block []string
+				for i+1 < len(lines) && (strings.TrimSpace(lines[i+1]) == "" ||
+					len(lines[i+1])-len(strings.TrimLeft(lines[i+1], " ")) > keyIndent) {
+					i++
+					block = append(block, lines[i])
+				}
+				value = strings.Join(block, "\n")
+			}
+			line = fmt.Sprintf("%s%s: %s", strings.Repeat(" ", indent), key, HashedValue(value))
+		}
+		redacted = append(redacted, line)
+	}
+
+	return redacted
+}
+
+// HashedValue returns a placeholder for a secret value that only changes when
+// the value does.
+func HashedValue(value string) string {
+	return fmt.Sprintf("%s-%x", redactedValue, sha256.Sum256([]byte(value)))[:len(redactedValue)+9]
+}
@@ -0,0 +1,188 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/logrusorgru/aurora"
+)
+
+// Level is the severity of a message.  Messages are written to stdout if their
+// level is at or below the console level and are always written to the log
+// file.
+type Level int
+
+const (
+	LevelError Level = iota
+	LevelWarn
+	LevelInfo
+	LevelDebug
+	LevelTrace
+)
+
+// String returns the name of the level.
+func (l Level) String() string {
+	switch l {
+	case LevelError:
+		return "error"
+	case LevelWarn:
+		return "warning"
+	case LevelInfo:
+		return "info"
+	case LevelDebug:
+		return "debug"
+	default:
+		return "trace"
+	}
+}
+
+var (
+	// consoleLevel is the highest level of message written to stdout.
+	consoleLevel = LevelInfo
+
+	// logFile receives every message with a timestamp if set.
+	logFile io.WriteCloser
+
+	// colorizer colours text output.  It is disabled when stdout is not a
+	// terminal or NO_COLOR is set.
+	colorizer = aurora.NewAurora(colorEnabled())
+
+	// outputMutex keeps messages from concurrent callers from interleaving.
+	outputMutex sync.Mutex
+)
+
+// SetVerbosity sets the console level from the --verbose and --quiet flags.
+// Quiet shows only errors and warnings, each verbose flag shows one more level
+// than info.
+func SetVerbosity(verbose int, quiet bool) error {
+	switch {
+	case quiet && verbose > 0:
+		return errors.New("quiet and verbose output cannot be used together")
+	case quiet:
+		consoleLevel = LevelWarn
+	case verbose >= 2:
+		consoleLevel = LevelTrace
+	case verbose == 1:
+		consoleLevel = LevelDebug
+	default:
+		consoleLevel = LevelInfo
+	}
+
+	return nil
+}
+
+// Enabled returns whether messages at a level are written anywhere, so
+// callers can skip building expensive messages.
+func Enabled(level Level) bool {
+	return level <= consoleLevel || logFile != nil
+}
+
+// SetLogFile opens a file, appending to it if it exists, that every message
+// is written to with a timestamp regardless of the console level.
+func SetLogFile(path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file %s: %w", path, err)
+	}
+	logFile = file
+
+	return nil
+}
+
+// CloseLogFile closes the log file if one is open.
+func CloseLogFile() {
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+
+	if logFile != nil {
+		logFile.Close()
+		logFile = nil
+	}
+}
+
+// colorEnabled returns whether text output is coloured.  Colour is disabled
+// if NO_COLOR is set, as described at https://no-color.org, or stdout is not a
+// terminal, e.g. when output is piped to a file.
+func colorEnabled() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Debug outputs a message with detail that is only useful when investigating
+// a problem.
+func Debug(message string) {
+	writeMessage(LevelDebug, jsonMessage{Level: LevelDebug.String(), Message: message},
+		colorizer.Faint(fmt.Sprintf("Debug: %s", message)).String())
+}
+
+// Trace outputs a message with the full detail of what tptctl is doing, such
+// as API requests and command output.
+func Trace(message string) {
+	writeMessage(LevelTrace, jsonMessage{Level: LevelTrace.String(), Message: message},
+		colorizer.Faint(fmt.Sprintf("Trace: %s", message)).String())
+}
+
+// writeMessage writes a message to the log file and, if its level is enabled,
+// to stdout as text or JSON.  Text is prefixed with the time when debug
+// output is enabled.
+func writeMessage(level Level, msg jsonMessage, text string) {
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+
+	now := time.Now()
+	writeLogFile(now, level, msg)
+	if level > consoleLevel {
+		return
+	}
+	writeConsole(now, msg, text)
+}
+
+// writeConsole writes a message to stdout as text or JSON.  The caller must
+// hold outputMutex.
+func writeConsole(now time.Time, msg jsonMessage, text string) {
+	if outputFormat == FormatJSON {
+		msg.Time = now.Format(time.RFC3339Nano)
+		printJSON(msg)
+		return
+	}
+	if consoleLevel >= LevelDebug {
+		text = fmt.Sprintf("%s %s", now.Format("15:04:05.000"), text)
+	}
+	fmt.Println(text)
+}
+
+// logOnly writes a message to the log file without writing it to stdout.
+func logOnly(level Level, message string) {
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+
+	writeLogFile(time.Now(), level, jsonMessage{Message: message})
+}
+
+// writeLogFile writes a message to the log file as a line of plain text with a
+// timestamp.  The caller must hold outputMutex.
+func writeLogFile(now time.Time, level Level, msg jsonMessage) {
+	if logFile == nil {
+		return
+	}
+	plain := msg.Message
+	if msg.Error != "" {
+		plain = fmt.Sprintf("%s: %s", plain, msg.Error)
+	}
+	if msg.Source != "" {
+		plain = fmt.Sprintf("[%s] %s", msg.Source, plain)
+	}
+	fmt.Fprintf(logFile, "%s %-7s %s\n", now.Format(time.RFC3339Nano), strings.ToUpper(level.String()), plain)
+}
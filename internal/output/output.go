@@ -1,32 +1,188 @@
 package output
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Format is a format in which tptctl writes its output.
+type Format string
 
-	. "github.com/logrusorgru/aurora"
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
 )
 
+// outputFormat is the format used by all output functions.
+var outputFormat = FormatText
+
+// SetFormat sets the format for all output.
+func SetFormat(format string) error {
+	switch Format(format) {
+	case FormatText, FormatJSON:
+		outputFormat = Format(format)
+	default:
+		return errors.New(fmt.Sprintf(
+			"unsupported output format '%s' - must be one of %s", format,
+			[]Format{FormatText, FormatJSON}))
+	}
+
+	return nil
+}
+
+// jsonMessage is a single line of output in JSON format.
+type jsonMessage struct {
+	Time    string              `json:"time,omitempty"`
+	Level   string              `json:"level"`
+	Event   string              `json:"event,omitempty"`
+	Source  string              `json:"source,omitempty"`
+	Message string              `json:"message"`
+	Error   string              `json:"error,omitempty"`
+	Rows    []map[string]string `json:"rows,omitempty"`
+}
+
+// printJSON writes a message as a single line of JSON.
+func printJSON(msg jsonMessage) {
+	msgJSON, err := json.Marshal(&msg)
+	if err != nil {
+		fmt.Printf("{\"level\":\"error\",\"message\":\"failed to marshal output to JSON: %s\"}\n", err)
+		return
+	}
+	fmt.Println(string(msgJSON))
+}
+
 // Error returns a formatted error message in red.
 func Error(message string, err error) {
+	msg := jsonMessage{Level: LevelError.String(), Message: message}
+	text := fmt.Sprintf("Error: %s\n", message)
 	if err != nil {
-		fmt.Println(Red(fmt.Sprintf("Error: %s\n%s", message, err)))
-	} else {
-		fmt.Println(Red(fmt.Sprintf("Error: %s\n", message)))
+		msg.Error = err.Error()
+		text = fmt.Sprintf("Error: %s\n%s", message, err)
 	}
+	writeMessage(LevelError, msg, colorizer.Red(text).String())
 }
 
 // Info returns a formatted info message.
 func Info(message string) {
-	fmt.Printf("Info: %s\n", message)
+	writeMessage(LevelInfo, jsonMessage{Level: LevelInfo.String(), Message: message},
+		fmt.Sprintf("Info: %s", message))
 }
 
 // Warning returns a formatted warning message in yellow.
 func Warning(message string) {
-	fmt.Println(Yellow(fmt.Sprintf("Warning: %s\n", message)))
+	writeMessage(LevelWarn, jsonMessage{Level: LevelWarn.String(), Message: message},
+		colorizer.Yellow(fmt.Sprintf("Warning: %s\n", message)).String())
 }
 
 // Complete returns a formatted message in green.  Used when operations are
 // finished.
 func Complete(message string) {
-	fmt.Println(Green(fmt.Sprintf("Complete: %s\n", message)))
+	writeMessage(LevelInfo, jsonMessage{Level: LevelInfo.String(), Event: "complete", Message: message},
+		colorizer.Green(fmt.Sprintf("Complete: %s\n", message)).String())
+}
+
+// Secret outputs a message with a secret, such as a generated password, that
+// the user can't get any other way.  It is shown even with quiet output and
+// the secret is redacted from the log file.
+func Secret(message, secret string) {
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+
+	now := time.Now()
+	writeLogFile(now, LevelInfo, jsonMessage{Message: fmt.Sprintf("%s: %s", message, redactedValue)})
+	message = fmt.Sprintf("%s: %s", message, secret)
+	writeConsole(now, jsonMessage{Level: LevelInfo.String(), Event: "secret", Message: message},
+		fmt.Sprintf("Info: %s", message))
+}
+
+// Progress returns a formatted progress message from a long-running operation,
+// such as provisioning infra.  In JSON format it is emitted as a progress event
+// that includes the source of the message.
+func Progress(source, message string) {
+	message = strings.TrimSpace(message)
+	writeMessage(LevelInfo, jsonMessage{Level: LevelInfo.String(), Event: "progress", Source: source, Message: message},
+		fmt.Sprintf("Info: %s", message))
+}
+
+// Diff outputs a unified diff with removed lines in red and added lines in
+// green.  In JSON format it is emitted as a single diff event.  Diffs are
+// shown even with quiet output because they are confirmed before changes are
+// made.
+func Diff(diff string) {
+	logOnly(LevelInfo, "diff:\n"+diff)
+	if outputFormat == FormatJSON {
+		printJSON(jsonMessage{Level: LevelInfo.String(), Event: "diff", Message: diff})
+		return
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			fmt.Println(colorizer.Bold(line))
+		case strings.HasPrefix(line, "@@"):
+			fmt.Println(colorizer.Cyan(line))
+		case strings.HasPrefix(line, "-"):
+			fmt.Println(colorizer.Red(line))
+		case strings.HasPrefix(line, "+"):
+			fmt.Println(colorizer.Green(line))
+		default:
+			fmt.Println(line)
+		}
+	}
+}
+
+// Table outputs a message followed by a table with a header line and a line
+// for each row.  In JSON format it is emitted as a single event with each row
+// as an object keyed by the lower case headers.
+func Table(event, message string, headers []string, rows [][]string) {
+	var table strings.Builder
+	writer := tabwriter.NewWriter(&table, 4, 4, 4, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(headers, "\t"))
+	jsonRows := make([]map[string]string, 0, len(rows))
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+		jsonRow := make(map[string]string)
+		for i, header := range headers {
+			if i < len(row) {
+				jsonRow[strings.ToLower(header)] = row[i]
+			}
+		}
+		jsonRows = append(jsonRows, jsonRow)
+	}
+	writer.Flush()
+
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+
+	now := time.Now()
+	writeLogFile(now, LevelInfo, jsonMessage{Message: fmt.Sprintf("%s\n%s", message, table.String())})
+	if LevelInfo > consoleLevel {
+		return
+	}
+	writeConsole(now, jsonMessage{Level: LevelInfo.String(), Event: event, Message: message, Rows: jsonRows},
+		fmt.Sprintf("Info: %s\n%s", message, strings.TrimSuffix(table.String(), "\n")))
+}
+
+// Confirm asks the user a yes/no question and returns true if they answer yes.
+// Input cannot be requested when output is in JSON format so the answer is
+// always no.
+func Confirm(question string) bool {
+	if outputFormat == FormatJSON {
+		return false
+	}
+
+	fmt.Printf("%s [y/N]: ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes"
 }
@@ -0,0 +1,89 @@
+package output
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	headers := []string{"ACTION", "NAME"}
+	rows := [][]string{{"create", "web-definition"}, {"skip", "web-instance"}}
+
+	testCases := []struct {
+		name   string
+		format Format
+		quiet  bool
+		check  func(t *testing.T, output string)
+	}{
+		{
+			name:   "text",
+			format: FormatText,
+			check: func(t *testing.T, output string) {
+				expected := "Info: plan:\nACTION    NAME\ncreate    web-definition\nskip      web-instance\n"
+				if output != expected {
+					t.Errorf("expected output %q, got %q", expected, output)
+				}
+			},
+		},
+		{
+			name:   "JSON",
+			format: FormatJSON,
+			check: func(t *testing.T, output string) {
+				var msg jsonMessage
+				if err := json.Unmarshal([]byte(output), &msg); err != nil {
+					t.Fatalf("expected a single JSON message, got %q: %s", output, err)
+				}
+				expected := []map[string]string{
+					{"action": "create", "name": "web-definition"},
+					{"action": "skip", "name": "web-instance"},
+				}
+				if msg.Event != "plan" || msg.Message != "plan:" || !reflect.DeepEqual(msg.Rows, expected) {
+					t.Errorf("expected plan event with rows %v, got %+v", expected, msg)
+				}
+			},
+		},
+		{
+			name:   "quiet",
+			format: FormatText,
+			quiet:  true,
+			check: func(t *testing.T, output string) {
+				if output != "" {
+					t.Errorf("expected no output, got %q", output)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			outputFormat = tc.format
+			if err := SetVerbosity(0, tc.quiet); err != nil {
+				t.Fatalf("failed to set verbosity: %s", err)
+			}
+			t.Cleanup(func() {
+				outputFormat = FormatText
+				SetVerbosity(0, false)
+			})
+
+			// capture stdout
+			reader, writer, err := os.Pipe()
+			if err != nil {
+				t.Fatalf("failed to create pipe: %s", err)
+			}
+			stdout := os.Stdout
+			os.Stdout = writer
+			Table("plan", "plan:", headers, rows)
+			os.Stdout = stdout
+			writer.Close()
+			output, err := ioutil.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("failed to read output: %s", err)
+			}
+
+			tc.check(t, string(output))
+		})
+	}
+}
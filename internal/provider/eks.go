@@ -3,32 +3,58 @@ package provider
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/nukleros/eks-cluster/pkg/resource"
 
+	"github.com/threeport/tptctl/internal/api"
 	"github.com/threeport/tptctl/internal/install"
 	qout "github.com/threeport/tptctl/internal/output"
 )
 
 // CreateControlPlaneOnEKS creates an EKS cluster on AWS and installs the
-// threeport control plane.
-func (c *ControlPlane) CreateControlPlaneOnEKS(providerConfigDir string) (string, error) {
+// threeport control plane.  Each completed step is recorded in a state file so
+// that, if resume is true, a previously interrupted creation continues from
+// the first incomplete step.  If ctx is cancelled, e.g. by the user
+// interrupting tptctl, creation stops and the user is offered a rollback of
+// the AWS resources created so far.
+func (c *ControlPlane) CreateControlPlaneOnEKS(
+	ctx context.Context,
+	providerConfigDir string,
+	resume bool,
+) (string, error) {
 	var threeportAPIEndpoint string
 
+	// load the state of any previous attempt
+	state, err := c.createState(providerConfigDir, resume)
+	if err != nil {
+		return threeportAPIEndpoint, err
+	}
+	if resume {
+		qout.Info(fmt.Sprintf("Resuming creation of Threeport instance %s", c.InstanceName))
+	}
+
 	// create and configure eks resource config
 	resourceConfig := resource.NewResourceConfig()
 	resourceConfig.Name = c.ThreeportClusterName()
 	resourceConfig.AWSAccountID = c.ProviderAccountID
+	resourceConfig.KubernetesVersion = c.KubernetesVersion
 	resourceConfig.MinNodes = c.MinClusterNodes
 	resourceConfig.MaxNodes = c.MaxClusterNodes
-	resourceConfig.InstanceTypes = []string{c.DefaultAWSInstanceType}
-	resourceConfig.Tags = map[string]string{"provisioner": "tptctl"}
+	resourceConfig.InitialNodes = c.DesiredClusterNodes
+	resourceConfig.InstanceTypes = c.instanceTypes()
+	resourceConfig.Tags = c.resourceTags()
+	if c.SpotInstances {
+		resourceConfig.CapacityType = "SPOT"
+	}
 	if c.RootDomainName != "" {
 		resourceConfig.DNSManagement = true
 		resourceConfig.DNSManagementServiceAccount = resource.DNSManagementServiceAccount{
@@ -37,77 +63,206 @@ func (c *ControlPlane) CreateControlPlaneOnEKS(providerConfigDir string) (string
 		}
 	}
 
-	// create eks resource client
-	msgChan := make(chan string)
-	go outputMessages(&msgChan)
-	ctx := context.Background()
-	cfg, err := config.LoadDefaultConfig(ctx)
+	// load AWS config - the region is the one given for the control plane,
+	// else the one from the AWS profile or environment, else the eks-cluster
+	// library's default
+	cfg, err := c.awsConfig(ctx, c.AWSRegion)
 	if err != nil {
-		return threeportAPIEndpoint, fmt.Errorf("failed to load default config for AWS: %w")
+		return threeportAPIEndpoint, err
+	}
+	if cfg.Region == "" {
+		qout.Info(fmt.Sprintf("No AWS region configured - using %s", resourceConfig.Region))
+		cfg.Region = resourceConfig.Region
 	}
-	resourceClient := resource.ResourceClient{&msgChan, ctx, &cfg}
+	setResourceConfigRegion(resourceConfig, cfg.Region)
 
-	// create resources in aws
-	qout.Info("Creating resources for EKS cluster...")
-	inventory, createErr := resourceClient.CreateResourceStack(resourceConfig)
+	// record the region used so that a resumed creation uses the same one
+	if state.Settings.AWSRegion != cfg.Region {
+		c.AWSRegion = cfg.Region
+		state.Settings.AWSRegion = cfg.Region
+		if err := state.write(); err != nil {
+			return threeportAPIEndpoint, err
+		}
+	}
 
-	// write inventory file
-	// important: write file even if there was some error so we can clean up
-	inventoryJSON, err := json.MarshalIndent(inventory, "", " ")
-	if err != nil {
-		return threeportAPIEndpoint, fmt.Errorf("failed to marshal inventory to JSON: %w")
+	// create resources in aws
+	var inventory *resource.ResourceInventory
+	if resume && !state.Completed(CreateStepResourceStack) {
+		// the inventory is written as resources are created so check what an
+		// interrupted creation left behind
+		partialInventory, err := c.readInventory(providerConfigDir)
+		switch {
+		case err == nil && inventoryComplete(partialInventory):
+			qout.Info("EKS cluster resources already created - reading inventory")
+			if err := state.Complete(CreateStepResourceStack); err != nil {
+				return threeportAPIEndpoint, err
+			}
+		case err == nil:
+			// the eks-cluster library can't continue a partially created
+			// resource stack so the resources are deleted and created again
+			qout.Info("Deleting EKS cluster resources partially created by the interrupted creation...")
+			if err := c.deleteResourceStack(ctx, cfg, partialInventory, providerConfigDir); err != nil {
+				return threeportAPIEndpoint, fmt.Errorf("failed to delete partially created resources: %w", err)
+			}
+		case !os.IsNotExist(err):
+			return threeportAPIEndpoint, err
+		}
 	}
-	ioutil.WriteFile(c.inventoryFilePath(providerConfigDir), inventoryJSON, 0644)
+	if state.Completed(CreateStepResourceStack) {
+		// reuse the inventory of the resources already created
+		existingInventory, err := c.readInventory(providerConfigDir)
+		if err != nil {
+			return threeportAPIEndpoint, err
+		}
+		inventory = existingInventory
+	} else {
+		qout.Info("Creating resources for EKS cluster...")
+		var createErr error
+		inventory, createErr = c.createResourceStack(ctx, cfg, resourceConfig, providerConfigDir)
 
-	// handle any resource creation error
-	if createErr != nil {
-		qout.Error("Problem encountered creating resources. Deleting resources that were created...", err)
-		if deleteErr := resourceClient.DeleteResourceStack(inventory); deleteErr != nil {
-			return threeportAPIEndpoint, fmt.Errorf("\nerror creating resources: %w\nerror deleting resources: %w", err, deleteErr)
+		// handle any resource creation error
+		if createErr != nil {
+			if ctx.Err() != nil {
+				// the user interrupted creation so let them decide whether to
+				// keep what has been created
+				qout.Warning("EKS cluster resource creation interrupted")
+				if !qout.Confirm("Delete the AWS resources created so far?") {
+					qout.Info(fmt.Sprintf(
+						"Inventory of resources created written to %s - continue with `tptctl create control-plane --name %s --provider eks --resume` or delete them with `tptctl delete control-plane --name %s`",
+						c.inventoryFilePath(providerConfigDir), c.InstanceName, c.InstanceName,
+					))
+					return threeportAPIEndpoint, fmt.Errorf("error creating resources: %w", createErr)
+				}
+			}
+			qout.Error("Problem encountered creating resources. Deleting resources that were created...", createErr)
+
+			// the original context may be cancelled so the rollback gets its own
+			if deleteErr := c.deleteResourceStack(context.Background(), cfg, inventory, providerConfigDir); deleteErr != nil {
+				return threeportAPIEndpoint, fmt.Errorf("\nerror creating resources: %w\nerror deleting resources: %w", createErr, deleteErr)
+			}
+			return threeportAPIEndpoint, fmt.Errorf("error creating resources: %w", createErr)
+		}
+		if err := state.Complete(CreateStepResourceStack); err != nil {
+			return threeportAPIEndpoint, err
 		}
-		return threeportAPIEndpoint, fmt.Errorf("error creating resources: %w", createErr)
+	}
+
+	if err := c.checkInterrupted(ctx, providerConfigDir); err != nil {
+		return threeportAPIEndpoint, err
 	}
 
 	// update kubeconfig
-	updateKubeconfig := exec.Command(
-		"aws",
-		"eks",
-		"update-kubeconfig",
-		"--name",
-		c.ThreeportClusterName(),
-		"--kubeconfig",
-		c.kubeconfigFilePath(providerConfigDir),
-	)
-	updateKubeconfigOut, err := updateKubeconfig.CombinedOutput()
-	if err != nil {
-		qout.Error(fmt.Sprintf("aws eks error: %s", updateKubeconfigOut), nil)
-		return threeportAPIEndpoint, fmt.Errorf("failed to update kubeconfig: %w", err)
+	if !state.Completed(CreateStepKubeconfig) {
+		updateKubeconfig := exec.Command(
+			"aws",
+			"eks",
+			"update-kubeconfig",
+			"--name",
+			c.ThreeportClusterName(),
+			"--kubeconfig",
+			c.KubeconfigFilePath(providerConfigDir),
+			"--region",
+			inventory.Region,
+		)
+		if c.AWSProfile != "" {
+			updateKubeconfig.Args = append(updateKubeconfig.Args, "--profile", c.AWSProfile)
+		}
+		if _, err := qout.RunCommand(updateKubeconfig); err != nil {
+			return threeportAPIEndpoint, fmt.Errorf("failed to update kubeconfig: %w", err)
+		}
+		qout.Info("kubeconfig updated to include new EKS cluster")
+		if err := state.Complete(CreateStepKubeconfig); err != nil {
+			return threeportAPIEndpoint, err
+		}
+	}
+
+	if err := c.checkInterrupted(ctx, providerConfigDir); err != nil {
+		return threeportAPIEndpoint, err
 	}
-	qout.Info("kubeconfig updated to include new EKS cluster")
 
 	// install support services operator
-	loadBalancerURL, err := install.InstallSupportServicesOperator(
-		c.kubeconfigFilePath(providerConfigDir),
-		inventory.DNSManagementRole.RoleARN,
-		c.RootDomainName,
-		c.AdminEmail,
-	)
-	if err != nil {
-		return threeportAPIEndpoint, fmt.Errorf("failed to install support services operator on EKS cluster: %w", err)
+	if !state.Completed(CreateStepSupportServices) {
+		loadBalancerURL, err := install.InstallSupportServicesOperator(
+			c.KubeconfigFilePath(providerConfigDir),
+			inventory.DNSManagementRole.RoleARN,
+			c.RootDomainName,
+			c.AdminEmail,
+		)
+		if err != nil {
+			return threeportAPIEndpoint, fmt.Errorf("failed to install support services operator on EKS cluster: %w", err)
+		}
+		state.LoadBalancerURL = loadBalancerURL
+		if err := state.Complete(CreateStepSupportServices); err != nil {
+			return threeportAPIEndpoint, err
+		}
+	}
+
+	if err := c.checkInterrupted(ctx, providerConfigDir); err != nil {
+		return threeportAPIEndpoint, err
 	}
 
 	// install threeport API
-	if err := install.InstallAPI(
-		c.kubeconfigFilePath(providerConfigDir), c.ThreeportClusterName(), c.RootDomainName,
-		loadBalancerURL,
-	); err != nil {
-		return threeportAPIEndpoint, fmt.Errorf("failed to install threeport API on EKS cluster: %w", err)
+	if !state.Completed(CreateStepThreeportAPI) {
+		if err := install.InstallAPI(
+			c.KubeconfigFilePath(providerConfigDir), c.ThreeportClusterName(), c.RootDomainName,
+			state.LoadBalancerURL,
+		); err != nil {
+			return threeportAPIEndpoint, fmt.Errorf("failed to install threeport API on EKS cluster: %w", err)
+		}
+		if err := state.Complete(CreateStepThreeportAPI); err != nil {
+			return threeportAPIEndpoint, err
+		}
+	}
+	if c.RootDomainName != "" {
+		threeportAPIEndpoint = fmt.Sprintf("https://%s.%s", c.ThreeportClusterName(), c.RootDomainName)
+	} else {
+		// without a root domain there is no TLS certificate so the API is
+		// served over plain HTTP on the load balancer's hostname
+		threeportAPIEndpoint = fmt.Sprintf("http://%s", state.LoadBalancerURL)
+	}
+
+	if err := c.checkInterrupted(ctx, providerConfigDir); err != nil {
+		return threeportAPIEndpoint, err
 	}
-	threeportAPIEndpoint = fmt.Sprintf("https://%s.%s", c.ThreeportClusterName(), c.RootDomainName)
 
 	// install workload controller
-	if err := install.InstallWorkloadController(c.kubeconfigFilePath(providerConfigDir)); err != nil {
-		return threeportAPIEndpoint, fmt.Errorf("failed to install workload controller on EKS cluster: %w", err)
+	if !state.Completed(CreateStepWorkloadController) {
+		if err := install.InstallWorkloadController(c.KubeconfigFilePath(providerConfigDir)); err != nil {
+			return threeportAPIEndpoint, fmt.Errorf("failed to install workload controller on EKS cluster: %w", err)
+		}
+		if err := state.Complete(CreateStepWorkloadController); err != nil {
+			return threeportAPIEndpoint, err
+		}
+	}
+
+	if err := c.checkInterrupted(ctx, providerConfigDir); err != nil {
+		return threeportAPIEndpoint, err
+	}
+
+	// add superuser - this is repeated when resuming so that the superuser ID
+	// is known for the steps that follow
+	client := api.NewClient(threeportAPIEndpoint)
+	if err := c.bootstrapSuperuser(client); err != nil {
+		return threeportAPIEndpoint, err
+	}
+
+	if err := c.checkInterrupted(ctx, providerConfigDir); err != nil {
+		return threeportAPIEndpoint, err
+	}
+
+	// add forward proxy definition
+	if !state.Completed(CreateStepForwardProxy) {
+		if err := c.registerForwardProxy(client, c.Superuser.ID); err != nil {
+			return threeportAPIEndpoint, err
+		}
+		if err := state.Complete(CreateStepForwardProxy); err != nil {
+			return threeportAPIEndpoint, err
+		}
+	}
+
+	// all steps complete - nothing left to resume
+	if err := state.remove(); err != nil {
+		return threeportAPIEndpoint, err
 	}
 
 	return threeportAPIEndpoint, nil
@@ -115,60 +270,211 @@ func (c *ControlPlane) CreateControlPlaneOnEKS(providerConfigDir string) (string
 
 // DeleteControlPlaneOnEKS deletes the ingress component of a control plane
 // cluster to remove any load balancers and then removes the infra to completely
-// destroy an instance of a threeport control plane.
-func (c *ControlPlane) DeleteControlPlaneOnEKS(providerConfigDir string) error {
-	// delete ingress resource to clean up DNS records
-	// we do not return an error here so that the deltion of AWS resources
-	// continues
-	if err := install.UninstallAPIIngress(c.kubeconfigFilePath(providerConfigDir)); err != nil {
-		qout.Error("Failed to delete threeport API ingress resource in Kubernetes", err)
-		qout.Warning("This may result in a dangling Route53 resources in AWS - recommend checking your AWS account")
-		qout.Info("Continuing with control plane deletion...")
-	}
-
-	// delete ingress component to remove cloud load balancer
-	// we do not return an error here so that the deltion of AWS resources
-	// continues
-	if err := install.UninstallIngressComponent(c.kubeconfigFilePath(providerConfigDir)); err != nil {
-		qout.Error("Failed to delete support services ingress component", err)
-		qout.Warning("This may result in a dangling load balancer resource in AWS - recommend checking your AWS account")
-		qout.Info("Continuing with control plane deletion...")
-	}
-
-	// get resource inventory
-	var resourceInventory resource.ResourceInventory
-	inventoryJSON, err := ioutil.ReadFile(c.inventoryFilePath(providerConfigDir))
+// destroy an instance of a threeport control plane.  Once the infra is removed
+// the AWS account is audited for orphaned resources.  If purgeOrphans is true,
+// any that are found are deleted.  An error is returned if any orphaned
+// resources remain so that the caller retains the instance config.
+func (c *ControlPlane) DeleteControlPlaneOnEKS(
+	ctx context.Context,
+	providerConfigDir string,
+	purgeOrphans bool,
+) error {
+	cfg, err := c.awsConfig(ctx, "")
 	if err != nil {
-		return fmt.Errorf("failed to read inventory file: %w", err)
+		return err
 	}
-	json.Unmarshal(inventoryJSON, &resourceInventory)
 
-	// create eks resource client
-	msgChan := make(chan string)
-	go outputMessages(&msgChan)
-	ctx := context.Background()
-	cfg, err := config.LoadDefaultConfig(ctx)
-	resourceClient := resource.ResourceClient{&msgChan, ctx, &cfg}
+	// get resource inventory - if it no longer exists the resource stack was
+	// deleted by a previous run and only the orphaned resource audit remains
+	var auditTarget AuditTarget
+	resourceInventory, err := c.readInventory(providerConfigDir)
+	switch {
+	case err == nil:
+		if resourceInventory.Region != "" {
+			cfg.Region = resourceInventory.Region
+		}
+
+		// delete ingress resource to clean up DNS records
+		// we do not return an error here so that the deltion of AWS resources
+		// continues
+		if err := install.UninstallAPIIngress(c.KubeconfigFilePath(providerConfigDir)); err != nil {
+			qout.Error("Failed to delete threeport API ingress resource in Kubernetes", err)
+			qout.Warning("This may result in dangling Route53 records in AWS - they will be reported once the cluster is deleted")
+			qout.Info("Continuing with control plane deletion...")
+		}
+
+		// delete ingress component to remove cloud load balancer
+		// we do not return an error here so that the deltion of AWS resources
+		// continues
+		if err := install.UninstallIngressComponent(c.KubeconfigFilePath(providerConfigDir)); err != nil {
+			qout.Error("Failed to delete support services ingress component", err)
+			qout.Warning("This may result in a dangling load balancer in AWS - it will be reported once the cluster is deleted")
+			qout.Info("Continuing with control plane deletion...")
+		}
+
+		// delete resources
+		qout.Info("Deleting resources for EKS cluster...")
+		if err := c.deleteResourceStack(ctx, cfg, resourceInventory, providerConfigDir); err != nil {
+			return fmt.Errorf("failed to delete EKS resources: %w", err)
+		}
+
+		// record what to audit in case the audit has to be re-run and remove
+		// the inventory of the deleted resources
+		auditTarget = AuditTarget{
+			ClusterName: c.ThreeportClusterName(),
+			Region:      resourceInventory.Region,
+			VPCID:       resourceInventory.VPCID,
+			RoleNames: []string{
+				resourceInventory.ClusterRole.RoleName,
+				resourceInventory.WorkerRole.RoleName,
+				resourceInventory.DNSManagementRole.RoleName,
+			},
+			RootDomainName: c.RootDomainName,
+		}
+		auditJSON, err := json.MarshalIndent(&auditTarget, "", " ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit target to JSON: %w", err)
+		}
+		if err := ioutil.WriteFile(c.auditFilePath(providerConfigDir), auditJSON, 0644); err != nil {
+			return fmt.Errorf("failed to write audit file: %w", err)
+		}
+		if err := os.Remove(c.inventoryFilePath(providerConfigDir)); err != nil {
+			return fmt.Errorf("failed to remove inventory file: %w", err)
+		}
+	case os.IsNotExist(err):
+		target, recorded, err := c.readAuditTarget(providerConfigDir, cfg.Region)
+		if err != nil {
+			return err
+		}
+		auditTarget = *target
+		if recorded {
+			qout.Info("EKS cluster resources already deleted")
+			break
+		}
+		// the instance was created before resources were recorded or its
+		// provider config directory was lost
+		qout.Warning(fmt.Sprintf(
+			"No inventory of AWS resources found for threeport instance %s in %s",
+			c.InstanceName, providerConfigDir,
+		))
+		if auditTarget.Region == "" {
+			qout.Warning("No AWS region configured so AWS resources can't be checked - delete any that remain manually")
+			return c.removeEKSFiles(providerConfigDir)
+		}
+		qout.Info(fmt.Sprintf("Looking up AWS resources for the instance by tag in region %s", auditTarget.Region))
+	default:
+		return err
+	}
+
+	// audit the AWS account for anything left behind
+	qout.Info("Checking for orphaned AWS resources...")
+	if auditTarget.Region != "" {
+		cfg.Region = auditTarget.Region
+	}
+	auditClient := NewAWSAuditClient(cfg)
+	orphans, err := AuditEKSResources(ctx, auditClient, &auditTarget)
+	if err != nil {
+		return fmt.Errorf("failed to audit AWS resources: %w", err)
+	}
+	if len(orphans) > 0 && purgeOrphans {
+		qout.Info(fmt.Sprintf("Deleting %d orphaned AWS resources...", len(orphans)))
+		if err := PurgeOrphanedResources(ctx, auditClient, orphans); err != nil {
+			qout.Error("Failed to delete some orphaned AWS resources", err)
+		}
+		orphans, err = AuditEKSResources(ctx, auditClient, &auditTarget)
+		if err != nil {
+			return fmt.Errorf("failed to audit AWS resources: %w", err)
+		}
+	}
+	if len(orphans) > 0 {
+		for _, orphan := range orphans {
+			qout.Warning(fmt.Sprintf("Orphaned AWS resource: %s", orphan))
+		}
+		qout.Info("Re-run with --purge-orphans to delete them or delete them manually and re-run")
+		return errors.New(fmt.Sprintf(
+			"%d AWS resources remain for threeport instance %s", len(orphans), c.InstanceName))
+	}
+	qout.Info("No orphaned AWS resources found")
 
-	// delete resources
-	qout.Info("Deleting resources for EKS cluster...")
-	if err := resourceClient.DeleteResourceStack(&resourceInventory); err != nil {
-		return fmt.Errorf("failed to delete EKS resources: %w", err)
+	return c.removeEKSFiles(providerConfigDir)
+}
+
+// readAuditTarget returns the record of a deleted control plane's AWS
+// resources from the audit file and true.  If there is no audit file, a target
+// that finds the control plane's resources by their tags in region is returned
+// with false.
+func (c *ControlPlane) readAuditTarget(providerConfigDir, region string) (*AuditTarget, bool, error) {
+	auditJSON, err := ioutil.ReadFile(c.auditFilePath(providerConfigDir))
+	if os.IsNotExist(err) {
+		return &AuditTarget{
+			ClusterName:    c.ThreeportClusterName(),
+			Region:         region,
+			RootDomainName: c.RootDomainName,
+		}, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read audit file: %w", err)
+	}
+	var auditTarget AuditTarget
+	if err := json.Unmarshal(auditJSON, &auditTarget); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal audit file: %w", err)
 	}
 
-	// remove inventory file
-	if err := os.Remove(c.inventoryFilePath(providerConfigDir)); err != nil {
-		fmt.Errorf("failed to remove inventory file: %w", err)
+	return &auditTarget, true, nil
+}
+
+// removeEKSFiles removes the files in the provider config directory for a
+// deleted control plane.
+func (c *ControlPlane) removeEKSFiles(providerConfigDir string) error {
+	// remove audit file
+	if err := os.Remove(c.auditFilePath(providerConfigDir)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove audit file: %w", err)
 	}
 
 	// remove kubeconfig
-	if err := os.Remove(c.kubeconfigFilePath(providerConfigDir)); err != nil {
-		fmt.Errorf("failed to remove kubeconfig file: %w", err)
+	if err := os.Remove(c.KubeconfigFilePath(providerConfigDir)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove kubeconfig file: %w", err)
+	}
+
+	// remove any state left from an incomplete creation
+	state := CreateState{path: c.createStateFilePath(providerConfigDir)}
+	if err := state.remove(); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// awsConfig loads the AWS config using the AWS profile for the control plane,
+// if set.  If region is not empty it overrides the region in the AWS config.
+func (c *ControlPlane) awsConfig(ctx context.Context, region string) (aws.Config, error) {
+	var options []func(*config.LoadOptions) error
+	if c.AWSProfile != "" {
+		options = append(options, config.WithSharedConfigProfile(c.AWSProfile))
+	}
+	if region != "" {
+		options = append(options, config.WithRegion(region))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to load default config for AWS: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// setResourceConfigRegion sets the region for the EKS cluster resources and
+// moves the availability zones into that region.
+func setResourceConfigRegion(resourceConfig *resource.ResourceConfig, region string) {
+	resourceConfig.Region = region
+	for i, az := range resourceConfig.AvailabilityZones {
+		// zone names are the region followed by a single letter
+		zoneLetter := az.Zone[len(az.Zone)-1:]
+		resourceConfig.AvailabilityZones[i].Zone = region + zoneLetter
+	}
+}
+
 // inventoryFilePath returns the default inventory filepath.  The inventory
 // contains all the cloud provider IDs for infra created for a threeport control
 // plane.
@@ -179,21 +485,269 @@ func (c *ControlPlane) inventoryFilePath(providerConfigDir string) string {
 	)
 }
 
-// kubeconfigFilePath returns a filepath for a kubeconfig to connect to the
-// Kubernetes API in a threeport control plane cluster.  This filepath is unique
-// to each threeport instance name so that each gets its own distinct file.
-func (c *ControlPlane) kubeconfigFilePath(providerConfigDir string) string {
+// auditFilePath returns the filepath for the record of a deleted control
+// plane's AWS resources that is used to audit for orphaned resources.
+func (c *ControlPlane) auditFilePath(providerConfigDir string) string {
 	return filepath.Join(
 		providerConfigDir,
-		fmt.Sprintf("kubeconfig-%s", c.ThreeportClusterName()),
+		fmt.Sprintf("eks-audit-%s.json", c.ThreeportClusterName()),
 	)
 }
 
-// outputMessages takes messages received on a channel from the eks-cluster
-// library as it provisions infra and prints it to the console for the user.
-func outputMessages(msgChan *chan string) {
+// createResourceStack creates the AWS resources for an EKS cluster.  Progress
+// messages from the eks-cluster library are output as they are received and
+// the inventory file is updated with each resource the messages report so
+// that resources can be cleaned up, or creation resumed, even if tptctl exits
+// before creation finishes.
+func (c *ControlPlane) createResourceStack(
+	ctx context.Context,
+	cfg aws.Config,
+	resourceConfig *resource.ResourceConfig,
+	providerConfigDir string,
+) (*resource.ResourceInventory, error) {
+	msgChan := make(chan string)
+	resourceClient := resource.ResourceClient{
+		MessageChan: &msgChan,
+		Context:     ctx,
+		AWSConfig:   &cfg,
+	}
+
+	type createResult struct {
+		inventory *resource.ResourceInventory
+		err       error
+	}
+	resultChan := make(chan createResult)
+	go func() {
+		inventory, err := resourceClient.CreateResourceStack(resourceConfig)
+		resultChan <- createResult{inventory, err}
+	}()
+
+	inventory := resource.ResourceInventory{Region: resourceConfig.Region}
+	for {
+		select {
+		case msg := <-msgChan:
+			qout.Progress("eks", msg)
+			if recordInventoryMessage(&inventory, msg) {
+				if err := c.writeInventory(providerConfigDir, &inventory); err != nil {
+					qout.Error("Failed to update inventory file", err)
+				}
+			}
+		case result := <-resultChan:
+			// the inventory returned is complete even if there was an error
+			// important: write file even if there was some error so we can
+			// clean up
+			if result.inventory != nil {
+				inventory = *result.inventory
+			}
+			if err := c.writeInventory(providerConfigDir, &inventory); err != nil {
+				qout.Error("Failed to write inventory file", err)
+			}
+			return &inventory, result.err
+		}
+	}
+}
+
+// deleteResourceStack deletes the AWS resources for an EKS cluster, outputting
+// progress messages from the eks-cluster library as they are received.  The
+// inventory file is updated as resources are deleted so that an interrupted
+// deletion can be re-run.
+func (c *ControlPlane) deleteResourceStack(
+	ctx context.Context,
+	cfg aws.Config,
+	inventory *resource.ResourceInventory,
+	providerConfigDir string,
+) error {
+	msgChan := make(chan string)
+	resourceClient := resource.ResourceClient{
+		MessageChan: &msgChan,
+		Context:     ctx,
+		AWSConfig:   &cfg,
+	}
+
+	errChan := make(chan error)
+	go func() {
+		errChan <- resourceClient.DeleteResourceStack(inventory)
+	}()
+
+	// the inventory passed in is left intact as it is used to audit for
+	// orphaned resources once deletion is done
+	remaining := *inventory
 	for {
-		msg := <-*msgChan
-		qout.Info(msg)
+		select {
+		case msg := <-msgChan:
+			qout.Progress("eks", msg)
+			if recordDeletionMessage(&remaining, msg) {
+				if err := c.writeInventory(providerConfigDir, &remaining); err != nil {
+					qout.Error("Failed to update inventory file", err)
+				}
+			}
+		case err := <-errChan:
+			return err
+		}
+	}
+}
+
+// checkInterrupted returns an error if control plane creation has been
+// interrupted.  The user is offered a rollback of the control plane and the
+// AWS resources created so far, otherwise the create state is kept so that
+// creation can be resumed.
+func (c *ControlPlane) checkInterrupted(ctx context.Context, providerConfigDir string) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	interruptErr := fmt.Errorf("control plane creation interrupted: %w", ctx.Err())
+
+	qout.Warning("Control plane creation interrupted")
+	if !qout.Confirm("Delete the control plane and the AWS resources created so far?") {
+		qout.Info(fmt.Sprintf(
+			"Continue with `tptctl create control-plane --name %s --provider eks --resume` or delete it with `tptctl delete control-plane --name %s`",
+			c.InstanceName, c.InstanceName,
+		))
+		return interruptErr
 	}
+
+	// the original context is cancelled so the rollback gets its own
+	qout.Info("Deleting control plane...")
+	if err := c.DeleteControlPlaneOnEKS(context.Background(), providerConfigDir, false); err != nil {
+		return fmt.Errorf("%w\nerror deleting control plane: %s", interruptErr, err)
+	}
+
+	return interruptErr
+}
+
+// readInventory reads the inventory of AWS resources from the inventory file.
+// The error for a missing file satisfies os.IsNotExist.
+func (c *ControlPlane) readInventory(providerConfigDir string) (*resource.ResourceInventory, error) {
+	inventoryJSON, err := ioutil.ReadFile(c.inventoryFilePath(providerConfigDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read inventory file: %w", err)
+	}
+	var inventory resource.ResourceInventory
+	if err := json.Unmarshal(inventoryJSON, &inventory); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal inventory file: %w", err)
+	}
+
+	return &inventory, nil
+}
+
+// inventoryComplete returns true if an inventory includes the last resources
+// the eks-cluster library creates, i.e. the cluster and its node groups.
+func inventoryComplete(inventory *resource.ResourceInventory) bool {
+	return inventory.ClusterName != "" && len(inventory.NodeGroupNames) > 0
+}
+
+// writeInventory writes the inventory of AWS resources to the inventory file.
+func (c *ControlPlane) writeInventory(providerConfigDir string, inventory *resource.ResourceInventory) error {
+	inventoryJSON, err := json.MarshalIndent(inventory, "", " ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal inventory to JSON: %w", err)
+	}
+	if err := ioutil.WriteFile(c.inventoryFilePath(providerConfigDir), inventoryJSON, 0644); err != nil {
+		return fmt.Errorf("failed to write inventory file: %w", err)
+	}
+
+	return nil
+}
+
+// recordInventoryMessage updates an inventory with the resource IDs reported
+// in a progress message from the eks-cluster library.  The library only
+// returns the inventory once all resources are created, so this keeps a record
+// of created resources while creation is in progress.  Returns true if the
+// inventory was updated.
+func recordInventoryMessage(inventory *resource.ResourceInventory, msg string) bool {
+	prefix, value, found := strings.Cut(strings.TrimSpace(msg), ": ")
+	if !found {
+		return false
+	}
+
+	switch prefix {
+	case "VPC created":
+		inventory.VPCID = value
+	case "Internet gateway created":
+		inventory.InternetGatewayID = value
+	case "Subnets created":
+		inventory.SubnetIDs = messageList(value)
+	case "Elastic IPs created":
+		inventory.ElasticIPIDs = messageList(value)
+	case "Route tables created":
+		// formatted as [[private IDs] public ID]
+		privateIDs, publicID, found := strings.Cut(strings.TrimPrefix(value, "["), "] ")
+		if !found {
+			return false
+		}
+		inventory.PrivateRouteTableIDs = messageList(privateIDs)
+		inventory.PublicRouteTableID = strings.TrimSuffix(publicID, "]")
+	case "IAM roles created":
+		// the policies the library attaches are recorded so that they can
+		// be detached before the roles are deleted
+		roleNames := messageList(value)
+		if len(roleNames) != 2 {
+			return false
+		}
+		inventory.ClusterRole = resource.RoleInventory{
+			RoleName:       roleNames[0],
+			RolePolicyARNs: []string{resource.ClusterPolicyARN},
+		}
+		inventory.WorkerRole = resource.RoleInventory{
+			RoleName: roleNames[1],
+			RolePolicyARNs: []string{
+				resource.WorkerNodePolicyARN,
+				resource.ContainerRegistryPolicyARN,
+				resource.CNIPolicyARN,
+			},
+		}
+	case "EKS cluster created":
+		inventory.ClusterName = value
+	case "EKS node group created":
+		inventory.NodeGroupNames = messageList(value)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// recordDeletionMessage removes the resources reported as deleted in a
+// progress message from the eks-cluster library from an inventory so that an
+// interrupted deletion isn't repeated for them.  Returns true if the inventory
+// was updated.
+func recordDeletionMessage(inventory *resource.ResourceInventory, msg string) bool {
+	prefix, _, found := strings.Cut(strings.TrimSpace(msg), ": ")
+	if !found {
+		return false
+	}
+
+	switch prefix {
+	case "Node groups deletion complete":
+		inventory.NodeGroupNames = nil
+	case "EKS cluster deletion complete":
+		inventory.ClusterName = ""
+	case "IAM roles deleted":
+		inventory.ClusterRole = resource.RoleInventory{}
+		inventory.WorkerRole = resource.RoleInventory{}
+	case "Internet gateway deleted":
+		inventory.InternetGatewayID = ""
+	case "Elastic IPs deleted":
+		inventory.ElasticIPIDs = nil
+	case "Subnets deleted":
+		inventory.SubnetIDs = nil
+	case "Route tables deleted":
+		inventory.PrivateRouteTableIDs = nil
+		inventory.PublicRouteTableID = ""
+	case "VPC deleted":
+		inventory.VPCID = ""
+	default:
+		return false
+	}
+
+	return true
+}
+
+// messageList returns the elements of a list formatted as [a b c] in an
+// eks-cluster library message.
+func messageList(value string) []string {
+	return strings.Fields(strings.Trim(value, "[]"))
 }
@@ -0,0 +1,450 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	elb "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	taggingtypes "github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi/types"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	route53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+const (
+	OrphanTypeTaggedResource   = "TaggedResource"
+	OrphanTypeLoadBalancer     = "LoadBalancer"
+	OrphanTypeNetworkInterface = "NetworkInterface"
+	OrphanTypeIAMRole          = "IAMRole"
+	OrphanTypeDNSRecord        = "DNSRecord"
+)
+
+// OrphanedResource is an AWS resource belonging to a threeport control plane
+// that still exists after the control plane has been deleted.
+type OrphanedResource struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+
+	// HostedZoneID is the Route53 hosted zone that contains a DNS record.
+	HostedZoneID string `json:"hostedZoneID,omitempty"`
+
+	// RecordType is the type of a DNS record, e.g. A or TXT.
+	RecordType string `json:"recordType,omitempty"`
+}
+
+// String returns a human readable description of the orphaned resource.
+func (o OrphanedResource) String() string {
+	if o.Type == OrphanTypeDNSRecord {
+		return fmt.Sprintf("%s %s (%s) in hosted zone %s", o.Type, o.ID, o.RecordType, o.HostedZoneID)
+	}
+	return fmt.Sprintf("%s %s", o.Type, o.ID)
+}
+
+// AuditTarget contains the identifying details of a control plane's AWS
+// resources used to look for orphans once the resource stack is deleted.
+type AuditTarget struct {
+	ClusterName    string   `json:"clusterName"`
+	Region         string   `json:"region"`
+	VPCID          string   `json:"vpcID"`
+	RoleNames      []string `json:"roleNames"`
+	RootDomainName string   `json:"rootDomainName"`
+}
+
+// AWSAuditClient looks up and deletes AWS resources that may be left behind
+// when a control plane is deleted.  It is an interface so that the audit can be
+// run against a stubbed client.
+type AWSAuditClient interface {
+	// GetTaggedResourceARNs returns the ARNs of all resources that have every
+	// one of the given tags.  An empty tag value matches any value.
+	GetTaggedResourceARNs(ctx context.Context, tags map[string]string) ([]string, error)
+
+	// GetNetworkInterfaceIDs returns the IDs of all network interfaces in a
+	// VPC.
+	GetNetworkInterfaceIDs(ctx context.Context, vpcID string) ([]string, error)
+
+	// GetExistingRoleNames returns the subset of the given IAM role names that
+	// still exist.
+	GetExistingRoleNames(ctx context.Context, roleNames []string) ([]string, error)
+
+	// GetDNSRecords returns the records in the public hosted zone for zoneName
+	// with a name that ends with recordName.
+	GetDNSRecords(ctx context.Context, zoneName, recordName string) ([]OrphanedResource, error)
+
+	// DeleteOrphanedResource deletes a single orphaned resource.
+	DeleteOrphanedResource(ctx context.Context, orphan OrphanedResource) error
+}
+
+// AuditEKSResources looks for any AWS resources that remain for a control
+// plane after its resource stack has been deleted.
+func AuditEKSResources(ctx context.Context, client AWSAuditClient, target *AuditTarget) ([]OrphanedResource, error) {
+	var orphans []OrphanedResource
+
+	// resources tagged by tptctl via the eks-cluster library and those tagged
+	// by Kubernetes for the cluster, e.g. load balancers for services
+	seenARNs := make(map[string]bool)
+	tagSets := []map[string]string{
+		{"provisioner": "tptctl", "Name": target.ClusterName},
+		{fmt.Sprintf("kubernetes.io/cluster/%s", target.ClusterName): ""},
+	}
+	for _, tags := range tagSets {
+		arns, err := client.GetTaggedResourceARNs(ctx, tags)
+		if err != nil {
+			return orphans, fmt.Errorf("failed to look up tagged resources: %w", err)
+		}
+		for _, arn := range arns {
+			if seenARNs[arn] {
+				continue
+			}
+			seenARNs[arn] = true
+			orphanType := OrphanTypeTaggedResource
+			if isLoadBalancerARN(arn) {
+				orphanType = OrphanTypeLoadBalancer
+			}
+			orphans = append(orphans, OrphanedResource{Type: orphanType, ID: arn})
+		}
+	}
+
+	// network interfaces left in the VPC prevent it from being deleted
+	if target.VPCID != "" {
+		eniIDs, err := client.GetNetworkInterfaceIDs(ctx, target.VPCID)
+		if err != nil {
+			return orphans, fmt.Errorf("failed to look up network interfaces: %w", err)
+		}
+		for _, eniID := range eniIDs {
+			orphans = append(orphans, OrphanedResource{Type: OrphanTypeNetworkInterface, ID: eniID})
+		}
+	}
+
+	// IAM roles are not supported by the tagging API so are looked up by name
+	if len(target.RoleNames) > 0 {
+		roleNames, err := client.GetExistingRoleNames(ctx, target.RoleNames)
+		if err != nil {
+			return orphans, fmt.Errorf("failed to look up IAM roles: %w", err)
+		}
+		for _, roleName := range roleNames {
+			orphans = append(orphans, OrphanedResource{Type: OrphanTypeIAMRole, ID: roleName})
+		}
+	}
+
+	// DNS records created by external-dns for the threeport API
+	if target.RootDomainName != "" {
+		records, err := client.GetDNSRecords(
+			ctx,
+			target.RootDomainName,
+			fmt.Sprintf("%s.%s", target.ClusterName, target.RootDomainName),
+		)
+		if err != nil {
+			return orphans, fmt.Errorf("failed to look up DNS records: %w", err)
+		}
+		orphans = append(orphans, records...)
+	}
+
+	return orphans, nil
+}
+
+// PurgeOrphanedResources deletes orphaned resources.  DNS records and load
+// balancers are removed first since they hold network interfaces, and IAM
+// roles last.  Deletion continues past failures and all errors are returned.
+func PurgeOrphanedResources(ctx context.Context, client AWSAuditClient, orphans []OrphanedResource) error {
+	deletionOrder := []string{
+		OrphanTypeDNSRecord,
+		OrphanTypeLoadBalancer,
+		OrphanTypeNetworkInterface,
+		OrphanTypeTaggedResource,
+		OrphanTypeIAMRole,
+	}
+
+	var failures []string
+	for _, orphanType := range deletionOrder {
+		for _, orphan := range orphans {
+			if orphan.Type != orphanType {
+				continue
+			}
+			if err := client.DeleteOrphanedResource(ctx, orphan); err != nil {
+				failures = append(failures, fmt.Sprintf("failed to delete %s: %s", orphan, err))
+			}
+		}
+	}
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "\n"))
+	}
+
+	return nil
+}
+
+// isLoadBalancerARN returns true if the ARN is for a classic, application or
+// network load balancer.
+func isLoadBalancerARN(arn string) bool {
+	return strings.Contains(arn, ":elasticloadbalancing:") && strings.Contains(arn, ":loadbalancer/")
+}
+
+// awsAuditClient is the AWSAuditClient implementation that calls the AWS APIs.
+type awsAuditClient struct {
+	tagging *resourcegroupstaggingapi.Client
+	ec2     *ec2.Client
+	elb     *elb.Client
+	elbv2   *elbv2.Client
+	iam     *iam.Client
+	route53 *route53.Client
+}
+
+// NewAWSAuditClient returns an AWSAuditClient that uses the AWS APIs.
+func NewAWSAuditClient(cfg aws.Config) AWSAuditClient {
+	return &awsAuditClient{
+		tagging: resourcegroupstaggingapi.NewFromConfig(cfg),
+		ec2:     ec2.NewFromConfig(cfg),
+		elb:     elb.NewFromConfig(cfg),
+		elbv2:   elbv2.NewFromConfig(cfg),
+		iam:     iam.NewFromConfig(cfg),
+		route53: route53.NewFromConfig(cfg),
+	}
+}
+
+// GetTaggedResourceARNs returns the ARNs of resources with all the given tags.
+func (a *awsAuditClient) GetTaggedResourceARNs(ctx context.Context, tags map[string]string) ([]string, error) {
+	var tagFilters []taggingtypes.TagFilter
+	for key, value := range tags {
+		tagFilter := taggingtypes.TagFilter{Key: aws.String(key)}
+		if value != "" {
+			tagFilter.Values = []string{value}
+		}
+		tagFilters = append(tagFilters, tagFilter)
+	}
+
+	var arns []string
+	paginator := resourcegroupstaggingapi.NewGetResourcesPaginator(
+		a.tagging,
+		&resourcegroupstaggingapi.GetResourcesInput{TagFilters: tagFilters},
+	)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return arns, err
+		}
+		for _, mapping := range page.ResourceTagMappingList {
+			arns = append(arns, aws.ToString(mapping.ResourceARN))
+		}
+	}
+
+	return arns, nil
+}
+
+// GetNetworkInterfaceIDs returns the IDs of the network interfaces in a VPC.
+func (a *awsAuditClient) GetNetworkInterfaceIDs(ctx context.Context, vpcID string) ([]string, error) {
+	var eniIDs []string
+	paginator := ec2.NewDescribeNetworkInterfacesPaginator(
+		a.ec2,
+		&ec2.DescribeNetworkInterfacesInput{
+			Filters: []ec2types.Filter{
+				{Name: aws.String("vpc-id"), Values: []string{vpcID}},
+			},
+		},
+	)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return eniIDs, err
+		}
+		for _, eni := range page.NetworkInterfaces {
+			eniIDs = append(eniIDs, aws.ToString(eni.NetworkInterfaceId))
+		}
+	}
+
+	return eniIDs, nil
+}
+
+// GetExistingRoleNames returns the names of the given IAM roles that exist.
+func (a *awsAuditClient) GetExistingRoleNames(ctx context.Context, roleNames []string) ([]string, error) {
+	var existing []string
+	for _, roleName := range roleNames {
+		if roleName == "" {
+			continue
+		}
+		_, err := a.iam.GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String(roleName)})
+		if err != nil {
+			var notFound *iamtypes.NoSuchEntityException
+			if errors.As(err, &notFound) {
+				continue
+			}
+			return existing, err
+		}
+		existing = append(existing, roleName)
+	}
+
+	return existing, nil
+}
+
+// GetDNSRecords returns the DNS records in a public hosted zone with a name
+// ending in recordName.
+func (a *awsAuditClient) GetDNSRecords(ctx context.Context, zoneName, recordName string) ([]OrphanedResource, error) {
+	var records []OrphanedResource
+
+	// find the hosted zone
+	zones, err := a.route53.ListHostedZonesByName(ctx, &route53.ListHostedZonesByNameInput{
+		DNSName: aws.String(zoneName),
+	})
+	if err != nil {
+		return records, err
+	}
+	var hostedZoneID string
+	for _, zone := range zones.HostedZones {
+		if aws.ToString(zone.Name) == zoneName+"." && (zone.Config == nil || !zone.Config.PrivateZone) {
+			hostedZoneID = aws.ToString(zone.Id)
+			break
+		}
+	}
+	if hostedZoneID == "" {
+		return records, nil
+	}
+
+	// find matching records
+	input := &route53.ListResourceRecordSetsInput{HostedZoneId: aws.String(hostedZoneID)}
+	for {
+		output, err := a.route53.ListResourceRecordSets(ctx, input)
+		if err != nil {
+			return records, err
+		}
+		for _, recordSet := range output.ResourceRecordSets {
+			if strings.HasSuffix(aws.ToString(recordSet.Name), recordName+".") {
+				records = append(records, OrphanedResource{
+					Type:         OrphanTypeDNSRecord,
+					ID:           aws.ToString(recordSet.Name),
+					HostedZoneID: hostedZoneID,
+					RecordType:   string(recordSet.Type),
+				})
+			}
+		}
+		if !output.IsTruncated {
+			break
+		}
+		input.StartRecordName = output.NextRecordName
+		input.StartRecordType = output.NextRecordType
+		input.StartRecordIdentifier = output.NextRecordIdentifier
+	}
+
+	return records, nil
+}
+
+// DeleteOrphanedResource deletes an orphaned resource.
+func (a *awsAuditClient) DeleteOrphanedResource(ctx context.Context, orphan OrphanedResource) error {
+	switch orphan.Type {
+	case OrphanTypeDNSRecord:
+		return a.deleteDNSRecord(ctx, orphan)
+	case OrphanTypeLoadBalancer:
+		return a.deleteLoadBalancer(ctx, orphan.ID)
+	case OrphanTypeNetworkInterface:
+		_, err := a.ec2.DeleteNetworkInterface(ctx, &ec2.DeleteNetworkInterfaceInput{
+			NetworkInterfaceId: aws.String(orphan.ID),
+		})
+		return err
+	case OrphanTypeIAMRole:
+		return a.deleteRole(ctx, orphan.ID)
+	case OrphanTypeTaggedResource:
+		// security groups created for load balancers are the only other
+		// resources that are expected to be left behind
+		if i := strings.Index(orphan.ID, ":security-group/"); i != -1 {
+			_, err := a.ec2.DeleteSecurityGroup(ctx, &ec2.DeleteSecurityGroupInput{
+				GroupId: aws.String(orphan.ID[i+len(":security-group/"):]),
+			})
+			return err
+		}
+		return errors.New("automatic deletion not supported for this resource type - delete it manually")
+	default:
+		return errors.New(fmt.Sprintf("unrecognized orphaned resource type %s", orphan.Type))
+	}
+}
+
+// deleteDNSRecord deletes a DNS record set.  The complete record set is needed
+// by Route53 to delete it so it is looked up first.
+func (a *awsAuditClient) deleteDNSRecord(ctx context.Context, orphan OrphanedResource) error {
+	output, err := a.route53.ListResourceRecordSets(ctx, &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(orphan.HostedZoneID),
+		StartRecordName: aws.String(orphan.ID),
+		StartRecordType: route53types.RRType(orphan.RecordType),
+		MaxItems:        aws.Int32(1),
+	})
+	if err != nil {
+		return err
+	}
+	if len(output.ResourceRecordSets) == 0 ||
+		aws.ToString(output.ResourceRecordSets[0].Name) != orphan.ID {
+		// already gone
+		return nil
+	}
+
+	_, err = a.route53.ChangeResourceRecordSets(ctx, &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(orphan.HostedZoneID),
+		ChangeBatch: &route53types.ChangeBatch{
+			Changes: []route53types.Change{
+				{
+					Action:            route53types.ChangeActionDelete,
+					ResourceRecordSet: &output.ResourceRecordSets[0],
+				},
+			},
+		},
+	})
+
+	return err
+}
+
+// deleteLoadBalancer deletes a classic, application or network load balancer.
+func (a *awsAuditClient) deleteLoadBalancer(ctx context.Context, arn string) error {
+	resource := arn[strings.Index(arn, ":loadbalancer/")+len(":loadbalancer/"):]
+	if strings.HasPrefix(resource, "app/") || strings.HasPrefix(resource, "net/") {
+		_, err := a.elbv2.DeleteLoadBalancer(ctx, &elbv2.DeleteLoadBalancerInput{
+			LoadBalancerArn: aws.String(arn),
+		})
+		return err
+	}
+
+	_, err := a.elb.DeleteLoadBalancer(ctx, &elb.DeleteLoadBalancerInput{
+		LoadBalancerName: aws.String(resource),
+	})
+
+	return err
+}
+
+// deleteRole detaches all policies from an IAM role and deletes it.
+func (a *awsAuditClient) deleteRole(ctx context.Context, roleName string) error {
+	attached, err := a.iam.ListAttachedRolePolicies(ctx, &iam.ListAttachedRolePoliciesInput{
+		RoleName: aws.String(roleName),
+	})
+	if err != nil {
+		return err
+	}
+	for _, policy := range attached.AttachedPolicies {
+		if _, err := a.iam.DetachRolePolicy(ctx, &iam.DetachRolePolicyInput{
+			RoleName:  aws.String(roleName),
+			PolicyArn: policy.PolicyArn,
+		}); err != nil {
+			return err
+		}
+	}
+
+	inline, err := a.iam.ListRolePolicies(ctx, &iam.ListRolePoliciesInput{
+		RoleName: aws.String(roleName),
+	})
+	if err != nil {
+		return err
+	}
+	for _, policyName := range inline.PolicyNames {
+		if _, err := a.iam.DeleteRolePolicy(ctx, &iam.DeleteRolePolicyInput{
+			RoleName:   aws.String(roleName),
+			PolicyName: aws.String(policyName),
+		}); err != nil {
+			return err
+		}
+	}
+
+	_, err = a.iam.DeleteRole(ctx, &iam.DeleteRoleInput{RoleName: aws.String(roleName)})
+
+	return err
+}
@@ -0,0 +1,248 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// fakeAuditClient is an AWSAuditClient that returns fixed resources and
+// records the resources deleted.
+type fakeAuditClient struct {
+	// taggedARNs are keyed by the tag set they are returned for, formatted by
+	// tagKey
+	taggedARNs        map[string][]string
+	networkInterfaces []string
+	roleNames         []string
+	dnsRecords        []OrphanedResource
+
+	// lookupErr is returned by every lookup if set
+	lookupErr error
+
+	// deleteErrs are returned when deleting the resource with the ID
+	deleteErrs map[string]error
+	deleted    []string
+}
+
+// tagKey returns a tag set formatted as sorted key=value pairs.
+func tagKey(tags map[string]string) string {
+	var pairs []string
+	for key, value := range tags {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+func (f *fakeAuditClient) GetTaggedResourceARNs(ctx context.Context, tags map[string]string) ([]string, error) {
+	return f.taggedARNs[tagKey(tags)], f.lookupErr
+}
+
+func (f *fakeAuditClient) GetNetworkInterfaceIDs(ctx context.Context, vpcID string) ([]string, error) {
+	return f.networkInterfaces, f.lookupErr
+}
+
+func (f *fakeAuditClient) GetExistingRoleNames(ctx context.Context, roleNames []string) ([]string, error) {
+	var existing []string
+	for _, roleName := range roleNames {
+		for _, name := range f.roleNames {
+			if name == roleName {
+				existing = append(existing, roleName)
+			}
+		}
+	}
+
+	return existing, f.lookupErr
+}
+
+func (f *fakeAuditClient) GetDNSRecords(ctx context.Context, zoneName, recordName string) ([]OrphanedResource, error) {
+	var records []OrphanedResource
+	for _, record := range f.dnsRecords {
+		if strings.HasSuffix(record.ID, recordName+".") {
+			records = append(records, record)
+		}
+	}
+
+	return records, f.lookupErr
+}
+
+func (f *fakeAuditClient) DeleteOrphanedResource(ctx context.Context, orphan OrphanedResource) error {
+	if err := f.deleteErrs[orphan.ID]; err != nil {
+		return err
+	}
+	f.deleted = append(f.deleted, orphan.ID)
+
+	return nil
+}
+
+const (
+	testSecurityGroupARN = "arn:aws:ec2:us-east-1:111111111111:security-group/sg-1"
+	testLoadBalancerARN  = "arn:aws:elasticloadbalancing:us-east-1:111111111111:loadbalancer/net/a1/b2"
+)
+
+func TestAuditEKSResources(t *testing.T) {
+	target := &AuditTarget{
+		ClusterName:    "threeport-test",
+		Region:         "us-east-1",
+		VPCID:          "vpc-1",
+		RoleNames:      []string{"cluster-role", "worker-role", ""},
+		RootDomainName: "example.com",
+	}
+	tptctlTags := tagKey(map[string]string{"provisioner": "tptctl", "Name": "threeport-test"})
+	kubernetesTags := tagKey(map[string]string{"kubernetes.io/cluster/threeport-test": ""})
+
+	testCases := []struct {
+		name     string
+		client   *fakeAuditClient
+		target   *AuditTarget
+		expected []OrphanedResource
+		wantErr  bool
+	}{
+		{
+			name:   "nothing left",
+			client: &fakeAuditClient{},
+			target: target,
+		},
+		{
+			name: "every type of orphan",
+			client: &fakeAuditClient{
+				taggedARNs: map[string][]string{
+					tptctlTags:     {testSecurityGroupARN},
+					kubernetesTags: {testLoadBalancerARN},
+				},
+				networkInterfaces: []string{"eni-1"},
+				roleNames:         []string{"worker-role", "unrelated-role"},
+				dnsRecords: []OrphanedResource{
+					{Type: OrphanTypeDNSRecord, ID: "threeport-test.example.com.", HostedZoneID: "Z1", RecordType: "A"},
+					{Type: OrphanTypeDNSRecord, ID: "other.example.com.", HostedZoneID: "Z1", RecordType: "A"},
+				},
+			},
+			target: target,
+			expected: []OrphanedResource{
+				{Type: OrphanTypeTaggedResource, ID: testSecurityGroupARN},
+				{Type: OrphanTypeLoadBalancer, ID: testLoadBalancerARN},
+				{Type: OrphanTypeNetworkInterface, ID: "eni-1"},
+				{Type: OrphanTypeIAMRole, ID: "worker-role"},
+				{Type: OrphanTypeDNSRecord, ID: "threeport-test.example.com.", HostedZoneID: "Z1", RecordType: "A"},
+			},
+		},
+		{
+			name: "resource with both tag sets reported once",
+			client: &fakeAuditClient{
+				taggedARNs: map[string][]string{
+					tptctlTags:     {testLoadBalancerARN},
+					kubernetesTags: {testLoadBalancerARN},
+				},
+			},
+			target: target,
+			expected: []OrphanedResource{
+				{Type: OrphanTypeLoadBalancer, ID: testLoadBalancerARN},
+			},
+		},
+		{
+			name: "no VPC, roles or root domain",
+			client: &fakeAuditClient{
+				networkInterfaces: []string{"eni-1"},
+				roleNames:         []string{"worker-role"},
+				dnsRecords: []OrphanedResource{
+					{Type: OrphanTypeDNSRecord, ID: "threeport-test.example.com.", HostedZoneID: "Z1", RecordType: "A"},
+				},
+			},
+			target: &AuditTarget{ClusterName: "threeport-test", Region: "us-east-1"},
+		},
+		{
+			name:    "lookup failure",
+			client:  &fakeAuditClient{lookupErr: errors.New("access denied")},
+			target:  target,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			orphans, err := AuditEKSResources(context.Background(), tc.client, tc.target)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(orphans, tc.expected) {
+				t.Errorf("expected orphans %v, got %v", tc.expected, orphans)
+			}
+		})
+	}
+}
+
+func TestPurgeOrphanedResources(t *testing.T) {
+	orphans := []OrphanedResource{
+		{Type: OrphanTypeIAMRole, ID: "worker-role"},
+		{Type: OrphanTypeTaggedResource, ID: testSecurityGroupARN},
+		{Type: OrphanTypeNetworkInterface, ID: "eni-1"},
+		{Type: OrphanTypeLoadBalancer, ID: testLoadBalancerARN},
+		{Type: OrphanTypeDNSRecord, ID: "threeport-test.example.com.", HostedZoneID: "Z1", RecordType: "A"},
+	}
+
+	testCases := []struct {
+		name            string
+		deleteErrs      map[string]error
+		expectedDeleted []string
+		expectedErrs    []string
+	}{
+		{
+			name: "all deleted in dependency order",
+			expectedDeleted: []string{
+				"threeport-test.example.com.",
+				testLoadBalancerARN,
+				"eni-1",
+				testSecurityGroupARN,
+				"worker-role",
+			},
+		},
+		{
+			name: "deletion continues past failures",
+			deleteErrs: map[string]error{
+				testLoadBalancerARN: errors.New("load balancer in use"),
+				"worker-role":       errors.New("access denied"),
+			},
+			expectedDeleted: []string{
+				"threeport-test.example.com.",
+				"eni-1",
+				testSecurityGroupARN,
+			},
+			expectedErrs: []string{"load balancer in use", "access denied"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &fakeAuditClient{deleteErrs: tc.deleteErrs}
+			err := PurgeOrphanedResources(context.Background(), client, orphans)
+			if !reflect.DeepEqual(client.deleted, tc.expectedDeleted) {
+				t.Errorf("expected deleted %v, got %v", tc.expectedDeleted, client.deleted)
+			}
+			if len(tc.expectedErrs) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, expected := range tc.expectedErrs {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("expected error to include %q, got %q", expected, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package provider
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nukleros/eks-cluster/pkg/resource"
+)
+
+// createMessages are the progress messages the eks-cluster library sends while
+// creating a resource stack, in order.
+var createMessages = []string{
+	"VPC created: vpc-1\n",
+	"Internet gateway created: igw-1\n",
+	"Subnets created: [subnet-1 subnet-2 subnet-3 subnet-4]\n",
+	"Elastic IPs created: [eipalloc-1 eipalloc-2]\n",
+	"NAT gateways created for subnets: [subnet-1 subnet-2]\n",
+	"Waiting for NAT gateways to become active for subnets: [subnet-1 subnet-2]\n",
+	"NAT gateways ready for subnets: [subnet-1 subnet-2]\n",
+	"Route tables created: [[rtb-1 rtb-2] rtb-3]\n",
+	"IAM roles created: [cluster-role worker-role]\n",
+	"EKS cluster created: threeport-test\n",
+	"Waiting for EKS cluster to become active: threeport-test\n",
+	"EKS cluster ready: threeport-test\n",
+	"EKS node group created: [threeport-test-node-group]\n",
+}
+
+// createdInventory is the inventory recorded from createMessages.
+var createdInventory = resource.ResourceInventory{
+	Region:               "us-east-2",
+	VPCID:                "vpc-1",
+	SubnetIDs:            []string{"subnet-1", "subnet-2", "subnet-3", "subnet-4"},
+	InternetGatewayID:    "igw-1",
+	ElasticIPIDs:         []string{"eipalloc-1", "eipalloc-2"},
+	PrivateRouteTableIDs: []string{"rtb-1", "rtb-2"},
+	PublicRouteTableID:   "rtb-3",
+	ClusterRole: resource.RoleInventory{
+		RoleName:       "cluster-role",
+		RolePolicyARNs: []string{resource.ClusterPolicyARN},
+	},
+	WorkerRole: resource.RoleInventory{
+		RoleName: "worker-role",
+		RolePolicyARNs: []string{
+			resource.WorkerNodePolicyARN,
+			resource.ContainerRegistryPolicyARN,
+			resource.CNIPolicyARN,
+		},
+	},
+	ClusterName:    "threeport-test",
+	NodeGroupNames: []string{"threeport-test-node-group"},
+}
+
+func TestRecordInventoryMessage(t *testing.T) {
+	inventory := resource.ResourceInventory{Region: "us-east-2"}
+	var recorded []string
+	for _, msg := range createMessages {
+		if recordInventoryMessage(&inventory, msg) {
+			recorded = append(recorded, msg)
+		}
+	}
+
+	if !reflect.DeepEqual(inventory, createdInventory) {
+		t.Errorf("expected inventory %+v, got %+v", createdInventory, inventory)
+	}
+	if len(recorded) != 8 {
+		t.Errorf("expected 8 messages to update the inventory, got %d: %q", len(recorded), recorded)
+	}
+	if !inventoryComplete(&inventory) {
+		t.Errorf("expected inventory to be complete once the node group is created")
+	}
+}
+
+func TestRecordDeletionMessage(t *testing.T) {
+	testCases := []struct {
+		name     string
+		messages []string
+		expected resource.ResourceInventory
+	}{
+		{
+			name: "interrupted after the cluster is deleted",
+			messages: []string{
+				"Node groups deletion initiated: [threeport-test-node-group]\n",
+				"Waiting for node groups to be deleted: [threeport-test-node-group]\n",
+				"Node groups deletion complete: [threeport-test-node-group]\n",
+				"EKS cluster deletion initiated: threeport-test\n",
+				"Waiting for EKS cluster to be deleted: threeport-test\n",
+				"EKS cluster deletion complete: threeport-test\n",
+			},
+			expected: resource.ResourceInventory{
+				Region:               "us-east-2",
+				VPCID:                "vpc-1",
+				SubnetIDs:            []string{"subnet-1", "subnet-2", "subnet-3", "subnet-4"},
+				InternetGatewayID:    "igw-1",
+				ElasticIPIDs:         []string{"eipalloc-1", "eipalloc-2"},
+				PrivateRouteTableIDs: []string{"rtb-1", "rtb-2"},
+				PublicRouteTableID:   "rtb-3",
+				ClusterRole:          createdInventory.ClusterRole,
+				WorkerRole:           createdInventory.WorkerRole,
+			},
+		},
+		{
+			name: "all deleted",
+			messages: []string{
+				"Node groups deletion complete: [threeport-test-node-group]\n",
+				"EKS cluster deletion complete: threeport-test\n",
+				"IAM roles deleted: [cluster-role worker-role]\n",
+				"NAT gateways deleted for VPC with ID: vpc-1\n",
+				"NAT gateway deletion complete for VPC with ID: vpc-1\n",
+				"Internet gateway deleted: igw-1\n",
+				"Elastic IPs deleted: [eipalloc-1 eipalloc-2]\n",
+				"Subnets deleted: [subnet-1 subnet-2 subnet-3 subnet-4]\n",
+				"Route tables deleted: [[rtb-1 rtb-2] rtb-3]\n",
+				"VPC deleted: vpc-1\n",
+			},
+			expected: resource.ResourceInventory{Region: "us-east-2"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			inventory := createdInventory
+			for _, msg := range tc.messages {
+				recordDeletionMessage(&inventory, msg)
+			}
+			if !reflect.DeepEqual(inventory, tc.expected) {
+				t.Errorf("expected inventory %+v, got %+v", tc.expected, inventory)
+			}
+		})
+	}
+}
+
+func TestReadAuditTarget(t *testing.T) {
+	controlPlane := ControlPlane{InstanceName: "test", RootDomainName: "example.com"}
+	recordedTarget := AuditTarget{
+		ClusterName: controlPlane.ThreeportClusterName(),
+		Region:      "us-west-2",
+		VPCID:       "vpc-1",
+		RoleNames:   []string{"cluster-role", "worker-role"},
+	}
+
+	testCases := []struct {
+		name         string
+		auditFile    string
+		wantTarget   *AuditTarget
+		wantRecorded bool
+		wantErr      string
+	}{
+		{
+			name:         "recorded",
+			auditFile:    `{"clusterName":"threeport-test","region":"us-west-2","vpcID":"vpc-1","roleNames":["cluster-role","worker-role"]}`,
+			wantTarget:   &recordedTarget,
+			wantRecorded: true,
+		},
+		{
+			name: "no audit file",
+			wantTarget: &AuditTarget{
+				ClusterName:    controlPlane.ThreeportClusterName(),
+				Region:         "us-east-1",
+				RootDomainName: "example.com",
+			},
+		},
+		{
+			name:      "invalid audit file",
+			auditFile: "{",
+			wantErr:   "failed to unmarshal audit file",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			providerConfigDir := t.TempDir()
+			if tc.auditFile != "" {
+				err := ioutil.WriteFile(controlPlane.auditFilePath(providerConfigDir), []byte(tc.auditFile), 0644)
+				if err != nil {
+					t.Fatalf("failed to write audit file: %s", err)
+				}
+			}
+			target, recorded, err := controlPlane.readAuditTarget(providerConfigDir, "us-east-1")
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(target, tc.wantTarget) {
+				t.Errorf("expected audit target %+v, got %+v", tc.wantTarget, target)
+			}
+			if recorded != tc.wantRecorded {
+				t.Errorf("expected recorded to be %t, got %t", tc.wantRecorded, recorded)
+			}
+		})
+	}
+}
@@ -2,19 +2,17 @@ package provider
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"time"
 
-	tpclient "github.com/threeport/threeport-go-client"
 	tpapi "github.com/threeport/threeport-rest-api/pkg/api/v0"
-	kubeclient "k8s.io/client-go/tools/clientcmd"
 
+	"github.com/threeport/tptctl/internal/api"
 	"github.com/threeport/tptctl/internal/install"
+	kube "github.com/threeport/tptctl/internal/kubernetes"
 	qout "github.com/threeport/tptctl/internal/output"
 	"github.com/threeport/tptctl/internal/threeport"
 )
@@ -65,16 +63,13 @@ func (c *ControlPlane) CreateControlPlaneOnKind(providerConfigDir string) error
 		"--config",
 		ThreeportKindConfigPath,
 	)
-	kindCreateOut, err := kindCreate.CombinedOutput()
-	if err != nil {
-		qout.Error(fmt.Sprintf("kind error: %s", kindCreateOut), nil)
+	if _, err := qout.RunCommand(kindCreate); err != nil {
 		return fmt.Errorf("failed to create new kind cluster: %w", err)
 	}
 	qout.Info("kind cluster created")
 
 	// write kubeconfig
-	kubeconfigFilePath := filepath.Join(providerConfigDir,
-		fmt.Sprintf("kubeconfig-%s", c.ThreeportClusterName()))
+	kubeconfigFilePath := c.KubeconfigFilePath(providerConfigDir)
 	kindKubeconfig := exec.Command(
 		"kind",
 		"get",
@@ -82,12 +77,11 @@ func (c *ControlPlane) CreateControlPlaneOnKind(providerConfigDir string) error
 		"--name",
 		c.ThreeportClusterName(),
 	)
-	kindKubeconfigOut, err := kindKubeconfig.CombinedOutput()
+	kindKubeconfigOut, err := qout.RunSecretCommand(kindKubeconfig)
 	if err != nil {
-		qout.Error(fmt.Sprintf("kind error: %s", kindKubeconfigOut), nil)
 		return fmt.Errorf("failed to get kubeconfig for kind cluster: %w", err)
 	}
-	ioutil.WriteFile(kubeconfigFilePath, []byte(kindKubeconfigOut), 0644)
+	ioutil.WriteFile(kubeconfigFilePath, []byte(kindKubeconfigOut), 0600)
 	qout.Info(fmt.Sprintf("kubeconfig for kind cluster written to %s", kubeconfigFilePath))
 
 	// install threeport API
@@ -104,55 +98,38 @@ func (c *ControlPlane) CreateControlPlaneOnKind(providerConfigDir string) error
 	qout.Info("waiting for control plane components to spin up...")
 	time.Sleep(time.Second * 200)
 
-	// get kubeconfig
-	defaultLoadRules := kubeclient.NewDefaultClientConfigLoadingRules()
-
-	clientConfigLoadRules, err := defaultLoadRules.Load()
+	// get cluster CA and client credentials for the kind cluster from the
+	// current kubeconfig context
+	credentials, err := kube.GetClusterCredentials("", "")
 	if err != nil {
-		return fmt.Errorf("failed to load default kubeconfig rules: %w", err)
+		return fmt.Errorf("failed to get Kubernetes cluster credentials: %w", err)
 	}
 
-	clientConfig := kubeclient.NewDefaultClientConfig(*clientConfigLoadRules, &kubeclient.ConfigOverrides{})
-	kubeConfig, err := clientConfig.RawConfig()
-	if err != nil {
-		return fmt.Errorf("failed to load kubeconfig: %w", err)
+	// setup default compute space cluster
+	client := api.NewClient(fmt.Sprintf("%s://%s:%s",
+		KindThreeportAPIProtocol, KindThreeportAPIHostname, KindThreeportAPIPort))
+	if err := registerDefaultCluster(client, credentials); err != nil {
+		return err
 	}
 
-	// get cluster CA and server endpoint
-	var caCert string
-	clusterFound := false
-	for clusterName, cluster := range kubeConfig.Clusters {
-		if clusterName == kubeConfig.CurrentContext {
-			caCert = string(cluster.CertificateAuthorityData)
-			clusterFound = true
-		}
-	}
-	if !clusterFound {
-		return fmt.Errorf(
-			"failed to get Kubernetes cluster CA and endpoint: %w",
-			errors.New("cluster config not found in kubeconfig"),
-		)
+	// add superuser
+	if err := c.bootstrapSuperuser(client); err != nil {
+		return err
 	}
 
-	// get client certificate and key
-	var cert string
-	var key string
-	userFound := false
-	for userName, user := range kubeConfig.AuthInfos {
-		if userName == kubeConfig.CurrentContext {
-			cert = string(user.ClientCertificateData)
-			key = string(user.ClientKeyData)
-			userFound = true
-		}
-	}
-	if !userFound {
-		return fmt.Errorf(
-			"failed to get user credentials to Kubernetes cluster: %w",
-			errors.New("kubeconfig user for threeport cluster not found"),
-		)
+	// add forward proxy definition
+	if err := c.registerForwardProxy(client, c.Superuser.ID); err != nil {
+		return err
 	}
 
-	// setup default compute space cluster
+	return nil
+}
+
+// registerDefaultCluster adds the kind cluster to the Threeport API as the
+// default workload cluster for the compute space.  It is registered with the
+// in-cluster API endpoint that the workload controller reaches it on and the
+// CA and client credentials from the kubeconfig.
+func registerDefaultCluster(client api.Client, credentials *kube.ClusterCredentials) error {
 	defaultClusterName := threeport.DefaultComputeClusterName
 	defaultClusterRegion := threeport.DefaultComputeClusterRegion
 	defaultClusterProvider := threeport.DefaultComputeClusterProvider
@@ -162,48 +139,27 @@ func (c *ControlPlane) CreateControlPlaneOnKind(providerConfigDir string) error
 		Region:        &defaultClusterRegion,
 		Provider:      &defaultClusterProvider,
 		APIEndpoint:   &defaultClusterAPIEndpoint,
-		CACertificate: &caCert,
-		Certificate:   &cert,
-		Key:           &key,
+		CACertificate: &credentials.CACertificate,
+		Certificate:   &credentials.Certificate,
+		Key:           &credentials.Key,
 	}
 	wcJSON, err := json.Marshal(&workloadCluster)
 	if err != nil {
 		return fmt.Errorf("failed to marshal workload cluster to json: %w", err)
 	}
-	wc, err := tpclient.CreateWorkloadCluster(wcJSON, install.GetThreeportAPIEndpoint(), "")
+	wc, err := client.CreateWorkloadCluster(wcJSON)
 	if err != nil {
 		return fmt.Errorf("failed to create workload cluster in Threeport API: %w", err)
 	}
 	qout.Info(fmt.Sprintf("default workload cluster %s for compute space set up", *wc.Name))
 
-	// TODO: add superuser
-	superuserID := uint(1)
-
-	// add forward proxy definition
-	fwdProxyDefName := threeport.ForwardProxyWorkloadDefinitionName
-	fwdProxyYAML := install.ForwardProxyManifest()
-	fwdProxyWorkloadDefinition := tpapi.WorkloadDefinition{
-		Name:         &fwdProxyDefName,
-		YAMLDocument: &fwdProxyYAML,
-		UserID:       &superuserID,
-	}
-	fpwdJSON, err := json.Marshal(&fwdProxyWorkloadDefinition)
-	if err != nil {
-		return fmt.Errorf("failed to marshal forward proxy workload definition to json: %w", err)
-	}
-	fpwd, err := tpclient.CreateWorkloadDefinition(fpwdJSON, install.GetThreeportAPIEndpoint(), "")
-	if err != nil {
-		return fmt.Errorf("failed to create forward proxy workload definition in Threeport API: %w", err)
-	}
-	qout.Info(fmt.Sprintf("forward proxy workload definition %s added", *fpwd.Name))
-
 	return nil
 }
 
 // DeleteControlPlaneOnKind deletes a kind cluster used for a threeport instance
 // to completely remove threeport.
 func (c *ControlPlane) DeleteControlPlaneOnKind() error {
-	fmt.Println("deleting kind cluster...")
+	qout.Info("deleting kind cluster...")
 	kindDelete := exec.Command(
 		"kind",
 		"delete",
@@ -211,9 +167,7 @@ func (c *ControlPlane) DeleteControlPlaneOnKind() error {
 		"--name",
 		c.ThreeportClusterName(),
 	)
-	kindDeleteOut, err := kindDelete.CombinedOutput()
-	if err != nil {
-		qout.Error(fmt.Sprintf("kind error: %s", kindDeleteOut), nil)
+	if _, err := qout.RunCommand(kindDelete); err != nil {
 		return fmt.Errorf("failed to delete kind cluster: %w", err)
 	}
 	qout.Info("kind cluster deleted")
@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/threeport/tptctl/internal/api"
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	"github.com/threeport/tptctl/internal/fakeapi"
+	kube "github.com/threeport/tptctl/internal/kubernetes"
+	"github.com/threeport/tptctl/internal/threeport"
+)
+
+func TestRegisterDefaultCluster(t *testing.T) {
+	server := fakeapi.NewServer()
+	defer server.Close()
+	client := api.NewClient(server.URL)
+	credentials := &kube.ClusterCredentials{
+		APIEndpoint:   "https://127.0.0.1:6443",
+		CACertificate: "ca-cert",
+		Certificate:   "client-cert",
+		Key:           "client-key",
+	}
+
+	if err := registerDefaultCluster(client, credentials); err != nil {
+		t.Fatalf("failed to register default cluster: %s", err)
+	}
+	workloadCluster, err := client.GetWorkloadClusterByName(threeport.DefaultComputeClusterName)
+	if err != nil {
+		t.Fatalf("failed to get default workload cluster: %s", err)
+	}
+	// the workload controller reaches the cluster it runs on at its
+	// in-cluster endpoint rather than the one in the kubeconfig
+	if *workloadCluster.APIEndpoint != threeport.DefaultComputeClusterAPIEndpoint ||
+		*workloadCluster.CACertificate != "ca-cert" || *workloadCluster.Certificate != "client-cert" ||
+		*workloadCluster.Key != "client-key" {
+		t.Errorf("expected default workload cluster with the kubeconfig credentials, got %+v", workloadCluster)
+	}
+
+	// the cluster can only be registered once
+	err = registerDefaultCluster(client, credentials)
+	if kind := tperrors.KindOf(err); kind != tperrors.KindConflict {
+		t.Errorf("expected registering the default cluster again to fail with %s, got %s: %v", tperrors.KindConflict, kind, err)
+	}
+}
@@ -1,6 +1,21 @@
 package provider
 
-import "fmt"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	tpapi "github.com/threeport/threeport-rest-api/pkg/api/v0"
+
+	"github.com/threeport/tptctl/internal/api"
+	"github.com/threeport/tptctl/internal/install"
+	"github.com/threeport/tptctl/internal/kubernetes"
+	qout "github.com/threeport/tptctl/internal/output"
+	"github.com/threeport/tptctl/internal/threeport"
+)
 
 // ControlPlane contains the attributes of a threeport control plane.
 type ControlPlane struct {
@@ -8,18 +23,47 @@ type ControlPlane struct {
 	ProviderAccountID      string
 	MinClusterNodes        int32
 	MaxClusterNodes        int32
+	DesiredClusterNodes    int32
 	DefaultAWSInstanceType string
+	AWSInstanceTypes       []string
+	AWSRegion              string
+	AWSProfile             string
+	SpotInstances          bool
+	KubernetesVersion      string
+	ResourceTags           map[string]string
 	RootDomainName         string
 	AdminEmail             string
+	ForwardProxy           install.ForwardProxyConfig
+	Superuser              Superuser
+}
+
+// Superuser is the admin user bootstrapped in the Threeport API when the
+// control plane is created.  The credentials are stored in the instance config
+// so tptctl can act as this user.
+type Superuser struct {
+	ID       uint
+	Email    string
+	Password string
 }
 
+var (
+	awsRegionPattern       = regexp.MustCompile(`^[a-z]{2}(-gov)?-[a-z]+-[0-9]$`)
+	awsInstanceTypePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*\.[a-z0-9]+$`)
+)
+
+// reservedTagKeys are resource tags set by tptctl that cannot be overridden.
+var reservedTagKeys = []string{"provisioner", "Name"}
+
 // NewControlPlane returns a ControlPlane with default values set.
 func NewControlPlane() *ControlPlane {
 	return &ControlPlane{
 		InstanceName:           "threeport-control-plane",
 		MinClusterNodes:        0,
 		MaxClusterNodes:        4,
+		DesiredClusterNodes:    2,
 		DefaultAWSInstanceType: "t3.medium",
+		KubernetesVersion:      kubernetes.KubernetesVersion,
+		ForwardProxy:           install.NewForwardProxyConfig(),
 	}
 }
 
@@ -28,3 +72,187 @@ func NewControlPlane() *ControlPlane {
 func (c *ControlPlane) ThreeportClusterName() string {
 	return fmt.Sprintf("threeport-%s", c.InstanceName)
 }
+
+// KubeconfigFilePath returns a filepath for a kubeconfig to connect to the
+// Kubernetes API in a threeport control plane cluster.  This filepath is unique
+// to each threeport instance name so that each gets its own distinct file.
+func (c *ControlPlane) KubeconfigFilePath(providerConfigDir string) string {
+	return filepath.Join(
+		providerConfigDir,
+		fmt.Sprintf("kubeconfig-%s", c.ThreeportClusterName()),
+	)
+}
+
+// ValidateEKSConfig checks the EKS cluster configuration for the control plane.
+// It is called before any calls are made to AWS so that invalid values are
+// caught before any resources are created.
+func (c *ControlPlane) ValidateEKSConfig() error {
+	if c.AWSRegion != "" && !awsRegionPattern.MatchString(c.AWSRegion) {
+		return errors.New(fmt.Sprintf("invalid AWS region '%s'", c.AWSRegion))
+	}
+
+	versionSupported := false
+	for _, version := range kubernetes.SupportedKubernetesVersions {
+		if c.KubernetesVersion == version {
+			versionSupported = true
+			break
+		}
+	}
+	if !versionSupported {
+		return errors.New(fmt.Sprintf("unsupported Kubernetes version '%s' - must be one of %s",
+			c.KubernetesVersion, kubernetes.SupportedKubernetesVersions))
+	}
+
+	for _, instanceType := range c.instanceTypes() {
+		if !awsInstanceTypePattern.MatchString(instanceType) {
+			return errors.New(fmt.Sprintf("invalid AWS instance type '%s'", instanceType))
+		}
+	}
+
+	if c.MinClusterNodes < 0 {
+		return errors.New("minimum cluster nodes cannot be negative")
+	}
+	if c.MaxClusterNodes < 1 {
+		return errors.New("maximum cluster nodes must be at least 1")
+	}
+	if c.MinClusterNodes > c.MaxClusterNodes {
+		return errors.New(fmt.Sprintf("minimum cluster nodes (%d) cannot be greater than maximum cluster nodes (%d)",
+			c.MinClusterNodes, c.MaxClusterNodes))
+	}
+	if c.DesiredClusterNodes < c.MinClusterNodes || c.DesiredClusterNodes > c.MaxClusterNodes {
+		return errors.New(fmt.Sprintf("desired cluster nodes (%d) must be between minimum (%d) and maximum (%d) cluster nodes",
+			c.DesiredClusterNodes, c.MinClusterNodes, c.MaxClusterNodes))
+	}
+
+	for key, value := range c.ResourceTags {
+		for _, reserved := range reservedTagKeys {
+			if key == reserved {
+				return errors.New(fmt.Sprintf("resource tag '%s' is set by tptctl and cannot be overridden", key))
+			}
+		}
+		if key == "" || len(key) > 128 || strings.HasPrefix(strings.ToLower(key), "aws:") {
+			return errors.New(fmt.Sprintf("invalid resource tag key '%s'", key))
+		}
+		if len(value) > 256 {
+			return errors.New(fmt.Sprintf("value for resource tag '%s' is longer than 256 characters", key))
+		}
+	}
+
+	return nil
+}
+
+// bootstrapSuperuser adds the superuser to the Threeport API and sets its ID
+// on the control plane.  The superuser's email defaults to the admin email, or
+// DefaultSuperuserEmail without one, and a password is generated if none is
+// set.  If the user already exists, e.g. when resuming creation, it is reused
+// and the password is left as it was.
+func (c *ControlPlane) bootstrapSuperuser(client api.Client) error {
+	if c.Superuser.Email == "" {
+		c.Superuser.Email = c.AdminEmail
+	}
+	if c.Superuser.Email == "" {
+		c.Superuser.Email = threeport.DefaultSuperuserEmail
+	}
+
+	users, err := client.GetUsers()
+	if err != nil {
+		return fmt.Errorf("failed to get users from Threeport API: %w", err)
+	}
+	for _, user := range *users {
+		if user.Email == nil || *user.Email != c.Superuser.Email {
+			continue
+		}
+		c.Superuser.ID = *user.ID
+		if c.Superuser.Password == "" {
+			qout.Warning(fmt.Sprintf(
+				"superuser %s already exists and its password is not in the Threeport config", c.Superuser.Email))
+		}
+		return nil
+	}
+
+	if c.Superuser.Password == "" {
+		password, err := threeport.GeneratePassword()
+		if err != nil {
+			return err
+		}
+		c.Superuser.Password = password
+	}
+	admin := true
+	superuser := tpapi.User{
+		Email:    &c.Superuser.Email,
+		Password: &c.Superuser.Password,
+		Admin:    &admin,
+	}
+	userJSON, err := json.Marshal(&superuser)
+	if err != nil {
+		return fmt.Errorf("failed to marshal superuser to json: %w", err)
+	}
+	user, err := client.CreateUser(userJSON)
+	if err != nil {
+		return fmt.Errorf("failed to create superuser in Threeport API: %w", err)
+	}
+	c.Superuser.ID = *user.ID
+	qout.Info(fmt.Sprintf("superuser %s added", c.Superuser.Email))
+
+	return nil
+}
+
+// registerForwardProxy adds the forward proxy workload definition to the
+// Threeport API so that workload service dependencies can be routed through
+// it.  If the definition already exists, e.g. when resuming creation, its YAML
+// document is replaced with the current forward proxy config.
+func (c *ControlPlane) registerForwardProxy(client api.Client, userID uint) error {
+	fwdProxyDefName := threeport.ForwardProxyWorkloadDefinitionName
+	fwdProxyYAML := install.ForwardProxyManifest(c.ForwardProxy)
+	fwdProxyWorkloadDefinition := tpapi.WorkloadDefinition{
+		Name:         &fwdProxyDefName,
+		YAMLDocument: &fwdProxyYAML,
+		UserID:       &userID,
+	}
+	fpwdJSON, err := json.Marshal(&fwdProxyWorkloadDefinition)
+	if err != nil {
+		return fmt.Errorf("failed to marshal forward proxy workload definition to json: %w", err)
+	}
+
+	workloadDefinitions, err := client.GetWorkloadDefinitions()
+	if err != nil {
+		return fmt.Errorf("failed to get workload definitions from Threeport API: %w", err)
+	}
+	for _, wd := range *workloadDefinitions {
+		if wd.Name == nil || *wd.Name != fwdProxyDefName {
+			continue
+		}
+		fpwd, err := client.UpdateWorkloadDefinition(*wd.ID, fpwdJSON)
+		if err != nil {
+			return fmt.Errorf("failed to update forward proxy workload definition in Threeport API: %w", err)
+		}
+		qout.Info(fmt.Sprintf("forward proxy workload definition %s updated", *fpwd.Name))
+		return nil
+	}
+
+	fpwd, err := client.CreateWorkloadDefinition(fpwdJSON)
+	if err != nil {
+		return fmt.Errorf("failed to create forward proxy workload definition in Threeport API: %w", err)
+	}
+	qout.Info(fmt.Sprintf("forward proxy workload definition %s added", *fpwd.Name))
+
+	return nil
+}
+
+// instanceTypes returns the AWS instance types to use for cluster nodes.
+func (c *ControlPlane) instanceTypes() []string {
+	if len(c.AWSInstanceTypes) > 0 {
+		return c.AWSInstanceTypes
+	}
+	return []string{c.DefaultAWSInstanceType}
+}
+
+// resourceTags returns the tags to add to AWS resources.  The provisioner tag
+// is always included so that resources created by tptctl can be identified.
+func (c *ControlPlane) resourceTags() map[string]string {
+	tags := map[string]string{"provisioner": "tptctl"}
+	for key, value := range c.ResourceTags {
+		tags[key] = value
+	}
+	return tags
+}
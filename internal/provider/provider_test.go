@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"testing"
+
+	tpapi "github.com/threeport/threeport-rest-api/pkg/api/v0"
+
+	"github.com/threeport/tptctl/internal/api"
+	"github.com/threeport/tptctl/internal/fakeapi"
+	"github.com/threeport/tptctl/internal/threeport"
+)
+
+func TestBootstrapSuperuser(t *testing.T) {
+	testCases := []struct {
+		name         string
+		superuser    Superuser
+		adminEmail   string
+		existing     string
+		wantEmail    string
+		wantPassword string
+		wantUsers    int
+	}{
+		{
+			name:      "default email",
+			wantEmail: threeport.DefaultSuperuserEmail,
+			wantUsers: 1,
+		},
+		{
+			name:       "admin email",
+			adminEmail: "admin@example.com",
+			wantEmail:  "admin@example.com",
+			wantUsers:  1,
+		},
+		{
+			name:         "password set",
+			superuser:    Superuser{Email: "root@example.com", Password: "hunter2"},
+			wantEmail:    "root@example.com",
+			wantPassword: "hunter2",
+			wantUsers:    1,
+		},
+		{
+			// creation is being resumed so the user is reused
+			name:      "existing user",
+			existing:  threeport.DefaultSuperuserEmail,
+			wantEmail: threeport.DefaultSuperuserEmail,
+			wantUsers: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := fakeapi.NewServer()
+			defer server.Close()
+			var existingID uint
+			if tc.existing != "" {
+				var err error
+				if existingID, err = server.Add(fakeapi.Users, &tpapi.User{Email: &tc.existing}); err != nil {
+					t.Fatalf("failed to add user: %s", err)
+				}
+			}
+
+			controlPlane := ControlPlane{AdminEmail: tc.adminEmail, Superuser: tc.superuser}
+			if err := controlPlane.bootstrapSuperuser(api.NewClient(server.URL)); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if controlPlane.Superuser.Email != tc.wantEmail {
+				t.Errorf("expected superuser email %s, got %s", tc.wantEmail, controlPlane.Superuser.Email)
+			}
+			if controlPlane.Superuser.ID == 0 || (existingID != 0 && controlPlane.Superuser.ID != existingID) {
+				t.Errorf("expected superuser ID to be set to that of the user in the API, got %d", controlPlane.Superuser.ID)
+			}
+			switch {
+			case tc.existing != "" && controlPlane.Superuser.Password != "":
+				t.Errorf("expected the password of an existing superuser to be left unset, got %s", controlPlane.Superuser.Password)
+			case tc.existing == "" && tc.wantPassword == "" && controlPlane.Superuser.Password == "":
+				t.Error("expected a password to be generated")
+			case tc.wantPassword != "" && controlPlane.Superuser.Password != tc.wantPassword:
+				t.Errorf("expected password %s, got %s", tc.wantPassword, controlPlane.Superuser.Password)
+			}
+			if count := server.Count(fakeapi.Users); count != tc.wantUsers {
+				t.Errorf("expected %d users, got %d", tc.wantUsers, count)
+			}
+		})
+	}
+}
+
+func TestRegisterForwardProxy(t *testing.T) {
+	server := fakeapi.NewServer()
+	defer server.Close()
+	client := api.NewClient(server.URL)
+	controlPlane := NewControlPlane()
+
+	// registering again, e.g. when resuming creation, updates the definition
+	for i := 0; i < 2; i++ {
+		if err := controlPlane.registerForwardProxy(client, 1); err != nil {
+			t.Fatalf("failed to register forward proxy: %s", err)
+		}
+	}
+
+	if count := server.Count(fakeapi.WorkloadDefinitions); count != 1 {
+		t.Errorf("expected 1 workload definition, got %d", count)
+	}
+	workloadDefinition, err := client.GetWorkloadDefinitionByName(threeport.ForwardProxyWorkloadDefinitionName)
+	if err != nil {
+		t.Fatalf("failed to get forward proxy workload definition: %s", err)
+	}
+	if workloadDefinition.UserID == nil || *workloadDefinition.UserID != 1 || workloadDefinition.YAMLDocument == nil {
+		t.Errorf("expected forward proxy workload definition with a manifest for user 1, got %+v", workloadDefinition)
+	}
+}
@@ -0,0 +1,172 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/threeport/tptctl/internal/install"
+)
+
+// CreateStep is a distinct step in the creation of a threeport control plane.
+// Completed steps are recorded so that an interrupted creation can be resumed.
+type CreateStep string
+
+const (
+	CreateStepResourceStack      CreateStep = "ResourceStack"
+	CreateStepKubeconfig         CreateStep = "Kubeconfig"
+	CreateStepSupportServices    CreateStep = "SupportServices"
+	CreateStepThreeportAPI       CreateStep = "ThreeportAPI"
+	CreateStepWorkloadController CreateStep = "WorkloadController"
+	CreateStepForwardProxy       CreateStep = "ForwardProxy"
+)
+
+// CreateState is a record of the progress made creating a threeport control
+// plane.  It is persisted to the provider config directory before the first
+// step and after each step so that creation can be resumed from the first
+// incomplete step.
+type CreateState struct {
+	Settings        CreateSettings `json:"settings"`
+	CompletedSteps  []CreateStep   `json:"completedSteps"`
+	LoadBalancerURL string         `json:"loadBalancerURL"`
+
+	path string
+}
+
+// CreateSettings are the settings a control plane is created with.  They are
+// recorded in the create state so that a resumed creation uses the same
+// settings as the one that was interrupted.
+type CreateSettings struct {
+	AWSRegion           string                     `json:"awsRegion"`
+	KubernetesVersion   string                     `json:"kubernetesVersion"`
+	AWSInstanceTypes    []string                   `json:"awsInstanceTypes"`
+	MinClusterNodes     int32                      `json:"minClusterNodes"`
+	MaxClusterNodes     int32                      `json:"maxClusterNodes"`
+	DesiredClusterNodes int32                      `json:"desiredClusterNodes"`
+	SpotInstances       bool                       `json:"spotInstances"`
+	ResourceTags        map[string]string          `json:"resourceTags"`
+	RootDomainName      string                     `json:"rootDomainName"`
+	ProviderAccountID   string                     `json:"providerAccountID"`
+	AdminEmail          string                     `json:"adminEmail"`
+	ForwardProxy        install.ForwardProxyConfig `json:"forwardProxy"`
+}
+
+// Completed returns true if the given step has already been completed.
+func (s *CreateState) Completed(step CreateStep) bool {
+	for _, completed := range s.CompletedSteps {
+		if completed == step {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Complete records the given step as completed and writes the state file.
+func (s *CreateState) Complete(step CreateStep) error {
+	if !s.Completed(step) {
+		s.CompletedSteps = append(s.CompletedSteps, step)
+	}
+
+	return s.write()
+}
+
+// write saves the state to its file in the provider config directory.
+func (s *CreateState) write() error {
+	stateJSON, err := json.MarshalIndent(s, "", " ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal create state to JSON: %w", err)
+	}
+	if err := ioutil.WriteFile(s.path, stateJSON, 0644); err != nil {
+		return fmt.Errorf("failed to write create state file: %w", err)
+	}
+
+	return nil
+}
+
+// remove deletes the state file.  A missing file is not an error.
+func (s *CreateState) remove() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove create state file: %w", err)
+	}
+
+	return nil
+}
+
+// createSettings returns the settings the control plane is created with.
+func (c *ControlPlane) createSettings() CreateSettings {
+	return CreateSettings{
+		AWSRegion:           c.AWSRegion,
+		KubernetesVersion:   c.KubernetesVersion,
+		AWSInstanceTypes:    c.instanceTypes(),
+		MinClusterNodes:     c.MinClusterNodes,
+		MaxClusterNodes:     c.MaxClusterNodes,
+		DesiredClusterNodes: c.DesiredClusterNodes,
+		SpotInstances:       c.SpotInstances,
+		ResourceTags:        c.ResourceTags,
+		RootDomainName:      c.RootDomainName,
+		ProviderAccountID:   c.ProviderAccountID,
+		AdminEmail:          c.AdminEmail,
+		ForwardProxy:        c.ForwardProxy,
+	}
+}
+
+// applyCreateSettings sets the control plane's settings to those recorded for
+// a previous creation.
+func (c *ControlPlane) applyCreateSettings(settings CreateSettings) {
+	c.AWSRegion = settings.AWSRegion
+	c.KubernetesVersion = settings.KubernetesVersion
+	c.AWSInstanceTypes = settings.AWSInstanceTypes
+	c.MinClusterNodes = settings.MinClusterNodes
+	c.MaxClusterNodes = settings.MaxClusterNodes
+	c.DesiredClusterNodes = settings.DesiredClusterNodes
+	c.SpotInstances = settings.SpotInstances
+	c.ResourceTags = settings.ResourceTags
+	c.RootDomainName = settings.RootDomainName
+	c.ProviderAccountID = settings.ProviderAccountID
+	c.AdminEmail = settings.AdminEmail
+	c.ForwardProxy = settings.ForwardProxy
+}
+
+// createState returns the state for creating the control plane.  If resume
+// is true the state is loaded from the existing state file and the control
+// plane's settings are replaced with those recorded so that creation continues
+// as it started.  Otherwise a new state with the control plane's settings is
+// written, overwriting any previous state.
+func (c *ControlPlane) createState(providerConfigDir string, resume bool) (*CreateState, error) {
+	state := CreateState{path: c.createStateFilePath(providerConfigDir)}
+	if !resume {
+		state.Settings = c.createSettings()
+		if err := state.write(); err != nil {
+			return nil, err
+		}
+		return &state, nil
+	}
+
+	stateJSON, err := ioutil.ReadFile(state.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New(fmt.Sprintf(
+				"no create state found for threeport instance %s - nothing to resume", c.InstanceName))
+		}
+		return nil, fmt.Errorf("failed to read create state file: %w", err)
+	}
+	if err := json.Unmarshal(stateJSON, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal create state file: %w", err)
+	}
+	c.applyCreateSettings(state.Settings)
+
+	return &state, nil
+}
+
+// createStateFilePath returns the filepath for the state file that records the
+// steps completed while creating a threeport control plane.
+func (c *ControlPlane) createStateFilePath(providerConfigDir string) string {
+	return filepath.Join(
+		providerConfigDir,
+		fmt.Sprintf("create-state-%s.json", c.ThreeportClusterName()),
+	)
+}
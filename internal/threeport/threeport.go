@@ -1,9 +1,29 @@
 package threeport
 
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+)
+
 const (
 	DefaultComputeClusterName          string = "default-threeport-compute-space"
 	DefaultComputeClusterRegion               = "local"
 	DefaultComputeClusterProvider             = "kind"
 	DefaultComputeClusterAPIEndpoint          = "kubernetes.default"
 	ForwardProxyWorkloadDefinitionName        = "forwardProxy"
+	DefaultSuperuserEmail                     = "admin@threeport.local"
 )
+
+// passwordLength is the number of random bytes in a generated password.
+const passwordLength = 24
+
+// GeneratePassword returns a random password for a Threeport user.
+func GeneratePassword() (string, error) {
+	randomBytes := make([]byte, passwordLength)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(randomBytes), nil
+}
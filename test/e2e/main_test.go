@@ -0,0 +1,91 @@
+//go:build e2e
+
+// Package e2e tests tptctl end to end against a Threeport control plane that
+// is created on kind through the same code path as `tptctl create
+// control-plane`.  The tests are behind the e2e build tag so that `go test
+// ./...` stays hermetic.  They need kind, kubectl and a container runtime:
+//
+//	make test-e2e
+//
+// The control plane is deleted when the tests finish unless TPTCTL_E2E_KEEP is
+// set, which is useful for investigating a failure.
+package e2e
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/threeport/tptctl/internal/api"
+	"github.com/threeport/tptctl/internal/install"
+	"github.com/threeport/tptctl/internal/provider"
+)
+
+const (
+	// instanceName is the name of the threeport instance the tests run
+	// against.
+	instanceName = "tptctl-e2e"
+
+	// keepEnv is the environment variable that keeps the control plane
+	// after the tests finish.
+	keepEnv = "TPTCTL_E2E_KEEP"
+)
+
+var (
+	// controlPlane is the control plane the tests run against.
+	controlPlane *provider.ControlPlane
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+// run creates the control plane, runs the tests and tears the control plane
+// down again.  It returns the exit code for the test binary.
+func run(m *testing.M) int {
+	for _, command := range []string{"kind", "kubectl"} {
+		if _, err := exec.LookPath(command); err != nil {
+			fmt.Fprintf(os.Stderr, "e2e tests need %s in PATH: %s\n", command, err)
+			return 1
+		}
+	}
+
+	providerConfigDir, err := os.MkdirTemp("", "tptctl-e2e-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create provider config directory: %s\n", err)
+		return 1
+	}
+	defer os.RemoveAll(providerConfigDir)
+
+	controlPlane = provider.NewControlPlane()
+	controlPlane.InstanceName = instanceName
+
+	// remove a control plane left behind by an earlier run, deleting a kind
+	// cluster that doesn't exist succeeds
+	if err := controlPlane.DeleteControlPlaneOnKind(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to delete existing control plane: %s\n", err)
+		return 1
+	}
+	if os.Getenv(keepEnv) == "" {
+		defer teardown()
+	}
+	if err := controlPlane.CreateControlPlaneOnKind(providerConfigDir); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create control plane on kind: %s\n", err)
+		return 1
+	}
+	install.SetThreeportAPIEndpoint(fmt.Sprintf("%s://%s:%s",
+		provider.KindThreeportAPIProtocol, provider.KindThreeportAPIHostname,
+		provider.KindThreeportAPIPort))
+	api.SetControlPlaneKubeconfig(controlPlane.KubeconfigFilePath(providerConfigDir))
+
+	return m.Run()
+}
+
+// teardown deletes the control plane.
+func teardown() {
+	if err := controlPlane.DeleteControlPlaneOnKind(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to delete control plane - delete kind cluster %s manually: %s\n",
+			controlPlane.ThreeportClusterName(), err)
+	}
+}
@@ -0,0 +1,159 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tpclient "github.com/threeport/threeport-go-client"
+	"gopkg.in/yaml.v2"
+
+	"github.com/threeport/tptctl/internal/api"
+	tperrors "github.com/threeport/tptctl/internal/errors"
+	"github.com/threeport/tptctl/internal/install"
+)
+
+const (
+	// sampleWorkloadConfigPath is the workload the tests deploy.
+	sampleWorkloadConfigPath = "../../sample/go-web3-workload.yaml"
+
+	// readyTimeout is how long a workload instance has to become ready,
+	// including pulling its images.
+	readyTimeout = time.Minute * 10
+
+	// updatedUpstreamPath is the upstream path the service dependency is
+	// updated to.
+	updatedUpstreamPath = "/eth/e2e"
+)
+
+// TestWorkloadLifecycle creates the sample workload, waits for its instance to
+// be ready, updates its service dependency and deletes it again.
+func TestWorkloadLifecycle(t *testing.T) {
+	workloadConfig := loadWorkloadConfig(t, sampleWorkloadConfigPath)
+
+	// create
+	if err := workloadConfig.Create(); err != nil {
+		t.Fatalf("failed to create workload: %s", err)
+	}
+	t.Cleanup(func() { deleteWorkload(t, workloadConfig) })
+	for _, wi := range workloadConfig.WorkloadInstances {
+		waitForReady(t, wi.Name)
+	}
+
+	// update
+	workloadConfig.WorkloadServiceDependencies[0].UpstreamPath = updatedUpstreamPath
+	outcomes, err := workloadConfig.Update(func(string) bool { return true })
+	if err != nil {
+		t.Fatalf("failed to update workload: %s", err)
+	}
+	for _, outcome := range outcomes {
+		expected := api.OutcomeUnchanged
+		if outcome.Object == "workload service dependency" {
+			expected = api.OutcomeUpdated
+		}
+		if outcome.Outcome != expected {
+			t.Errorf("expected %s %s to be %s, got %s", outcome.Object, outcome.Name, expected, outcome.Outcome)
+		}
+	}
+	wsd, err := tpclient.GetWorkloadServiceDependencyByName(
+		workloadConfig.WorkloadServiceDependencies[0].Name, install.GetThreeportAPIEndpoint(), "")
+	if err != nil {
+		t.Fatalf("failed to get workload service dependency: %s", err)
+	}
+	if wsd.UpstreamPath == nil || *wsd.UpstreamPath != updatedUpstreamPath {
+		t.Errorf("expected upstream path %s after update, got %v", updatedUpstreamPath, wsd.UpstreamPath)
+	}
+
+	// delete
+	deleteWorkload(t, workloadConfig)
+	workloadDefinitions, err := tpclient.GetWorkloadDefinitions(install.GetThreeportAPIEndpoint(), "")
+	if err != nil {
+		t.Fatalf("failed to get workload definitions: %s", err)
+	}
+	for _, wd := range *workloadDefinitions {
+		if wd.Name != nil && *wd.Name == workloadConfig.WorkloadDefinition.Name {
+			t.Errorf("workload definition %s still exists after delete", *wd.Name)
+		}
+	}
+}
+
+// loadWorkloadConfig reads a workload config the way `tptctl create workload`
+// does.  The definition is created for the superuser of the control plane.
+func loadWorkloadConfig(t *testing.T, path string) *api.WorkloadConfig {
+	t.Helper()
+
+	configContent, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read workload config: %s", err)
+	}
+	var workloadConfig api.WorkloadConfig
+	if err := yaml.Unmarshal(configContent, &workloadConfig); err != nil {
+		t.Fatalf("failed to unmarshal workload config: %s", err)
+	}
+	workloadConfig.WorkloadDefinition.ConfigDir = filepath.Dir(path)
+	workloadConfig.WorkloadDefinition.UserID = controlPlane.Superuser.ID
+	if err := workloadConfig.Resolve(); err != nil {
+		t.Fatalf("failed to resolve workload config: %s", err)
+	}
+
+	return &workloadConfig
+}
+
+// waitForReady waits for a workload instance to be ready the way `tptctl wait
+// workload-instance` does.  The rollout is checked with the kubeconfig for the
+// kind cluster as the default workload cluster is registered with its
+// in-cluster API endpoint, which can't be reached from outside the cluster.
+func waitForReady(t *testing.T, name string) {
+	t.Helper()
+
+	err := api.WaitForWorkloadInstance(context.Background(), name, api.WaitConditionReady, readyTimeout)
+	if err != nil {
+		t.Fatalf("workload instance %s not ready: %s", name, err)
+	}
+}
+
+// deleteWorkload deletes the objects of a workload that still exist in the
+// reverse order to which they were created.  The instances are deleted the way
+// `tptctl delete workload-instance` does and the workload definition is only
+// deleted once the workload controller has removed them.
+func deleteWorkload(t *testing.T, workloadConfig *api.WorkloadConfig) {
+	t.Helper()
+	apiEndpoint := install.GetThreeportAPIEndpoint()
+
+	for _, wsdc := range workloadConfig.WorkloadServiceDependencies {
+		wsd, err := tpclient.GetWorkloadServiceDependencyByName(wsdc.Name, apiEndpoint, "")
+		if err != nil || wsd.ID == nil {
+			continue
+		}
+		if _, err := tpclient.DeleteWorkloadServiceDependency(*wsd.ID, apiEndpoint, ""); err != nil {
+			t.Errorf("failed to delete workload service dependency %s: %s", wsdc.Name, err)
+		}
+	}
+
+	for _, wic := range workloadConfig.WorkloadInstances {
+		_, err := api.DeleteWorkloadInstance(context.Background(), wic.Name, readyTimeout)
+		if tperrors.Is(err, tperrors.KindNotFound) {
+			continue
+		}
+		if err != nil {
+			t.Errorf("failed to delete workload instance %s: %s", wic.Name, err)
+			continue
+		}
+		err = api.WaitForWorkloadInstance(context.Background(), wic.Name, api.WaitConditionDeleted, readyTimeout)
+		if err != nil {
+			t.Errorf("workload instance %s was not deleted: %s", wic.Name, err)
+		}
+	}
+
+	wd, err := tpclient.GetWorkloadDefinitionByName(workloadConfig.WorkloadDefinition.Name, apiEndpoint, "")
+	if err != nil || wd.ID == nil {
+		return
+	}
+	if _, err := tpclient.DeleteWorkloadDefinition(*wd.ID, apiEndpoint, ""); err != nil {
+		t.Errorf("failed to delete workload definition %s: %s", workloadConfig.WorkloadDefinition.Name, err)
+	}
+}